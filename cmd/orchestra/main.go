@@ -93,7 +93,9 @@ func printExitSummary(sessionID string) {
 	fmt.Printf("Continue  orchestra -s %s\n", sessionID)
 }
 
-func bootstrapRuntime(cfg *config.Config, mode, resumeSessionID string) (*runtimeDeps, error) {
+// bootstrapRuntime wires the databases, session and agents for cfg. The state
+// database is stateDB, or the one in the resolved state dir when it is empty.
+func bootstrapRuntime(cfg *config.Config, mode, resumeSessionID, stateDB string) (*runtimeDeps, error) {
 	if cfg == nil {
 		return nil, errors.New("config is nil")
 	}
@@ -106,7 +108,11 @@ func bootstrapRuntime(cfg *config.Config, mode, resumeSessionID string) (*runtim
 	rt := &runtimeDeps{}
 	rt.ctx, rt.cancel = context.WithCancel(context.Background())
 
-	db, err := state.Connect("orchestra.db")
+	loc := orchestracli.StateLocation(cfg)
+	if strings.TrimSpace(stateDB) == "" {
+		stateDB = loc.StateDB
+	}
+	db, err := state.Connect(stateDB)
 	if err != nil {
 		rt.Close()
 		return nil, err
@@ -140,9 +146,6 @@ func bootstrapRuntime(cfg *config.Config, mode, resumeSessionID string) (*runtim
 	}
 	rt.session = session
 
-	startupCtx, cancelStartup := context.WithTimeout(rt.ctx, 5*time.Second)
-	defer cancelStartup()
-
 	disableRAG := func(reason string, err error) {
 		fmt.Fprintf(os.Stderr, "warning: %s, running without RAG: %v\n", reason, err)
 		if rt.ragStore != nil {
@@ -153,27 +156,22 @@ func bootstrapRuntime(cfg *config.Config, mode, resumeSessionID string) (*runtim
 	}
 
 	if cfg.RAG.Enabled {
-		rt.ragStore, err = rag.NewStore("orchestra_vec.db")
+		rt.ragStore, err = rag.NewStore(loc.RAGDB)
 		if err != nil {
 			disableRAG("failed to initialize rag store", err)
+		} else if embedder, err := orchestracli.NewEmbeddingProvider(cfg); err != nil {
+			disableRAG("invalid rag embedder config", err)
 		} else {
-			embedder := rag.NewEmbedder(cfg.RAG.OllamaURL, cfg.RAG.Embedder)
-			if err := embedder.EnsureReady(startupCtx); err != nil {
-				disableRAG("failed to initialize rag embedder", err)
-			} else {
-				rt.indexer = rag.NewIndexer(rt.ragStore, embedder, workingDir)
-				if err := rt.indexer.Start(rt.ctx); err != nil {
-					disableRAG("failed to start rag indexer", err)
-				}
+			// An unreachable embedder leaves the indexer in keyword-only mode
+			// rather than failing here.
+			rt.indexer = rag.NewIndexer(rt.ragStore, embedder, workingDir)
+			rt.indexer.Chunking = rag.ChunkOptions{Size: cfg.RAG.ChunkSize, Overlap: cfg.RAG.ChunkOverlap}
+			if err := rt.indexer.Start(rt.ctx); err != nil {
+				disableRAG("failed to start rag indexer", err)
 			}
 		}
 	}
 
-	projectBrief, err := rag.BuildProjectBrief(workingDir)
-	if err != nil {
-		projectBrief = fmt.Sprintf("Working directory: %s", workingDir)
-	}
-
 	provider := providers.NewAnthropic()
 
 	planner := agent.NewAgent(agent.RolePlanner, cfg.Providers.Anthropic.DefaultModel, provider, rt.ragStore, rt.indexer)
@@ -195,13 +193,111 @@ func bootstrapRuntime(cfg *config.Config, mode, resumeSessionID string) (*runtim
 		UpdateChan:       make(chan agent.StepUpdate, 100),
 		EventChan:        make(chan agent.AgentEvent, 200),
 		PlanApprovalChan: make(chan agent.PlanApproval, 4),
+		DiffReviewChan:   make(chan agent.DiffReview, 4),
+		ReviewWrites:     cfg.Review.StageWrites,
 		WorkingDir:       workingDir,
-		ProjectBrief:     projectBrief,
+		BriefTokens:      cfg.RAG.BriefTokens,
+		Git: agent.GitOptions{
+			Enabled:    cfg.Git.Enabled,
+			StashDirty: strings.EqualFold(strings.TrimSpace(cfg.Git.OnDirty), "stash"),
+			Worktrees:  cfg.Git.Worktrees,
+		},
+		VerifyCommands: cfg.Verify.Commands,
+		VerifyTimeout:  time.Duration(cfg.Verify.TimeoutSeconds) * time.Second,
 	}
 
 	return rt, nil
 }
 
+// promptCommandWords builds the words a prompt command is rendered from: the
+// positional words as the shell split them, then each name=value from --arg.
+func promptCommandWords(words, named []string) ([]string, error) {
+	out := append([]string(nil), words...)
+	for _, kv := range named {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid --arg %q, expected name=value", kv)
+		}
+		out = append(out, strings.TrimSpace(name)+"="+value)
+	}
+	return out, nil
+}
+
+// runHeadless runs one prompt, or resumes a saved run, without the TUI.
+// Plans are approved as they are and writes are applied directly; progress
+// goes to stderr and the agents' replies to stdout.
+func runHeadless(rt *runtimeDeps, run func(context.Context, *agent.Orchestrator) error) error {
+	orc := rt.orchestrator
+	orc.PlanApprovalChan = nil
+	orc.DiffReviewChan = nil
+	orc.ReviewWrites = false
+
+	ctx, stop := signal.NotifyContext(rt.ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- run(ctx, orc)
+	}()
+
+	streaming := agent.Role("")
+	for {
+		select {
+		case update := <-orc.UpdateChan:
+			if streaming != "" {
+				fmt.Println()
+				streaming = ""
+			}
+			fmt.Fprintf(os.Stderr, "[%s] %s: %s\n", update.StepID, update.Status, update.Msg)
+		case event := <-orc.EventChan:
+			payload, _ := event.Payload.(map[string]any)
+			if token, ok := payload["token"].(string); ok && event.Type == agent.EventThinking {
+				if streaming != event.Role {
+					if streaming != "" {
+						fmt.Println()
+					}
+					fmt.Printf("%s: ", strings.ToUpper(string(event.Role)))
+					streaming = event.Role
+				}
+				fmt.Print(token)
+			}
+		case err := <-done:
+			if streaming != "" {
+				fmt.Println()
+			}
+			if runID := orc.LastRunID(); runID != "" && (agent.IsUserCancelled(err) || errors.Is(err, agent.ErrRunPaused)) {
+				fmt.Fprintf(os.Stderr, "continue with: orchestra resume-run %s\n", runID)
+			}
+			if agent.IsUserCancelled(err) {
+				return errors.New("run cancelled")
+			}
+			return err
+		}
+	}
+}
+
+// printResumableRuns lists the saved runs that did not complete, most
+// recently active first.
+func printResumableRuns(ctx context.Context, db *state.DB) error {
+	runs, err := db.ListResumableRuns(ctx)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		fmt.Println("No runs to resume.")
+		return nil
+	}
+	for _, run := range runs {
+		status := run.Status
+		if run.Interrupted(time.Now()) {
+			status = "interrupted"
+		}
+		fmt.Printf("%s  %-11s  %d/%d tasks  %s\n", run.ID, status, run.DoneTasks(), len(run.Tasks), agent.PromptPreview(run.Prompt))
+	}
+	fmt.Println("Continue one with: orchestra resume-run <run-id>")
+	return nil
+}
+
 func main() {
 	var orchestrate bool
 	var resumeSessionID string
@@ -220,13 +316,22 @@ func main() {
 				mode = "orchestrated"
 			}
 
-			rt, err := bootstrapRuntime(cfg, mode, resumeSessionID)
+			rt, err := bootstrapRuntime(cfg, mode, resumeSessionID, "")
 			if err != nil {
 				return err
 			}
 			defer rt.Close()
 
+			theme, err := tui.LoadTheme(cfg.TUI.Theme, cfg.TUI.Colors)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+			}
+			tui.ApplyTheme(theme)
+
 			app := tui.NewAppModel(cfg, rt.db, rt.session, rt.orchestrator)
+			if rt.indexer != nil {
+				app.SetIndexProgress(rt.indexer.Progress)
+			}
 			p := tea.NewProgram(app, tea.WithAltScreen(), tea.WithMouseCellMotion(), tea.WithContext(rt.ctx))
 			_, err = p.Run()
 			if err != nil {
@@ -237,6 +342,25 @@ func main() {
 		},
 	}
 
+	// Every command resolves its databases the same way, so move the ones
+	// older releases left in the current directory before any of them runs.
+	// A database passed with --db or --rag-db is the one the command opens,
+	// so it is never moved away from under it.
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			// Commands that need the config report the error themselves.
+			return nil
+		}
+		var inUse []string
+		for _, name := range []string{"db", "rag-db"} {
+			if flag := cmd.Flags().Lookup(name); flag != nil && flag.Changed {
+				inUse = append(inUse, flag.Value.String())
+			}
+		}
+		_, err = orchestracli.PrepareStateLocation(cfg, inUse...)
+		return err
+	}
 	rootCmd.Flags().BoolVar(&orchestrate, "orchestrate", false, "Launch TUI in Orchestrated mode")
 	rootCmd.Flags().StringVarP(&resumeSessionID, "session", "s", "", "Resume an existing session ID")
 
@@ -265,7 +389,7 @@ func main() {
 				return err
 			}
 
-			rt, err := bootstrapRuntime(cfg, "orchestrated", headlessSession)
+			rt, err := bootstrapRuntime(cfg, "orchestrated", headlessSession, "")
 			if err != nil {
 				return err
 			}
@@ -291,6 +415,121 @@ func main() {
 	serveCmd.Flags().StringVar(&headlessSession, "session", "", "Session Name")
 	serveCmd.Flags().BoolVar(&headlessMode, "headless", true, "Headless mode")
 
+	var runCommand string
+	var runArgs []string
+	var runSession string
+	runCmd := &cobra.Command{
+		Use:   "run [prompt]",
+		Short: "Run one prompt or prompt command without the TUI",
+		Long: "Run one prompt without the TUI, or with --command a prompt command from\n" +
+			".orchestra/commands or ~/.config/orchestra/commands. Positional words and\n" +
+			"--arg name=value fill the command's arguments. Plans are approved\n" +
+			"automatically.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			prompt := strings.TrimSpace(strings.Join(args, " "))
+			var overrides agent.RunOverrides
+			if name := strings.TrimPrefix(strings.TrimSpace(runCommand), "/"); name != "" {
+				workingDir := cfg.Defaults.WorkingDir
+				commands, errs := agent.LoadPromptCommands(workingDir, agent.UserCommandsDir())
+				for _, err := range errs {
+					fmt.Fprintf(os.Stderr, "warning: skipped prompt command: %v\n", err)
+				}
+				var found *agent.PromptCommand
+				for idx := range commands {
+					if commands[idx].Name == name {
+						found = &commands[idx]
+						break
+					}
+				}
+				if found == nil {
+					return fmt.Errorf("prompt command %q not found", name)
+				}
+				words, err := promptCommandWords(args, runArgs)
+				if err != nil {
+					return err
+				}
+				if prompt, err = found.RenderWords(words); err != nil {
+					return err
+				}
+				overrides = found.Overrides
+			} else if len(runArgs) > 0 {
+				return errors.New("--arg requires --command")
+			}
+			if prompt == "" {
+				return errors.New("nothing to run: pass a prompt or --command")
+			}
+
+			rt, err := bootstrapRuntime(cfg, cfg.Defaults.Mode, runSession, "")
+			if err != nil {
+				return err
+			}
+			defer rt.Close()
+			err = runHeadless(rt, func(ctx context.Context, orc *agent.Orchestrator) error {
+				return orc.RunWithOverrides(ctx, prompt, overrides)
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "session: %s\n", rt.session.ID)
+			return nil
+		},
+	}
+	runCmd.Flags().StringVar(&runCommand, "command", "", "Prompt command to run, e.g. write-tests")
+	runCmd.Flags().StringArrayVar(&runArgs, "arg", nil, "Prompt command argument as name=value (repeatable)")
+	runCmd.Flags().StringVarP(&runSession, "session", "s", "", "Run in an existing session ID")
+
+	var resumeDBPath string
+	resumeRunCmd := &cobra.Command{
+		Use:   "resume-run [run-id]",
+		Short: "Continue an interrupted, paused or cancelled run without the TUI",
+		Long: "Continue a saved run from its first unfinished task, skipping the tasks\n" +
+			"it already completed. Without a run ID, lists the runs that can be resumed.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(resumeDBPath) == "" {
+				resumeDBPath = orchestracli.StateLocation(cfg).StateDB
+			}
+			db, err := state.Connect(resumeDBPath)
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				defer db.Close()
+				return printResumableRuns(cmd.Context(), db)
+			}
+			run, err := db.GetRun(cmd.Context(), args[0])
+			_ = db.Close()
+			if err != nil {
+				return err
+			}
+
+			rt, err := bootstrapRuntime(cfg, cfg.Defaults.Mode, run.SessionID, resumeDBPath)
+			if err != nil {
+				return err
+			}
+			defer rt.Close()
+			err = runHeadless(rt, func(ctx context.Context, orc *agent.Orchestrator) error {
+				return orc.ResumeRun(ctx, run.ID)
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "session: %s\n", rt.session.ID)
+			return nil
+		},
+	}
+
+	resumeRunCmd.Flags().StringVar(&resumeDBPath, "db", "", "Path to SQLite state database (default: the project's state dir)")
+
 	mapCmd := &cobra.Command{
 		Use:   "map [path]",
 		Short: "Runs Analyst on path, outputs FeatureReport.md",
@@ -313,14 +552,18 @@ func main() {
 	rootCmd.AddCommand(
 		configCmd,
 		serveCmd,
+		runCmd,
+		resumeRunCmd,
 		orchestracli.NewAttachCmd(),
 		orchestracli.NewAuthCmd(),
 		orchestracli.NewMCPCmd(),
 		orchestracli.NewStatsCmd(),
 		orchestracli.NewSessionCmd(),
 		orchestracli.NewDBCmd(),
+		orchestracli.NewIndexCmd(),
 		orchestracli.NewExportCmd(),
 		orchestracli.NewImportCmd(),
+		orchestracli.NewRewindCmd(),
 		orchestracli.NewAgentCmd(),
 		orchestracli.NewPRCmd(),
 		orchestracli.NewModelsCmd(),
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/yubzen/orchestra/internal/mcp"
 	"github.com/yubzen/orchestra/internal/providers"
@@ -39,6 +40,15 @@ type RunOptions struct {
 	Mode       DispatchMode
 	OnToken    func(token string)
 	OnToolCall func(name string, params map[string]any, result ToolResult, err error)
+	// RunID and TaskID tag the persisted tool-call log.
+	RunID  string
+	TaskID string
+	// TraceParent is the trace span LLM calls are recorded under; 0
+	// disables tracing.
+	TraceParent int64
+	// Steer returns steering notes queued since the last call. They are
+	// added to the conversation before the next model call.
+	Steer func(role Role) []string
 }
 
 var ErrAgentNotReady = errors.New("agent is not initialized")
@@ -47,6 +57,7 @@ func NewAgent(role Role, model string, provider providers.Provider, store *rag.S
 	toolSet := DefaultToolSetForRole(role, ToolEnv{
 		WorkingDir: ".",
 		Role:       role,
+		Index:      indexer,
 	})
 	basePrompt := strings.TrimSpace(LoadSystemPrompt(string(role)))
 	taskPrompt := buildTaskSystemPrompt(role, basePrompt)
@@ -101,6 +112,9 @@ func (a *Agent) BindToolSet(env ToolEnv) {
 		return
 	}
 	env.Role = a.Role
+	if env.Index == nil {
+		env.Index = a.Indexer
+	}
 	a.ToolSet = DefaultToolSetForRole(a.Role, env)
 	a.AllowedTools = a.ToolSet.Names()
 }
@@ -174,7 +188,7 @@ func (a *Agent) RunWithOptions(ctx context.Context, userPrompt string, session *
 
 	var ragContext string
 	if dispatchMode == DispatchModeTask && a.Indexer != nil {
-		chunks, err := a.Indexer.Query(ctx, userPrompt)
+		chunks, err := a.Indexer.Query(ctx, userPrompt, rag.QueryOptions{})
 		if err != nil {
 			err = normalizeCancellationErr(err)
 			if IsUserCancelled(err) {
@@ -185,7 +199,11 @@ func (a *Agent) RunWithOptions(ctx context.Context, userPrompt string, session *
 			var sb strings.Builder
 			sb.WriteString("Relevant codebase context:\n")
 			for _, chunk := range chunks {
-				sb.WriteString(fmt.Sprintf("---\nFile: %s\n%s\n", chunk.Filepath, mcp.Clean(chunk.Content)))
+				header := chunk.Location()
+				if chunk.Symbol != "" {
+					header += " (" + chunk.Symbol + ")"
+				}
+				sb.WriteString(fmt.Sprintf("---\nFile: %s\n%s\n", header, mcp.Clean(chunk.Content)))
 			}
 			ragContext = sb.String()
 		}
@@ -242,11 +260,23 @@ func (a *Agent) RunWithOptions(ctx context.Context, userPrompt string, session *
 	const maxIterations = 25
 	var finalText string
 
+	traced := 0
 	for iteration := 0; iteration < maxIterations; iteration++ {
 		if err := checkContextCancelled(ctx); err != nil {
 			return "", err
 		}
+		if options.Steer != nil {
+			if notes := options.Steer(a.Role); len(notes) > 0 {
+				messages = append(messages, providers.Message{
+					Role:    "user",
+					Content: mcp.Clean(renderSteering(notes)),
+				})
+			}
+		}
+		llmSpan := a.startLLMSpan(ctx, db, session, options, iteration, messages[traced:])
+		traced = len(messages)
 		response, err := a.Provider.Complete(ctx, a.Model, messages, pTools, options.OnToken)
+		finishTraceSpan(ctx, db, llmSpan, renderTraceResponse(response), err)
 		if err != nil {
 			err = normalizeCancellationErr(err)
 			if IsUserCancelled(err) {
@@ -278,9 +308,15 @@ func (a *Agent) RunWithOptions(ctx context.Context, userPrompt string, session *
 				if options.OnToolCall != nil {
 					options.OnToolCall(tc.Name, nil, ToolResult{}, parseErr)
 				}
+				a.recordToolCall(ctx, db, session, options, iteration, tc, ToolResult{}, parseErr, 0)
+				finishTraceSpan(ctx, db, a.startToolSpan(ctx, db, session, options, llmSpan, tc), "", parseErr)
 				resultContent = fmt.Sprintf("error: invalid tool arguments for %s: %v", strings.TrimSpace(tc.Name), parseErr)
 			} else {
+				toolSpan := a.startToolSpan(ctx, db, session, options, llmSpan, tc)
+				start := time.Now()
 				result, execErr := a.ExecuteTool(ctx, tc.Name, params)
+				a.recordToolCall(ctx, db, session, options, iteration, tc, result, execErr, time.Since(start))
+				finishTraceSpan(ctx, db, toolSpan, result.Output, execErr)
 				if options.OnToolCall != nil {
 					options.OnToolCall(tc.Name, params, result, execErr)
 				}
@@ -328,6 +364,63 @@ func (a *Agent) RunWithOptions(ctx context.Context, userPrompt string, session *
 	return finalText, nil
 }
 
+// recordToolCall persists one tool invocation for auditing, with secrets
+// scrubbed the same way as prompts. Save failures are ignored so that
+// logging never breaks a run.
+func (a *Agent) recordToolCall(ctx context.Context, db *state.DB, session *state.Session, options RunOptions, iteration int, tc providers.ToolCall, result ToolResult, err error, duration time.Duration) {
+	if db == nil || session == nil {
+		return
+	}
+	call := state.ToolCall{
+		SessionID: session.ID,
+		RunID:     options.RunID,
+		TaskID:    options.TaskID,
+		Role:      string(a.Role),
+		Tool:      tc.Name,
+		Args:      mcp.Clean(strings.TrimSpace(string(tc.Arguments))),
+		Result:    mcp.Clean(result.Output),
+		Duration:  duration,
+		Iteration: iteration + 1,
+	}
+	if err != nil {
+		call.Error = err.Error()
+	}
+	_ = db.SaveToolCall(context.WithoutCancel(ctx), call)
+}
+
+// startLLMSpan traces one provider call under options.TraceParent. Its
+// input is the messages added since the previous call, so the first call
+// carries the full prompt and later ones the tool results it acted on.
+func (a *Agent) startLLMSpan(ctx context.Context, db *state.DB, session *state.Session, options RunOptions, iteration int, messages []providers.Message) int64 {
+	if options.TraceParent <= 0 {
+		return 0
+	}
+	return startTraceSpan(ctx, db, session, state.TraceSpan{
+		ParentID: options.TraceParent,
+		RunID:    options.RunID,
+		Kind:     state.SpanKindLLM,
+		Name:     fmt.Sprintf("%s call %d", a.Model, iteration+1),
+		Role:     string(a.Role),
+		TaskID:   options.TaskID,
+		Input:    mcp.Clean(renderTraceMessages(messages)),
+	})
+}
+
+func (a *Agent) startToolSpan(ctx context.Context, db *state.DB, session *state.Session, options RunOptions, llmSpan int64, tc providers.ToolCall) int64 {
+	if llmSpan <= 0 {
+		return 0
+	}
+	return startTraceSpan(ctx, db, session, state.TraceSpan{
+		ParentID: llmSpan,
+		RunID:    options.RunID,
+		Kind:     state.SpanKindTool,
+		Name:     tc.Name,
+		Role:     string(a.Role),
+		TaskID:   options.TaskID,
+		Input:    mcp.Clean(strings.TrimSpace(string(tc.Arguments))),
+	})
+}
+
 func parseToolArguments(raw json.RawMessage) (map[string]any, error) {
 	if len(raw) == 0 {
 		return map[string]any{}, nil
@@ -1,8 +1,13 @@
 package agent
 
 import (
+	"context"
 	"encoding/json"
+	"strings"
 	"testing"
+
+	"github.com/yubzen/orchestra/internal/providers"
+	"github.com/yubzen/orchestra/internal/state"
 )
 
 func TestParseToolArgumentsSupportsJSONStringPayload(t *testing.T) {
@@ -23,3 +28,37 @@ func TestParseToolArgumentsSupportsJSONStringPayload(t *testing.T) {
 		t.Fatalf("expected content hello, got %#v", got)
 	}
 }
+
+func TestAgentRunPersistsToolCalls(t *testing.T) {
+	t.Parallel()
+	workDir := t.TempDir()
+	orc := newCheckpointTestOrchestrator(t, workDir)
+	orc.Planner = newTestAgent(RolePlanner, &scriptedToolProvider{calls: []providers.ToolCall{
+		toolCall(t, "tc-1", "write_file", map[string]any{"path": "hamid.ts", "content": "export const hamid = 1"}),
+		toolCall(t, "tc-2", "read_file", map[string]any{"path": "missing.ts"}),
+	}})
+	orc.UpdateChan = make(chan StepUpdate, 64)
+	orc.ProjectBrief = "Working directory: ."
+	orc.Session.ExecutionMode = state.ExecutionModeFast
+
+	if err := orc.Run(context.Background(), "create hamid.ts"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	calls, err := orc.DB.ListToolCalls(context.Background(), orc.Session.ID, "")
+	if err != nil {
+		t.Fatalf("list tool calls: %v", err)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 tool calls, got %+v", calls)
+	}
+	write, read := calls[0], calls[1]
+	if write.Tool != "write_file" || write.Role != string(RolePlanner) || write.TaskID != "task-1" || write.RunID == "" {
+		t.Fatalf("unexpected write_file record: %+v", write)
+	}
+	if write.Iteration != 1 || write.Error != "" || !strings.Contains(write.Args, "hamid.ts") {
+		t.Fatalf("unexpected write_file record: %+v", write)
+	}
+	if read.Tool != "read_file" || read.Error == "" {
+		t.Fatalf("expected failed read_file to be recorded with its error, got %+v", read)
+	}
+}
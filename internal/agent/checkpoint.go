@@ -0,0 +1,231 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yubzen/orchestra/internal/state"
+)
+
+var (
+	ErrNoCheckpoints      = errors.New("no file checkpoints recorded")
+	ErrCheckpointConflict = errors.New("files changed outside orchestra since checkpoint")
+)
+
+type RewindOptions struct {
+	RunID    string
+	TaskID   string
+	LastTask bool
+	Force    bool
+}
+
+type RewindResult struct {
+	RunID     string
+	TaskID    string
+	Restored  []string
+	Removed   []string
+	Conflicts []string
+}
+
+// RewindFiles restores files written by orchestra to their content before the
+// selected run or task. Files modified since the snapshot are reported as
+// conflicts and left untouched unless opts.Force is set.
+func RewindFiles(ctx context.Context, db *state.DB, workingDir, sessionID string, opts RewindOptions) (RewindResult, error) {
+	if db == nil {
+		return RewindResult{}, errors.New("state db is not available")
+	}
+	sessionID = strings.TrimSpace(sessionID)
+	if sessionID == "" {
+		return RewindResult{}, errors.New("session id is empty")
+	}
+
+	runID := strings.TrimSpace(opts.RunID)
+	taskID := strings.TrimSpace(opts.TaskID)
+	if runID == "" {
+		latest, err := db.LatestCheckpoint(ctx, sessionID, taskID)
+		if err != nil {
+			return RewindResult{}, err
+		}
+		if latest == nil {
+			return RewindResult{}, ErrNoCheckpoints
+		}
+		runID = latest.RunID
+		if opts.LastTask && taskID == "" {
+			taskID = latest.TaskID
+		}
+	}
+
+	checkpoints, err := db.ListFileCheckpoints(ctx, sessionID, runID, taskID)
+	if err != nil {
+		return RewindResult{}, err
+	}
+	if len(checkpoints) == 0 {
+		return RewindResult{}, ErrNoCheckpoints
+	}
+
+	type fileHistory struct {
+		first state.FileCheckpoint
+		last  state.FileCheckpoint
+	}
+	byPath := make(map[string]*fileHistory)
+	ids := make([]int64, 0, len(checkpoints))
+	for _, cp := range checkpoints {
+		ids = append(ids, cp.ID)
+		if h, ok := byPath[cp.Path]; ok {
+			h.last = cp
+			continue
+		}
+		byPath[cp.Path] = &fileHistory{first: cp, last: cp}
+	}
+	paths := make([]string, 0, len(byPath))
+	for path := range byPath {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	result := RewindResult{RunID: runID, TaskID: taskID}
+	root := effectiveWorkingDir(workingDir)
+	for _, path := range paths {
+		absPath, _, err := resolveWorkspacePath(root, path)
+		if err != nil {
+			return result, err
+		}
+		current, err := fileContentHash(absPath)
+		if err != nil {
+			return result, err
+		}
+		if current != byPath[path].last.AfterHash {
+			result.Conflicts = append(result.Conflicts, path)
+		}
+	}
+	if len(result.Conflicts) > 0 && !opts.Force {
+		return result, fmt.Errorf("%w: %s", ErrCheckpointConflict, strings.Join(result.Conflicts, ", "))
+	}
+
+	for _, path := range paths {
+		if err := checkContextCancelled(ctx); err != nil {
+			return result, err
+		}
+		original := byPath[path].first
+		absPath, _, err := resolveWorkspacePath(root, path)
+		if err != nil {
+			return result, err
+		}
+		if !original.Existed {
+			if err := os.Remove(absPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return result, err
+			}
+			result.Removed = append(result.Removed, path)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(absPath), 0o755); err != nil {
+			return result, err
+		}
+		// WriteFile only applies the mode to new files, so an existing file
+		// whose mode changed since is set back explicitly.
+		mode := original.Mode.Perm()
+		if mode == 0 {
+			mode = 0o644
+		}
+		if err := os.WriteFile(absPath, original.Before, mode); err != nil {
+			return result, err
+		}
+		if err := os.Chmod(absPath, mode); err != nil {
+			return result, err
+		}
+		result.Restored = append(result.Restored, path)
+	}
+
+	if err := db.DeleteFileCheckpoints(ctx, ids); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+func (o *Orchestrator) beginCheckpointRun() {
+	if o == nil {
+		return
+	}
+	o.checkpointMu.Lock()
+	o.checkpointRunID = "run_" + time.Now().UTC().Format("20060102_150405.000000")
+	o.checkpointTaskID = ""
+	o.traceStack = nil
+	o.lastRunID = ""
+	o.checkpointMu.Unlock()
+}
+
+func (o *Orchestrator) setCheckpointTask(taskID string) {
+	if o == nil {
+		return
+	}
+	o.checkpointMu.Lock()
+	o.checkpointTaskID = strings.TrimSpace(taskID)
+	o.checkpointMu.Unlock()
+}
+
+// runScope returns the current run and task IDs, which also tag the
+// persisted tool-call log.
+func (o *Orchestrator) runScope() (string, string) {
+	if o == nil {
+		return "", ""
+	}
+	o.checkpointMu.Lock()
+	defer o.checkpointMu.Unlock()
+	return o.checkpointRunID, o.checkpointTaskID
+}
+
+func (o *Orchestrator) withRunScope(options RunOptions) RunOptions {
+	options.RunID, options.TaskID = o.runScope()
+	options.TraceParent = o.traceParent()
+	options.Steer = o.deliverSteering
+	return options
+}
+
+func (o *Orchestrator) recordCheckpoint(relPath string, existed bool, mode os.FileMode, before, after []byte) {
+	if o == nil || o.DB == nil || o.Session == nil {
+		return
+	}
+	runID, taskID := o.runScope()
+	if runID == "" {
+		return
+	}
+	err := o.DB.SaveFileCheckpoint(context.Background(), state.FileCheckpoint{
+		SessionID: o.Session.ID,
+		RunID:     runID,
+		TaskID:    taskID,
+		Path:      relPath,
+		Existed:   existed,
+		Mode:      mode,
+		Before:    before,
+		AfterHash: contentHash(after),
+	})
+	if err != nil {
+		o.emitEvent(AgentEvent{Type: EventError, Role: RolePlanner, Detail: fmt.Sprintf("checkpoint %s: %v", relPath, err)})
+	}
+}
+
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// fileContentHash returns an empty hash for missing files so that a deleted
+// file never matches a recorded post-write hash.
+func fileContentHash(absPath string) (string, error) {
+	content, err := os.ReadFile(absPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return contentHash(content), nil
+}
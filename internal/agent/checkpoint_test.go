@@ -0,0 +1,169 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yubzen/orchestra/internal/state"
+)
+
+func newCheckpointTestOrchestrator(t *testing.T, workDir string) *Orchestrator {
+	t.Helper()
+	db, err := state.Connect(":memory:")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	session, err := db.CreateSession(context.Background(), workDir, "orchestrated")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	return &Orchestrator{DB: db, Session: session, WorkingDir: workDir}
+}
+
+func TestRewindFilesRestoresRunAndTask(t *testing.T) {
+	t.Parallel()
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatalf("seed file: %v", err)
+	}
+
+	orc := newCheckpointTestOrchestrator(t, workDir)
+	tool := newWriteFileTool(ToolEnv{WorkingDir: workDir, Role: RoleCoder, Checkpoint: orc.recordCheckpoint})
+	ctx := context.Background()
+
+	orc.beginCheckpointRun()
+	orc.setCheckpointTask("t1")
+	if _, err := tool.Execute(ctx, map[string]any{"path": "main.go", "content": "package main\n\nfunc main() {}\n"}); err != nil {
+		t.Fatalf("write t1: %v", err)
+	}
+	orc.setCheckpointTask("t2")
+	if _, err := tool.Execute(ctx, map[string]any{"path": "util.go", "content": "package main\n"}); err != nil {
+		t.Fatalf("write t2: %v", err)
+	}
+
+	result, err := RewindFiles(ctx, orc.DB, workDir, orc.Session.ID, RewindOptions{LastTask: true})
+	if err != nil {
+		t.Fatalf("rewind last task: %v", err)
+	}
+	if result.TaskID != "t2" || len(result.Removed) != 1 || result.Removed[0] != "util.go" {
+		t.Fatalf("unexpected last-task rewind result: %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "util.go")); !os.IsNotExist(err) {
+		t.Fatalf("expected util.go to be removed, stat err=%v", err)
+	}
+
+	result, err = RewindFiles(ctx, orc.DB, workDir, orc.Session.ID, RewindOptions{})
+	if err != nil {
+		t.Fatalf("rewind run: %v", err)
+	}
+	if len(result.Restored) != 1 || result.Restored[0] != "main.go" {
+		t.Fatalf("unexpected run rewind result: %+v", result)
+	}
+	content, err := os.ReadFile(filepath.Join(workDir, "main.go"))
+	if err != nil {
+		t.Fatalf("read main.go: %v", err)
+	}
+	if string(content) != "package main\n" {
+		t.Fatalf("expected original main.go content, got %q", string(content))
+	}
+
+	if _, err := RewindFiles(ctx, orc.DB, workDir, orc.Session.ID, RewindOptions{}); !errors.Is(err, ErrNoCheckpoints) {
+		t.Fatalf("expected ErrNoCheckpoints after full rewind, got %v", err)
+	}
+}
+
+func TestRewindFilesRestoresFileMode(t *testing.T) {
+	t.Parallel()
+	workDir := t.TempDir()
+	target := filepath.Join(workDir, "build.sh")
+	if err := os.WriteFile(target, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatalf("seed script: %v", err)
+	}
+	orc := newCheckpointTestOrchestrator(t, workDir)
+	tool := newWriteFileTool(ToolEnv{WorkingDir: workDir, Role: RoleCoder, Checkpoint: orc.recordCheckpoint})
+	ctx := context.Background()
+
+	orc.beginCheckpointRun()
+	orc.setCheckpointTask("t1")
+	if _, err := tool.Execute(ctx, map[string]any{"path": "build.sh", "content": "#!/bin/sh\nmake\n"}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.Chmod(target, 0o644); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+
+	if _, err := RewindFiles(ctx, orc.DB, workDir, orc.Session.ID, RewindOptions{}); err != nil {
+		t.Fatalf("rewind: %v", err)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if info.Mode().Perm() != 0o755 {
+		t.Fatalf("expected the script to stay executable, got %v", info.Mode().Perm())
+	}
+}
+
+func TestRewindFilesRefusesExternalChangesUnlessForced(t *testing.T) {
+	t.Parallel()
+	workDir := t.TempDir()
+	orc := newCheckpointTestOrchestrator(t, workDir)
+	tool := newWriteFileTool(ToolEnv{WorkingDir: workDir, Role: RoleCoder, Checkpoint: orc.recordCheckpoint})
+	ctx := context.Background()
+
+	orc.beginCheckpointRun()
+	orc.setCheckpointTask("t1")
+	if _, err := tool.Execute(ctx, map[string]any{"path": "notes.md", "content": "from agent\n"}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	target := filepath.Join(workDir, "notes.md")
+	if err := os.WriteFile(target, []byte("edited by hand\n"), 0o644); err != nil {
+		t.Fatalf("external edit: %v", err)
+	}
+
+	result, err := RewindFiles(ctx, orc.DB, workDir, orc.Session.ID, RewindOptions{})
+	if !errors.Is(err, ErrCheckpointConflict) {
+		t.Fatalf("expected ErrCheckpointConflict, got %v", err)
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0] != "notes.md" {
+		t.Fatalf("unexpected conflicts: %+v", result.Conflicts)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "edited by hand\n" {
+		t.Fatalf("expected conflicting file to be left untouched, got %q", string(content))
+	}
+
+	if _, err := RewindFiles(ctx, orc.DB, workDir, orc.Session.ID, RewindOptions{Force: true}); err != nil {
+		t.Fatalf("forced rewind: %v", err)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Fatalf("expected forced rewind to remove created file, stat err=%v", err)
+	}
+}
+
+func TestOrchestratorRecordsCheckpointsForWrites(t *testing.T) {
+	t.Parallel()
+	workDir := t.TempDir()
+	orc := newCheckpointTestOrchestrator(t, workDir)
+	orc.Planner = newTestAgent(RolePlanner, &toolLoopProvider{})
+	orc.UpdateChan = make(chan StepUpdate, 64)
+	orc.ProjectBrief = "Working directory: ."
+	orc.Session.ExecutionMode = state.ExecutionModeFast
+
+	if err := orc.Run(context.Background(), "create hamid.ts with basic functions"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	checkpoints, err := orc.DB.ListFileCheckpoints(context.Background(), orc.Session.ID, "", "")
+	if err != nil {
+		t.Fatalf("list checkpoints: %v", err)
+	}
+	if len(checkpoints) != 1 || checkpoints[0].Path != "hamid.ts" || checkpoints[0].Existed {
+		t.Fatalf("unexpected checkpoints: %+v", checkpoints)
+	}
+	if checkpoints[0].TaskID != "task-1" || checkpoints[0].RunID == "" {
+		t.Fatalf("expected checkpoint tagged with run and task, got %+v", checkpoints[0])
+	}
+}
@@ -0,0 +1,346 @@
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/yubzen/orchestra/internal/state"
+)
+
+const (
+	CommandScopeProject = "project"
+	CommandScopeUser    = "user"
+)
+
+// CommandArg is one argument a prompt command accepts.
+type CommandArg struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Required    bool   `yaml:"required"`
+	Default     string `yaml:"default"`
+}
+
+// PromptCommand is a user-defined slash command: a prompt template loaded
+// from a markdown file whose YAML front-matter declares its arguments and,
+// optionally, the role, dispatch mode and execution mode it runs with.
+type PromptCommand struct {
+	Name        string
+	Description string
+	Args        []CommandArg
+	Overrides   RunOverrides
+	Template    string
+	Path        string
+	Scope       string
+
+	tmpl *template.Template
+}
+
+type commandFrontMatter struct {
+	Description string       `yaml:"description"`
+	Args        []CommandArg `yaml:"args"`
+	Role        string       `yaml:"role"`
+	Dispatch    string       `yaml:"dispatch"`
+	Mode        string       `yaml:"mode"`
+}
+
+var (
+	commandNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+	commandArgPattern  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// ProjectCommandsDir is where a project keeps its shared prompt commands.
+func ProjectCommandsDir(workingDir string) string {
+	return filepath.Join(effectiveWorkingDir(workingDir), ".orchestra", "commands")
+}
+
+// UserCommandsDir is where a user keeps personal prompt commands.
+func UserCommandsDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "orchestra", "commands")
+}
+
+// LoadPromptCommands reads the *.md commands in the user and project
+// directories. A project command replaces a user command of the same name.
+// Files that fail to parse are reported and skipped; missing directories
+// are not an error.
+func LoadPromptCommands(workingDir, userDir string) ([]PromptCommand, []error) {
+	byName := make(map[string]PromptCommand)
+	var errs []error
+	for _, source := range []struct{ dir, scope string }{
+		{dir: userDir, scope: CommandScopeUser},
+		{dir: ProjectCommandsDir(workingDir), scope: CommandScopeProject},
+	} {
+		if strings.TrimSpace(source.dir) == "" {
+			continue
+		}
+		paths, err := filepath.Glob(filepath.Join(source.dir, "*.md"))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			cmd, err := LoadPromptCommand(path)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			cmd.Scope = source.scope
+			byName[cmd.Name] = cmd
+		}
+	}
+
+	commands := make([]PromptCommand, 0, len(byName))
+	for _, cmd := range byName {
+		commands = append(commands, cmd)
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+	return commands, errs
+}
+
+// LoadPromptCommand parses one command file. The command is named after the
+// file, so write-tests.md becomes /write-tests.
+func LoadPromptCommand(path string) (PromptCommand, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return PromptCommand{}, err
+	}
+	name := strings.ToLower(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	if !commandNamePattern.MatchString(name) {
+		return PromptCommand{}, fmt.Errorf("%s: invalid command name %q", path, name)
+	}
+
+	meta, body, err := splitFrontMatter(string(data))
+	if err != nil {
+		return PromptCommand{}, fmt.Errorf("%s: %w", path, err)
+	}
+	var fm commandFrontMatter
+	if meta != "" {
+		if err := yaml.Unmarshal([]byte(meta), &fm); err != nil {
+			return PromptCommand{}, fmt.Errorf("%s: front-matter: %w", path, err)
+		}
+	}
+
+	cmd := PromptCommand{
+		Name:        name,
+		Description: strings.TrimSpace(fm.Description),
+		Args:        fm.Args,
+		Template:    strings.TrimSpace(body),
+		Path:        path,
+	}
+	if cmd.Template == "" {
+		return PromptCommand{}, fmt.Errorf("%s: prompt template is empty", path)
+	}
+	seen := make(map[string]bool)
+	for _, arg := range cmd.Args {
+		if !commandArgPattern.MatchString(arg.Name) || arg.Name == "Args" {
+			return PromptCommand{}, fmt.Errorf("%s: invalid argument name %q", path, arg.Name)
+		}
+		if seen[arg.Name] {
+			return PromptCommand{}, fmt.Errorf("%s: argument %q declared twice", path, arg.Name)
+		}
+		seen[arg.Name] = true
+	}
+
+	switch role := Role(strings.ToLower(strings.TrimSpace(fm.Role))); role {
+	case "":
+	case RolePlanner, RoleCoder, RoleReviewer:
+		cmd.Overrides.Role = role
+	default:
+		return PromptCommand{}, fmt.Errorf("%s: unknown role %q", path, fm.Role)
+	}
+	switch dispatch := DispatchMode(strings.ToLower(strings.TrimSpace(fm.Dispatch))); dispatch {
+	case "":
+	case DispatchModeTask, DispatchModeChat:
+		cmd.Overrides.Dispatch = dispatch
+	default:
+		return PromptCommand{}, fmt.Errorf("%s: unknown dispatch mode %q", path, fm.Dispatch)
+	}
+	switch mode := strings.ToLower(strings.TrimSpace(fm.Mode)); mode {
+	case "":
+	case state.ExecutionModeFast, state.ExecutionModePlan:
+		cmd.Overrides.ExecutionMode = mode
+	default:
+		return PromptCommand{}, fmt.Errorf("%s: unknown execution mode %q", path, fm.Mode)
+	}
+
+	cmd.tmpl, err = template.New(name).Option("missingkey=error").Parse(cmd.Template)
+	if err != nil {
+		return PromptCommand{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return cmd, nil
+}
+
+// splitFrontMatter separates a leading "---" delimited block from the body.
+func splitFrontMatter(content string) (string, string, error) {
+	content = strings.TrimPrefix(strings.ReplaceAll(content, "\r\n", "\n"), "\ufeff")
+	if !strings.HasPrefix(content, "---\n") {
+		return "", content, nil
+	}
+	rest := content[len("---\n"):]
+	end := strings.Index(rest, "\n---")
+	if end < 0 {
+		return "", "", errors.New("front-matter is not closed with ---")
+	}
+	body := rest[end+len("\n---"):]
+	if nl := strings.IndexByte(body, '\n'); nl >= 0 {
+		body = body[nl+1:]
+	} else {
+		body = ""
+	}
+	return rest[:end], body, nil
+}
+
+// Usage describes how to call the command, for example
+// "/write-tests <path> [focus]".
+func (c PromptCommand) Usage() string {
+	parts := []string{"/" + c.Name}
+	for _, arg := range c.Args {
+		if arg.Required {
+			parts = append(parts, "<"+arg.Name+">")
+		} else {
+			parts = append(parts, "["+arg.Name+"]")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Render fills the template from raw, the text typed after the command.
+// Words of the form name=value set that argument; other words fill the
+// declared arguments in order, the last one taking whatever remains. The
+// whole of raw is also available to the template as {{.Args}}.
+func (c PromptCommand) Render(raw string) (string, error) {
+	words, err := splitCommandWords(raw)
+	if err != nil {
+		return "", fmt.Errorf("/%s: %w", c.Name, err)
+	}
+	return c.render(words, strings.TrimSpace(raw))
+}
+
+// RenderWords is Render for arguments already split into words, as a shell
+// passes them; quotes in the words are kept as typed.
+func (c PromptCommand) RenderWords(words []string) (string, error) {
+	return c.render(words, strings.Join(words, " "))
+}
+
+func (c PromptCommand) render(words []string, raw string) (string, error) {
+	values, err := c.bindArgs(words)
+	if err != nil {
+		return "", err
+	}
+	tmpl := c.tmpl
+	if tmpl == nil {
+		tmpl, err = template.New(c.Name).Option("missingkey=error").Parse(c.Template)
+		if err != nil {
+			return "", err
+		}
+	}
+	data := map[string]string{"Args": raw}
+	for name, value := range values {
+		data[name] = value
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("/%s: %w", c.Name, err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+func (c PromptCommand) bindArgs(words []string) (map[string]string, error) {
+	values := make(map[string]string, len(c.Args))
+	declared := make(map[string]bool, len(c.Args))
+	for _, arg := range c.Args {
+		declared[arg.Name] = true
+	}
+	var positional []string
+	for _, word := range words {
+		if key, value, ok := strings.Cut(word, "="); ok && declared[key] {
+			values[key] = value
+			continue
+		}
+		positional = append(positional, word)
+	}
+
+	open := make([]string, 0, len(c.Args))
+	for _, arg := range c.Args {
+		if _, ok := values[arg.Name]; !ok {
+			open = append(open, arg.Name)
+		}
+	}
+	for idx, name := range open {
+		if len(positional) == 0 {
+			break
+		}
+		if idx == len(open)-1 {
+			values[name] = strings.Join(positional, " ")
+			positional = nil
+			break
+		}
+		values[name] = positional[0]
+		positional = positional[1:]
+	}
+
+	for _, arg := range c.Args {
+		if _, ok := values[arg.Name]; ok {
+			continue
+		}
+		if arg.Required {
+			return nil, fmt.Errorf("missing argument %q; usage: %s", arg.Name, c.Usage())
+		}
+		values[arg.Name] = arg.Default
+	}
+	return values, nil
+}
+
+// splitCommandWords splits s on whitespace, keeping single- or double-quoted
+// runs together. A quote only opens such a run at the start of a word or
+// of a name=value's value, so apostrophes in free text stay as typed.
+func splitCommandWords(s string) ([]string, error) {
+	var (
+		words   []string
+		current strings.Builder
+		quote   rune
+		inWord  bool
+	)
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+				continue
+			}
+			current.WriteRune(r)
+		case (r == '"' || r == '\'') && (!inWord || strings.HasSuffix(current.String(), "=")):
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inWord {
+		words = append(words, current.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeCommandFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func TestLoadPromptCommandsProjectOverridesUser(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	userDir := t.TempDir()
+	projectDir := ProjectCommandsDir(root)
+	writeCommandFile(t, userDir, "write-tests.md", "---\ndescription: personal tests\n---\nWrite tests.\n")
+	writeCommandFile(t, userDir, "explain.md", "Explain {{.Args}}.\n")
+	writeCommandFile(t, projectDir, "write-tests.md", `---
+description: Write table-driven tests
+args:
+  - name: path
+    required: true
+  - name: focus
+    default: edge cases
+role: coder
+dispatch: task
+mode: plan
+---
+Write tests for {{.path}} covering {{.focus}}.
+`)
+	writeCommandFile(t, projectDir, "broken.md", "---\nrole: janitor\n---\nDo things.\n")
+	writeCommandFile(t, projectDir, "notes.txt", "not a command")
+
+	commands, errs := LoadPromptCommands(root, userDir)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), `unknown role "janitor"`) {
+		t.Fatalf("expected one error for the broken command, got %v", errs)
+	}
+	if len(commands) != 2 || commands[0].Name != "explain" || commands[1].Name != "write-tests" {
+		t.Fatalf("unexpected commands: %+v", commands)
+	}
+	if commands[0].Scope != CommandScopeUser {
+		t.Fatalf("expected explain to come from the user directory, got %q", commands[0].Scope)
+	}
+	cmd := commands[1]
+	if cmd.Scope != CommandScopeProject || cmd.Description != "Write table-driven tests" {
+		t.Fatalf("expected the project command to win, got %+v", cmd)
+	}
+	want := RunOverrides{Role: RoleCoder, Dispatch: DispatchModeTask, ExecutionMode: "plan"}
+	if cmd.Overrides != want {
+		t.Fatalf("expected overrides %+v, got %+v", want, cmd.Overrides)
+	}
+	if got := cmd.Usage(); got != "/write-tests <path> [focus]" {
+		t.Fatalf("unexpected usage %q", got)
+	}
+}
+
+func TestPromptCommandRenderBindsArguments(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeCommandFile(t, dir, "review.md", `---
+args:
+  - name: path
+    required: true
+  - name: focus
+    default: injection
+---
+Review {{.path}} for {{.focus}}.`)
+	cmd, err := LoadPromptCommand(filepath.Join(dir, "review.md"))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	cases := map[string]string{
+		"main.go":                             "Review main.go for injection.",
+		`main.go auth and "session handling"`: "Review main.go for auth and session handling.",
+		"focus=secrets path=cmd/app.go":       "Review cmd/app.go for secrets.",
+		`path=main.go focus='token reuse'`:    "Review main.go for token reuse.",
+		"main.go the user's input":            "Review main.go for the user's input.",
+		`main.go "it's quoted"`:               "Review main.go for it's quoted.",
+	}
+	for raw, want := range cases {
+		got, err := cmd.Render(raw)
+		if err != nil {
+			t.Fatalf("render %q: %v", raw, err)
+		}
+		if got != want {
+			t.Fatalf("render %q: expected %q, got %q", raw, want, got)
+		}
+	}
+	if _, err := cmd.Render(""); err == nil || !strings.Contains(err.Error(), `missing argument "path"`) {
+		t.Fatalf("expected a missing argument error, got %v", err)
+	}
+	if _, err := cmd.Render(`"open`); err == nil {
+		t.Fatal("expected an unterminated quote to fail")
+	}
+
+	// Words from a shell arrive split, quotes and all.
+	got, err := cmd.RenderWords([]string{"main.go", "focus=it's"})
+	if err != nil {
+		t.Fatalf("render words: %v", err)
+	}
+	if want := `Review main.go for it's.`; got != want {
+		t.Fatalf("render words: expected %q, got %q", want, got)
+	}
+	got, err = cmd.RenderWords([]string{"main.go", `the "admin" role`})
+	if err != nil || got != `Review main.go for the "admin" role.` {
+		t.Fatalf("expected a split word to stay whole, got %q (%v)", got, err)
+	}
+}
@@ -15,6 +15,8 @@ const (
 	EventFileDiff
 	EventDone
 	EventError
+	// EventSteer reports that queued steering notes reached an agent.
+	EventSteer
 )
 
 type FileDiffPayload struct {
@@ -29,4 +31,6 @@ type AgentEvent struct {
 	Detail  string
 	Payload any
 	At      time.Time
+	// TaskID is the plan task running when the event was emitted, if any.
+	TaskID string
 }
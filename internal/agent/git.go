@@ -0,0 +1,229 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+var ErrDirtyWorkingTree = errors.New("working tree has uncommitted changes")
+
+type GitOptions struct {
+	Enabled bool
+	// StashDirty stashes uncommitted changes before a task run instead of
+	// refusing it. The stash is popped back onto the run branch when the run
+	// completes; a run that fails, pauses or is cancelled leaves it in place
+	// and reports the git stash pop command that restores it.
+	StashDirty bool
+	Worktrees  bool
+}
+
+// gitPathspecExcludes keeps orchestra's own artifacts (plans, checkpoints and
+// local databases) out of dirty checks, stashes and task commits.
+var gitPathspecExcludes = []string{
+	".",
+	":(exclude).orchestra",
+	":(exclude)orchestra.db*",
+	":(exclude)orchestra_vec.db*",
+}
+
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = effectiveWorkingDir(dir)
+	out, err := cmd.CombinedOutput()
+	output := strings.TrimSpace(string(out))
+	if err != nil {
+		err = normalizeCancellationErr(err)
+		if IsUserCancelled(err) {
+			return "", err
+		}
+		if output == "" {
+			return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+		}
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, output)
+	}
+	return output, nil
+}
+
+func gitDirtyPaths(ctx context.Context, dir string) ([]string, error) {
+	args := append([]string{"status", "--porcelain", "--"}, gitPathspecExcludes...)
+	out, err := runGit(ctx, dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) < 3 {
+			continue
+		}
+		paths = append(paths, strings.TrimSpace(line[2:]))
+	}
+	return paths, nil
+}
+
+func gitBranchName(planID string) string {
+	return "orchestra/" + normalizePlanID(planID)
+}
+
+// prepareGitRun verifies the working tree is clean before any agent writes,
+// stashing local changes first when configured to.
+func (o *Orchestrator) prepareGitRun(ctx context.Context) error {
+	if o == nil || !o.Git.Enabled {
+		return nil
+	}
+	dir := o.effectiveWorkingDir()
+	if _, err := runGit(ctx, dir, "rev-parse", "--is-inside-work-tree"); err != nil {
+		return fmt.Errorf("git integration enabled but %s is not a git repository: %w", dir, err)
+	}
+	dirty, err := gitDirtyPaths(ctx, dir)
+	if err != nil {
+		return err
+	}
+	if len(dirty) == 0 {
+		return nil
+	}
+	if !o.Git.StashDirty {
+		return fmt.Errorf("%w: %s (commit or stash them, or enable stashing)", ErrDirtyWorkingTree, strings.Join(dirty, ", "))
+	}
+	args := append([]string{"stash", "push", "--include-untracked", "-m", "orchestra: auto-stash before run", "--"}, gitPathspecExcludes...)
+	if _, err := runGit(ctx, dir, args...); err != nil {
+		return err
+	}
+	sha, err := runGit(ctx, dir, "rev-parse", "refs/stash")
+	if err != nil {
+		return err
+	}
+	o.gitStash = sha
+	o.emit(StepUpdate{StepID: "git", Status: "done", Msg: fmt.Sprintf("Stashed %d local change(s) before run", len(dirty))})
+	return nil
+}
+
+// restoreGitStash pops the changes prepareGitRun stashed once the run
+// completes. Otherwise the stash is kept, since the run branch holds partial
+// work, and the command that restores it is reported instead.
+func (o *Orchestrator) restoreGitStash(ctx context.Context, runErr error) {
+	if o == nil || o.gitStash == "" {
+		return
+	}
+	sha := o.gitStash
+	o.gitStash = ""
+	ctx = context.WithoutCancel(ctx)
+	dir := o.effectiveWorkingDir()
+	ref, err := gitStashRef(ctx, dir, sha)
+	if err != nil {
+		o.emit(StepUpdate{StepID: "git", Status: "failed", Msg: fmt.Sprintf("Find stashed local changes: %v", err)})
+		return
+	}
+	if runErr != nil {
+		o.emit(StepUpdate{StepID: "git", Status: "done", Msg: fmt.Sprintf("Local changes stashed before the run are kept; restore them with: git stash pop %s", ref)})
+		return
+	}
+	if _, err := runGit(ctx, dir, "stash", "pop", ref); err != nil {
+		o.emit(StepUpdate{StepID: "git", Status: "failed", Msg: fmt.Sprintf("Restore stashed local changes: %v; restore them with: git stash pop %s", err, ref)})
+		return
+	}
+	o.emit(StepUpdate{StepID: "git", Status: "done", Msg: "Restored local changes stashed before the run"})
+}
+
+// gitStashRef returns the stash@{n} reference of the stash commit sha, which
+// moves down the list as later stashes are pushed.
+func gitStashRef(ctx context.Context, dir, sha string) (string, error) {
+	out, err := runGit(ctx, dir, "stash", "list", "--format=%H")
+	if err != nil {
+		return "", err
+	}
+	for i, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) == sha {
+			return fmt.Sprintf("stash@{%d}", i), nil
+		}
+	}
+	return "", fmt.Errorf("stash %s is no longer in the stash list", sha)
+}
+
+func (o *Orchestrator) createGitBranch(ctx context.Context, planID string) (string, error) {
+	if o == nil || !o.Git.Enabled {
+		return "", nil
+	}
+	branch := gitBranchName(planID)
+	if _, err := runGit(ctx, o.effectiveWorkingDir(), "checkout", "-b", branch); err != nil {
+		return "", err
+	}
+	o.emit(StepUpdate{StepID: "git", Status: "done", Msg: fmt.Sprintf("Switched to branch %s", branch)})
+	return branch, nil
+}
+
+// checkoutGitBranch switches a resumed run back to its plan branch, creating
+// it when the run started without git integration. Unlike prepareGitRun it
+// keeps uncommitted changes: they are the interrupted task's partial work,
+// which the retried task builds on.
+func (o *Orchestrator) checkoutGitBranch(ctx context.Context, planID string) (string, error) {
+	if o == nil || !o.Git.Enabled {
+		return "", nil
+	}
+	dir := o.effectiveWorkingDir()
+	branch := gitBranchName(planID)
+	current, err := runGit(ctx, dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("git integration enabled but %s is not a git repository: %w", dir, err)
+	}
+	if current == branch {
+		return branch, nil
+	}
+	args := []string{"checkout", branch}
+	if _, err := runGit(ctx, dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err != nil {
+		args = []string{"checkout", "-b", branch}
+	}
+	if _, err := runGit(ctx, dir, args...); err != nil {
+		return "", err
+	}
+	o.emit(StepUpdate{StepID: "git", Status: "done", Msg: fmt.Sprintf("Switched to branch %s", branch)})
+	return branch, nil
+}
+
+// commitTask commits everything the task changed and returns the new SHA, or
+// an empty string when the task left the tree untouched.
+func (o *Orchestrator) commitTask(ctx context.Context, dir, planID string, task PlanTask) (string, error) {
+	if o == nil || !o.Git.Enabled {
+		return "", nil
+	}
+	addArgs := append([]string{"add", "-A", "--"}, gitPathspecExcludes...)
+	if _, err := runGit(ctx, dir, addArgs...); err != nil {
+		return "", err
+	}
+	staged, err := runGit(ctx, dir, "diff", "--cached", "--name-only")
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(staged) == "" {
+		return "", nil
+	}
+	subject, body := taskCommitMessage(planID, task)
+	if _, err := runGit(ctx, dir, "commit", "-m", subject, "-m", body); err != nil {
+		return "", err
+	}
+	return runGit(ctx, dir, "rev-parse", "HEAD")
+}
+
+func taskCommitMessage(planID string, task PlanTask) (string, string) {
+	description := strings.TrimSpace(task.Description)
+	if description == "" {
+		description = task.ID
+	}
+	subject := strings.TrimSpace(strings.SplitN(description, "\n", 2)[0])
+	if runes := []rune(subject); len(runes) > 72 {
+		subject = strings.TrimSpace(string(runes[:69])) + "..."
+	}
+
+	lines := []string{description, ""}
+	if files := append(append([]string(nil), task.FilesToModify...), task.FilesToCreate...); len(files) > 0 {
+		lines = append(lines, "Files: "+strings.Join(files, ", "))
+	}
+	lines = append(lines, "Task: "+task.ID)
+	if planID = strings.TrimSpace(planID); planID != "" {
+		lines = append(lines, "Plan: "+planID)
+	}
+	return subject, strings.TrimSpace(strings.Join(lines, "\n"))
+}
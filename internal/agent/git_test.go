@@ -0,0 +1,192 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yubzen/orchestra/internal/state"
+)
+
+func initGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.name", "Orchestra Test"},
+		{"config", "user.email", "orchestra@example.com"},
+		{"config", "commit.gpgsign", "false"},
+	} {
+		if _, err := runGit(context.Background(), dir, args...); err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# repo\n"), 0o644); err != nil {
+		t.Fatalf("write readme: %v", err)
+	}
+	for _, args := range [][]string{{"add", "README.md"}, {"commit", "-q", "-m", "initial"}} {
+		if _, err := runGit(context.Background(), dir, args...); err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+	}
+	return dir
+}
+
+func newGitTestOrchestrator(t *testing.T, repo string, opts GitOptions) *Orchestrator {
+	t.Helper()
+	orc := newCheckpointTestOrchestrator(t, repo)
+	orc.Planner = newTestAgent(RolePlanner, &toolLoopProvider{})
+	orc.UpdateChan = make(chan StepUpdate, 64)
+	orc.ProjectBrief = "Working directory: ."
+	orc.Session.ExecutionMode = state.ExecutionModeFast
+	orc.Git = opts
+	return orc
+}
+
+func TestOrchestratorGitCommitsApprovedTaskOnRunBranch(t *testing.T) {
+	t.Parallel()
+	repo := initGitRepo(t)
+	orc := newGitTestOrchestrator(t, repo, GitOptions{Enabled: true})
+	ctx := context.Background()
+
+	if err := orc.Run(ctx, "create hamid.ts with basic functions"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	branch, err := runGit(ctx, repo, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		t.Fatalf("current branch: %v", err)
+	}
+	if !strings.HasPrefix(branch, "orchestra/task_") {
+		t.Fatalf("expected orchestra/<plan-id> branch, got %q", branch)
+	}
+	head, err := runGit(ctx, repo, "rev-parse", "HEAD")
+	if err != nil {
+		t.Fatalf("head: %v", err)
+	}
+	subject, err := runGit(ctx, repo, "log", "-1", "--format=%s")
+	if err != nil {
+		t.Fatalf("log: %v", err)
+	}
+	if subject != "create hamid.ts with basic functions" {
+		t.Fatalf("expected commit subject from task description, got %q", subject)
+	}
+	files, err := runGit(ctx, repo, "show", "--name-only", "--format=", "HEAD")
+	if err != nil {
+		t.Fatalf("show: %v", err)
+	}
+	if files != "hamid.ts" {
+		t.Fatalf("expected only hamid.ts in task commit, got %q", files)
+	}
+
+	results, err := orc.DB.GetTaskResults(ctx, orc.Session.ID)
+	if err != nil {
+		t.Fatalf("task results: %v", err)
+	}
+	if len(results) != 1 || results[0].CommitSHA != head {
+		t.Fatalf("expected task result with commit %s, got %+v", head, results)
+	}
+}
+
+func TestOrchestratorGitRefusesDirtyTree(t *testing.T) {
+	t.Parallel()
+	repo := initGitRepo(t)
+	if err := os.WriteFile(filepath.Join(repo, "README.md"), []byte("# local edit\n"), 0o644); err != nil {
+		t.Fatalf("dirty tree: %v", err)
+	}
+	orc := newGitTestOrchestrator(t, repo, GitOptions{Enabled: true})
+
+	err := orc.Run(context.Background(), "create hamid.ts with basic functions")
+	if !errors.Is(err, ErrDirtyWorkingTree) {
+		t.Fatalf("expected ErrDirtyWorkingTree, got %v", err)
+	}
+	if _, statErr := os.Stat(filepath.Join(repo, "hamid.ts")); !os.IsNotExist(statErr) {
+		t.Fatalf("expected no writes on refused run, stat err=%v", statErr)
+	}
+}
+
+func TestOrchestratorGitStashesDirtyTree(t *testing.T) {
+	t.Parallel()
+	repo := initGitRepo(t)
+	if err := os.WriteFile(filepath.Join(repo, "README.md"), []byte("# local edit\n"), 0o644); err != nil {
+		t.Fatalf("dirty tree: %v", err)
+	}
+	orc := newGitTestOrchestrator(t, repo, GitOptions{Enabled: true, StashDirty: true})
+	ctx := context.Background()
+
+	if err := orc.Run(ctx, "create hamid.ts with basic functions"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	stashes, err := runGit(ctx, repo, "stash", "list")
+	if err != nil {
+		t.Fatalf("stash list: %v", err)
+	}
+	if stashes != "" {
+		t.Fatalf("expected the stash to be popped after the run, got %q", stashes)
+	}
+	files, err := runGit(ctx, repo, "show", "--name-only", "--format=", "HEAD")
+	if err != nil {
+		t.Fatalf("show: %v", err)
+	}
+	if files != "hamid.ts" {
+		t.Fatalf("expected the stashed edit to stay out of the task commit, got %q", files)
+	}
+	content, err := os.ReadFile(filepath.Join(repo, "README.md"))
+	if err != nil {
+		t.Fatalf("read readme: %v", err)
+	}
+	if string(content) != "# local edit\n" {
+		t.Fatalf("expected the local README edit to be restored, got %q", string(content))
+	}
+}
+
+func TestOrchestratorGitKeepsStashWhenRunFails(t *testing.T) {
+	t.Parallel()
+	repo := initGitRepo(t)
+	if err := os.WriteFile(filepath.Join(repo, "README.md"), []byte("# local edit\n"), 0o644); err != nil {
+		t.Fatalf("dirty tree: %v", err)
+	}
+	orc := newGitTestOrchestrator(t, repo, GitOptions{Enabled: true, StashDirty: true})
+	ctx := context.Background()
+	if err := orc.prepareGitRun(ctx); err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+
+	orc.restoreGitStash(ctx, errors.New("task failed"))
+	close(orc.UpdateChan)
+	var hint string
+	for update := range orc.UpdateChan {
+		hint = update.Msg
+	}
+	if !strings.Contains(hint, "git stash pop stash@{0}") {
+		t.Fatalf("expected a pop hint for the kept stash, got %q", hint)
+	}
+	if stashes, _ := runGit(ctx, repo, "stash", "list"); !strings.Contains(stashes, "orchestra: auto-stash before run") {
+		t.Fatalf("expected the stash to be kept, got %q", stashes)
+	}
+}
+
+func TestTaskCommitMessage(t *testing.T) {
+	t.Parallel()
+
+	subject, body := taskCommitMessage("task_1", PlanTask{
+		ID:            "t1",
+		Description:   strings.Repeat("x", 80) + "\nmore detail",
+		FilesToCreate: []string{"a.go"},
+	})
+	if len([]rune(subject)) != 72 || !strings.HasSuffix(subject, "...") {
+		t.Fatalf("expected truncated 72-char subject, got %q", subject)
+	}
+	for _, want := range []string{"more detail", "Files: a.go", "Task: t1", "Plan: task_1"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected commit body to contain %q, got %q", want, body)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yubzen/orchestra/internal/ignore"
+	"github.com/yubzen/orchestra/internal/providers"
+	"github.com/yubzen/orchestra/internal/rag"
+)
+
+const (
+	maxMentionListing    = 200
+	maxMentionSymbols    = 3
+	maxMentionCandidates = 5000
+	maxMentionFileBytes  = 1 << 20
+)
+
+type MentionKind string
+
+const (
+	MentionFile   MentionKind = "file"
+	MentionRange  MentionKind = "range"
+	MentionDir    MentionKind = "dir"
+	MentionSymbol MentionKind = "symbol"
+)
+
+// Attachment is the content one @mention pulls into a prompt.
+type Attachment struct {
+	Ref       string
+	Kind      MentionKind
+	Label     string
+	Content   string
+	Lines     int
+	Tokens    int
+	Truncated bool
+	Err       string
+}
+
+// MentionOptions controls how @mentions resolve. TokenBudget bounds the
+// attached content; zero means a quarter of the default context window.
+type MentionOptions struct {
+	WorkingDir  string
+	Index       *rag.Indexer
+	TokenBudget int
+}
+
+// MentionBudget is the share of model's context @mentions may fill.
+func MentionBudget(model string) int {
+	return providers.ContextWindow(model) / 4
+}
+
+var (
+	mentionPattern = regexp.MustCompile(`(?:^|[\s(\[{"'])@([^\s@]+)`)
+	mentionRange   = regexp.MustCompile(`^(.+):(\d+)(?:-(\d+))?$`)
+)
+
+// ParseMentions returns the references in input, without the "@", in the
+// order they appear. Trailing punctuation is not part of a reference, and
+// an "@" inside a word (an email address) is not a mention.
+func ParseMentions(input string) []string {
+	var refs []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(input, -1) {
+		ref := strings.TrimRight(match[1], ".,;!?)]}\"'")
+		ref = strings.TrimSuffix(ref, ":")
+		if ref == "" || seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// ExpandMentions resolves the @mentions in input and returns the prompt to
+// send: input unchanged, followed by each attachment. Files and line ranges
+// are read from the workspace, directories are listed, and names that are
+// not paths are looked up in the symbol index. Ignored and secret files are
+// refused, and content beyond the token budget is cut at line boundaries.
+func ExpandMentions(ctx context.Context, input string, opts MentionOptions) (string, []Attachment) {
+	refs := ParseMentions(input)
+	if len(refs) == 0 {
+		return input, nil
+	}
+	root := effectiveWorkingDir(opts.WorkingDir)
+	matcher := ignore.New(root)
+	attachments := make([]Attachment, 0, len(refs))
+	for _, ref := range refs {
+		attachments = append(attachments, resolveMention(ctx, root, matcher, opts.Index, ref))
+	}
+	budget := opts.TokenBudget
+	if budget <= 0 {
+		budget = providers.DefaultContextWindow / 4
+	}
+	fitAttachments(attachments, budget)
+
+	var sb strings.Builder
+	sb.WriteString(input)
+	for _, att := range attachments {
+		sb.WriteString("\n\n")
+		if att.Err != "" {
+			fmt.Fprintf(&sb, "--- @%s (%s) ---", att.Ref, att.Err)
+			continue
+		}
+		fmt.Fprintf(&sb, "--- @%s ---\n%s", att.Label, att.Content)
+	}
+	return sb.String(), attachments
+}
+
+func resolveMention(ctx context.Context, root string, matcher *ignore.Matcher, index *rag.Indexer, ref string) Attachment {
+	att := Attachment{Ref: ref, Kind: MentionFile, Label: ref}
+	target, start, end := ref, 0, 0
+	if m := mentionRange.FindStringSubmatch(ref); m != nil {
+		target = m[1]
+		start, _ = strconv.Atoi(m[2])
+		end = start
+		if m[3] != "" {
+			end, _ = strconv.Atoi(m[3])
+		}
+		att.Kind = MentionRange
+	}
+
+	absPath, relPath, err := resolveWorkspacePath(root, target)
+	if err != nil {
+		att.Err = "outside the workspace"
+		return att
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		if att.Kind == MentionFile && !strings.Contains(ref, "/") {
+			if sym, ok := resolveSymbolMention(ctx, root, matcher, index, ref); ok {
+				return sym
+			}
+		}
+		att.Err = "not found"
+		return att
+	}
+	if info.IsDir() {
+		return listMentionDir(ctx, root, relPath, att)
+	}
+	if readDenied(matcher, relPath) {
+		att.Err = "excluded by ignore rules or a secret file"
+		return att
+	}
+	if info.Size() > maxMentionFileBytes {
+		att.Err = "file too large; mention a line range"
+		return att
+	}
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		att.Err = "cannot read"
+		return att
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		att.Err = "binary file"
+		return att
+	}
+	lines := splitContentLines(string(data))
+	att.Label = relPath
+	if att.Kind == MentionRange {
+		if start < 1 || end < start || start > len(lines) {
+			att.Err = fmt.Sprintf("line range %d-%d is outside 1-%d", start, end, len(lines))
+			return att
+		}
+		end = min(end, len(lines))
+		lines = lines[start-1 : end]
+		att.Label = fmt.Sprintf("%s:%d-%d", relPath, start, end)
+	}
+	att.Content = strings.Join(lines, "\n")
+	att.Lines = len(lines)
+	return att
+}
+
+func listMentionDir(ctx context.Context, root, relPath string, att Attachment) Attachment {
+	att.Kind = MentionDir
+	att.Label = strings.TrimSuffix(relPath, "/") + "/"
+	var files []string
+	more := false
+	err := walkWorkspace(ctx, root, relPath, "", func(rel, _ string) bool {
+		if len(files) >= maxMentionListing {
+			more = true
+			return false
+		}
+		files = append(files, rel)
+		return true
+	})
+	if err != nil {
+		att.Err = "cannot read directory"
+		return att
+	}
+	sort.Strings(files)
+	if more {
+		files = append(files, fmt.Sprintf("... (stopped after %d files)", maxMentionListing))
+	}
+	att.Content = strings.Join(files, "\n")
+	att.Lines = len(files)
+	return att
+}
+
+// resolveSymbolMention attaches the source of up to maxMentionSymbols
+// definitions named ref.
+func resolveSymbolMention(ctx context.Context, root string, matcher *ignore.Matcher, index *rag.Indexer, ref string) (Attachment, bool) {
+	if index == nil {
+		return Attachment{}, false
+	}
+	found, err := index.FindSymbols(ctx, ref)
+	if err != nil {
+		return Attachment{}, false
+	}
+	// FindSymbols falls back to substring matches; a mention names one
+	// definition, so only exact names count.
+	base := ref[strings.LastIndex(ref, ".")+1:]
+	var symbols []rag.Symbol
+	for _, sym := range found {
+		if sym.Name == base {
+			symbols = append(symbols, sym)
+		}
+	}
+	if len(symbols) == 0 {
+		return Attachment{}, false
+	}
+	att := Attachment{Ref: ref, Kind: MentionSymbol, Label: "symbol " + ref}
+	var parts []string
+	for _, sym := range symbols {
+		if len(parts) == maxMentionSymbols {
+			break
+		}
+		absPath, relPath, err := resolveWorkspacePath(root, sym.File)
+		if err != nil || readDenied(matcher, relPath) {
+			continue
+		}
+		data, err := os.ReadFile(absPath)
+		if err != nil {
+			continue
+		}
+		lines := splitContentLines(string(data))
+		if sym.Line < 1 || sym.Line > len(lines) {
+			continue
+		}
+		end := min(max(sym.EndLine, sym.Line), len(lines))
+		body := lines[sym.Line-1 : end]
+		parts = append(parts, fmt.Sprintf("// %s:%d-%d\n%s", relPath, sym.Line, end, strings.Join(body, "\n")))
+		att.Lines += len(body)
+	}
+	if len(parts) == 0 {
+		return Attachment{}, false
+	}
+	if len(symbols) > maxMentionSymbols {
+		parts = append(parts, fmt.Sprintf("... (%d more definitions)", len(symbols)-maxMentionSymbols))
+	}
+	att.Content = strings.Join(parts, "\n\n")
+	return att, true
+}
+
+// fitAttachments shares budget between attachments: small ones are kept
+// whole and the rest split what is left evenly, each cut at a line boundary.
+func fitAttachments(attachments []Attachment, budget int) {
+	order := make([]int, 0, len(attachments))
+	for idx := range attachments {
+		if attachments[idx].Err == "" {
+			attachments[idx].Tokens = rag.EstimateTokens(attachments[idx].Content)
+			order = append(order, idx)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return attachments[order[i]].Tokens < attachments[order[j]].Tokens
+	})
+	remaining := budget
+	for pos, idx := range order {
+		share := remaining / (len(order) - pos)
+		att := &attachments[idx]
+		if att.Tokens > share {
+			truncateAttachment(att, share)
+		}
+		remaining -= att.Tokens
+	}
+}
+
+func truncateAttachment(att *Attachment, tokens int) {
+	lines := strings.Split(att.Content, "\n")
+	// Leave room for the truncation note.
+	limit := tokens*4 - 160
+	used, kept := 0, 0
+	for _, line := range lines {
+		if used+len(line)+1 > limit {
+			break
+		}
+		used += len(line) + 1
+		kept++
+	}
+	note := fmt.Sprintf("... (truncated to fit the context budget: %d of %d lines", kept, len(lines))
+	if att.Kind == MentionFile || att.Kind == MentionRange {
+		note += "; mention a line range such as @" + strings.SplitN(att.Label, ":", 2)[0] + ":1-100"
+	}
+	att.Content = strings.Join(append(lines[:kept:kept], note+")"), "\n")
+	att.Tokens = rag.EstimateTokens(att.Content)
+	att.Truncated = true
+}
+
+// MentionCandidates lists workspace files and their directories (with a
+// trailing "/") for the @mention picker, skipping ignored paths.
+func MentionCandidates(ctx context.Context, workingDir string) []string {
+	root := effectiveWorkingDir(workingDir)
+	dirs := make(map[string]bool)
+	var out []string
+	_ = walkWorkspace(ctx, root, ".", "", func(rel, _ string) bool {
+		if len(out) >= maxMentionCandidates {
+			return false
+		}
+		out = append(out, rel)
+		for dir := path.Dir(rel); dir != "." && !dirs[dir]; dir = path.Dir(dir) {
+			dirs[dir] = true
+			out = append(out, dir+"/")
+		}
+		return true
+	})
+	sort.Strings(out)
+	return out
+}
+
+func splitContentLines(content string) []string {
+	content = strings.TrimSuffix(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if content == "" {
+		return nil
+	}
+	return strings.Split(content, "\n")
+}
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMentionsKeepsOrderAndSkipsEmails(t *testing.T) {
+	t.Parallel()
+
+	got := ParseMentions("compare @b.go:3-4 with (@a.go), mail me@example.com about @docs/ and @b.go:3-4.")
+	want := []string{"b.go:3-4", "a.go", "docs/"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestExpandMentionsResolvesFilesRangesAndDirs(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	mustWrite := func(rel, content string) {
+		t.Helper()
+		abs := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(abs, []byte(content), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	mustWrite("main.go", "one\ntwo\nthree\nfour\n")
+	mustWrite("pkg/a.go", "package pkg\n")
+	mustWrite("pkg/b.go", "package pkg\n")
+	mustWrite(".env", "TOKEN=secret\n")
+
+	input := "explain @main.go:2-3 then @pkg/ but not @.env or @../outside.go or @missing.go"
+	prompt, attachments := ExpandMentions(context.Background(), input, MentionOptions{WorkingDir: root})
+	if !strings.HasPrefix(prompt, input+"\n\n") {
+		t.Fatalf("expected the prompt to keep the original text first, got %q", prompt)
+	}
+	if len(attachments) != 5 {
+		t.Fatalf("expected 5 attachments, got %+v", attachments)
+	}
+	if att := attachments[0]; att.Kind != MentionRange || att.Label != "main.go:2-3" || att.Content != "two\nthree" {
+		t.Fatalf("unexpected range attachment: %+v", att)
+	}
+	if att := attachments[1]; att.Kind != MentionDir || att.Content != "pkg/a.go\npkg/b.go" {
+		t.Fatalf("unexpected dir attachment: %+v", att)
+	}
+	if att := attachments[2]; !strings.Contains(att.Err, "secret") {
+		t.Fatalf("expected the .env mention to be refused, got %+v", att)
+	}
+	if att := attachments[3]; att.Err != "outside the workspace" {
+		t.Fatalf("expected the outside path to be refused, got %+v", att)
+	}
+	if att := attachments[4]; att.Err != "not found" {
+		t.Fatalf("expected a missing file error, got %+v", att)
+	}
+	if strings.Contains(prompt, "TOKEN=secret") {
+		t.Fatalf("secret content leaked into the prompt: %q", prompt)
+	}
+
+	candidates := MentionCandidates(context.Background(), root)
+	if !reflect.DeepEqual(candidates, []string{"main.go", "pkg/", "pkg/a.go", "pkg/b.go"}) {
+		t.Fatalf("unexpected candidates: %v", candidates)
+	}
+}
+
+func TestExpandMentionsFitsTokenBudget(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	var big strings.Builder
+	for i := 1; i <= 400; i++ {
+		fmt.Fprintf(&big, "line %03d of the large file\n", i)
+	}
+	if err := os.WriteFile(filepath.Join(root, "big.txt"), []byte(big.String()), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "small.txt"), []byte("tiny\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	_, attachments := ExpandMentions(context.Background(), "@big.txt @small.txt", MentionOptions{WorkingDir: root, TokenBudget: 500})
+	big0, small := attachments[0], attachments[1]
+	if small.Truncated || small.Content != "tiny" {
+		t.Fatalf("expected the small file to be kept whole, got %+v", small)
+	}
+	if !big0.Truncated || big0.Tokens > 500 {
+		t.Fatalf("expected the large file to be cut to the budget, got %d tokens", big0.Tokens)
+	}
+	lines := strings.Split(big0.Content, "\n")
+	if !strings.HasPrefix(lines[len(lines)-2], "line ") || !strings.Contains(lines[len(lines)-1], "@big.txt:1-100") {
+		t.Fatalf("expected a whole-line cut with a range hint, got %q", lines[len(lines)-2:])
+	}
+}
@@ -10,10 +10,13 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"gopkg.in/yaml.v3"
 
+	"github.com/yubzen/orchestra/internal/ignore"
 	"github.com/yubzen/orchestra/internal/rag"
 	"github.com/yubzen/orchestra/internal/state"
 )
@@ -24,6 +27,8 @@ type StepUpdate struct {
 	Msg      string
 	PlanID   string
 	PlanYAML string
+	// Staged lists the writes awaiting review in a "review_ready" update.
+	Staged []StagedFile
 }
 
 type PlanTask struct {
@@ -32,6 +37,7 @@ type PlanTask struct {
 	FilesToModify []string `yaml:"files_to_modify"`
 	FilesToCreate []string `yaml:"files_to_create"`
 	DependsOn     []string `yaml:"depends_on"`
+	Verify        []string `yaml:"verify,omitempty"`
 }
 
 type YAMLPlan struct {
@@ -84,7 +90,43 @@ type Orchestrator struct {
 	PlanApprovalChan chan PlanApproval
 	WorkingDir       string
 	ProjectBrief     string
-	writePlanLockFn  func(context.Context, string) error
+	// BriefTokens caps the project brief; 0 uses rag.DefaultBriefTokens.
+	BriefTokens    int
+	Git            GitOptions
+	VerifyCommands []string
+	VerifyTimeout  time.Duration
+	// DiffReviewChan receives hunk decisions when ReviewWrites is set. Without
+	// it staged writes are applied as they are.
+	DiffReviewChan chan DiffReview
+	// ReviewWrites stages task file writes for review instead of applying them.
+	ReviewWrites bool
+
+	writePlanLockFn func(context.Context, string) error
+	runPlanID       string
+	// gitStash is the commit of the changes stashed before the current run.
+	gitStash string
+	// runExecutionMode overrides the session's execution mode for one run.
+	runExecutionMode string
+	activeWorktree   *taskWorktree
+	// briefHead is the git HEAD ProjectBrief was built for; it is empty for
+	// briefs set by the caller, which are used as is.
+	briefHead string
+
+	stagedWrites *StagedWrites
+
+	checkpointMu     sync.Mutex
+	checkpointRunID  string
+	checkpointTaskID string
+	// traceStack holds the open trace spans, innermost last.
+	traceStack []int64
+
+	steering steeringQueue
+
+	// run is the saved state of the planned run in progress; it is nil for
+	// conversational runs and without a state DB.
+	run            *runState
+	lastRunID      string
+	pauseRequested atomic.Bool
 }
 
 var ErrOrchestratorNotReady = errors.New("orchestrator is not initialized")
@@ -109,10 +151,28 @@ func (o *Orchestrator) emitEvent(event AgentEvent) {
 	if event.At.IsZero() {
 		event.At = time.Now()
 	}
+	if event.TaskID == "" {
+		_, event.TaskID = o.runScope()
+	}
 	o.EventChan <- event
 }
 
+// RunOverrides pins choices Run otherwise makes itself. Zero fields keep the
+// default: the role picked by strategy, dispatch inferred from the prompt
+// and the session's execution mode.
+type RunOverrides struct {
+	Role          Role
+	Dispatch      DispatchMode
+	ExecutionMode string
+}
+
 func (o *Orchestrator) Run(ctx context.Context, prompt string) error {
+	return o.RunWithOverrides(ctx, prompt, RunOverrides{})
+}
+
+// RunWithOverrides is Run with the role, dispatch mode or execution mode
+// fixed by the caller, as prompt commands do.
+func (o *Orchestrator) RunWithOverrides(ctx context.Context, prompt string, overrides RunOverrides) (err error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -128,6 +188,9 @@ func (o *Orchestrator) Run(ctx context.Context, prompt string) error {
 	if o == nil {
 		return ErrOrchestratorNotReady
 	}
+	o.beginSteering()
+	defer o.endSteering()
+	o.pauseRequested.Store(false)
 
 	availability := o.collectAvailability(ctx)
 	if err := checkContextCancelled(ctx); err != nil {
@@ -140,8 +203,15 @@ func (o *Orchestrator) Run(ctx context.Context, prompt string) error {
 		return err
 	}
 	o.bindAgentToolSets(strategy)
+	o.beginCheckpointRun()
+	runSpan := o.beginSpan(ctx, state.TraceSpan{Kind: state.SpanKindRun, Name: PromptPreview(prompt), Input: prompt})
+	defer func() { o.endSpan(ctx, runSpan, "", err) }()
+	defer func() { o.finishRunState(ctx, err) }()
 
+	o.runExecutionMode = overrides.ExecutionMode
+	defer func() { o.runExecutionMode = "" }()
 	execMode := o.executionMode()
+	roleAgent := o.overrideAgent(overrides.Role, availability)
 	projectBrief := o.ensureProjectBrief()
 	if execMode == state.ExecutionModePlan {
 		o.emitEvent(AgentEvent{Type: EventPlanning, Role: RolePlanner, Detail: fmt.Sprintf("strategy=%s mode=%s", strategyName(strategy), execMode)})
@@ -154,9 +224,23 @@ func (o *Orchestrator) Run(ctx context.Context, prompt string) error {
 		Msg:      fmt.Sprintf("Strategy: %s | mode: %s", strategyName(strategy), execMode),
 		PlanYAML: "",
 	})
-	if !isTaskMessage(prompt) {
-		return o.runConversational(ctx, prompt, strategy)
+	taskMode := isTaskMessage(prompt)
+	if overrides.Dispatch != "" {
+		taskMode = overrides.Dispatch.Normalize() == DispatchModeTask
 	}
+	if !taskMode {
+		return o.runConversational(ctx, prompt, strategy, roleAgent)
+	}
+	if err := o.prepareGitRun(ctx); err != nil {
+		err = normalizeCancellationErr(err)
+		if IsUserCancelled(err) {
+			return err
+		}
+		o.emit(StepUpdate{StepID: "git", Status: "failed", Msg: err.Error()})
+		o.emitEvent(AgentEvent{Type: EventError, Role: RolePlanner, Detail: err.Error()})
+		return err
+	}
+	defer func() { o.restoreGitStash(ctx, err) }()
 
 	var (
 		planYAML string
@@ -201,6 +285,9 @@ func (o *Orchestrator) Run(ctx context.Context, prompt string) error {
 		}
 		if strings.TrimSpace(approvedPlanYAML) != strings.TrimSpace(planYAML) {
 			plan, err = parseYAMLPlan(approvedPlanYAML)
+			if err == nil {
+				err = planIssuesError(ValidatePlan(plan, o.effectiveWorkingDir()))
+			}
 			if err != nil {
 				err = fmt.Errorf("edited plan is invalid: %w", err)
 				o.emit(StepUpdate{StepID: "planner", Status: "failed", Msg: err.Error()})
@@ -235,6 +322,9 @@ func (o *Orchestrator) Run(ctx context.Context, prompt string) error {
 	}
 
 	executor := o.selectExecutor(strategy)
+	if roleAgent != nil {
+		executor = roleAgent
+	}
 	if executor == nil {
 		err := errors.New("no execution agent available")
 		o.emit(StepUpdate{StepID: "orchestrator", Status: "failed", Msg: err.Error()})
@@ -243,27 +333,72 @@ func (o *Orchestrator) Run(ctx context.Context, prompt string) error {
 	}
 	reviewer := o.selectReviewer(strategy, executor)
 
+	o.runPlanID = planID
+	if o.runPlanID == "" {
+		o.runPlanID = o.newPlanID()
+	}
+	if _, err := o.createGitBranch(ctx, o.runPlanID); err != nil {
+		err = normalizeCancellationErr(err)
+		if IsUserCancelled(err) {
+			return err
+		}
+		o.emit(StepUpdate{StepID: "git", Status: "failed", Msg: err.Error()})
+		o.emitEvent(AgentEvent{Type: EventError, Role: executor.Role, Detail: err.Error()})
+		return err
+	}
+
+	o.startRunState(ctx, prompt, overrides, execMode, planPath, planYAML, plan)
+	return o.runPlan(ctx, prompt, projectBrief, plan, planPath, execMode, strategy, executor, reviewer)
+}
+
+// runPlan executes an approved plan and reports how the run ended. A paused
+// run is reported with the ID that resumes it rather than as a failure.
+func (o *Orchestrator) runPlan(ctx context.Context, prompt, projectBrief string, plan YAMLPlan, planPath, execMode string, strategy ExecutionStrategy, executor, reviewer *Agent) error {
 	if err := o.executePlan(ctx, prompt, projectBrief, plan, planPath, strategy, executor, reviewer); err != nil {
 		err = normalizeCancellationErr(err)
 		if IsUserCancelled(err) {
 			return err
 		}
+		if errors.Is(err, ErrRunPaused) {
+			o.emit(StepUpdate{StepID: "orchestrator", Status: "paused", Msg: fmt.Sprintf("Run paused; resume it with run ID %s", o.LastRunID())})
+			return err
+		}
 		o.emit(StepUpdate{StepID: "orchestrator", Status: "failed", Msg: err.Error()})
 		o.emitEvent(AgentEvent{Type: EventError, Role: executor.Role, Detail: err.Error()})
 		return err
 	}
 	o.emit(StepUpdate{StepID: "orchestrator", Status: "done", Msg: "All tasks completed"})
-	doneRole := RolePlanner
-	if executor != nil {
-		doneRole = executor.Role
-	}
-	o.emitEvent(AgentEvent{Type: EventDone, Role: doneRole, Detail: "all tasks completed"})
+	o.emitEvent(AgentEvent{Type: EventDone, Role: executor.Role, Detail: "all tasks completed"})
 	if execMode == state.ExecutionModePlan {
-		o.schedulePlanLockWrite(planID)
+		o.schedulePlanLockWrite(o.runPlanID)
 	}
 	return nil
 }
 
+// overrideAgent returns the agent for role when it is ready, and nil (with a
+// notice) when the run has to fall back to the strategy's choice.
+func (o *Orchestrator) overrideAgent(role Role, availability roleAvailability) *Agent {
+	var (
+		candidate *Agent
+		ready     bool
+	)
+	switch role {
+	case "":
+		return nil
+	case RolePlanner:
+		candidate, ready = o.Planner, availability.planner
+	case RoleCoder:
+		candidate, ready = o.Coder, availability.coder
+	case RoleReviewer:
+		candidate, ready = o.Reviewer, availability.reviewer
+	}
+	if candidate == nil || !ready {
+		o.emit(StepUpdate{StepID: "orchestrator", Status: "running", Msg: fmt.Sprintf("%s is not available; using the default role", role)})
+		return nil
+	}
+	return candidate
+}
+
 func executionRoleForStrategy(strategy ExecutionStrategy) Role {
 	switch strategy {
 	case StrategyNoCoder, StrategySolo:
@@ -292,16 +427,18 @@ func (o *Orchestrator) SubmitPlanApproval(decision PlanApproval) {
 }
 
 func (o *Orchestrator) executionMode() string {
+	if o != nil && o.runExecutionMode != "" {
+		return state.NormalizeExecutionMode(o.runExecutionMode)
+	}
 	if o == nil || o.Session == nil {
 		return state.ExecutionModeFast
 	}
 	return state.NormalizeExecutionMode(o.Session.ExecutionMode)
 }
 
+// ensureProjectBrief returns the project brief, rebuilding it (or loading it
+// from the on-disk cache) only when git HEAD moved since it was built.
 func (o *Orchestrator) ensureProjectBrief() string {
-	if strings.TrimSpace(o.ProjectBrief) != "" {
-		return o.ProjectBrief
-	}
 	workingDir := strings.TrimSpace(o.WorkingDir)
 	if workingDir == "" && o.Session != nil {
 		workingDir = strings.TrimSpace(o.Session.WorkingDir)
@@ -309,12 +446,16 @@ func (o *Orchestrator) ensureProjectBrief() string {
 	if workingDir == "" {
 		workingDir = "."
 	}
-	brief, err := rag.BuildProjectBrief(workingDir)
+	if strings.TrimSpace(o.ProjectBrief) != "" && (o.briefHead == "" || o.briefHead == rag.GitHead(workingDir)) {
+		return o.ProjectBrief
+	}
+	brief, head, err := rag.CachedProjectBrief(workingDir, rag.BriefOptions{TokenBudget: o.BriefTokens})
 	if err != nil {
 		o.ProjectBrief = fmt.Sprintf("Working directory: %s", workingDir)
 		return o.ProjectBrief
 	}
 	o.ProjectBrief = brief
+	o.briefHead = head
 	return brief
 }
 
@@ -378,30 +519,44 @@ func (o *Orchestrator) buildPlan(ctx context.Context, prompt, projectBrief strin
 		var parsed YAMLPlan
 		var planErr error
 
+		planSpan := o.beginSpan(ctx, state.TraceSpan{Kind: state.SpanKindTask, Name: "plan", Role: string(RolePlanner), Input: prompt})
+		attempts := o.newAttemptSpans(ctx, RolePlanner)
+		defer func() {
+			attempts.finish(planErr)
+			o.endSpan(ctx, planSpan, planYAML, planErr)
+		}()
 		for attempt := 1; attempt <= 3; attempt++ {
 			if err := checkContextCancelled(ctx); err != nil {
+				planErr = err
 				return "", YAMLPlan{}, err
 			}
+			attempts.start(attempt)
 			o.emit(StepUpdate{StepID: "planner", Status: "running", Msg: fmt.Sprintf("Generating plan (attempt %d/3)", attempt)})
 			o.emitEvent(AgentEvent{
 				Type:   EventThinking,
 				Role:   RolePlanner,
 				Detail: fmt.Sprintf("Planner is drafting execution plan (attempt %d/3)", attempt),
 			})
-			plannerPrompt := o.buildPlannerPrompt(projectBrief, prompt)
-			planYAML, planErr = o.Planner.RunWithOptions(ctx, plannerPrompt, o.Session, o.DB, RunOptions{
+			plannerPrompt := o.withSteering(o.buildPlannerPrompt(projectBrief, prompt), RolePlanner)
+			planYAML, planErr = o.Planner.RunWithOptions(ctx, plannerPrompt, o.Session, o.DB, o.withRunScope(RunOptions{
 				Mode:    DispatchModeTask,
 				OnToken: o.streamTokenCallback(RolePlanner),
-			})
+			}))
 			if planErr != nil {
 				planErr = normalizeCancellationErr(planErr)
 				if IsUserCancelled(planErr) {
 					return "", YAMLPlan{}, planErr
 				}
+				attempts.retry("planner failed: " + planErr.Error())
 				continue
 			}
 
 			parsed, planErr = parseYAMLPlan(planYAML)
+			if planErr != nil {
+				attempts.retry("invalid plan: " + planErr.Error())
+			} else if len(parsed.Tasks) == 0 {
+				attempts.retry("planner returned empty plan")
+			}
 			if planErr == nil && len(parsed.Tasks) > 0 {
 				o.emit(StepUpdate{StepID: "planner", Status: "done", Msg: fmt.Sprintf("Plan generated with %d task(s)", len(parsed.Tasks))})
 				o.emitEvent(AgentEvent{Type: EventDone, Role: RolePlanner, Detail: fmt.Sprintf("plan generated with %d task(s)", len(parsed.Tasks))})
@@ -411,7 +566,8 @@ func (o *Orchestrator) buildPlan(ctx context.Context, prompt, projectBrief strin
 		if planErr == nil {
 			planErr = errors.New("planner returned empty plan")
 		}
-		return "", YAMLPlan{}, fmt.Errorf("planner failed: %w", planErr)
+		planErr = fmt.Errorf("planner failed: %w", planErr)
+		return "", YAMLPlan{}, planErr
 	}
 	return "", YAMLPlan{}, errors.New("planner is unavailable; assign a planner model before running tasks")
 }
@@ -492,10 +648,10 @@ There is no coder available. Provide a concise implementation strategy, risks, a
 	`, projectBrief, prompt, planYAML))
 	o.emit(StepUpdate{StepID: "analysis", Status: "running", Msg: "Generating implementation strategy without coder"})
 	o.emitEvent(AgentEvent{Type: EventThinking, Role: analyst.Role, Detail: "analysis-only mode: generating implementation guidance"})
-	_, err := analyst.RunWithOptions(ctx, analysisPrompt, o.Session, o.DB, RunOptions{
+	_, err := analyst.RunWithOptions(ctx, analysisPrompt, o.Session, o.DB, o.withRunScope(RunOptions{
 		Mode:    DispatchModeTask,
 		OnToken: o.streamTokenCallback(analyst.Role),
-	})
+	}))
 	if err != nil {
 		return normalizeCancellationErr(err)
 	}
@@ -545,6 +701,11 @@ func (o *Orchestrator) executePlan(ctx context.Context, prompt, projectBrief str
 	}
 
 	completed := make(map[string]bool, len(plan.Tasks))
+	for _, task := range plan.Tasks {
+		if o.runTaskDone(task.ID) {
+			completed[task.ID] = true
+		}
+	}
 	for len(completed) < len(plan.Tasks) {
 		if err := checkContextCancelled(ctx); err != nil {
 			return err
@@ -561,6 +722,9 @@ func (o *Orchestrator) executePlan(ctx context.Context, prompt, projectBrief str
 				continue
 			}
 			progress = true
+			if o.pauseRequested.Load() {
+				return ErrRunPaused
+			}
 			if err := o.runTask(ctx, prompt, projectBrief, task, planPath, strategy, executor, reviewer); err != nil {
 				return err
 			}
@@ -573,7 +737,7 @@ func (o *Orchestrator) executePlan(ctx context.Context, prompt, projectBrief str
 	return nil
 }
 
-func (o *Orchestrator) runTask(ctx context.Context, prompt, projectBrief string, task PlanTask, planPath string, strategy ExecutionStrategy, executor, reviewer *Agent) error {
+func (o *Orchestrator) runTask(ctx context.Context, prompt, projectBrief string, task PlanTask, planPath string, strategy ExecutionStrategy, executor, reviewer *Agent) (err error) {
 	if err := checkContextCancelled(ctx); err != nil {
 		return err
 	}
@@ -581,8 +745,46 @@ func (o *Orchestrator) runTask(ctx context.Context, prompt, projectBrief string,
 		return errors.New("no executor agent available")
 	}
 
+	o.setCheckpointTask(task.ID)
+	taskSpan := o.beginSpan(ctx, state.TraceSpan{
+		Kind:  state.SpanKindTask,
+		Name:  task.ID + ": " + PromptPreview(task.Description),
+		Role:  string(executor.Role),
+		Input: task.Description,
+	})
+	attempts := o.newAttemptSpans(ctx, executor.Role)
+	o.updateRunTask(ctx, task.ID, func(t *state.RunTask) { t.Status = state.RunTaskRunning })
+	defer func() {
+		attempts.finish(err)
+		o.endSpan(ctx, taskSpan, "", err)
+		o.updateRunTask(ctx, task.ID, func(t *state.RunTask) { t.Status = runTaskOutcome(err) })
+	}()
+	if o.ReviewWrites {
+		o.stagedWrites.setActive(true)
+		defer o.stagedWrites.setActive(false)
+	}
+	if o.worktreesEnabled() {
+		wt, err := o.createTaskWorktree(ctx, o.runPlanID, task, planPath)
+		if wt != nil {
+			o.activeWorktree = wt
+			o.bindAgentToolSetsAt(strategy, wt.Dir)
+			defer func() {
+				o.activeWorktree = nil
+				o.bindAgentToolSetsAt(strategy, o.effectiveWorkingDir())
+				o.removeTaskWorktree(context.Background(), wt)
+			}()
+		}
+		if err != nil {
+			err = normalizeCancellationErr(err)
+			if !IsUserCancelled(err) {
+				o.emit(StepUpdate{StepID: task.ID, Status: "blocked", Msg: fmt.Sprintf("Failed to create task worktree: %v", err)})
+				o.emitEvent(AgentEvent{Type: EventError, Role: executor.Role, Detail: err.Error()})
+			}
+			return err
+		}
+	}
 	execMode := o.executionMode()
-	basePrompt := o.buildTaskPrompt(projectBrief, prompt, task, planPath, strategy, "", executor.Role, execMode)
+	basePrompt := o.buildTaskPrompt(projectBrief, prompt, task, planPath, strategy, o.runTaskFindings(task.ID), executor.Role, execMode)
 	o.emit(StepUpdate{StepID: task.ID, Status: "running", Msg: "Executing task"})
 	o.emitEvent(AgentEvent{
 		Type:   EventRunning,
@@ -607,13 +809,15 @@ func (o *Orchestrator) runTask(ctx context.Context, prompt, projectBrief string,
 		if err := checkContextCancelled(ctx); err != nil {
 			return err
 		}
+		attempts.start(attempt)
+		o.updateRunTask(ctx, task.ID, func(t *state.RunTask) { t.Attempts++ })
 		planFileRead := !mustReadPlanFile
 		o.emitEvent(AgentEvent{
 			Type:   EventThinking,
 			Role:   executor.Role,
 			Detail: fmt.Sprintf("%s is thinking about %s (attempt %d/3)", rolePrompt, task.ID, attempt),
 		})
-		coderOut, err := executor.RunWithOptions(ctx, taskPrompt, o.Session, o.DB, RunOptions{
+		coderOut, err := executor.RunWithOptions(ctx, o.withSteering(taskPrompt, executor.Role), o.Session, o.DB, o.withRunScope(RunOptions{
 			Mode:    DispatchModeTask,
 			OnToken: o.streamTokenCallback(executor.Role),
 			OnToolCall: func(name string, params map[string]any, _ ToolResult, toolErr error) {
@@ -627,7 +831,7 @@ func (o *Orchestrator) runTask(ctx context.Context, prompt, projectBrief string,
 					planFileRead = true
 				}
 			},
-		})
+		}))
 		if err != nil {
 			err = normalizeCancellationErr(err)
 			if IsUserCancelled(err) {
@@ -638,6 +842,7 @@ func (o *Orchestrator) runTask(ctx context.Context, prompt, projectBrief string,
 				o.emitEvent(AgentEvent{Type: EventError, Role: executor.Role, Detail: fmt.Sprintf("execution failed for %s", task.ID)})
 				return err
 			}
+			attempts.retry("executor failed: " + err.Error())
 			continue
 		}
 		if mustReadPlanFile && !planFileRead {
@@ -662,11 +867,33 @@ func (o *Orchestrator) runTask(ctx context.Context, prompt, projectBrief string,
 				o.emitEvent(AgentEvent{Type: EventError, Role: executor.Role, Detail: err.Error()})
 				return err
 			}
+			attempts.retry(fmt.Sprintf("coder did not read the plan file %s", planPath))
 			taskPrompt = strings.TrimSpace(taskPrompt +
 				"\n\nEnforcement:\n- Call read_file with path " + planPath + " before making any edits.\n- Use that file as the source of truth.\n- Do not proceed from memory.")
 			continue
 		}
 
+		if o.ReviewWrites {
+			feedback, err := o.reviewStagedWrites(ctx, task, executor.Role)
+			if err != nil {
+				return err
+			}
+			if feedback != "" {
+				if attempt == 3 {
+					err := fmt.Errorf("task %s: changes rejected in review after retries", task.ID)
+					o.emit(StepUpdate{StepID: task.ID, Status: "blocked", Msg: "Changes were still rejected in review after retries"})
+					o.emitEvent(AgentEvent{Type: EventError, Role: executor.Role, Detail: err.Error()})
+					return err
+				}
+				attempts.retry("changes rejected in review")
+				taskPrompt = strings.TrimSpace(basePrompt + "\n\n" + feedback)
+				if fileContext != "" {
+					taskPrompt = strings.TrimSpace(taskPrompt + "\n\nRelevant file contents:\n" + fileContext)
+				}
+				continue
+			}
+		}
+
 		missingFiles, verifyErr := o.verifyCreatedFiles(task.FilesToCreate)
 		if verifyErr != nil {
 			if attempt == 3 {
@@ -674,6 +901,7 @@ func (o *Orchestrator) runTask(ctx context.Context, prompt, projectBrief string,
 				o.emitEvent(AgentEvent{Type: EventError, Role: executor.Role, Detail: fmt.Sprintf("verification failed for %s", task.ID)})
 				return verifyErr
 			}
+			attempts.retry("verification error: " + verifyErr.Error())
 			taskPrompt = strings.TrimSpace(taskPrompt + "\n\nVerification failed due to an environment error:\n" + verifyErr.Error() + "\nRetry the task and ensure required file writes are executed via tools.")
 			continue
 		}
@@ -700,6 +928,7 @@ func (o *Orchestrator) runTask(ctx context.Context, prompt, projectBrief string,
 				return err
 			}
 
+			attempts.retry("expected file(s) missing on disk: " + missingList)
 			retryPrompt := []string{
 				taskPrompt,
 				"",
@@ -716,11 +945,40 @@ func (o *Orchestrator) runTask(ctx context.Context, prompt, projectBrief string,
 			continue
 		}
 
+		verifyResults, err := o.runVerification(ctx, executor.Role, task)
+		if err != nil {
+			return normalizeCancellationErr(err)
+		}
+		if failed, ok := verificationFailed(verifyResults); ok {
+			o.emit(StepUpdate{
+				StepID: task.ID,
+				Status: "running",
+				Msg:    fmt.Sprintf("Verification failed: %s. Retrying with command output.", failed.Command),
+			})
+			o.emitEvent(AgentEvent{
+				Type:   EventWaiting,
+				Role:   executor.Role,
+				Detail: fmt.Sprintf("retrying %s after failed verification: %s", task.ID, failed.Command),
+			})
+			if attempt == 3 {
+				err := fmt.Errorf("task %s failed verification after retries: %s: %v", task.ID, failed.Command, failed.Err)
+				o.emit(StepUpdate{StepID: task.ID, Status: "blocked", Msg: fmt.Sprintf("Verification command kept failing: %s", failed.Command)})
+				o.emitEvent(AgentEvent{Type: EventError, Role: executor.Role, Detail: err.Error()})
+				return err
+			}
+			attempts.retry("verification command failed: " + failed.Command)
+			taskPrompt = strings.TrimSpace(basePrompt + "\n\n" + renderVerificationFailure(failed))
+			if fileContext != "" {
+				taskPrompt = strings.TrimSpace(taskPrompt + "\n\nRelevant file contents:\n" + fileContext)
+			}
+			continue
+		}
+
 		if reviewer == nil {
-			o.emit(StepUpdate{StepID: task.ID, Status: "done", Msg: "Task completed (no reviewer configured)"})
-			if err := o.updatePlanTaskStatus(ctx, planPath, task.ID, true); err != nil {
-				o.emitEvent(AgentEvent{Type: EventError, Role: RolePlanner, Detail: err.Error()})
+			if err := o.finishTask(ctx, task, planPath, executor.Role, coderOut, "done"); err != nil {
+				return err
 			}
+			o.emit(StepUpdate{StepID: task.ID, Status: "done", Msg: "Task completed (no reviewer configured)"})
 			o.emitEvent(AgentEvent{Type: EventDone, Role: executor.Role, Detail: fmt.Sprintf("task %s complete", task.ID)})
 			return nil
 		}
@@ -731,16 +989,16 @@ func (o *Orchestrator) runTask(ctx context.Context, prompt, projectBrief string,
 			Role:   reviewer.Role,
 			Detail: fmt.Sprintf("reviewing %s", task.ID),
 		})
-		reviewPrompt := o.buildReviewPrompt(projectBrief, prompt, task, coderOut)
+		reviewPrompt := o.buildReviewPrompt(projectBrief, prompt, task, coderOut, renderVerificationSummary(verifyResults))
 		o.emitEvent(AgentEvent{
 			Type:   EventThinking,
 			Role:   reviewer.Role,
 			Detail: fmt.Sprintf("%s analyzing %s (attempt %d/3)", strings.ToUpper(strings.TrimSpace(string(reviewer.Role))), task.ID, attempt),
 		})
-		reviewOut, err := reviewer.RunWithOptions(ctx, reviewPrompt, o.Session, o.DB, RunOptions{
+		reviewOut, err := reviewer.RunWithOptions(ctx, o.withSteering(reviewPrompt, reviewer.Role), o.Session, o.DB, o.withRunScope(RunOptions{
 			Mode:    DispatchModeTask,
 			OnToken: o.streamTokenCallback(reviewer.Role),
-		})
+		}))
 		if err != nil {
 			err = normalizeCancellationErr(err)
 			if IsUserCancelled(err) {
@@ -751,19 +1009,22 @@ func (o *Orchestrator) runTask(ctx context.Context, prompt, projectBrief string,
 				o.emitEvent(AgentEvent{Type: EventError, Role: reviewer.Role, Detail: fmt.Sprintf("review failed for %s", task.ID)})
 				return err
 			}
+			attempts.retry("reviewer failed: " + err.Error())
 			continue
 		}
 
 		approved, findings := parseReviewDecision(reviewOut)
 		if approved {
-			o.emit(StepUpdate{StepID: task.ID, Status: "done", Msg: "Approved"})
-			if err := o.updatePlanTaskStatus(ctx, planPath, task.ID, true); err != nil {
-				o.emitEvent(AgentEvent{Type: EventError, Role: RolePlanner, Detail: err.Error()})
+			if err := o.finishTask(ctx, task, planPath, executor.Role, coderOut, "approved"); err != nil {
+				return err
 			}
+			o.emit(StepUpdate{StepID: task.ID, Status: "done", Msg: "Approved"})
 			o.emitEvent(AgentEvent{Type: EventDone, Role: reviewer.Role, Detail: fmt.Sprintf("approved %s", task.ID)})
 			return nil
 		}
 
+		attempts.retry("reviewer requested changes:\n" + findings)
+		o.updateRunTask(ctx, task.ID, func(t *state.RunTask) { t.Findings = findings })
 		taskPrompt = o.buildTaskPrompt(projectBrief, prompt, task, planPath, strategy, findings, executor.Role, execMode)
 		if fileContext != "" {
 			taskPrompt = strings.TrimSpace(taskPrompt + "\n\nRelevant file contents:\n" + fileContext)
@@ -780,6 +1041,60 @@ func (o *Orchestrator) runTask(ctx context.Context, prompt, projectBrief string,
 	return fmt.Errorf("task %s blocked after retries", task.ID)
 }
 
+// finishTask marks the task done in the plan file, commits its changes when
+// git integration is enabled, and records the outcome in task_results.
+func (o *Orchestrator) finishTask(ctx context.Context, task PlanTask, planPath string, role Role, output, status string) error {
+	if err := o.updatePlanTaskStatus(ctx, planPath, task.ID, true); err != nil {
+		o.emitEvent(AgentEvent{Type: EventError, Role: RolePlanner, Detail: err.Error()})
+	}
+
+	var (
+		sha string
+		err error
+	)
+	if o.activeWorktree != nil {
+		sha, err = o.mergeTaskWorktree(ctx, o.activeWorktree, o.runPlanID, task)
+	} else {
+		sha, err = o.commitTask(ctx, o.effectiveWorkingDir(), o.runPlanID, task)
+	}
+	if err != nil {
+		err = normalizeCancellationErr(err)
+		if errors.Is(err, ErrMergeConflict) {
+			o.emit(StepUpdate{StepID: task.ID, Status: "blocked", Msg: fmt.Sprintf("%v; task branch kept for manual resolution", err)})
+			o.emitEvent(AgentEvent{Type: EventError, Role: role, Detail: fmt.Sprintf("merge conflict for %s", task.ID)})
+		} else if !IsUserCancelled(err) {
+			o.emit(StepUpdate{StepID: task.ID, Status: "blocked", Msg: fmt.Sprintf("Commit failed: %v", err)})
+			o.emitEvent(AgentEvent{Type: EventError, Role: role, Detail: fmt.Sprintf("commit failed for %s: %v", task.ID, err)})
+		}
+		return err
+	}
+	if sha != "" {
+		o.emit(StepUpdate{StepID: task.ID, Status: "running", Msg: fmt.Sprintf("Committed %s", shortSHA(sha))})
+	}
+
+	if o.DB != nil && o.Session != nil {
+		if err := o.DB.SaveTaskResult(ctx, state.TaskResult{
+			SessionID: o.Session.ID,
+			AgentRole: string(role),
+			Input:     task.ID + ": " + task.Description,
+			Output:    output,
+			Status:    status,
+			CommitSHA: sha,
+		}); err != nil {
+			o.emitEvent(AgentEvent{Type: EventError, Role: role, Detail: fmt.Sprintf("save task result %s: %v", task.ID, err)})
+		}
+	}
+	return nil
+}
+
+func shortSHA(sha string) string {
+	sha = strings.TrimSpace(sha)
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
+
 func (o *Orchestrator) buildPlannerPrompt(projectBrief, prompt string) string {
 	return strings.TrimSpace(fmt.Sprintf(`
 Project context:
@@ -795,6 +1110,7 @@ tasks:
     files_to_modify: [optional, ...]
     files_to_create: [optional, ...]
     depends_on: [optional, ...]
+    verify: [optional shell commands that must pass, e.g. go test ./pkg/...]
 `, projectBrief, prompt))
 }
 
@@ -820,6 +1136,9 @@ func (o *Orchestrator) buildTaskPrompt(projectBrief, prompt string, task PlanTas
 	if strings.TrimSpace(reviewerFindings) != "" {
 		feedbackBlock = "\nReviewer feedback to address before completing:\n" + reviewerFindings + "\n"
 	}
+	if commands := o.verificationCommands(task); len(commands) > 0 {
+		feedbackBlock += "\nThese verification commands must pass before review: " + strings.Join(commands, "; ") + "\n"
+	}
 
 	if shouldRequirePlanFileRead(executionMode, executorRole, planPath) {
 		return strings.TrimSpace(fmt.Sprintf(`
@@ -888,7 +1207,10 @@ func normalizeRelativePathForMatch(path string) string {
 	return strings.TrimPrefix(path, "./")
 }
 
-func (o *Orchestrator) buildReviewPrompt(projectBrief, prompt string, task PlanTask, executorOutput string) string {
+func (o *Orchestrator) buildReviewPrompt(projectBrief, prompt string, task PlanTask, executorOutput, verification string) string {
+	if verification = strings.TrimSpace(verification); verification != "" {
+		verification = "\n" + verification + "\n"
+	}
 	return strings.TrimSpace(fmt.Sprintf(`
 Project context:
 %s
@@ -901,10 +1223,10 @@ Task description: %s
 
 Executor output:
 %s
-
+%s
 You are the Reviewer. Return ONLY JSON with this schema:
 {"approved": true|false, "findings":[{"file":"path","line":1,"severity":"critical|high|medium|low","description":"issue"}]}
-`, projectBrief, prompt, task.ID, task.Description, executorOutput))
+`, projectBrief, prompt, task.ID, task.Description, executorOutput, verification))
 }
 
 func parseYAMLPlan(raw string) (YAMLPlan, error) {
@@ -1025,12 +1347,23 @@ func depsSatisfied(deps []string, completed map[string]bool) bool {
 var planIDUnsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
 
 func (o *Orchestrator) bindAgentToolSets(strategy ExecutionStrategy) {
-	workingDir := o.effectiveWorkingDir()
+	o.bindAgentToolSetsAt(strategy, o.effectiveWorkingDir())
+}
+
+func (o *Orchestrator) bindAgentToolSetsAt(strategy ExecutionStrategy, workingDir string) {
+	if o.stagedWrites == nil {
+		o.stagedWrites = NewStagedWrites()
+	}
+	matcher := ignore.New(effectiveWorkingDir(workingDir))
 	if o.Planner != nil {
 		plannerEnv := ToolEnv{
 			WorkingDir: workingDir,
 			Role:       RolePlanner,
 			Emit:       o.emitEvent,
+			Checkpoint: o.recordCheckpoint,
+			Index:      o.Planner.Indexer,
+			Stage:      o.stagedWrites,
+			Ignore:     matcher,
 		}
 		plannerTools := DefaultToolSetForRole(RolePlanner, plannerEnv)
 		if strategy == StrategyNoCoder || strategy == StrategySolo {
@@ -1048,6 +1381,9 @@ func (o *Orchestrator) bindAgentToolSets(strategy ExecutionStrategy) {
 			WorkingDir: workingDir,
 			Role:       RoleCoder,
 			Emit:       o.emitEvent,
+			Checkpoint: o.recordCheckpoint,
+			Stage:      o.stagedWrites,
+			Ignore:     matcher,
 		})
 	}
 	if o.Reviewer != nil {
@@ -1055,6 +1391,7 @@ func (o *Orchestrator) bindAgentToolSets(strategy ExecutionStrategy) {
 			WorkingDir: workingDir,
 			Role:       RoleReviewer,
 			Emit:       o.emitEvent,
+			Ignore:     matcher,
 		})
 	}
 }
@@ -1389,7 +1726,7 @@ func (o *Orchestrator) verifyCreatedFiles(paths []string) ([]string, error) {
 	if len(paths) == 0 {
 		return nil, nil
 	}
-	workingDir := o.effectiveWorkingDir()
+	workingDir := o.taskWorkingDir()
 	missing := make([]string, 0, len(paths))
 	seen := make(map[string]struct{}, len(paths))
 	for _, path := range paths {
@@ -1429,11 +1766,13 @@ func (o *Orchestrator) streamTokenCallback(role Role) func(token string) {
 	}
 }
 
-func (o *Orchestrator) runConversational(ctx context.Context, prompt string, strategy ExecutionStrategy) error {
+func (o *Orchestrator) runConversational(ctx context.Context, prompt string, strategy ExecutionStrategy, responder *Agent) error {
 	if err := checkContextCancelled(ctx); err != nil {
 		return err
 	}
-	responder := o.Planner
+	if responder == nil {
+		responder = o.Planner
+	}
 	if responder == nil {
 		responder = o.selectExecutor(strategy)
 	}
@@ -1449,10 +1788,10 @@ func (o *Orchestrator) runConversational(ctx context.Context, prompt string, str
 		Role:   responder.Role,
 		Detail: "preparing conversational response",
 	})
-	reply, err := responder.RunWithOptions(ctx, prompt, o.Session, o.DB, RunOptions{
+	reply, err := responder.RunWithOptions(ctx, prompt, o.Session, o.DB, o.withRunScope(RunOptions{
 		Mode:    DispatchModeChat,
 		OnToken: o.streamTokenCallback(responder.Role),
-	})
+	}))
 	if err != nil {
 		err = normalizeCancellationErr(err)
 		if IsUserCancelled(err) {
@@ -755,3 +755,34 @@ func TestOrchestratorConversationalBypassesPlanning(t *testing.T) {
 		t.Fatal("expected done event for conversational response")
 	}
 }
+
+func TestOrchestratorOverridesPinDispatchAndRole(t *testing.T) {
+	t.Parallel()
+	workDir := t.TempDir()
+
+	plannerProv := &sequenceProvider{replies: []string{"planner reply"}}
+	reviewerProv := &sequenceProvider{replies: []string{"no issues found"}}
+	orc := &Orchestrator{
+		Planner:    newTestAgent(RolePlanner, plannerProv),
+		Reviewer:   newTestAgent(RoleReviewer, reviewerProv),
+		UpdateChan: make(chan StepUpdate, 32),
+		EventChan:  make(chan AgentEvent, 64),
+		WorkingDir: workDir,
+		Session:    &state.Session{ExecutionMode: state.ExecutionModePlan},
+	}
+
+	// "fix" would normally make this a planned task.
+	overrides := RunOverrides{Role: RoleReviewer, Dispatch: DispatchModeChat}
+	if err := orc.RunWithOverrides(context.Background(), "audit the fix in auth.go", overrides); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(reviewerProv.prompts) != 1 || len(plannerProv.prompts) != 0 {
+		t.Fatalf("expected only the reviewer to answer, planner=%d reviewer=%d", len(plannerProv.prompts), len(reviewerProv.prompts))
+	}
+	if _, err := os.Stat(filepath.Join(workDir, ".orchestra", "plans")); !os.IsNotExist(err) {
+		t.Fatalf("expected the chat override to skip planning, stat err=%v", err)
+	}
+	if got := orc.executionMode(); got != state.ExecutionModePlan {
+		t.Fatalf("expected the session mode back after the run, got %q", got)
+	}
+}
@@ -0,0 +1,164 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PlanIssue is one problem ValidatePlan found in a plan.
+type PlanIssue struct {
+	TaskID string
+	Msg    string
+}
+
+func (i PlanIssue) Error() string {
+	if i.TaskID == "" {
+		return i.Msg
+	}
+	return fmt.Sprintf("%s: %s", i.TaskID, i.Msg)
+}
+
+// ParsePlanYAML parses and normalizes a plan the way the orchestrator does
+// before running it.
+func ParsePlanYAML(raw string) (YAMLPlan, error) {
+	return parseYAMLPlan(raw)
+}
+
+// RenderPlanYAML renders a plan back to the YAML the planner emits.
+func RenderPlanYAML(plan YAMLPlan) string {
+	return renderPlanYAML(plan)
+}
+
+// ValidatePlan reports duplicate or empty task IDs, dependencies on unknown
+// tasks, dependency cycles and file paths outside root. An empty root means
+// the current directory.
+func ValidatePlan(plan YAMLPlan, root string) []PlanIssue {
+	var issues []PlanIssue
+	if len(plan.Tasks) == 0 {
+		return []PlanIssue{{Msg: "plan has no tasks"}}
+	}
+	root = effectiveWorkingDir(root)
+
+	ids := make(map[string]int, len(plan.Tasks))
+	for idx, task := range plan.Tasks {
+		id := strings.TrimSpace(task.ID)
+		if id == "" {
+			issues = append(issues, PlanIssue{Msg: fmt.Sprintf("task %d has no id", idx+1)})
+			continue
+		}
+		if _, ok := ids[id]; ok {
+			issues = append(issues, PlanIssue{TaskID: id, Msg: "duplicate task id"})
+			continue
+		}
+		ids[id] = idx
+	}
+
+	for _, task := range plan.Tasks {
+		id := strings.TrimSpace(task.ID)
+		if strings.TrimSpace(task.Description) == "" {
+			issues = append(issues, PlanIssue{TaskID: id, Msg: "description is empty"})
+		}
+		for _, dep := range task.DependsOn {
+			dep = strings.TrimSpace(dep)
+			switch {
+			case dep == id:
+				issues = append(issues, PlanIssue{TaskID: id, Msg: "depends on itself"})
+			case dep == "":
+				issues = append(issues, PlanIssue{TaskID: id, Msg: "has an empty dependency"})
+			default:
+				if _, ok := ids[dep]; !ok {
+					issues = append(issues, PlanIssue{TaskID: id, Msg: fmt.Sprintf("depends on unknown task %q", dep)})
+				}
+			}
+		}
+		for _, path := range append(append([]string(nil), task.FilesToModify...), task.FilesToCreate...) {
+			if strings.TrimSpace(path) == "" {
+				continue
+			}
+			if _, _, err := resolveWorkspacePath(root, path); err != nil {
+				issues = append(issues, PlanIssue{TaskID: id, Msg: fmt.Sprintf("path %q is outside the workspace", path)})
+			}
+		}
+	}
+
+	for _, cycle := range planCycles(plan) {
+		issues = append(issues, PlanIssue{TaskID: cycle[0], Msg: "dependency cycle " + strings.Join(cycle, " -> ")})
+	}
+	return issues
+}
+
+// planIssuesError folds issues into one error, or nil when there are none.
+func planIssuesError(issues []PlanIssue) error {
+	if len(issues) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		msgs = append(msgs, issue.Error())
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+// planCycles returns each dependency cycle once, as the task IDs along it
+// with the first repeated at the end.
+func planCycles(plan YAMLPlan) [][]string {
+	deps := make(map[string][]string, len(plan.Tasks))
+	order := make([]string, 0, len(plan.Tasks))
+	for _, task := range plan.Tasks {
+		id := strings.TrimSpace(task.ID)
+		if _, ok := deps[id]; ok || id == "" {
+			continue
+		}
+		order = append(order, id)
+		for _, dep := range task.DependsOn {
+			dep = strings.TrimSpace(dep)
+			if dep != "" && dep != id {
+				deps[id] = append(deps[id], dep)
+			}
+		}
+		if deps[id] == nil {
+			deps[id] = []string{}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(order))
+	var (
+		stack  []string
+		cycles [][]string
+		visit  func(id string)
+	)
+	visit = func(id string) {
+		state[id] = visiting
+		stack = append(stack, id)
+		for _, dep := range deps[id] {
+			if _, known := deps[dep]; !known {
+				continue
+			}
+			switch state[dep] {
+			case unvisited:
+				visit(dep)
+			case visiting:
+				start := len(stack) - 1
+				for stack[start] != dep {
+					start--
+				}
+				cycle := append([]string(nil), stack[start:]...)
+				cycles = append(cycles, append(cycle, dep))
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = visited
+	}
+	for _, id := range order {
+		if state[id] == unvisited {
+			visit(id)
+		}
+	}
+	return cycles
+}
@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidatePlanAcceptsAValidPlan(t *testing.T) {
+	t.Parallel()
+
+	plan := YAMLPlan{Tasks: []PlanTask{
+		{ID: "a", Description: "first", FilesToCreate: []string{"cmd/a.go"}},
+		{ID: "b", Description: "second", FilesToModify: []string{"./internal/b.go"}, DependsOn: []string{"a"}},
+	}}
+	if issues := ValidatePlan(plan, t.TempDir()); len(issues) != 0 {
+		t.Fatalf("expected no issues, got %v", issues)
+	}
+}
+
+func TestValidatePlanReportsEachProblem(t *testing.T) {
+	t.Parallel()
+
+	plan := YAMLPlan{Tasks: []PlanTask{
+		{ID: "a", Description: "first", DependsOn: []string{"c"}},
+		{ID: "a", Description: "duplicate"},
+		{ID: "b", Description: "", DependsOn: []string{"missing", "b"}, FilesToModify: []string{"../outside.go"}},
+		{ID: "c", Description: "third", DependsOn: []string{"a"}, FilesToCreate: []string{"/etc/passwd"}},
+	}}
+	issues := ValidatePlan(plan, t.TempDir())
+	var msgs []string
+	for _, issue := range issues {
+		msgs = append(msgs, issue.Error())
+	}
+	joined := strings.Join(msgs, "\n")
+	for _, want := range []string{
+		"a: duplicate task id",
+		"b: description is empty",
+		`b: depends on unknown task "missing"`,
+		"b: depends on itself",
+		`b: path "../outside.go" is outside the workspace`,
+		`c: path "/etc/passwd" is outside the workspace`,
+		"a: dependency cycle a -> c -> a",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("expected %q in:\n%s", want, joined)
+		}
+	}
+	if len(issues) != 7 {
+		t.Fatalf("expected 7 issues, got %d:\n%s", len(issues), joined)
+	}
+	if err := planIssuesError(issues); err == nil || !strings.Contains(err.Error(), "; ") {
+		t.Fatalf("expected the issues folded into one error, got %v", err)
+	}
+}
@@ -0,0 +1,325 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/yubzen/orchestra/internal/state"
+)
+
+// ErrRunPaused is returned by a run stopped with Pause. ResumeRun continues
+// it with the next unfinished task.
+var ErrRunPaused = errors.New("run paused")
+
+// runState mirrors the saved state of the planned run in progress, so task
+// transitions can be written without reading the run back.
+type runState struct {
+	id    string
+	tasks map[string]state.RunTask
+	// stopHeartbeat ends the goroutine keeping the run's heartbeat fresh.
+	stopHeartbeat chan struct{}
+}
+
+// Pause stops the running orchestration once its current task finishes.
+func (o *Orchestrator) Pause() error {
+	if o == nil {
+		return ErrOrchestratorNotReady
+	}
+	o.steering.mu.Lock()
+	active := o.steering.active
+	o.steering.mu.Unlock()
+	if !active {
+		return ErrNoActiveRun
+	}
+	o.pauseRequested.Store(true)
+	return nil
+}
+
+// LastRunID returns the saved run ID of the latest run, which ResumeRun
+// accepts once that run has stopped. It is empty when that run was not
+// saved, as conversational runs are not.
+func (o *Orchestrator) LastRunID() string {
+	if o == nil {
+		return ""
+	}
+	o.checkpointMu.Lock()
+	defer o.checkpointMu.Unlock()
+	return o.lastRunID
+}
+
+// ResumeRun continues a saved run that did not complete. Its plan runs
+// again from the first unfinished task with finished tasks skipped, under
+// the prompt, role and execution mode the run was started with. The resumed
+// part is traced and checkpointed as a new run scope.
+func (o *Orchestrator) ResumeRun(ctx context.Context, runID string) (err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := checkContextCancelled(ctx); err != nil {
+		return err
+	}
+	if o == nil {
+		return ErrOrchestratorNotReady
+	}
+	if o.DB == nil || o.Session == nil {
+		return errors.New("resuming a run needs the state db and a session")
+	}
+	record, err := o.DB.GetRun(ctx, runID)
+	if err != nil {
+		return err
+	}
+	if record.Status == state.RunStatusCompleted {
+		return fmt.Errorf("run %s already completed", record.ID)
+	}
+	if !record.Resumable() {
+		return fmt.Errorf("run %s has no saved plan to resume", record.ID)
+	}
+	plan, err := parseYAMLPlan(record.PlanYAML)
+	if err != nil {
+		return fmt.Errorf("run %s has an unreadable plan: %w", record.ID, err)
+	}
+	// Claiming the run refuses one another process is still running.
+	if err := o.DB.ClaimRun(context.WithoutCancel(ctx), record.ID, os.Getpid()); err != nil {
+		return fmt.Errorf("resume run %s: %w", record.ID, err)
+	}
+	o.useRunState(*record)
+	defer func() { o.finishRunState(ctx, err) }()
+	o.beginSteering()
+	defer o.endSteering()
+	o.pauseRequested.Store(false)
+
+	availability := o.collectAvailability(ctx)
+	if err := checkContextCancelled(ctx); err != nil {
+		return err
+	}
+	strategy, err := deriveStrategy(availability)
+	if err != nil {
+		o.emit(StepUpdate{StepID: "orchestrator", Status: "failed", Msg: err.Error()})
+		o.emitEvent(AgentEvent{Type: EventError, Role: RolePlanner, Detail: err.Error()})
+		return err
+	}
+	o.bindAgentToolSets(strategy)
+	o.beginCheckpointRun()
+	runSpan := o.beginSpan(ctx, state.TraceSpan{Kind: state.SpanKindRun, Name: "resume: " + PromptPreview(record.Prompt), Input: record.Prompt})
+	defer func() { o.endSpan(ctx, runSpan, "", err) }()
+
+	o.runExecutionMode = record.ExecutionMode
+	defer func() { o.runExecutionMode = "" }()
+	execMode := o.executionMode()
+	roleAgent := o.overrideAgent(Role(record.Role), availability)
+	projectBrief := o.ensureProjectBrief()
+	o.emitEvent(AgentEvent{Type: EventRunning, Role: executionRoleForStrategy(strategy), Detail: fmt.Sprintf("resuming %s: %d/%d tasks done", record.ID, record.DoneTasks(), len(record.Tasks))})
+	o.emit(StepUpdate{
+		StepID: "orchestrator",
+		Status: "running",
+		Msg:    fmt.Sprintf("Resuming run %s: %d of %d task(s) already done | strategy: %s | mode: %s", record.ID, record.DoneTasks(), len(record.Tasks), strategyName(strategy), execMode),
+	})
+	for _, task := range record.Tasks {
+		if task.Status == state.RunTaskDone {
+			o.emit(StepUpdate{StepID: task.TaskID, Status: "done", Msg: "Completed before the run stopped; skipped"})
+		}
+	}
+
+	executor := o.selectExecutor(strategy)
+	if roleAgent != nil {
+		executor = roleAgent
+	}
+	if executor == nil {
+		err := errors.New("no execution agent available")
+		o.emit(StepUpdate{StepID: "orchestrator", Status: "failed", Msg: err.Error()})
+		o.emitEvent(AgentEvent{Type: EventError, Role: executionRoleForStrategy(strategy), Detail: err.Error()})
+		return err
+	}
+	reviewer := o.selectReviewer(strategy, executor)
+
+	o.runPlanID = record.PlanID
+	if o.runPlanID == "" {
+		o.runPlanID = o.newPlanID()
+	}
+	if _, err := o.checkoutGitBranch(ctx, o.runPlanID); err != nil {
+		err = normalizeCancellationErr(err)
+		if IsUserCancelled(err) {
+			return err
+		}
+		o.emit(StepUpdate{StepID: "git", Status: "failed", Msg: err.Error()})
+		o.emitEvent(AgentEvent{Type: EventError, Role: executor.Role, Detail: err.Error()})
+		return err
+	}
+	planPath, err := o.restorePlanFile(ctx, record, plan)
+	if err != nil {
+		err = normalizeCancellationErr(err)
+		if IsUserCancelled(err) {
+			return err
+		}
+		o.emit(StepUpdate{StepID: "planner", Status: "failed", Msg: err.Error()})
+		o.emitEvent(AgentEvent{Type: EventError, Role: RolePlanner, Detail: err.Error()})
+		return err
+	}
+	return o.runPlan(ctx, record.Prompt, projectBrief, plan, planPath, execMode, strategy, executor, reviewer)
+}
+
+// restorePlanFile returns the run's plan file, writing it again with the
+// finished tasks ticked when it was removed since the run stopped.
+func (o *Orchestrator) restorePlanFile(ctx context.Context, record *state.Run, plan YAMLPlan) (string, error) {
+	if record.PlanPath == "" {
+		return "", nil
+	}
+	absPath, _, err := resolveWorkspacePath(o.effectiveWorkingDir(), record.PlanPath)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(absPath); err == nil || !errors.Is(err, os.ErrNotExist) {
+		return record.PlanPath, err
+	}
+	planPath, err := o.persistPlanMarkdown(ctx, record.PlanID, record.Prompt, plan, record.PlanYAML)
+	if err != nil {
+		return "", err
+	}
+	for _, task := range record.Tasks {
+		if task.Status != state.RunTaskDone {
+			continue
+		}
+		if err := o.updatePlanTaskStatus(ctx, planPath, task.TaskID, true); err != nil {
+			return "", err
+		}
+	}
+	return planPath, nil
+}
+
+// startRunState saves a run that is about to execute plan. A run that could
+// not be saved still runs; it just cannot be resumed.
+func (o *Orchestrator) startRunState(ctx context.Context, prompt string, overrides RunOverrides, execMode, planPath, planYAML string, plan YAMLPlan) {
+	o.run = nil
+	if o.DB == nil || o.Session == nil {
+		return
+	}
+	runID, _ := o.runScope()
+	record := state.Run{
+		ID:            runID,
+		SessionID:     o.Session.ID,
+		Prompt:        prompt,
+		Role:          string(overrides.Role),
+		ExecutionMode: execMode,
+		PlanID:        o.runPlanID,
+		PlanPath:      planPath,
+		PlanYAML:      planYAML,
+		OwnerPID:      os.Getpid(),
+	}
+	for _, task := range plan.Tasks {
+		record.Tasks = append(record.Tasks, state.RunTask{TaskID: task.ID})
+	}
+	if err := o.DB.CreateRun(context.WithoutCancel(ctx), record); err != nil {
+		o.emitEvent(AgentEvent{Type: EventError, Role: RolePlanner, Detail: fmt.Sprintf("save run %s: %v", runID, err)})
+		return
+	}
+	o.useRunState(record)
+}
+
+// useRunState tracks a saved run this process now owns and keeps its
+// heartbeat fresh until finishRunState.
+func (o *Orchestrator) useRunState(record state.Run) {
+	run := &runState{
+		id:            record.ID,
+		tasks:         make(map[string]state.RunTask, len(record.Tasks)),
+		stopHeartbeat: make(chan struct{}),
+	}
+	for _, task := range record.Tasks {
+		task.RunID = record.ID
+		run.tasks[task.TaskID] = task
+	}
+	o.run = run
+	o.checkpointMu.Lock()
+	o.lastRunID = record.ID
+	o.checkpointMu.Unlock()
+	go o.heartbeatRun(run.id, run.stopHeartbeat)
+}
+
+// heartbeatRun refreshes the run's heartbeat until stop is closed, so other
+// processes can tell the run is live rather than interrupted.
+func (o *Orchestrator) heartbeatRun(runID string, stop <-chan struct{}) {
+	ticker := time.NewTicker(state.RunHeartbeatInterval)
+	defer ticker.Stop()
+	pid := os.Getpid()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			_ = o.DB.HeartbeatRun(context.Background(), runID, pid)
+		}
+	}
+}
+
+// finishRunState records how the run ended. A process that dies first
+// leaves the run marked running with a heartbeat that goes stale, which is
+// how interrupted runs are found.
+func (o *Orchestrator) finishRunState(ctx context.Context, err error) {
+	o.pauseRequested.Store(false)
+	if o.run == nil {
+		return
+	}
+	close(o.run.stopHeartbeat)
+	status, errMsg := runStatus(err)
+	if err := o.DB.SetRunStatus(context.WithoutCancel(ctx), o.run.id, status, errMsg); err != nil {
+		o.emitEvent(AgentEvent{Type: EventError, Role: RolePlanner, Detail: fmt.Sprintf("save run %s: %v", o.run.id, err)})
+	}
+	o.run = nil
+}
+
+func runStatus(err error) (string, string) {
+	switch {
+	case err == nil:
+		return state.RunStatusCompleted, ""
+	case errors.Is(err, ErrRunPaused):
+		return state.RunStatusPaused, ""
+	case IsUserCancelled(err):
+		return state.RunStatusCancelled, ""
+	default:
+		return state.RunStatusFailed, err.Error()
+	}
+}
+
+// updateRunTask applies change to the saved state of taskID.
+func (o *Orchestrator) updateRunTask(ctx context.Context, taskID string, change func(*state.RunTask)) {
+	if o.run == nil {
+		return
+	}
+	task, ok := o.run.tasks[taskID]
+	if !ok {
+		return
+	}
+	change(&task)
+	o.run.tasks[taskID] = task
+	if err := o.DB.SaveRunTask(context.WithoutCancel(ctx), task); err != nil {
+		o.emitEvent(AgentEvent{Type: EventError, Role: RolePlanner, Detail: fmt.Sprintf("save task state %s: %v", taskID, err)})
+	}
+}
+
+// runTaskOutcome is the saved status of a task that returned err. Tasks
+// stopped by a cancel are left pending so a resume runs them again.
+func runTaskOutcome(err error) string {
+	switch {
+	case err == nil:
+		return state.RunTaskDone
+	case IsUserCancelled(err):
+		return state.RunTaskPending
+	default:
+		return state.RunTaskBlocked
+	}
+}
+
+func (o *Orchestrator) runTaskDone(taskID string) bool {
+	return o.run != nil && o.run.tasks[taskID].Status == state.RunTaskDone
+}
+
+// runTaskFindings returns the reviewer findings saved for taskID, so a
+// resumed task picks up the review that was pending when the run stopped.
+func (o *Orchestrator) runTaskFindings(taskID string) string {
+	if o.run == nil {
+		return ""
+	}
+	return o.run.tasks[taskID].Findings
+}
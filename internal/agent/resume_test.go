@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yubzen/orchestra/internal/providers"
+	"github.com/yubzen/orchestra/internal/state"
+)
+
+// pausingProvider plans two tasks, answers every later call and runs
+// onCall before answering, with the 1-based call number.
+type pausingProvider struct {
+	onCall  func(n int)
+	prompts []string
+}
+
+func (p *pausingProvider) Name() string                                     { return "pausing" }
+func (p *pausingProvider) Ping(ctx context.Context) error                   { return nil }
+func (p *pausingProvider) ListModels(ctx context.Context) ([]string, error) { return nil, nil }
+func (p *pausingProvider) Complete(ctx context.Context, model string, messages []providers.Message, tools []providers.Tool, onToken providers.TokenCallback) (providers.CompletionResponse, error) {
+	p.prompts = append(p.prompts, messages[len(messages)-1].Content)
+	if p.onCall != nil {
+		p.onCall(len(p.prompts))
+	}
+	if len(p.prompts) == 1 {
+		return providers.CompletionResponse{Text: "```yaml\ntasks:\n  - id: t1\n    description: add the cache\n  - id: t2\n    description: wire the cache\n    depends_on: [t1]\n```"}, nil
+	}
+	return providers.CompletionResponse{Text: `{"status":"done"}`}, nil
+}
+
+func TestPausedRunResumesFromTheFirstUnfinishedTask(t *testing.T) {
+	t.Parallel()
+	workDir := t.TempDir()
+	orc := newCheckpointTestOrchestrator(t, workDir)
+	provider := &pausingProvider{}
+	provider.onCall = func(n int) {
+		if n == 2 {
+			if err := orc.Pause(); err != nil {
+				t.Errorf("pause: %v", err)
+			}
+		}
+	}
+	orc.Planner = newTestAgent(RolePlanner, provider)
+	orc.UpdateChan = make(chan StepUpdate, 128)
+	orc.ProjectBrief = "Working directory: ."
+	orc.Session.ExecutionMode = state.ExecutionModePlan
+	orc.writePlanLockFn = func(context.Context, string) error { return nil }
+
+	ctx := context.Background()
+	if err := orc.Run(ctx, "add a cache"); !errors.Is(err, ErrRunPaused) {
+		t.Fatalf("expected the run to pause, got %v", err)
+	}
+	runID := orc.LastRunID()
+	run, err := orc.DB.GetRun(ctx, runID)
+	if err != nil {
+		t.Fatalf("get run: %v", err)
+	}
+	if run.Status != state.RunStatusPaused || run.ExecutionMode != state.ExecutionModePlan || run.PlanPath == "" {
+		t.Fatalf("unexpected paused run: %+v", run)
+	}
+	if len(run.Tasks) != 2 || run.Tasks[0].Status != state.RunTaskDone || run.Tasks[0].Attempts != 1 || run.Tasks[1].Status != state.RunTaskPending {
+		t.Fatalf("expected t1 done and t2 pending, got %+v", run.Tasks)
+	}
+	if err := orc.Pause(); !errors.Is(err, ErrNoActiveRun) {
+		t.Fatalf("expected pause without a run to fail, got %v", err)
+	}
+
+	if err := orc.ResumeRun(ctx, runID); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if len(provider.prompts) != 3 {
+		t.Fatalf("expected only t2 to run on resume, got %d model calls", len(provider.prompts))
+	}
+	if resumed := provider.prompts[2]; !strings.Contains(resumed, "Task ID: t2") {
+		t.Fatalf("expected the resumed call to execute t2, got %q", resumed)
+	}
+	run, err = orc.DB.GetRun(ctx, runID)
+	if err != nil {
+		t.Fatalf("get run: %v", err)
+	}
+	if run.Status != state.RunStatusCompleted || run.DoneTasks() != 2 {
+		t.Fatalf("expected the resumed run to complete, got %+v", run)
+	}
+	if err := orc.ResumeRun(ctx, runID); err == nil || !strings.Contains(err.Error(), "already completed") {
+		t.Fatalf("expected a completed run to refuse resuming, got %v", err)
+	}
+}
+
+func TestRunInProgressIsLiveAndRefusesResume(t *testing.T) {
+	t.Parallel()
+	workDir := t.TempDir()
+	orc := newCheckpointTestOrchestrator(t, workDir)
+	ctx := context.Background()
+	provider := &pausingProvider{}
+	provider.onCall = func(n int) {
+		if n != 2 {
+			return
+		}
+		run, err := orc.DB.GetRun(ctx, orc.LastRunID())
+		if err != nil {
+			t.Errorf("get run: %v", err)
+			return
+		}
+		if run.OwnerPID != os.Getpid() || !run.Live(time.Now()) {
+			t.Errorf("expected the run in progress to be live, got %+v", run)
+		}
+		if interrupted, _ := orc.DB.ListInterruptedRuns(ctx); len(interrupted) != 0 {
+			t.Errorf("expected no interrupted runs while running, got %+v", interrupted)
+		}
+		if err := orc.DB.ClaimRun(ctx, run.ID, os.Getpid()+1); !errors.Is(err, state.ErrRunLive) {
+			t.Errorf("expected another process's claim to fail, got %v", err)
+		}
+	}
+	orc.Planner = newTestAgent(RolePlanner, provider)
+	orc.UpdateChan = make(chan StepUpdate, 128)
+	orc.ProjectBrief = "Working directory: ."
+	orc.Session.ExecutionMode = state.ExecutionModePlan
+	orc.writePlanLockFn = func(context.Context, string) error { return nil }
+
+	if err := orc.Run(ctx, "add a cache"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	live := state.Run{ID: "run-elsewhere", SessionID: orc.Session.ID, Prompt: "wire the cache", PlanYAML: "tasks:\n  - id: t1\n    description: wire it\n", OwnerPID: os.Getpid() + 1, Tasks: []state.RunTask{{TaskID: "t1"}}}
+	if err := orc.DB.CreateRun(ctx, live); err != nil {
+		t.Fatalf("create run: %v", err)
+	}
+	calls := len(provider.prompts)
+	if err := orc.ResumeRun(ctx, live.ID); !errors.Is(err, state.ErrRunLive) {
+		t.Fatalf("expected a live run to refuse resuming, got %v", err)
+	}
+	if len(provider.prompts) != calls {
+		t.Fatal("expected no model calls for a refused resume")
+	}
+	if run, _ := orc.DB.GetRun(ctx, live.ID); run.Status != state.RunStatusRunning || run.OwnerPID != live.OwnerPID {
+		t.Fatalf("expected the live run to be left alone, got %+v", run)
+	}
+}
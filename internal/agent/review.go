@@ -0,0 +1,558 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// diffContextLines is how many unchanged lines a hunk shows around a change.
+const diffContextLines = 3
+
+type HunkDecision int
+
+const (
+	HunkPending HunkDecision = iota
+	HunkAccepted
+	HunkRejected
+	HunkEdited
+)
+
+// Hunk is one contiguous change between a file's old and new lines. Old is
+// replaced by New (or by Edited when the user edited the hunk) starting at
+// old line OldStart, counted from 0. Before and After are context for display.
+type Hunk struct {
+	OldStart int
+	NewStart int
+	Old      []string
+	New      []string
+	Before   []string
+	After    []string
+	Decision HunkDecision
+	Edited   []string
+}
+
+// Header returns the unified diff header, e.g. "@@ -3,2 +3,4 @@".
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart+1, len(h.Old), h.NewStart+1, len(h.New))
+}
+
+// Replacement returns the lines the hunk puts in place of Old.
+func (h Hunk) Replacement() []string {
+	switch h.Decision {
+	case HunkAccepted:
+		return h.New
+	case HunkEdited:
+		return h.Edited
+	default:
+		return h.Old
+	}
+}
+
+// StagedFile is a write held back for review. Before is the file as it was
+// on disk when it was first staged; After is the latest proposed content.
+type StagedFile struct {
+	Path    string
+	Existed bool
+	Before  []byte
+	After   []byte
+}
+
+// Hunks splits the staged change into hunks, all pending.
+func (f StagedFile) Hunks() []Hunk {
+	return ComputeHunks(splitLinesForDiff(string(f.Before)), splitLinesForDiff(string(f.After)))
+}
+
+// Apply returns the content after applying the decided hunks to Before.
+// Pending and rejected hunks keep the original lines.
+func (f StagedFile) Apply(hunks []Hunk) []byte {
+	changed := false
+	for _, h := range hunks {
+		if h.Decision == HunkAccepted || h.Decision == HunkEdited {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return f.Before
+	}
+	lines := ApplyHunks(splitLinesForDiff(string(f.Before)), hunks)
+	if len(lines) == 0 {
+		return []byte{}
+	}
+	newline := "\n"
+	if strings.Contains(string(f.Before), "\r\n") {
+		newline = "\r\n"
+	}
+	content := strings.Join(lines, newline)
+	if len(f.After) == 0 || strings.HasSuffix(string(f.After), "\n") {
+		content += newline
+	}
+	return []byte(content)
+}
+
+// ComputeHunks diffs two line slices and groups each run of changed lines
+// into its own hunk, so every change can be decided on separately.
+func ComputeHunks(oldLines, newLines []string) []Hunk {
+	// Trim the common prefix and suffix so only the changed middle is diffed.
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+	oldMid := oldLines[prefix : len(oldLines)-suffix]
+	newMid := newLines[prefix : len(newLines)-suffix]
+
+	var hunks []Hunk
+	var current *Hunk
+	flush := func() {
+		if current == nil {
+			return
+		}
+		hunks = append(hunks, *current)
+		current = nil
+	}
+	i, j := 0, 0
+	for _, op := range diffLineOps(oldMid, newMid) {
+		if op == diffEqual {
+			flush()
+			i++
+			j++
+			continue
+		}
+		if current == nil {
+			current = &Hunk{OldStart: prefix + i, NewStart: prefix + j}
+		}
+		if op == diffDelete {
+			current.Old = append(current.Old, oldMid[i])
+			i++
+		} else {
+			current.New = append(current.New, newMid[j])
+			j++
+		}
+	}
+	flush()
+
+	for idx := range hunks {
+		h := &hunks[idx]
+		start := max(0, h.OldStart-diffContextLines)
+		h.Before = oldLines[start:h.OldStart]
+		end := min(len(oldLines), h.OldStart+len(h.Old)+diffContextLines)
+		h.After = oldLines[h.OldStart+len(h.Old) : end]
+	}
+	return hunks
+}
+
+type diffOp byte
+
+const (
+	diffEqual diffOp = iota
+	diffDelete
+	diffInsert
+)
+
+// diffLineOps returns a shortest edit script turning oldLines into
+// newLines. It uses Myers' linear-space divide and conquer, so memory stays
+// linear in the line counts however large the files are, and time grows
+// with the size of the change rather than the product of the lengths.
+func diffLineOps(oldLines, newLines []string) []diffOp {
+	// Comparing interned ids keeps the snake scans cheap.
+	ids := make(map[string]int)
+	intern := func(lines []string) []int {
+		out := make([]int, len(lines))
+		for idx, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = len(ids)
+				ids[line] = id
+			}
+			out[idx] = id
+		}
+		return out
+	}
+	a, b := intern(oldLines), intern(newLines)
+	return appendDiffOps(make([]diffOp, 0, len(a)+len(b)), a, b)
+}
+
+func appendDiffOps(ops []diffOp, a, b []int) []diffOp {
+	// Equal ends need no search; trimming them at every level keeps files
+	// with a few scattered changes close to linear time.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		ops = append(ops, diffEqual)
+		prefix++
+	}
+	a, b = a[prefix:], b[prefix:]
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ops = appendDiffMiddle(ops, a[:len(a)-suffix], b[:len(b)-suffix])
+	for range suffix {
+		ops = append(ops, diffEqual)
+	}
+	return ops
+}
+
+func appendDiffMiddle(ops []diffOp, a, b []int) []diffOp {
+	x, y, ok := middleSnake(a, b)
+	if len(a) == 0 || len(b) == 0 || !ok {
+		for range a {
+			ops = append(ops, diffDelete)
+		}
+		for range b {
+			ops = append(ops, diffInsert)
+		}
+		return ops
+	}
+	ops = appendDiffOps(ops, a[:x], b[:y])
+	return appendDiffOps(ops, a[x:], b[y:])
+}
+
+// middleSnake runs Myers' search from both ends of a and b at once and
+// returns where the two paths meet, which lies on a shortest edit script.
+// It reports false when a and b share no line.
+func middleSnake(a, b []int) (int, int, bool) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	forward := make([]int, 2*maxD+2)
+	backward := make([]int, 2*maxD+2)
+	for idx := range forward {
+		forward[idx] = -1
+		backward[idx] = -1
+	}
+	forward[offset+1] = 0
+	backward[offset+1] = 0
+	delta := n - m
+	// With an odd delta the paths meet while extending the forward one.
+	odd := delta%2 != 0
+	kStart, kEnd, rStart, rEnd := 0, 0, 0, 0
+	for d := 0; d < maxD; d++ {
+		for k := -d + kStart; k <= d-kEnd; k += 2 {
+			idx := offset + k
+			var x int
+			if k == -d || (k != d && forward[idx-1] < forward[idx+1]) {
+				x = forward[idx+1]
+			} else {
+				x = forward[idx-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[idx] = x
+			switch {
+			case x > n:
+				kEnd += 2
+			case y > m:
+				kStart += 2
+			case odd:
+				if r := offset + delta - k; r >= 0 && r < len(backward) && backward[r] != -1 && x >= n-backward[r] {
+					return x, y, true
+				}
+			}
+		}
+		for k := -d + rStart; k <= d-rEnd; k += 2 {
+			idx := offset + k
+			var x int
+			if k == -d || (k != d && backward[idx-1] < backward[idx+1]) {
+				x = backward[idx+1]
+			} else {
+				x = backward[idx-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			backward[idx] = x
+			switch {
+			case x > n:
+				rEnd += 2
+			case y > m:
+				rStart += 2
+			case !odd:
+				if f := offset + delta - k; f >= 0 && f < len(forward) && forward[f] != -1 {
+					fx := forward[f]
+					if fx >= n-x {
+						return fx, offset + fx - f, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// ApplyHunks replaces each hunk's old lines with its Replacement. Hunks must
+// come from ComputeHunks on the same old lines.
+func ApplyHunks(oldLines []string, hunks []Hunk) []string {
+	out := make([]string, 0, len(oldLines))
+	pos := 0
+	for _, h := range hunks {
+		if h.OldStart < pos || h.OldStart+len(h.Old) > len(oldLines) {
+			continue
+		}
+		out = append(out, oldLines[pos:h.OldStart]...)
+		out = append(out, h.Replacement()...)
+		pos = h.OldStart + len(h.Old)
+	}
+	return append(out, oldLines[pos:]...)
+}
+
+// StagedWrites holds write_file calls back while a review is active. Reads
+// of a staged path see the staged content.
+type StagedWrites struct {
+	mu     sync.Mutex
+	active bool
+	files  map[string]*StagedFile
+	order  []string
+}
+
+func NewStagedWrites() *StagedWrites {
+	return &StagedWrites{files: make(map[string]*StagedFile)}
+}
+
+// stage records a write and reports whether it was held back. It is a no-op
+// while no review is active.
+func (s *StagedWrites) stage(relPath string, existed bool, before, after []byte) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.active {
+		return false
+	}
+	if file, ok := s.files[relPath]; ok {
+		file.After = append([]byte(nil), after...)
+		return true
+	}
+	s.files[relPath] = &StagedFile{
+		Path:    relPath,
+		Existed: existed,
+		Before:  append([]byte(nil), before...),
+		After:   append([]byte(nil), after...),
+	}
+	s.order = append(s.order, relPath)
+	return true
+}
+
+func (s *StagedWrites) content(relPath string) ([]byte, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, ok := s.files[relPath]
+	if !ok {
+		return nil, false
+	}
+	return file.After, true
+}
+
+// setActive starts or stops holding writes back. Stopping drops whatever is
+// still staged.
+func (s *StagedWrites) setActive(active bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active = active
+	if !active {
+		s.files = make(map[string]*StagedFile)
+		s.order = nil
+	}
+}
+
+// take returns the staged files in write order and clears them. Files whose
+// proposed content matches the original are dropped.
+func (s *StagedWrites) take() []StagedFile {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]StagedFile, 0, len(s.order))
+	for _, path := range s.order {
+		file := s.files[path]
+		if file.Existed && string(file.Before) == string(file.After) {
+			continue
+		}
+		out = append(out, *file)
+	}
+	s.files = make(map[string]*StagedFile)
+	s.order = nil
+	return out
+}
+
+// FileReview carries the user's hunk decisions for one staged file. A staged
+// file without a FileReview is treated as rejected.
+type FileReview struct {
+	Path  string
+	Hunks []Hunk
+}
+
+// DiffReview answers a "review_ready" update for a task.
+type DiffReview struct {
+	TaskID string
+	Files  []FileReview
+}
+
+func (o *Orchestrator) SubmitDiffReview(review DiffReview) {
+	if o == nil || o.DiffReviewChan == nil {
+		return
+	}
+	select {
+	case o.DiffReviewChan <- review:
+	default:
+		select {
+		case <-o.DiffReviewChan:
+		default:
+		}
+		select {
+		case o.DiffReviewChan <- review:
+		default:
+		}
+	}
+}
+
+// reviewStagedWrites hands the task's staged writes to the user, applies the
+// accepted and edited hunks, and returns feedback describing the rejected
+// ones, which is empty when nothing was rejected.
+func (o *Orchestrator) reviewStagedWrites(ctx context.Context, task PlanTask, role Role) (string, error) {
+	files := o.stagedWrites.take()
+	if len(files) == 0 {
+		return "", nil
+	}
+
+	reviews := make(map[string][]Hunk, len(files))
+	if o.DiffReviewChan == nil {
+		for _, file := range files {
+			hunks := file.Hunks()
+			for idx := range hunks {
+				hunks[idx].Decision = HunkAccepted
+			}
+			reviews[file.Path] = hunks
+		}
+	} else {
+		o.emit(StepUpdate{
+			StepID: task.ID,
+			Status: "review_ready",
+			Msg:    fmt.Sprintf("%d file(s) staged for review", len(files)),
+			Staged: files,
+		})
+		o.emitEvent(AgentEvent{Type: EventWaiting, Role: role, Detail: fmt.Sprintf("waiting for review of %s changes", task.ID)})
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				return "", normalizeCancellationErr(ctx.Err())
+			case review := <-o.DiffReviewChan:
+				if strings.TrimSpace(review.TaskID) != task.ID {
+					continue
+				}
+				for _, file := range review.Files {
+					reviews[file.Path] = file.Hunks
+				}
+				break wait
+			}
+		}
+	}
+
+	var rejected []string
+	applied := 0
+	for _, file := range files {
+		hunks, reviewed := reviews[file.Path]
+		if !reviewed {
+			hunks = file.Hunks()
+			for idx := range hunks {
+				hunks[idx].Decision = HunkRejected
+			}
+		}
+		content := file.Apply(hunks)
+		if string(content) != string(file.Before) || (!file.Existed && len(content) > 0) {
+			if err := o.applyReviewedWrite(file, content, role); err != nil {
+				return "", err
+			}
+			applied++
+		}
+		for _, h := range hunks {
+			if h.Decision == HunkRejected || h.Decision == HunkPending {
+				rejected = append(rejected, renderRejectedHunk(file.Path, h))
+			}
+		}
+	}
+
+	msg := fmt.Sprintf("Applied reviewed changes to %d file(s)", applied)
+	if len(rejected) > 0 {
+		msg += fmt.Sprintf("; %d hunk(s) rejected", len(rejected))
+	}
+	o.emit(StepUpdate{StepID: task.ID, Status: "running", Msg: msg})
+	if len(rejected) == 0 {
+		return "", nil
+	}
+	return "The user reviewed your changes and rejected these hunks. They were not applied; the files on disk hold only the accepted changes. Rework them to address the rejection:\n\n" +
+		strings.Join(rejected, "\n\n"), nil
+}
+
+// applyReviewedWrite writes the reviewed content of a staged file the way
+// write_file would have, with a checkpoint and a diff event.
+func (o *Orchestrator) applyReviewedWrite(file StagedFile, content []byte, role Role) error {
+	absPath, relPath, err := resolveWorkspacePath(o.taskWorkingDir(), file.Path)
+	if err != nil {
+		return err
+	}
+	// The file may have changed on disk while the review was open.
+	existed := false
+	var mode os.FileMode
+	before, readErr := os.ReadFile(absPath)
+	if readErr == nil {
+		existed = true
+		if info, statErr := os.Stat(absPath); statErr == nil {
+			mode = info.Mode().Perm()
+		}
+	} else if !errors.Is(readErr, os.ErrNotExist) {
+		return readErr
+	}
+	if err := os.MkdirAll(filepath.Dir(absPath), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(absPath, content, 0o644); err != nil {
+		return err
+	}
+	o.recordCheckpoint(relPath, existed, mode, before, content)
+	o.emitEvent(AgentEvent{
+		Type:   EventFileDiff,
+		Role:   role,
+		Detail: fmt.Sprintf("diff %s", relPath),
+		Payload: FileDiffPayload{
+			Path:     relPath,
+			OldLines: splitLinesForDiff(string(before)),
+			NewLines: splitLinesForDiff(string(content)),
+		},
+	})
+	return nil
+}
+
+func renderRejectedHunk(path string, h Hunk) string {
+	lines := []string{path + " " + h.Header()}
+	for _, line := range h.Old {
+		lines = append(lines, "-"+line)
+	}
+	for _, line := range h.New {
+		lines = append(lines, "+"+line)
+	}
+	return strings.Join(lines, "\n")
+}
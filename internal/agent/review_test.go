@@ -0,0 +1,252 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/yubzen/orchestra/internal/providers"
+	"github.com/yubzen/orchestra/internal/state"
+)
+
+func TestComputeHunksSplitsSeparateChanges(t *testing.T) {
+	t.Parallel()
+
+	old := []string{"one", "two", "three", "four", "five"}
+	updated := []string{"one", "TWO", "three", "five", "six"}
+	hunks := ComputeHunks(old, updated)
+	if len(hunks) != 3 {
+		t.Fatalf("expected 3 hunks, got %d: %+v", len(hunks), hunks)
+	}
+	if hunks[0].Header() != "@@ -2,1 +2,1 @@" || hunks[0].Old[0] != "two" || hunks[0].New[0] != "TWO" {
+		t.Fatalf("unexpected first hunk: %+v", hunks[0])
+	}
+	if len(hunks[1].Old) != 1 || hunks[1].Old[0] != "four" || len(hunks[1].New) != 0 {
+		t.Fatalf("expected the second hunk to delete four, got %+v", hunks[1])
+	}
+	if len(hunks[2].New) != 1 || hunks[2].New[0] != "six" || hunks[2].OldStart != 5 {
+		t.Fatalf("expected the third hunk to append six, got %+v", hunks[2])
+	}
+	if strings.Join(hunks[0].Before, ",") != "one" || strings.Join(hunks[0].After, ",") != "three,four,five" {
+		t.Fatalf("unexpected context around the first hunk: %+v", hunks[0])
+	}
+
+	for idx := range hunks {
+		hunks[idx].Decision = HunkAccepted
+	}
+	if got := ApplyHunks(old, hunks); strings.Join(got, ",") != strings.Join(updated, ",") {
+		t.Fatalf("accepting every hunk should give the new lines, got %v", got)
+	}
+}
+
+func TestComputeHunksHandlesLargeFiles(t *testing.T) {
+	t.Parallel()
+
+	old := make([]string, 20000)
+	updated := make([]string, 0, len(old))
+	for idx := range old {
+		old[idx] = fmt.Sprintf("line %d", idx)
+		if idx%100 == 50 {
+			updated = append(updated, fmt.Sprintf("changed %d", idx))
+			continue
+		}
+		updated = append(updated, old[idx])
+	}
+	hunks := ComputeHunks(old, updated)
+	if len(hunks) != len(old)/100 {
+		t.Fatalf("expected one hunk per changed line, got %d", len(hunks))
+	}
+	for idx := range hunks {
+		hunks[idx].Decision = HunkAccepted
+	}
+	if got := ApplyHunks(old, hunks); strings.Join(got, "\n") != strings.Join(updated, "\n") {
+		t.Fatal("accepting every hunk should give the new lines")
+	}
+}
+
+func TestStagedFileApplyMixesDecisions(t *testing.T) {
+	t.Parallel()
+
+	file := StagedFile{
+		Path:    "a.txt",
+		Existed: true,
+		Before:  []byte("one\ntwo\nthree\nfour\nfive\n"),
+		After:   []byte("one\nTWO\nthree\nFOUR\nfive\nsix\n"),
+	}
+	hunks := file.Hunks()
+	if len(hunks) != 3 {
+		t.Fatalf("expected 3 hunks, got %d", len(hunks))
+	}
+	hunks[0].Decision = HunkAccepted
+	hunks[1].Decision = HunkRejected
+	hunks[2].Decision = HunkEdited
+	hunks[2].Edited = []string{"seven", "eight"}
+
+	if got := string(file.Apply(hunks)); got != "one\nTWO\nthree\nfour\nfive\nseven\neight\n" {
+		t.Fatalf("unexpected applied content %q", got)
+	}
+
+	for idx := range hunks {
+		hunks[idx].Decision = HunkRejected
+	}
+	if got := string(file.Apply(hunks)); got != string(file.Before) {
+		t.Fatalf("rejecting everything should keep the original, got %q", got)
+	}
+}
+
+func TestWriteFileStagesWhileReviewIsActive(t *testing.T) {
+	t.Parallel()
+
+	workDir := t.TempDir()
+	target := filepath.Join(workDir, "a.txt")
+	if err := os.WriteFile(target, []byte("original\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	stage := NewStagedWrites()
+	stage.setActive(true)
+	tools := DefaultToolSetForRole(RoleCoder, ToolEnv{WorkingDir: workDir, Role: RoleCoder, Stage: stage})
+	write, _ := tools.Get("write_file")
+	read, _ := tools.Get("read_file")
+
+	if _, err := write.Execute(context.Background(), map[string]any{"path": "a.txt", "content": "first\n"}); err != nil {
+		t.Fatalf("write_file: %v", err)
+	}
+	if _, err := write.Execute(context.Background(), map[string]any{"path": "a.txt", "content": "second\n"}); err != nil {
+		t.Fatalf("write_file: %v", err)
+	}
+	if got, _ := os.ReadFile(target); string(got) != "original\n" {
+		t.Fatalf("staged write reached the disk: %q", got)
+	}
+	result, err := read.Execute(context.Background(), map[string]any{"path": "a.txt"})
+	if err != nil || result.Output != "second" {
+		t.Fatalf("read_file should see the staged content, got %q (%v)", result.Output, err)
+	}
+
+	staged := stage.take()
+	if len(staged) != 1 || string(staged[0].Before) != "original\n" || string(staged[0].After) != "second" {
+		t.Fatalf("unexpected staged files: %+v", staged)
+	}
+
+	stage.setActive(false)
+	if _, err := write.Execute(context.Background(), map[string]any{"path": "a.txt", "content": "direct\n"}); err != nil {
+		t.Fatalf("write_file: %v", err)
+	}
+	if got, _ := os.ReadFile(target); string(got) != "direct" {
+		t.Fatalf("expected an inactive stage to write through, got %q", got)
+	}
+}
+
+// rewriteProvider writes one version of a.txt per attempt and then finishes.
+type rewriteProvider struct {
+	mu       sync.Mutex
+	versions []string
+	calls    int
+	prompts  []string
+}
+
+func (p *rewriteProvider) Name() string                                     { return "rewrite" }
+func (p *rewriteProvider) Ping(ctx context.Context) error                   { return nil }
+func (p *rewriteProvider) ListModels(ctx context.Context) ([]string, error) { return nil, nil }
+func (p *rewriteProvider) Complete(ctx context.Context, model string, messages []providers.Message, tools []providers.Tool, onToken providers.TokenCallback) (providers.CompletionResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if p.calls%2 == 0 {
+		return providers.CompletionResponse{Text: `{"status":"done"}`}, nil
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			p.prompts = append(p.prompts, messages[i].Content)
+			break
+		}
+	}
+	version := p.versions[min(p.calls/2, len(p.versions)-1)]
+	args, _ := json.Marshal(map[string]string{"path": "a.txt", "content": version})
+	return providers.CompletionResponse{
+		ToolCalls:  []providers.ToolCall{{ID: "write", Name: "write_file", Arguments: args}},
+		StopReason: "tool_calls",
+	}, nil
+}
+
+func TestOrchestratorReviewAppliesAcceptedHunksAndReturnsRejected(t *testing.T) {
+	t.Parallel()
+
+	workDir := t.TempDir()
+	target := filepath.Join(workDir, "a.txt")
+	if err := os.WriteFile(target, []byte("one\ntwo\nthree\nfour\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	provider := &rewriteProvider{versions: []string{
+		"one\nTWO\nthree\nFOUR\n",
+		"one\nTWO\nthree\nfour!\n",
+	}}
+	updates := make(chan StepUpdate, 64)
+	orc := &Orchestrator{
+		Planner:        newTestAgent(RolePlanner, provider),
+		UpdateChan:     updates,
+		DiffReviewChan: make(chan DiffReview, 1),
+		ReviewWrites:   true,
+		WorkingDir:     workDir,
+		ProjectBrief:   "Working directory: .",
+		Session:        &state.Session{ExecutionMode: state.ExecutionModeFast},
+	}
+
+	var reviews []agentReviewSeen
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for update := range updates {
+			if update.Status != "review_ready" {
+				continue
+			}
+			if got, _ := os.ReadFile(target); string(got) != "one\ntwo\nthree\nfour\n" && len(reviews) == 0 {
+				t.Errorf("file changed before review: %q", got)
+			}
+			review := DiffReview{TaskID: update.StepID}
+			for _, file := range update.Staged {
+				hunks := file.Hunks()
+				for idx := range hunks {
+					hunks[idx].Decision = HunkAccepted
+				}
+				if len(reviews) == 0 && len(hunks) == 2 {
+					hunks[1].Decision = HunkRejected
+				}
+				reviews = append(reviews, agentReviewSeen{path: file.Path, hunks: len(hunks)})
+				review.Files = append(review.Files, FileReview{Path: file.Path, Hunks: hunks})
+			}
+			orc.SubmitDiffReview(review)
+		}
+	}()
+
+	err := orc.Run(context.Background(), "update a.txt")
+	close(updates)
+	<-done
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	if len(reviews) != 2 || reviews[0].hunks != 2 || reviews[1].hunks != 1 {
+		t.Fatalf("expected two reviews of a.txt (2 hunks, then 1), got %+v", reviews)
+	}
+	// write_file trims the content it is given, so the trailing newline goes.
+	if got, _ := os.ReadFile(target); string(got) != "one\nTWO\nthree\nfour!" {
+		t.Fatalf("unexpected final content %q", got)
+	}
+	if len(provider.prompts) < 2 {
+		t.Fatalf("expected a second attempt after the rejection, got %d prompt(s)", len(provider.prompts))
+	}
+	retry := provider.prompts[1]
+	if !strings.Contains(retry, "rejected these hunks") || !strings.Contains(retry, "-four\n+FOUR") {
+		t.Fatalf("expected the rejected hunk in the retry prompt, got %q", retry)
+	}
+}
+
+type agentReviewSeen struct {
+	path  string
+	hunks int
+}
@@ -0,0 +1,203 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/yubzen/orchestra/internal/ignore"
+)
+
+var errStopWalk = errors.New("stop walk")
+
+const (
+	maxListedFiles     = 500
+	maxSearchMatches   = 200
+	maxSearchFileBytes = 1 << 20
+)
+
+// readDenied reports whether read_file must refuse relPath because the
+// workspace's ignore rules or the built-in secret patterns exclude it.
+// Orchestra's own plan files stay readable even when a project ignores
+// .orchestra/.
+func readDenied(matcher *ignore.Matcher, relPath string) bool {
+	if !shouldEmitFileDiff(relPath) {
+		return false
+	}
+	return matcher.Match(relPath, false)
+}
+
+func newListFilesTool(env ToolEnv) Tool {
+	return Tool{
+		Name:        "list_files",
+		Description: "List workspace files under a directory, optionally filtered by a glob. Ignored files are skipped.",
+		Execute: func(ctx context.Context, params map[string]any) (ToolResult, error) {
+			if err := checkContextCancelled(ctx); err != nil {
+				return ToolResult{}, err
+			}
+			root := effectiveWorkingDir(env.WorkingDir)
+			var files []string
+			truncated := false
+			err := walkWorkspace(ctx, root, optionalStringParam(params, "path"), optionalStringParam(params, "pattern"), func(relPath, _ string) bool {
+				if len(files) >= maxListedFiles {
+					truncated = true
+					return false
+				}
+				files = append(files, relPath)
+				return true
+			})
+			if err != nil {
+				return ToolResult{}, err
+			}
+			sort.Strings(files)
+			output := strings.Join(files, "\n")
+			if truncated {
+				output += fmt.Sprintf("\n... (stopped after %d files; narrow path or pattern)", maxListedFiles)
+			}
+			if output == "" {
+				output = "no files found"
+			}
+			return ToolResult{Output: output, Data: map[string]any{"count": len(files)}}, nil
+		},
+	}
+}
+
+func newSearchFilesTool(env ToolEnv) Tool {
+	return Tool{
+		Name:        "search_files",
+		Description: "Search workspace file contents with a regular expression and return path:line matches. Ignored files are skipped.",
+		Execute: func(ctx context.Context, params map[string]any) (ToolResult, error) {
+			if err := checkContextCancelled(ctx); err != nil {
+				return ToolResult{}, err
+			}
+			query, err := requiredStringParam(params, "query")
+			if err != nil {
+				return ToolResult{}, err
+			}
+			re, err := regexp.Compile(query)
+			if err != nil {
+				return ToolResult{}, fmt.Errorf("invalid query regexp: %w", err)
+			}
+			emitToolEvent(env, EventReading, fmt.Sprintf("searching %s", query), map[string]any{"query": query})
+
+			root := effectiveWorkingDir(env.WorkingDir)
+			var matches []string
+			truncated := false
+			err = walkWorkspace(ctx, root, optionalStringParam(params, "path"), optionalStringParam(params, "pattern"), func(relPath, absPath string) bool {
+				matches = append(matches, searchFile(absPath, relPath, re, maxSearchMatches-len(matches))...)
+				if len(matches) >= maxSearchMatches {
+					truncated = true
+					return false
+				}
+				return true
+			})
+			if err != nil {
+				return ToolResult{}, err
+			}
+			output := strings.Join(matches, "\n")
+			if truncated {
+				output += fmt.Sprintf("\n... (stopped after %d matches; narrow the query)", maxSearchMatches)
+			}
+			if output == "" {
+				output = "no matches"
+			}
+			return ToolResult{Output: output, Data: map[string]any{"count": len(matches)}}, nil
+		},
+	}
+}
+
+// walkWorkspace visits non-ignored files under dir (relative to root) whose
+// path or base name matches the optional glob, until visit returns false.
+func walkWorkspace(ctx context.Context, root, dir, pattern string, visit func(relPath, absPath string) bool) error {
+	if dir == "" {
+		dir = "."
+	}
+	start, _, err := resolveWorkspacePath(root, dir)
+	if err != nil {
+		return err
+	}
+	rootAbs, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	matcher := ignore.New(rootAbs, ignore.IndexPatterns...)
+	err = filepath.WalkDir(start, func(p string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return nil
+		}
+		if err := checkContextCancelled(ctx); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(rootAbs, p)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if matcher.Match(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if pattern != "" {
+			if ok, _ := path.Match(pattern, rel); !ok {
+				if ok, _ := path.Match(pattern, path.Base(rel)); !ok {
+					return nil
+				}
+			}
+		}
+		if !visit(rel, p) {
+			return errStopWalk
+		}
+		return nil
+	})
+	if errors.Is(err, errStopWalk) {
+		return nil
+	}
+	return err
+}
+
+func searchFile(absPath, relPath string, re *regexp.Regexp, limit int) []string {
+	info, err := os.Stat(absPath)
+	if err != nil || info.Size() > maxSearchFileBytes || limit <= 0 {
+		return nil
+	}
+	content, err := os.ReadFile(absPath)
+	if err != nil || bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0 {
+		return nil
+	}
+	var out []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSearchFileBytes)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if !re.MatchString(text) {
+			continue
+		}
+		if runes := []rune(text); len(runes) > 200 {
+			text = string(runes[:200]) + "..."
+		}
+		out = append(out, fmt.Sprintf("%s:%d: %s", relPath, line, strings.TrimSpace(text)))
+		if len(out) >= limit {
+			break
+		}
+	}
+	return out
+}
+
+func optionalStringParam(params map[string]any, key string) string {
+	value, _ := params[key].(string)
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrNoActiveRun is returned by Steer when there is no run to steer.
+var ErrNoActiveRun = errors.New("no run in progress")
+
+// steeringQueue holds follow-up instructions sent while a run is in
+// progress. Pending notes have not reached an agent yet; applied notes have,
+// and are repeated in every later task and review prompt of the run.
+type steeringQueue struct {
+	mu      sync.Mutex
+	active  bool
+	pending []string
+	applied []string
+}
+
+// Steer queues an instruction for the running orchestration. The active
+// agent sees it before its next model call, and every later task prompt of
+// the run includes it.
+func (o *Orchestrator) Steer(text string) error {
+	if o == nil {
+		return ErrOrchestratorNotReady
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return errors.New("steering message is empty")
+	}
+	o.steering.mu.Lock()
+	defer o.steering.mu.Unlock()
+	if !o.steering.active {
+		return ErrNoActiveRun
+	}
+	o.steering.pending = append(o.steering.pending, text)
+	return nil
+}
+
+// TakeUndeliveredSteering returns and clears the notes that were queued too
+// late for the last run to use, so the caller can send them as new prompts.
+func (o *Orchestrator) TakeUndeliveredSteering() []string {
+	if o == nil {
+		return nil
+	}
+	o.steering.mu.Lock()
+	defer o.steering.mu.Unlock()
+	if o.steering.active {
+		return nil
+	}
+	notes := o.steering.pending
+	o.steering.pending = nil
+	return notes
+}
+
+func (o *Orchestrator) beginSteering() {
+	o.steering.mu.Lock()
+	o.steering.active = true
+	o.steering.pending = nil
+	o.steering.applied = nil
+	o.steering.mu.Unlock()
+}
+
+func (o *Orchestrator) endSteering() {
+	o.steering.mu.Lock()
+	o.steering.active = false
+	o.steering.applied = nil
+	o.steering.mu.Unlock()
+}
+
+// deliverSteering hands the pending notes to role and reports the handoff.
+func (o *Orchestrator) deliverSteering(role Role) []string {
+	if o == nil {
+		return nil
+	}
+	o.steering.mu.Lock()
+	notes := o.steering.pending
+	o.steering.pending = nil
+	o.steering.applied = append(o.steering.applied, notes...)
+	o.steering.mu.Unlock()
+	if len(notes) == 0 {
+		return nil
+	}
+	o.emitEvent(AgentEvent{
+		Type:   EventSteer,
+		Role:   role,
+		Detail: fmt.Sprintf("received %d steering note(s)", len(notes)),
+	})
+	return notes
+}
+
+// withSteering delivers pending notes to role and appends every note of the
+// run to prompt, so a correction made during task 2 still holds for task 5
+// and for retries.
+func (o *Orchestrator) withSteering(prompt string, role Role) string {
+	if o == nil {
+		return prompt
+	}
+	o.deliverSteering(role)
+	o.steering.mu.Lock()
+	notes := append([]string(nil), o.steering.applied...)
+	o.steering.mu.Unlock()
+	if len(notes) == 0 {
+		return prompt
+	}
+	return strings.TrimSpace(prompt + "\n\n" + renderSteering(notes))
+}
+
+// renderSteering formats notes for a prompt or a mid-loop user message.
+func renderSteering(notes []string) string {
+	var sb strings.Builder
+	sb.WriteString("User steering (sent while this run was in progress; it overrides earlier instructions where they conflict):")
+	for _, note := range notes {
+		sb.WriteString("\n- ")
+		sb.WriteString(strings.ReplaceAll(strings.TrimSpace(note), "\n", "\n  "))
+	}
+	return sb.String()
+}
@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/yubzen/orchestra/internal/providers"
+	"github.com/yubzen/orchestra/internal/state"
+)
+
+// steeredProvider requests one tool call, running onFirstCall while the
+// agent is mid-loop, then answers. It keeps the messages of every call.
+type steeredProvider struct {
+	onFirstCall func()
+	calls       [][]providers.Message
+}
+
+func (p *steeredProvider) Name() string                                     { return "steered" }
+func (p *steeredProvider) Ping(ctx context.Context) error                   { return nil }
+func (p *steeredProvider) ListModels(ctx context.Context) ([]string, error) { return nil, nil }
+func (p *steeredProvider) Complete(ctx context.Context, model string, messages []providers.Message, tools []providers.Tool, onToken providers.TokenCallback) (providers.CompletionResponse, error) {
+	p.calls = append(p.calls, append([]providers.Message(nil), messages...))
+	if len(p.calls) == 1 {
+		p.onFirstCall()
+		return providers.CompletionResponse{ToolCalls: []providers.ToolCall{{ID: "tc-1", Name: "list_files", Arguments: []byte(`{"path":"."}`)}}}, nil
+	}
+	return providers.CompletionResponse{Text: `{"status":"done"}`}, nil
+}
+
+func TestSteeringReachesTheRunningAgentLoop(t *testing.T) {
+	t.Parallel()
+	workDir := t.TempDir()
+	orc := newCheckpointTestOrchestrator(t, workDir)
+	provider := &steeredProvider{}
+	provider.onFirstCall = func() {
+		if err := orc.Steer("use the v2 API, not v1"); err != nil {
+			t.Errorf("steer: %v", err)
+		}
+	}
+	orc.Planner = newTestAgent(RolePlanner, provider)
+	orc.UpdateChan = make(chan StepUpdate, 64)
+	orc.EventChan = make(chan AgentEvent, 64)
+	orc.ProjectBrief = "Working directory: ."
+	orc.Session.ExecutionMode = state.ExecutionModeFast
+
+	if err := orc.Run(context.Background(), "create the client"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(provider.calls) != 2 {
+		t.Fatalf("expected 2 model calls, got %d", len(provider.calls))
+	}
+	last := provider.calls[1][len(provider.calls[1])-1]
+	if last.Role != "user" || !strings.Contains(last.Content, "use the v2 API, not v1") {
+		t.Fatalf("expected the steering note as the newest message, got %+v", last)
+	}
+	for _, msg := range provider.calls[0] {
+		if strings.Contains(msg.Content, "v2 API") {
+			t.Fatal("steering must not appear before it was sent")
+		}
+	}
+
+	close(orc.EventChan)
+	steered := false
+	for event := range orc.EventChan {
+		if event.Type == EventSteer && event.Role == RolePlanner {
+			steered = true
+		}
+	}
+	if !steered {
+		t.Fatal("expected a steer event for the planner")
+	}
+	if err := orc.Steer("too late"); !errors.Is(err, ErrNoActiveRun) {
+		t.Fatalf("expected steering after the run to fail, got %v", err)
+	}
+}
+
+func TestSteeringCarriesIntoLaterPrompts(t *testing.T) {
+	t.Parallel()
+
+	orc := &Orchestrator{}
+	orc.beginSteering()
+	if got := orc.withSteering("task 1", RoleCoder); got != "task 1" {
+		t.Fatalf("expected no steering section yet, got %q", got)
+	}
+	if err := orc.Steer("keep the public API unchanged"); err != nil {
+		t.Fatalf("steer: %v", err)
+	}
+	for _, prompt := range []string{"task 2", "task 3"} {
+		got := orc.withSteering(prompt, RoleCoder)
+		if !strings.HasPrefix(got, prompt) || !strings.Contains(got, "- keep the public API unchanged") {
+			t.Fatalf("expected the note in %s's prompt, got %q", prompt, got)
+		}
+	}
+
+	if err := orc.Steer("and add a changelog entry"); err != nil {
+		t.Fatalf("steer: %v", err)
+	}
+	orc.endSteering()
+	if notes := orc.TakeUndeliveredSteering(); len(notes) != 1 || notes[0] != "and add a changelog entry" {
+		t.Fatalf("expected the undelivered note back, got %v", notes)
+	}
+	if notes := orc.TakeUndeliveredSteering(); len(notes) != 0 {
+		t.Fatalf("expected undelivered notes to be taken once, got %v", notes)
+	}
+}
@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/yubzen/orchestra/internal/rag"
+)
+
+const maxSymbolResults = 100
+
+func newFindSymbolTool(env ToolEnv) Tool {
+	return Tool{
+		Name:        "find_symbol",
+		Description: "Find where a function, method, type, variable or constant is defined, with its signature. Uses the code index, so it may lag behind unsaved edits.",
+		Execute: func(ctx context.Context, params map[string]any) (ToolResult, error) {
+			if err := checkContextCancelled(ctx); err != nil {
+				return ToolResult{}, err
+			}
+			name, err := requiredStringParam(params, "name")
+			if err != nil {
+				return ToolResult{}, err
+			}
+			emitToolEvent(env, EventReading, fmt.Sprintf("finding symbol %s", name), map[string]any{"name": name})
+			symbols, err := env.Index.FindSymbols(ctx, name)
+			if err != nil {
+				return ToolResult{}, err
+			}
+			lines := make([]string, 0, len(symbols))
+			for idx, sym := range symbols {
+				if idx == maxSymbolResults {
+					lines = append(lines, fmt.Sprintf("... (%d more)", len(symbols)-idx))
+					break
+				}
+				lines = append(lines, formatSymbol(sym))
+			}
+			output := strings.Join(lines, "\n")
+			if output == "" {
+				output = fmt.Sprintf("no symbol named %s", name)
+			}
+			return ToolResult{Output: output, Data: map[string]any{"count": len(symbols)}}, nil
+		},
+	}
+}
+
+func newFindReferencesTool(env ToolEnv) Tool {
+	return Tool{
+		Name:        "find_references",
+		Description: "List the places that use a symbol, as path:line with the enclosing declaration. Qualify the name (pkg.Name or Type.Method) to skip unrelated uses of the same name.",
+		Execute: func(ctx context.Context, params map[string]any) (ToolResult, error) {
+			if err := checkContextCancelled(ctx); err != nil {
+				return ToolResult{}, err
+			}
+			name, err := requiredStringParam(params, "name")
+			if err != nil {
+				return ToolResult{}, err
+			}
+			emitToolEvent(env, EventReading, fmt.Sprintf("finding references to %s", name), map[string]any{"name": name})
+			refs, err := env.Index.FindReferences(ctx, name)
+			if err != nil {
+				return ToolResult{}, err
+			}
+			lines := make([]string, 0, len(refs))
+			for idx, ref := range refs {
+				if idx == maxSymbolResults {
+					lines = append(lines, fmt.Sprintf("... (%d more)", len(refs)-idx))
+					break
+				}
+				line := fmt.Sprintf("%s:%d", ref.File, ref.Line)
+				if ref.Caller != "" {
+					line += " in " + ref.Caller
+				}
+				if ref.Target == "" {
+					line += " (unresolved)"
+				}
+				lines = append(lines, line)
+			}
+			output := strings.Join(lines, "\n")
+			if output == "" {
+				output = fmt.Sprintf("no references to %s", name)
+			}
+			return ToolResult{Output: output, Data: map[string]any{"count": len(refs)}}, nil
+		},
+	}
+}
+
+func newListPackageTool(env ToolEnv) Tool {
+	return Tool{
+		Name:        "list_package",
+		Description: "Summarize a package: its files, top-level declarations, imports and the packages that import it.",
+		Execute: func(ctx context.Context, params map[string]any) (ToolResult, error) {
+			if err := checkContextCancelled(ctx); err != nil {
+				return ToolResult{}, err
+			}
+			dir, err := requiredStringParam(params, "path")
+			if err != nil {
+				return ToolResult{}, err
+			}
+			emitToolEvent(env, EventReading, fmt.Sprintf("listing package %s", dir), map[string]any{"path": dir})
+			pkg, err := env.Index.ListPackage(ctx, dir)
+			if err != nil {
+				return ToolResult{}, err
+			}
+
+			var b strings.Builder
+			fmt.Fprintf(&b, "package %s (%s)\n", pkg.Name, pkg.Path)
+			fmt.Fprintf(&b, "files: %s\n", strings.Join(pkg.Files, ", "))
+			if len(pkg.Imports) > 0 {
+				fmt.Fprintf(&b, "imports: %s\n", strings.Join(pkg.Imports, ", "))
+			}
+			if len(pkg.ImportedBy) > 0 {
+				fmt.Fprintf(&b, "imported by: %s\n", strings.Join(pkg.ImportedBy, ", "))
+			}
+			b.WriteString("declarations:\n")
+			for _, sym := range pkg.Symbols {
+				b.WriteString("  " + formatSymbol(sym) + "\n")
+			}
+			return ToolResult{Output: strings.TrimRight(b.String(), "\n"), Data: map[string]any{"count": len(pkg.Symbols)}}, nil
+		},
+	}
+}
+
+func formatSymbol(sym rag.Symbol) string {
+	signature := sym.Signature
+	if signature == "" {
+		signature = sym.Kind + " " + sym.QualifiedName()
+	}
+	return fmt.Sprintf("%s:%d: %s", sym.File, sym.Line, signature)
+}
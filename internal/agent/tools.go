@@ -10,7 +10,9 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/yubzen/orchestra/internal/ignore"
 	"github.com/yubzen/orchestra/internal/providers"
+	"github.com/yubzen/orchestra/internal/rag"
 )
 
 var (
@@ -38,6 +40,17 @@ type ToolEnv struct {
 	WorkingDir string
 	Role       Role
 	Emit       func(AgentEvent)
+	// Checkpoint records a write; mode is the file's mode before it, 0 for
+	// a new file.
+	Checkpoint func(relPath string, existed bool, mode os.FileMode, before, after []byte)
+	// Index backs the symbol tools; they are left out when it is nil.
+	Index *rag.Indexer
+	// Stage holds write_file calls back for review while it is active.
+	Stage *StagedWrites
+	// Ignore decides which files read_file refuses. DefaultToolSetForRole
+	// builds one for WorkingDir when it is nil, shared by the set's tools so
+	// write_file can reset it after changing an ignore file.
+	Ignore *ignore.Matcher
 }
 
 func NewToolSet(tools ...Tool) ToolSet {
@@ -145,6 +158,61 @@ func toolInputSchema(name string) map[string]interface{} {
 			},
 			"required": []string{"path", "content"},
 		}
+	case "list_files":
+		return map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Directory to list, relative to the workspace. Defaults to the workspace root.",
+				},
+				"pattern": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional glob matched against the relative path or file name, e.g. *.go.",
+				},
+			},
+		}
+	case "search_files":
+		return map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "Regular expression (RE2 syntax) to search for.",
+				},
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Directory to search, relative to the workspace. Defaults to the workspace root.",
+				},
+				"pattern": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional glob restricting which files are searched, e.g. *.go.",
+				},
+			},
+			"required": []string{"query"},
+		}
+	case "find_symbol", "find_references":
+		return map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"name": map[string]interface{}{
+					"type":        "string",
+					"description": "Symbol name, optionally qualified by package or receiver, e.g. NewStore, rag.NewStore or Indexer.Query.",
+				},
+			},
+			"required": []string{"name"},
+		}
+	case "list_package":
+		return map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Package directory relative to the workspace, or its import path.",
+				},
+			},
+			"required": []string{"path"},
+		}
 	case "run_command":
 		return map[string]interface{}{
 			"type": "object",
@@ -168,36 +236,35 @@ func DefaultToolSetForRole(role Role, env ToolEnv) ToolSet {
 	if strings.TrimSpace(env.WorkingDir) == "" {
 		env.WorkingDir = "."
 	}
+	if env.Ignore == nil {
+		env.Ignore = ignore.New(effectiveWorkingDir(env.WorkingDir))
+	}
+	tools := []Tool{
+		newReadFileTool(env),
+		newListFilesTool(env),
+		newSearchFilesTool(env),
+	}
+	if env.Index != nil {
+		tools = append(tools, newFindSymbolTool(env), newFindReferencesTool(env), newListPackageTool(env))
+	}
 	switch role {
 	case RolePlanner:
-		return NewToolSet(
-			newReadFileTool(env),
-			newWritePlanTool(env),
-		)
+		tools = append(tools, newWritePlanTool(env))
 	case RoleCoder:
-		return NewToolSet(
-			newReadFileTool(env),
-			newWriteFileTool(env),
-			newRunCommandTool(env, false),
-		)
-	case RoleReviewer:
-		return NewToolSet(
-			newReadFileTool(env),
-		)
+		tools = append(tools, newWriteFileTool(env), newRunCommandTool(env, false))
 	case RoleAnalyst:
-		return NewToolSet(
-			newReadFileTool(env),
-			newRunCommandTool(env, true),
-		)
-	default:
-		return NewToolSet(newReadFileTool(env))
+		tools = append(tools, newRunCommandTool(env, true))
 	}
+	return NewToolSet(tools...)
 }
 
 func newReadFileTool(env ToolEnv) Tool {
+	if env.Ignore == nil {
+		env.Ignore = ignore.New(effectiveWorkingDir(env.WorkingDir))
+	}
 	return Tool{
 		Name:        "read_file",
-		Description: "Read UTF-8 text from a file under the current workspace.",
+		Description: "Read UTF-8 text from a file under the current workspace. Ignored and secret files (.env, keys) are denied.",
 		Execute: func(ctx context.Context, params map[string]any) (ToolResult, error) {
 			if err := checkContextCancelled(ctx); err != nil {
 				return ToolResult{}, err
@@ -211,8 +278,20 @@ func newReadFileTool(env ToolEnv) Tool {
 			if err != nil {
 				return ToolResult{}, err
 			}
+			if readDenied(env.Ignore, relPath) {
+				return ToolResult{}, fmt.Errorf("read_file denied: %s is excluded by ignore rules or is a secret file", relPath)
+			}
 			emitToolEvent(env, EventReading, fmt.Sprintf("reading %s", relPath), map[string]any{"path": relPath})
 
+			if staged, ok := env.Stage.content(relPath); ok {
+				return ToolResult{
+					Output: string(staged),
+					Data: map[string]any{
+						"path":   relPath,
+						"staged": true,
+					},
+				}, nil
+			}
 			content, err := os.ReadFile(absPath)
 			if err != nil {
 				return ToolResult{}, err
@@ -250,12 +329,29 @@ func newWriteFileTool(env ToolEnv) Tool {
 			}
 
 			var oldContent string
+			var oldMode os.FileMode
+			existed := false
 			if existing, readErr := os.ReadFile(absPath); readErr == nil {
 				oldContent = string(existing)
+				existed = true
+				if info, statErr := os.Stat(absPath); statErr == nil {
+					oldMode = info.Mode().Perm()
+				}
 			} else if !errors.Is(readErr, os.ErrNotExist) {
 				return ToolResult{}, readErr
 			}
 
+			if shouldEmitFileDiff(relPath) && env.Stage.stage(relPath, existed, []byte(oldContent), []byte(content)) {
+				emitToolEvent(env, EventWriting, fmt.Sprintf("staging %s for review", relPath), map[string]any{"path": relPath})
+				return ToolResult{
+					Output: "staged for review; read_file returns the staged content",
+					Data: map[string]any{
+						"path":   relPath,
+						"staged": true,
+					},
+				}, nil
+			}
+
 			emitToolEvent(env, EventWriting, fmt.Sprintf("writing %s", relPath), map[string]any{"path": relPath})
 
 			if err := os.MkdirAll(filepath.Dir(absPath), 0o755); err != nil {
@@ -264,6 +360,12 @@ func newWriteFileTool(env ToolEnv) Tool {
 			if err := os.WriteFile(absPath, []byte(content), 0o644); err != nil {
 				return ToolResult{}, err
 			}
+			if env.Checkpoint != nil && shouldEmitFileDiff(relPath) {
+				env.Checkpoint(relPath, existed, oldMode, []byte(oldContent), []byte(content))
+			}
+			if ignore.IsIgnoreFile(relPath) {
+				env.Ignore.Reset()
+			}
 			if shouldEmitFileDiff(relPath) {
 				emitToolEvent(env, EventFileDiff, fmt.Sprintf("diff %s", relPath), FileDiffPayload{
 					Path:     relPath,
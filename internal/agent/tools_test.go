@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/yubzen/orchestra/internal/rag"
 )
 
 func TestDefaultToolSetForRole(t *testing.T) {
@@ -165,3 +167,163 @@ func TestWriteFileToolSkipsDiffEventsForOrchestraInternalPaths(t *testing.T) {
 		}
 	}
 }
+
+func TestReadFileDeniesIgnoredAndSecretFiles(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	for rel, content := range map[string]string{
+		".gitignore":            "secrets/\n.orchestra/\n",
+		".env":                  "API_KEY=sk-test",
+		".env.example":          "API_KEY=",
+		"secrets/token.txt":     "token",
+		".orchestra/plans/p.md": "# plan",
+		"main.go":               "package main",
+	} {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", rel, err)
+		}
+	}
+	tool := newReadFileTool(ToolEnv{WorkingDir: root, Role: RoleCoder})
+	ctx := context.Background()
+
+	for _, denied := range []string{".env", "secrets/token.txt"} {
+		if _, err := tool.Execute(ctx, map[string]any{"path": denied}); err == nil || !strings.Contains(err.Error(), "denied") {
+			t.Fatalf("expected read of %s to be denied, got %v", denied, err)
+		}
+	}
+	for _, allowed := range []string{".env.example", ".orchestra/plans/p.md", "main.go"} {
+		if _, err := tool.Execute(ctx, map[string]any{"path": allowed}); err != nil {
+			t.Fatalf("expected read of %s to succeed: %v", allowed, err)
+		}
+	}
+}
+
+func TestToolSetSharesIgnoreRulesAcrossWrites(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "notes.txt"), []byte("draft"), 0o644); err != nil {
+		t.Fatalf("write notes: %v", err)
+	}
+	set := DefaultToolSetForRole(RoleCoder, ToolEnv{WorkingDir: root, Role: RoleCoder})
+	read, _ := set.Get("read_file")
+	write, _ := set.Get("write_file")
+	ctx := context.Background()
+
+	if _, err := read.Execute(ctx, map[string]any{"path": "notes.txt"}); err != nil {
+		t.Fatalf("expected notes.txt to be readable: %v", err)
+	}
+	if _, err := write.Execute(ctx, map[string]any{"path": ".gitignore", "content": "notes.txt\n"}); err != nil {
+		t.Fatalf("write .gitignore: %v", err)
+	}
+	if _, err := read.Execute(ctx, map[string]any{"path": "notes.txt"}); err == nil || !strings.Contains(err.Error(), "denied") {
+		t.Fatalf("expected the new ignore rule to apply to the cached matcher, got %v", err)
+	}
+}
+
+func TestListAndSearchFilesSkipIgnoredPaths(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	for rel, content := range map[string]string{
+		".gitignore":                "build/\n",
+		"main.go":                   "package main\n\nfunc writePlanLock() {}\n",
+		"internal/lock.go":          "package internal\n\n// writePlanLock is documented here.\n",
+		"build/out.go":              "func writePlanLock() {}\n",
+		"node_modules/pkg/index.js": "writePlanLock()\n",
+		".env":                      "writePlanLock=1\n",
+	} {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", rel, err)
+		}
+	}
+	env := ToolEnv{WorkingDir: root, Role: RoleReviewer}
+	ctx := context.Background()
+
+	listed, err := newListFilesTool(env).Execute(ctx, map[string]any{"pattern": "*.go"})
+	if err != nil {
+		t.Fatalf("list_files: %v", err)
+	}
+	if listed.Output != "internal/lock.go\nmain.go" {
+		t.Fatalf("unexpected list_files output: %q", listed.Output)
+	}
+
+	found, err := newSearchFilesTool(env).Execute(ctx, map[string]any{"query": `writePlanLock`})
+	if err != nil {
+		t.Fatalf("search_files: %v", err)
+	}
+	lines := strings.Split(found.Output, "\n")
+	if len(lines) != 2 || !strings.Contains(found.Output, "main.go:3: func writePlanLock() {}") || !strings.Contains(found.Output, "internal/lock.go:3:") {
+		t.Fatalf("unexpected search_files output: %q", found.Output)
+	}
+
+	if _, err := newSearchFilesTool(env).Execute(ctx, map[string]any{"query": "x", "path": "../"}); err == nil {
+		t.Fatal("expected search outside the workspace to be rejected")
+	}
+}
+
+func TestSymbolToolsQueryTheIndex(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	for rel, content := range map[string]string{
+		"go.mod":       "module example.com/app\n",
+		"lock/lock.go": "package lock\n\nfunc Acquire(name string) bool { return name != \"\" }\n",
+		"main.go":      "package main\n\nimport \"example.com/app/lock\"\n\nfunc main() {\n\tlock.Acquire(\"plan\")\n}\n",
+	} {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", rel, err)
+		}
+	}
+	store, err := rag.NewStore(filepath.Join(t.TempDir(), "rag.db"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+	indexer := rag.NewIndexer(store, rag.NewHashingEmbedder(16), root)
+	ctx := context.Background()
+	if err := indexer.Sync(ctx); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	reviewer := DefaultToolSetForRole(RoleReviewer, ToolEnv{WorkingDir: root, Role: RoleReviewer})
+	if _, ok := reviewer.Get("find_symbol"); ok {
+		t.Fatal("symbol tools need an index")
+	}
+	tools := DefaultToolSetForRole(RoleReviewer, ToolEnv{WorkingDir: root, Role: RoleReviewer, Index: indexer})
+
+	for _, tc := range []struct {
+		tool   string
+		params map[string]any
+		want   string
+	}{
+		{"find_symbol", map[string]any{"name": "lock.Acquire"}, "lock/lock.go:3: func Acquire(name string) bool"},
+		{"find_references", map[string]any{"name": "Acquire"}, "main.go:6 in main"},
+		{"list_package", map[string]any{"path": "lock"}, "imported by: ."},
+	} {
+		tool, ok := tools.Get(tc.tool)
+		if !ok {
+			t.Fatalf("reviewer should have %s", tc.tool)
+		}
+		result, err := tool.Execute(ctx, tc.params)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.tool, err)
+		}
+		if !strings.Contains(result.Output, tc.want) {
+			t.Fatalf("%s output %q does not contain %q", tc.tool, result.Output, tc.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/yubzen/orchestra/internal/mcp"
+	"github.com/yubzen/orchestra/internal/providers"
+	"github.com/yubzen/orchestra/internal/state"
+)
+
+// startTraceSpan records span for the session and returns its ID, or 0 when
+// there is nothing to record into. Like the tool-call log, tracing never
+// fails a run.
+func startTraceSpan(ctx context.Context, db *state.DB, session *state.Session, span state.TraceSpan) int64 {
+	if db == nil || session == nil || strings.TrimSpace(span.RunID) == "" {
+		return 0
+	}
+	span.SessionID = session.ID
+	id, err := db.StartSpan(context.WithoutCancel(ctx), span)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// finishTraceSpan closes span id with a status derived from err.
+func finishTraceSpan(ctx context.Context, db *state.DB, id int64, output string, err error) {
+	if db == nil || id <= 0 {
+		return
+	}
+	errMsg := ""
+	if err != nil {
+		errMsg = mcp.Clean(err.Error())
+	}
+	_ = db.FinishSpan(context.WithoutCancel(ctx), id, spanStatus(err), mcp.Clean(output), errMsg)
+}
+
+func spanStatus(err error) string {
+	switch {
+	case err == nil:
+		return state.SpanStatusOK
+	case IsUserCancelled(normalizeCancellationErr(err)), errors.Is(err, ErrRunPaused):
+		return state.SpanStatusCancelled
+	default:
+		return state.SpanStatusError
+	}
+}
+
+// beginSpan opens a span under the innermost open one and makes it the
+// parent of later spans, including the LLM calls of agents run through
+// withRunScope. Tasks run one at a time, so a stack is enough.
+func (o *Orchestrator) beginSpan(ctx context.Context, span state.TraceSpan) int64 {
+	if o == nil {
+		return 0
+	}
+	span.RunID, span.TaskID = o.runScope()
+	span.ParentID = o.traceParent()
+	id := startTraceSpan(ctx, o.DB, o.Session, span)
+	if id == 0 {
+		return 0
+	}
+	o.checkpointMu.Lock()
+	o.traceStack = append(o.traceStack, id)
+	o.checkpointMu.Unlock()
+	return id
+}
+
+// endSpan closes id and any spans still open inside it.
+func (o *Orchestrator) endSpan(ctx context.Context, id int64, output string, err error) {
+	if o == nil || id <= 0 {
+		return
+	}
+	o.checkpointMu.Lock()
+	for idx := len(o.traceStack) - 1; idx >= 0; idx-- {
+		if o.traceStack[idx] == id {
+			o.traceStack = o.traceStack[:idx]
+			break
+		}
+	}
+	o.checkpointMu.Unlock()
+	finishTraceSpan(ctx, o.DB, id, output, err)
+}
+
+func (o *Orchestrator) traceParent() int64 {
+	o.checkpointMu.Lock()
+	defer o.checkpointMu.Unlock()
+	if len(o.traceStack) == 0 {
+		return 0
+	}
+	return o.traceStack[len(o.traceStack)-1]
+}
+
+// attemptSpans keeps one attempt span open at a time inside a retry loop.
+// Each retry path records why it retried, so the trace shows which check
+// sent the agent round again.
+type attemptSpans struct {
+	o      *Orchestrator
+	ctx    context.Context
+	role   Role
+	id     int64
+	reason string
+}
+
+func (o *Orchestrator) newAttemptSpans(ctx context.Context, role Role) *attemptSpans {
+	return &attemptSpans{o: o, ctx: ctx, role: role}
+}
+
+func (a *attemptSpans) start(attempt int) {
+	a.finish(nil)
+	a.id = a.o.beginSpan(a.ctx, state.TraceSpan{
+		Kind:    state.SpanKindAttempt,
+		Name:    fmt.Sprintf("attempt %d/3", attempt),
+		Role:    string(a.role),
+		Attempt: attempt,
+	})
+}
+
+func (a *attemptSpans) retry(reason string) {
+	a.reason = strings.TrimSpace(reason)
+}
+
+// finish closes the open attempt. A nil err after retry counts as a failed
+// attempt with the retry reason as its error.
+func (a *attemptSpans) finish(err error) {
+	if a.id == 0 {
+		return
+	}
+	if err == nil && a.reason != "" {
+		err = errors.New(a.reason)
+	}
+	a.o.endSpan(a.ctx, a.id, "", err)
+	a.id = 0
+	a.reason = ""
+}
+
+// PromptPreview shortens a prompt to its first line, at most 60 runes, as
+// used for span names and run listings.
+func PromptPreview(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	if runes := []rune(line); len(runes) > 60 {
+		line = string(runes[:57]) + "..."
+	}
+	return line
+}
+
+// renderTraceMessages is the span input of an LLM call: the messages it
+// sent that the previous call in the same loop had not.
+func renderTraceMessages(messages []providers.Message) string {
+	var sb strings.Builder
+	for idx, msg := range messages {
+		if idx > 0 {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString("[" + msg.Role + "]\n")
+		sb.WriteString(msg.Content)
+		for _, tc := range msg.ToolCalls {
+			sb.WriteString(fmt.Sprintf("\n-> %s %s", tc.Name, strings.TrimSpace(string(tc.Arguments))))
+		}
+	}
+	return sb.String()
+}
+
+// renderTraceResponse is the span output of an LLM call.
+func renderTraceResponse(response providers.CompletionResponse) string {
+	out := strings.TrimSpace(response.Text)
+	for _, tc := range response.ToolCalls {
+		out = strings.TrimSpace(out + fmt.Sprintf("\n-> %s %s", tc.Name, strings.TrimSpace(string(tc.Arguments))))
+	}
+	return out
+}
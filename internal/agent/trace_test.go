@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/yubzen/orchestra/internal/providers"
+	"github.com/yubzen/orchestra/internal/state"
+)
+
+func TestRunRecordsTraceTree(t *testing.T) {
+	t.Parallel()
+	workDir := t.TempDir()
+	orc := newCheckpointTestOrchestrator(t, workDir)
+	orc.Planner = newTestAgent(RolePlanner, &scriptedToolProvider{calls: []providers.ToolCall{
+		toolCall(t, "tc-1", "write_file", map[string]any{"path": "hamid.ts", "content": "export const hamid = 1"}),
+		toolCall(t, "tc-2", "read_file", map[string]any{"path": "missing.ts"}),
+	}})
+	orc.UpdateChan = make(chan StepUpdate, 64)
+	orc.ProjectBrief = "Working directory: ."
+	orc.Session.ExecutionMode = state.ExecutionModeFast
+
+	if err := orc.Run(context.Background(), "create hamid.ts"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	ctx := context.Background()
+	runs, err := orc.DB.ListTraceRuns(ctx, orc.Session.ID)
+	if err != nil {
+		t.Fatalf("list runs: %v", err)
+	}
+	if len(runs) != 1 || runs[0].Status != state.SpanStatusOK || runs[0].Name != "create hamid.ts" {
+		t.Fatalf("expected one finished run span, got %+v", runs)
+	}
+	spans, err := orc.DB.ListTraceSpans(ctx, orc.Session.ID, runs[0].RunID)
+	if err != nil {
+		t.Fatalf("list spans: %v", err)
+	}
+
+	byID := make(map[int64]state.TraceSpan, len(spans))
+	var kinds []string
+	for _, span := range spans {
+		byID[span.ID] = span
+		kinds = append(kinds, span.Kind)
+		if span.Kind != state.SpanKindRun && byID[span.ParentID].ID == 0 {
+			t.Fatalf("span %+v has no recorded parent", span)
+		}
+		if span.Status == state.SpanStatusRunning {
+			t.Fatalf("span %+v was never finished", span)
+		}
+	}
+	want := "run task attempt llm tool tool llm"
+	if got := strings.Join(kinds, " "); got != want {
+		t.Fatalf("expected spans %q, got %q", want, got)
+	}
+
+	task, attempt, firstLLM, write, read := spans[1], spans[2], spans[3], spans[4], spans[5]
+	if task.TaskID != "task-1" || attempt.ParentID != task.ID || attempt.Attempt != 1 {
+		t.Fatalf("unexpected task/attempt spans: %+v %+v", task, attempt)
+	}
+	if !strings.Contains(firstLLM.Input, "[system]") || !strings.Contains(firstLLM.Input, "create hamid.ts") {
+		t.Fatalf("expected the first LLM span to hold the full prompt, got %q", firstLLM.Input)
+	}
+	if !strings.Contains(firstLLM.Output, "-> write_file") {
+		t.Fatalf("expected the LLM output to list requested tools, got %q", firstLLM.Output)
+	}
+	if write.ParentID != firstLLM.ID || write.Name != "write_file" || write.Status != state.SpanStatusOK {
+		t.Fatalf("unexpected write_file span: %+v", write)
+	}
+	if read.Status != state.SpanStatusError || read.Error == "" {
+		t.Fatalf("expected the failed read_file span to carry its error, got %+v", read)
+	}
+	if second := spans[6]; !strings.HasPrefix(second.Input, "[assistant]") || !strings.Contains(second.Input, "[tool]") {
+		t.Fatalf("expected the second LLM span to hold only the new messages, got %q", second.Input)
+	}
+}
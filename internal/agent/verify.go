@@ -0,0 +1,162 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultVerifyTimeout = 5 * time.Minute
+	maxVerifyOutputChars = 4000
+)
+
+type verifyResult struct {
+	Command  string
+	Output   string
+	Err      error
+	Duration time.Duration
+}
+
+func (r verifyResult) passed() bool {
+	return r.Err == nil
+}
+
+// verificationCommands returns the project-wide commands followed by the
+// task's own verify commands, with blanks and duplicates dropped.
+func (o *Orchestrator) verificationCommands(task PlanTask) []string {
+	seen := make(map[string]struct{})
+	var out []string
+	for _, command := range append(append([]string(nil), o.VerifyCommands...), task.Verify...) {
+		command = strings.TrimSpace(command)
+		if command == "" {
+			continue
+		}
+		if _, ok := seen[command]; ok {
+			continue
+		}
+		seen[command] = struct{}{}
+		out = append(out, command)
+	}
+	return out
+}
+
+// runVerification runs each command in the task working directory and stops
+// at the first failure, since later steps (tests, linters) rarely mean much
+// once the build is broken.
+func (o *Orchestrator) runVerification(ctx context.Context, role Role, task PlanTask) ([]verifyResult, error) {
+	commands := o.verificationCommands(task)
+	results := make([]verifyResult, 0, len(commands))
+	timeout := o.VerifyTimeout
+	if timeout <= 0 {
+		timeout = defaultVerifyTimeout
+	}
+	for _, command := range commands {
+		if err := checkContextCancelled(ctx); err != nil {
+			return results, err
+		}
+		o.emit(StepUpdate{StepID: task.ID, Status: "running", Msg: fmt.Sprintf("Verifying: %s", command)})
+		o.emitEvent(AgentEvent{
+			Type:    EventRunning,
+			Role:    role,
+			Detail:  fmt.Sprintf("verifying %s", command),
+			Payload: map[string]any{"command": command},
+		})
+
+		cmdCtx, cancel := context.WithTimeout(ctx, timeout)
+		cmd := exec.CommandContext(cmdCtx, "bash", "-lc", command)
+		cmd.Dir = o.taskWorkingDir()
+		start := time.Now()
+		out, err := cmd.CombinedOutput()
+		timedOut := errors.Is(cmdCtx.Err(), context.DeadlineExceeded)
+		cancel()
+
+		if err != nil {
+			if ctxErr := checkContextCancelled(ctx); ctxErr != nil {
+				return results, ctxErr
+			}
+			if timedOut {
+				err = fmt.Errorf("timed out after %s", timeout)
+			}
+		}
+		result := verifyResult{
+			Command:  command,
+			Output:   truncateVerifyOutput(strings.TrimSpace(string(out))),
+			Err:      err,
+			Duration: time.Since(start),
+		}
+		results = append(results, result)
+		if !result.passed() {
+			break
+		}
+	}
+	return results, nil
+}
+
+func verificationFailed(results []verifyResult) (verifyResult, bool) {
+	for _, result := range results {
+		if !result.passed() {
+			return result, true
+		}
+	}
+	return verifyResult{}, false
+}
+
+func renderVerificationFailure(result verifyResult) string {
+	lines := []string{
+		"Verification failure:",
+		fmt.Sprintf("- Command: %s", result.Command),
+		fmt.Sprintf("- Error: %v", result.Err),
+	}
+	if result.Output != "" {
+		lines = append(lines, "- Output:", result.Output)
+	}
+	lines = append(lines, "Fix the problems above, then finish the task. Do not only describe the fix.")
+	return strings.Join(lines, "\n")
+}
+
+func renderVerificationSummary(results []verifyResult) string {
+	if len(results) == 0 {
+		return ""
+	}
+	lines := []string{"Verification (all passed):"}
+	for _, result := range results {
+		line := fmt.Sprintf("- %s (%s)", result.Command, result.Duration.Round(time.Millisecond))
+		if result.Output != "" {
+			line += "\n" + indentVerifyOutput(truncateTail(result.Output, 600))
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncateVerifyOutput keeps the tail, where compilers and test runners put
+// the summary and the first hard error is usually still visible.
+func truncateVerifyOutput(output string) string {
+	return truncateTail(output, maxVerifyOutputChars)
+}
+
+// truncateTail keeps at most the last limit bytes of output, moving the cut
+// forward to a rune boundary so multi-byte characters are not split.
+func truncateTail(output string, limit int) string {
+	if len(output) <= limit {
+		return output
+	}
+	cut := len(output) - limit
+	for cut < len(output) && !utf8.RuneStart(output[cut]) {
+		cut++
+	}
+	return "... (truncated)\n" + output[cut:]
+}
+
+func indentVerifyOutput(output string) string {
+	lines := strings.Split(output, "\n")
+	for i := range lines {
+		lines[i] = "    " + lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
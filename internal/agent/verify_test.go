@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/yubzen/orchestra/internal/state"
+)
+
+func TestOrchestratorVerificationFailureNeverReachesReviewer(t *testing.T) {
+	t.Parallel()
+	workDir := t.TempDir()
+
+	coderProv := &sequenceProvider{replies: []string{`{"status":"done"}`}}
+	reviewerProv := &sequenceProvider{replies: []string{`{"approved": true, "findings": []}`}}
+	orc := &Orchestrator{
+		Planner:        newTestAgent(RolePlanner, &sequenceProvider{}),
+		Coder:          newTestAgent(RoleCoder, coderProv),
+		Reviewer:       newTestAgent(RoleReviewer, reviewerProv),
+		UpdateChan:     make(chan StepUpdate, 128),
+		WorkingDir:     workDir,
+		ProjectBrief:   "Working directory: .",
+		Session:        &state.Session{ExecutionMode: state.ExecutionModeFast},
+		VerifyCommands: []string{"echo 'main.go:3: undefined: hamid' && false"},
+	}
+
+	err := orc.Run(context.Background(), "implement feature")
+	if err == nil || !strings.Contains(err.Error(), "failed verification") {
+		t.Fatalf("expected verification error, got %v", err)
+	}
+	if len(reviewerProv.prompts) != 0 {
+		t.Fatalf("expected reviewer to be skipped when verification fails, got %d prompt(s)", len(reviewerProv.prompts))
+	}
+	if len(coderProv.prompts) != 3 {
+		t.Fatalf("expected 3 coder attempts, got %d", len(coderProv.prompts))
+	}
+	retryPrompt := coderProv.prompts[1]
+	if !strings.Contains(retryPrompt, "Verification failure:") || !strings.Contains(retryPrompt, "undefined: hamid") {
+		t.Fatalf("expected retry prompt to include failing command output, got %q", retryPrompt)
+	}
+	if strings.Count(coderProv.prompts[2], "Verification failure:") != 1 {
+		t.Fatalf("expected retry prompts not to accumulate failures, got %q", coderProv.prompts[2])
+	}
+}
+
+func TestOrchestratorVerificationResultsAttachedToReview(t *testing.T) {
+	t.Parallel()
+	workDir := t.TempDir()
+
+	plannerProv := &sequenceProvider{
+		replies: []string{"```yaml\ntasks:\n  - id: t1\n    description: a\n    verify: [\"echo task-check-ok\"]\n```"},
+	}
+	coderProv := &planFileReadProvider{}
+	reviewerProv := &sequenceProvider{replies: []string{`{"approved": true, "findings": []}`}}
+	orc := &Orchestrator{
+		Planner:        newTestAgent(RolePlanner, plannerProv),
+		Coder:          newTestAgent(RoleCoder, coderProv),
+		Reviewer:       newTestAgent(RoleReviewer, reviewerProv),
+		UpdateChan:     make(chan StepUpdate, 128),
+		WorkingDir:     workDir,
+		ProjectBrief:   "Working directory: .",
+		Session:        &state.Session{ExecutionMode: state.ExecutionModePlan},
+		VerifyCommands: []string{"echo build-ok"},
+	}
+
+	if err := orc.Run(context.Background(), "implement feature"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(reviewerProv.prompts) != 1 {
+		t.Fatalf("expected one review, got %d", len(reviewerProv.prompts))
+	}
+	review := reviewerProv.prompts[0]
+	for _, want := range []string{"Verification (all passed):", "echo build-ok", "build-ok", "echo task-check-ok"} {
+		if !strings.Contains(review, want) {
+			t.Fatalf("expected review prompt to contain %q, got %q", want, review)
+		}
+	}
+	requireDirEntryWithSuffixEventually(t, filepath.Join(workDir, ".orchestra", "plans"), ".lock", 3*time.Second)
+}
+
+func TestVerificationCommandsMergesProjectAndTask(t *testing.T) {
+	t.Parallel()
+
+	orc := &Orchestrator{VerifyCommands: []string{"go build ./...", " ", "go vet ./..."}}
+	got := orc.verificationCommands(PlanTask{Verify: []string{"go vet ./...", "go test ./pkg/..."}})
+	want := []string{"go build ./...", "go vet ./...", "go test ./pkg/..."}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestTruncateTailKeepsRunesWhole(t *testing.T) {
+	t.Parallel()
+
+	got := truncateTail("ok\nerreur: é", 1)
+	if got != "... (truncated)\n" || !utf8.ValidString(got) {
+		t.Fatalf("expected the split rune to be dropped, got %q", got)
+	}
+	got = truncateTail("ok\nerreur: é", 2)
+	if got != "... (truncated)\né" {
+		t.Fatalf("expected the whole rune to be kept, got %q", got)
+	}
+}
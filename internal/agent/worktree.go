@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrMergeConflict = errors.New("merge conflict")
+
+type taskWorktree struct {
+	Dir        string
+	Branch     string
+	Conflicted bool
+}
+
+func (o *Orchestrator) worktreesEnabled() bool {
+	return o != nil && o.Git.Enabled && o.Git.Worktrees
+}
+
+// taskWorkingDir is where the current task's agents read and write: the task
+// worktree when one is active, otherwise the main working tree.
+func (o *Orchestrator) taskWorkingDir() string {
+	if o != nil && o.activeWorktree != nil {
+		return o.activeWorktree.Dir
+	}
+	return o.effectiveWorkingDir()
+}
+
+func (o *Orchestrator) createTaskWorktree(ctx context.Context, planID string, task PlanTask, planPath string) (*taskWorktree, error) {
+	root, err := filepath.Abs(o.effectiveWorkingDir())
+	if err != nil {
+		return nil, err
+	}
+	name := normalizePlanID(planID + "-" + task.ID)
+	wt := &taskWorktree{
+		Dir:    filepath.Join(root, ".orchestra", "worktrees", name),
+		Branch: "orchestra/" + name,
+	}
+	if _, err := os.Stat(wt.Dir); err == nil {
+		if _, err := runGit(ctx, root, "worktree", "remove", "--force", wt.Dir); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := runGit(ctx, root, "worktree", "add", "-q", "-B", wt.Branch, wt.Dir, "HEAD"); err != nil {
+		return nil, err
+	}
+
+	// Plan files live untracked under .orchestra/, so carry the approved plan
+	// into the worktree for plan-mode coders that must read it.
+	if planPath = strings.TrimSpace(planPath); planPath != "" {
+		srcPath, _, err := resolveWorkspacePath(root, planPath)
+		if err != nil {
+			return wt, err
+		}
+		dstPath, _, err := resolveWorkspacePath(wt.Dir, planPath)
+		if err != nil {
+			return wt, err
+		}
+		content, err := os.ReadFile(srcPath)
+		if err != nil {
+			return wt, err
+		}
+		if err := os.MkdirAll(filepath.Dir(dstPath), 0o755); err != nil {
+			return wt, err
+		}
+		if err := os.WriteFile(dstPath, content, 0o644); err != nil {
+			return wt, err
+		}
+	}
+	return wt, nil
+}
+
+// mergeTaskWorktree commits the task inside its worktree and merges the task
+// branch into the main tree. Conflicting merges are aborted and reported with
+// ErrMergeConflict so the main tree is left as it was.
+func (o *Orchestrator) mergeTaskWorktree(ctx context.Context, wt *taskWorktree, planID string, task PlanTask) (string, error) {
+	if _, err := o.commitTask(ctx, wt.Dir, planID, task); err != nil {
+		return "", err
+	}
+	root := o.effectiveWorkingDir()
+	ahead, err := runGit(ctx, root, "rev-list", "--count", "HEAD.."+wt.Branch)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(ahead) == "0" {
+		return "", nil
+	}
+	if _, mergeErr := runGit(ctx, root, "merge", "--no-edit", wt.Branch); mergeErr != nil {
+		if IsUserCancelled(mergeErr) {
+			return "", mergeErr
+		}
+		conflicts, _ := runGit(ctx, root, "diff", "--name-only", "--diff-filter=U")
+		if _, err := runGit(ctx, root, "merge", "--abort"); err != nil {
+			return "", fmt.Errorf("%v; abort failed: %w", mergeErr, err)
+		}
+		wt.Conflicted = true
+		files := strings.Join(strings.Fields(conflicts), ", ")
+		if files == "" {
+			return "", fmt.Errorf("%w merging %s: %v", ErrMergeConflict, wt.Branch, mergeErr)
+		}
+		return "", fmt.Errorf("%w merging %s: %s", ErrMergeConflict, wt.Branch, files)
+	}
+	return runGit(ctx, root, "rev-parse", "HEAD")
+}
+
+// removeTaskWorktree drops the worktree directory. The task branch is kept
+// after a conflict so the user can resolve it by hand.
+func (o *Orchestrator) removeTaskWorktree(ctx context.Context, wt *taskWorktree) {
+	if wt == nil {
+		return
+	}
+	root := o.effectiveWorkingDir()
+	if _, err := runGit(ctx, root, "worktree", "remove", "--force", wt.Dir); err != nil {
+		o.emitEvent(AgentEvent{Type: EventError, Role: RolePlanner, Detail: fmt.Sprintf("remove worktree %s: %v", wt.Branch, err)})
+		return
+	}
+	if wt.Conflicted {
+		return
+	}
+	if _, err := runGit(ctx, root, "branch", "-D", wt.Branch); err != nil {
+		o.emitEvent(AgentEvent{Type: EventError, Role: RolePlanner, Detail: fmt.Sprintf("delete branch %s: %v", wt.Branch, err)})
+	}
+}
@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yubzen/orchestra/internal/providers"
+)
+
+// scriptedToolProvider issues one batch of tool calls, records the tool
+// results it gets back, then finishes the task.
+type scriptedToolProvider struct {
+	calls       []providers.ToolCall
+	beforeCalls func()
+	callIdx     int
+	toolResults []string
+}
+
+func (p *scriptedToolProvider) Name() string                                     { return "scripted" }
+func (p *scriptedToolProvider) Ping(ctx context.Context) error                   { return nil }
+func (p *scriptedToolProvider) ListModels(ctx context.Context) ([]string, error) { return nil, nil }
+func (p *scriptedToolProvider) Complete(ctx context.Context, model string, messages []providers.Message, tools []providers.Tool, onToken providers.TokenCallback) (providers.CompletionResponse, error) {
+	p.callIdx++
+	if p.callIdx == 1 {
+		if p.beforeCalls != nil {
+			p.beforeCalls()
+		}
+		return providers.CompletionResponse{Text: "working", ToolCalls: p.calls, StopReason: "tool_calls"}, nil
+	}
+	for _, msg := range messages {
+		if msg.Role == "tool" {
+			p.toolResults = append(p.toolResults, msg.Content)
+		}
+	}
+	return providers.CompletionResponse{Text: `{"status":"done"}`}, nil
+}
+
+func toolCall(t *testing.T, id, name string, args map[string]any) providers.ToolCall {
+	t.Helper()
+	raw, err := json.Marshal(args)
+	if err != nil {
+		t.Fatalf("marshal tool args: %v", err)
+	}
+	return providers.ToolCall{ID: id, Name: name, Arguments: raw}
+}
+
+func TestOrchestratorWorktreeRunsTaskInIsolationAndMerges(t *testing.T) {
+	t.Parallel()
+	repo := initGitRepo(t)
+	orc := newGitTestOrchestrator(t, repo, GitOptions{Enabled: true, Worktrees: true})
+	provider := &scriptedToolProvider{calls: []providers.ToolCall{
+		toolCall(t, "tc-1", "write_file", map[string]any{"path": "hamid.ts", "content": "export const hamid = 1\n"}),
+		toolCall(t, "tc-2", "run_command", map[string]any{"command": "pwd"}),
+	}}
+	orc.Planner = newTestAgent(RolePlanner, provider)
+	ctx := context.Background()
+
+	if err := orc.Run(ctx, "create hamid.ts"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	if len(provider.toolResults) != 2 || !strings.Contains(provider.toolResults[1], filepath.Join(".orchestra", "worktrees")) {
+		t.Fatalf("expected tools to run inside the task worktree, got %q", provider.toolResults)
+	}
+	content, err := os.ReadFile(filepath.Join(repo, "hamid.ts"))
+	if err != nil {
+		t.Fatalf("expected merged file in main tree: %v", err)
+	}
+	if strings.TrimSpace(string(content)) != "export const hamid = 1" {
+		t.Fatalf("unexpected merged content: %q", string(content))
+	}
+	entries, _ := os.ReadDir(filepath.Join(repo, ".orchestra", "worktrees"))
+	if len(entries) != 0 {
+		t.Fatalf("expected task worktree to be removed, found %d entries", len(entries))
+	}
+	branches, err := runGit(ctx, repo, "branch", "--list", "orchestra/*")
+	if err != nil {
+		t.Fatalf("list branches: %v", err)
+	}
+	if strings.Count(branches, "orchestra/") != 1 {
+		t.Fatalf("expected only the run branch to remain, got %q", branches)
+	}
+	results, err := orc.DB.GetTaskResults(ctx, orc.Session.ID)
+	if err != nil {
+		t.Fatalf("task results: %v", err)
+	}
+	head, _ := runGit(ctx, repo, "rev-parse", "HEAD")
+	if len(results) != 1 || results[0].CommitSHA != head {
+		t.Fatalf("expected task result with merged commit %s, got %+v", head, results)
+	}
+}
+
+func TestOrchestratorWorktreeReportsMergeConflictAsBlocked(t *testing.T) {
+	t.Parallel()
+	repo := initGitRepo(t)
+	orc := newGitTestOrchestrator(t, repo, GitOptions{Enabled: true, Worktrees: true})
+	ctx := context.Background()
+	provider := &scriptedToolProvider{
+		calls: []providers.ToolCall{
+			toolCall(t, "tc-1", "write_file", map[string]any{"path": "README.md", "content": "# from task\n"}),
+		},
+		beforeCalls: func() {
+			// Simulate the main tree moving on while the task is running.
+			_ = os.WriteFile(filepath.Join(repo, "README.md"), []byte("# from main\n"), 0o644)
+			_, _ = runGit(ctx, repo, "commit", "-q", "-am", "main change")
+		},
+	}
+	orc.Planner = newTestAgent(RolePlanner, provider)
+
+	err := orc.Run(ctx, "update README.md")
+	if !errors.Is(err, ErrMergeConflict) {
+		t.Fatalf("expected ErrMergeConflict, got %v", err)
+	}
+
+	close(orc.UpdateChan)
+	blocked := false
+	for up := range orc.UpdateChan {
+		if up.Status == "blocked" && strings.Contains(up.Msg, "README.md") {
+			blocked = true
+		}
+	}
+	if !blocked {
+		t.Fatal("expected blocked step update naming the conflicting file")
+	}
+	content, _ := os.ReadFile(filepath.Join(repo, "README.md"))
+	if string(content) != "# from main\n" {
+		t.Fatalf("expected main tree to be left as it was, got %q", string(content))
+	}
+	branches, _ := runGit(ctx, repo, "branch", "--list", "orchestra/*")
+	if strings.Count(branches, "orchestra/") != 2 {
+		t.Fatalf("expected conflicting task branch to be kept, got %q", branches)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"text/tabwriter"
 	"time"
 
@@ -20,53 +21,16 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/zalando/go-keyring"
 
+	"github.com/yubzen/orchestra/internal/agent"
 	"github.com/yubzen/orchestra/internal/config"
+	"github.com/yubzen/orchestra/internal/migrate"
 	"github.com/yubzen/orchestra/internal/providers"
+	"github.com/yubzen/orchestra/internal/rag"
+	"github.com/yubzen/orchestra/internal/state"
 )
 
 const keyringServiceName = "orchestra"
 
-const stateSchema = `
-CREATE TABLE IF NOT EXISTS sessions (
-	id TEXT PRIMARY KEY,
-	created_at DATETIME,
-	working_dir TEXT,
-	mode TEXT
-);
-CREATE TABLE IF NOT EXISTS messages (
-	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	session_id TEXT,
-	role TEXT,
-	agent_role TEXT,
-	content TEXT,
-	tokens_used INTEGER,
-	created_at DATETIME
-);
-CREATE TABLE IF NOT EXISTS memory_blocks (
-	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	session_id TEXT,
-	summary TEXT,
-	created_at DATETIME
-);
-CREATE TABLE IF NOT EXISTS task_results (
-	id TEXT PRIMARY KEY,
-	session_id TEXT,
-	agent_role TEXT,
-	input TEXT,
-	output TEXT,
-	status TEXT,
-	created_at DATETIME
-);
-CREATE TABLE IF NOT EXISTS session_model_selections (
-	session_id TEXT NOT NULL,
-	role TEXT NOT NULL,
-	provider_key TEXT NOT NULL,
-	model_id TEXT NOT NULL,
-	updated_at DATETIME,
-	PRIMARY KEY (session_id, role)
-);
-`
-
 type providerSpec struct {
 	Name           string
 	DisplayName    string
@@ -191,6 +155,7 @@ type exportSession struct {
 	Messages     []exportMessage    `json:"messages"`
 	MemoryBlocks []exportMemory     `json:"memory_blocks"`
 	TaskResults  []exportTaskResult `json:"task_results"`
+	ToolCalls    []exportToolCall   `json:"tool_calls,omitempty"`
 }
 
 type exportMessage struct {
@@ -212,18 +177,65 @@ type exportTaskResult struct {
 	Input     string `json:"input"`
 	Output    string `json:"output"`
 	Status    string `json:"status"`
+	CommitSHA string `json:"commit_sha,omitempty"`
 	CreatedAt string `json:"created_at"`
 }
 
+type exportToolCall struct {
+	RunID      string `json:"run_id,omitempty"`
+	TaskID     string `json:"task_id,omitempty"`
+	Role       string `json:"role"`
+	Tool       string `json:"tool"`
+	Args       string `json:"args"`
+	Result     string `json:"result"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+	Iteration  int    `json:"iteration"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// StateLocation resolves where the project's databases live for cfg. If that
+// fails it falls back to the current directory, as older releases did.
+func StateLocation(cfg *config.Config) state.Location {
+	workingDir, configured := ".", ""
+	if cfg != nil {
+		workingDir, configured = cfg.Defaults.WorkingDir, cfg.State.Dir
+	}
+	loc, err := state.ResolveLocation(workingDir, configured)
+	if err != nil {
+		return state.Location{Dir: ".", StateDB: state.StateDBName, RAGDB: state.RAGDBName}
+	}
+	return loc
+}
+
+// PrepareStateLocation creates the state directory for cfg and moves
+// databases that older releases left in the current or working directory.
+// Databases at the paths in inUse, which a command was told to open, stay
+// where they are.
+func PrepareStateLocation(cfg *config.Config, inUse ...string) (state.Location, error) {
+	loc := StateLocation(cfg)
+	legacyDirs := []string{"."}
+	if cfg != nil && strings.TrimSpace(cfg.Defaults.WorkingDir) != "" {
+		legacyDirs = append(legacyDirs, cfg.Defaults.WorkingDir)
+	}
+	moved, err := loc.PrepareExcept(inUse, legacyDirs...)
+	for _, path := range moved {
+		fmt.Fprintf(os.Stderr, "moved %s to %s\n", path, loc.Dir)
+	}
+	return loc, err
+}
+
+// defaultLocation backs the --db and --rag-db flag defaults.
+var defaultLocation = sync.OnceValue(func() state.Location {
+	cfg, _ := config.Load()
+	return StateLocation(cfg)
+})
+
 func openStateDB(dbPath string) (*sql.DB, error) {
-	conn, err := sql.Open("sqlite3", dbPath)
+	conn, err := state.Open(dbPath)
 	if err != nil {
 		return nil, fmt.Errorf("open db: %w", err)
 	}
-	if _, err := conn.Exec(stateSchema); err != nil {
-		_ = conn.Close()
-		return nil, fmt.Errorf("migrate db: %w", err)
-	}
 	return conn, nil
 }
 
@@ -242,6 +254,21 @@ func asString(v any) string {
 	}
 }
 
+func orDash(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "-"
+	}
+	return value
+}
+
+func truncateForDisplay(value string, limit int) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if runes := []rune(value); len(runes) > limit {
+		return string(runes[:limit-3]) + "..."
+	}
+	return value
+}
+
 func parseExportedTime(raw string) time.Time {
 	raw = strings.TrimSpace(raw)
 	if raw == "" {
@@ -628,7 +655,7 @@ func NewStatsCmd() *cobra.Command {
 			return w.Flush()
 		},
 	}
-	statsCmd.Flags().StringVar(&dbPath, "db", "orchestra.db", "Path to SQLite database")
+	statsCmd.Flags().StringVar(&dbPath, "db", defaultLocation().StateDB, "Path to SQLite database")
 	return statsCmd
 }
 
@@ -870,11 +897,83 @@ func NewSessionCmd() *cobra.Command {
 		},
 	}
 
-	sessionCmd.Flags().StringVar(&dbPath, "db", "orchestra.db", "Path to SQLite database")
-	sessionCmd.AddCommand(listCmd, manageCmd, resumeCmd)
+	var toolsTask string
+	var toolsFull bool
+	toolsCmd := &cobra.Command{
+		Use:   "tools <session-id>",
+		Short: "Show the tool calls recorded for a session",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSessionTools(dbPath, args[0], toolsTask, toolsFull)
+		},
+	}
+	toolsCmd.Flags().StringVar(&dbPath, "db", defaultLocation().StateDB, "Path to SQLite database")
+	toolsCmd.Flags().StringVar(&toolsTask, "task", "", "Only show calls made for this task ID")
+	toolsCmd.Flags().BoolVar(&toolsFull, "full", false, "Print arguments, results and errors of every call")
+
+	sessionCmd.Flags().StringVar(&dbPath, "db", defaultLocation().StateDB, "Path to SQLite database")
+	sessionCmd.AddCommand(listCmd, manageCmd, resumeCmd, toolsCmd)
 	return sessionCmd
 }
 
+func runSessionTools(dbPath, sessionID, taskID string, full bool) error {
+	db, err := state.Connect(dbPath)
+	if err != nil {
+		return fmt.Errorf("open db: %w", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	session, err := db.GetSession(ctx, strings.TrimSpace(sessionID))
+	if err != nil {
+		return err
+	}
+	calls, err := db.ListToolCalls(ctx, session.ID, taskID)
+	if err != nil {
+		return fmt.Errorf("query tool calls: %w", err)
+	}
+	if len(calls) == 0 {
+		fmt.Println("No tool calls recorded.")
+		return nil
+	}
+
+	if full {
+		for _, call := range calls {
+			fmt.Printf("#%d %s %s/%s %s iter=%d %s\n",
+				call.ID, call.CreatedAt.Format(time.RFC3339), orDash(call.TaskID), call.Role,
+				call.Tool, call.Iteration, call.Duration)
+			fmt.Printf("  args: %s\n", call.Args)
+			if call.Error != "" {
+				fmt.Printf("  error: %s\n", call.Error)
+			}
+			if result := strings.TrimSpace(call.Result); result != "" {
+				fmt.Printf("  result:\n    %s\n", strings.ReplaceAll(result, "\n", "\n    "))
+			}
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 2, 2, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tTASK\tROLE\tITER\tTOOL\tDURATION\tSTATUS\tARGS")
+	for _, call := range calls {
+		status := "ok"
+		if call.Error != "" {
+			status = "error"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+			call.CreatedAt.Format("15:04:05"),
+			orDash(call.TaskID),
+			call.Role,
+			call.Iteration,
+			call.Tool,
+			call.Duration,
+			status,
+			truncateForDisplay(call.Args, 60),
+		)
+	}
+	return w.Flush()
+}
+
 func runSessionList(dbPath string) error {
 	conn, err := openStateDB(dbPath)
 	if err != nil {
@@ -975,6 +1074,8 @@ func NewDBCmd() *cobra.Command {
 		Use:   "path",
 		Short: "Print configured database paths",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			loc := defaultLocation()
+			fmt.Printf("state_dir=%s (%s)\n", loc.Dir, loc.Source)
 			fmt.Printf("state_db=%s\n", dbPath)
 			fmt.Printf("rag_db=%s\n", ragDBPath)
 			return nil
@@ -1092,12 +1193,327 @@ func NewDBCmd() *cobra.Command {
 		},
 	}
 
-	dbCmd.Flags().StringVar(&dbPath, "db", "orchestra.db", "Path to SQLite state database")
-	dbCmd.Flags().StringVar(&ragDBPath, "rag-db", "orchestra_vec.db", "Path to SQLite RAG index database")
-	dbCmd.AddCommand(pathCmd, queryCmd, clearIndexCmd, vacuumCmd)
+	var showStatus bool
+	var stateTarget, ragTarget int
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Upgrade the state and RAG database schemas",
+		Long: "Applies pending schema migrations to the state and RAG databases, after backing each up to <db>.v<version>.bak. Migrations also run automatically on startup.\n\n" +
+			"The two schemas are versioned separately: --state-to and --rag-to stop a store at an earlier version, and migrate only the stores they name.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pinned := cmd.Flags().Changed("state-to") || cmd.Flags().Changed("rag-to")
+			stores := []struct {
+				label  string
+				path   string
+				flag   string
+				target int
+				status func(string) (migrate.Status, error)
+				run    func(string, int) (migrate.Result, error)
+			}{
+				{"State DB", dbPath, "state-to", stateTarget, state.MigrationStatus, state.MigrateTo},
+				{"RAG DB", ragDBPath, "rag-to", ragTarget, rag.MigrationStatus, rag.MigrateTo},
+			}
+			for _, store := range stores {
+				if pinned && !showStatus && !cmd.Flags().Changed(store.flag) {
+					continue
+				}
+				if _, err := os.Stat(store.path); err != nil {
+					fmt.Printf("%s: %s does not exist\n", store.label, store.path)
+					continue
+				}
+				if showStatus {
+					status, err := store.status(store.path)
+					if err != nil {
+						return fmt.Errorf("%s: %w", store.path, err)
+					}
+					fmt.Printf("%s: %s at version %d of %d\n", store.label, store.path, status.Current, status.Latest)
+					for _, applied := range status.Applied {
+						fmt.Printf("  applied  %d %s (%s)\n", applied.Version, applied.Name, applied.AppliedAt.Local().Format("2006-01-02 15:04"))
+					}
+					for _, pending := range status.Pending {
+						fmt.Printf("  pending  %d %s\n", pending.Version, pending.Name)
+					}
+					continue
+				}
+				result, err := store.run(store.path, store.target)
+				if err != nil {
+					return fmt.Errorf("%s: %w", store.path, err)
+				}
+				if len(result.Applied) == 0 {
+					fmt.Printf("%s: %s is at version %d, nothing to do\n", store.label, store.path, result.To)
+					continue
+				}
+				fmt.Printf("%s: %s migrated from version %d to %d\n", store.label, store.path, result.From, result.To)
+				for _, applied := range result.Applied {
+					fmt.Printf("  applied  %d %s\n", applied.Version, applied.Name)
+				}
+				if result.Backup != "" {
+					fmt.Printf("  backup   %s\n", result.Backup)
+				}
+			}
+			return nil
+		},
+	}
+	migrateCmd.Flags().BoolVar(&showStatus, "status", false, "Show applied and pending migrations without migrating")
+	migrateCmd.Flags().IntVar(&stateTarget, "state-to", 0, "Migrate the state database up to this version instead of the latest")
+	migrateCmd.Flags().IntVar(&ragTarget, "rag-to", 0, "Migrate the RAG database up to this version instead of the latest")
+
+	dbCmd.PersistentFlags().StringVar(&dbPath, "db", defaultLocation().StateDB, "Path to SQLite state database")
+	dbCmd.PersistentFlags().StringVar(&ragDBPath, "rag-db", defaultLocation().RAGDB, "Path to SQLite RAG index database")
+	dbCmd.AddCommand(pathCmd, queryCmd, clearIndexCmd, vacuumCmd, migrateCmd)
 	return dbCmd
 }
 
+// NewEmbeddingProvider builds the embedder selected by the [rag] config.
+func NewEmbeddingProvider(cfg *config.Config) (rag.EmbeddingProvider, error) {
+	embedderCfg := rag.EmbedderConfig{
+		Backend:    cfg.RAG.Backend,
+		Model:      cfg.RAG.Embedder,
+		URL:        cfg.RAG.OllamaURL,
+		Dimensions: cfg.RAG.Dimensions,
+	}
+	if strings.EqualFold(strings.TrimSpace(cfg.RAG.Backend), "openai") {
+		embedderCfg.URL = strings.TrimSpace(cfg.RAG.BaseURL)
+		if embedderCfg.URL == "" {
+			embedderCfg.URL = cfg.Providers.OpenAI.BaseURL
+		}
+		keyName := strings.TrimSpace(cfg.RAG.KeyName)
+		if keyName == "" {
+			keyName = "openai"
+		}
+		// Local OpenAI-compatible servers usually need no key.
+		embedderCfg.APIKey, _ = providers.LoadCredential(keyName)
+	}
+	return rag.NewEmbeddingProvider(embedderCfg)
+}
+
+func NewIndexCmd() *cobra.Command {
+	var ragDBPath string
+	var workingDir string
+	indexCmd := &cobra.Command{
+		Use:   "index",
+		Short: "Inspect and maintain the RAG index",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runIndexStatus(ragDBPath, workingDir)
+		},
+	}
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show indexed files, stale files and search backends",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runIndexStatus(ragDBPath, workingDir)
+		},
+	}
+
+	var rebuildPaths []string
+	rebuildCmd := &cobra.Command{
+		Use:   "rebuild",
+		Short: "Re-embed the whole project, or only the given paths",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			indexer, closeIndexer, err := openIndexer(ragDBPath, workingDir, true)
+			if err != nil {
+				return err
+			}
+			defer closeIndexer()
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for progress := range indexer.Progress {
+					if progress.Phase == rag.IndexPhaseIndexing {
+						status := "ok"
+						if progress.Err != nil {
+							status = progress.Err.Error()
+						}
+						fmt.Printf("[%d/%d] %s %s\n", progress.Done, progress.Total, progress.Path, status)
+					}
+				}
+			}()
+			err = indexer.Reindex(context.Background(), rebuildPaths...)
+			close(indexer.Progress)
+			<-done
+			if err != nil {
+				return err
+			}
+			fmt.Println("Index rebuilt.")
+			return nil
+		},
+	}
+	rebuildCmd.Flags().StringSliceVar(&rebuildPaths, "path", nil, "Only re-embed files under this path (repeatable)")
+
+	var queryK int
+	var queryMode string
+	var queryBudget int
+	var queryFull bool
+	queryCmd := &cobra.Command{
+		Use:   "query <text>",
+		Short: "Run a retrieval query and print scored chunks",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			indexer, closeIndexer, err := openIndexer(ragDBPath, workingDir, false)
+			if err != nil {
+				return err
+			}
+			defer closeIndexer()
+
+			chunks, err := indexer.Query(context.Background(), args[0], rag.QueryOptions{
+				Mode:        rag.QueryMode(queryMode),
+				TokenBudget: queryBudget,
+				MaxPerFile:  queryK,
+				Candidates:  queryK * 2,
+				Limit:       queryK,
+			})
+			if err != nil {
+				return err
+			}
+			if len(chunks) == 0 {
+				fmt.Println("No matching chunks.")
+				return nil
+			}
+			for idx, chunk := range chunks {
+				header := fmt.Sprintf("%2d. %.4f  %s", idx+1, chunk.Score, chunk.Location())
+				if chunk.Symbol != "" {
+					header += fmt.Sprintf("  (%s)", chunk.Symbol)
+				}
+				fmt.Println(header)
+				lines := strings.Split(strings.TrimSpace(chunk.Content), "\n")
+				if !queryFull && len(lines) > 3 {
+					lines = append(lines[:3], "...")
+				}
+				fmt.Printf("    %s\n", strings.Join(lines, "\n    "))
+			}
+			return nil
+		},
+	}
+	queryCmd.Flags().IntVarP(&queryK, "k", "k", 10, "Maximum number of chunks to print")
+	queryCmd.Flags().StringVar(&queryMode, "mode", "hybrid", "Retrieval mode: hybrid, vector or lexical")
+	queryCmd.Flags().IntVar(&queryBudget, "budget", 100000, "Token budget for returned chunks (agents use 2000)")
+	queryCmd.Flags().BoolVar(&queryFull, "full", false, "Print whole chunks instead of the first lines")
+
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Drop index data for deleted or ignored files",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			indexer, closeIndexer, err := openIndexer(ragDBPath, workingDir, false)
+			if err != nil {
+				return err
+			}
+			defer closeIndexer()
+
+			pruned, err := indexer.Prune(context.Background())
+			for _, path := range pruned {
+				fmt.Printf("pruned %s\n", path)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Pruned %d file(s).\n", len(pruned))
+			return nil
+		},
+	}
+
+	indexCmd.PersistentFlags().StringVar(&ragDBPath, "rag-db", defaultLocation().RAGDB, "Path to SQLite RAG index database")
+	indexCmd.PersistentFlags().StringVar(&workingDir, "dir", "", "Project directory the index covers (default: config working_dir)")
+	indexCmd.AddCommand(statusCmd, rebuildCmd, queryCmd, pruneCmd)
+	return indexCmd
+}
+
+// openIndexer opens the RAG store and an indexer with the configured
+// embedder and chunking, without starting the file watcher. A missing store
+// is only created when create is set.
+func openIndexer(ragDBPath, workingDir string, create bool) (*rag.Indexer, func(), error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("load config: %w", err)
+	}
+	if strings.TrimSpace(workingDir) == "" {
+		workingDir = strings.TrimSpace(cfg.Defaults.WorkingDir)
+	}
+	if workingDir == "" {
+		workingDir = "."
+	}
+	embedder, err := NewEmbeddingProvider(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := os.Stat(ragDBPath); err != nil && !(create && errors.Is(err, os.ErrNotExist)) {
+		return nil, nil, fmt.Errorf("open rag index: %w", err)
+	}
+	store, err := rag.NewStore(ragDBPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	indexer := rag.NewIndexer(store, embedder, workingDir)
+	indexer.Chunking = rag.ChunkOptions{Size: cfg.RAG.ChunkSize, Overlap: cfg.RAG.ChunkOverlap}
+	return indexer, func() { _ = store.Close() }, nil
+}
+
+func runIndexStatus(ragDBPath, workingDir string) error {
+	indexer, closeIndexer, err := openIndexer(ragDBPath, workingDir, false)
+	if err != nil {
+		return err
+	}
+	defer closeIndexer()
+
+	status, err := indexer.Status(context.Background())
+	if err != nil {
+		return err
+	}
+
+	embedderState := "reachable"
+	if status.EmbedderErr != nil {
+		embedderState = "unreachable: " + status.EmbedderErr.Error()
+	}
+	vecState := "in-memory scan"
+	if enabled, dims := indexer.Store.VectorSearch(); enabled {
+		vecState = fmt.Sprintf("sqlite-vec (%d dims)", dims)
+	}
+	ftsState := "in-memory BM25"
+	if status.FTSEnabled {
+		ftsState = "FTS5"
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 2, 2, 2, ' ', 0)
+	fmt.Fprintf(w, "RAG DB:\t%s\n", ragDBPath)
+	fmt.Fprintf(w, "Working dir:\t%s\n", indexer.WorkingDir)
+	fmt.Fprintf(w, "Embedder:\t%s (%s)\n", status.Embedder, embedderState)
+	indexedWith := orDash(status.IndexedWith)
+	if status.Dimensions > 0 {
+		indexedWith += fmt.Sprintf(", %d dims", status.Dimensions)
+	}
+	fmt.Fprintf(w, "Indexed with:\t%s\n", indexedWith)
+	fmt.Fprintf(w, "Vector search:\t%s\n", vecState)
+	fmt.Fprintf(w, "Lexical search:\t%s\n", ftsState)
+	fmt.Fprintf(w, "Files:\t%d indexed, %d stale, %d missing\n", status.Files, len(status.Stale), len(status.Missing))
+	fmt.Fprintf(w, "Chunks:\t%d\n", status.Chunks)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if status.IndexedWith != "" && status.IndexedWith != status.Embedder {
+		fmt.Println("\nThe configured embedder differs from the indexed one; the next start rebuilds the index.")
+	}
+	printPathList("Stale (re-embedded on next start)", status.Stale)
+	printPathList("Missing (run `orchestra index prune`)", status.Missing)
+	return nil
+}
+
+func printPathList(title string, paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	const maxShown = 20
+	fmt.Printf("\n%s:\n", title)
+	for idx, path := range paths {
+		if idx == maxShown {
+			fmt.Printf("  ... and %d more\n", len(paths)-maxShown)
+			break
+		}
+		fmt.Printf("  %s\n", path)
+	}
+}
+
 func NewExportCmd() *cobra.Command {
 	var dbPath string
 	var outPath string
@@ -1180,14 +1596,14 @@ func NewExportCmd() *cobra.Command {
 				}
 				_ = memRows.Close()
 
-				taskRows, err := conn.Query("SELECT id, agent_role, input, output, status, created_at FROM task_results WHERE session_id = ? ORDER BY created_at ASC", s.ID)
+				taskRows, err := conn.Query("SELECT id, agent_role, input, output, status, COALESCE(commit_sha, ''), created_at FROM task_results WHERE session_id = ? ORDER BY created_at ASC", s.ID)
 				if err != nil {
 					return err
 				}
 				for taskRows.Next() {
 					var task exportTaskResult
 					var taskCreated any
-					if err := taskRows.Scan(&task.ID, &task.AgentRole, &task.Input, &task.Output, &task.Status, &taskCreated); err != nil {
+					if err := taskRows.Scan(&task.ID, &task.AgentRole, &task.Input, &task.Output, &task.Status, &task.CommitSHA, &taskCreated); err != nil {
 						_ = taskRows.Close()
 						return err
 					}
@@ -1200,6 +1616,29 @@ func NewExportCmd() *cobra.Command {
 				}
 				_ = taskRows.Close()
 
+				toolRows, err := conn.Query(`
+					SELECT COALESCE(run_id, ''), COALESCE(task_id, ''), COALESCE(role, ''), tool, COALESCE(args, ''),
+						COALESCE(result, ''), COALESCE(error, ''), COALESCE(duration_ms, 0), COALESCE(iteration, 0), created_at
+					FROM tool_calls WHERE session_id = ? ORDER BY id ASC`, s.ID)
+				if err != nil {
+					return err
+				}
+				for toolRows.Next() {
+					var call exportToolCall
+					var callCreated any
+					if err := toolRows.Scan(&call.RunID, &call.TaskID, &call.Role, &call.Tool, &call.Args, &call.Result, &call.Error, &call.DurationMS, &call.Iteration, &callCreated); err != nil {
+						_ = toolRows.Close()
+						return err
+					}
+					call.CreatedAt = asString(callCreated)
+					s.ToolCalls = append(s.ToolCalls, call)
+				}
+				if err := toolRows.Err(); err != nil {
+					_ = toolRows.Close()
+					return err
+				}
+				_ = toolRows.Close()
+
 				bundle.Sessions = append(bundle.Sessions, s)
 			}
 			if err := rows.Err(); err != nil {
@@ -1223,7 +1662,7 @@ func NewExportCmd() *cobra.Command {
 		},
 	}
 
-	exportCmd.Flags().StringVar(&dbPath, "db", "orchestra.db", "Path to SQLite state database")
+	exportCmd.Flags().StringVar(&dbPath, "db", defaultLocation().StateDB, "Path to SQLite state database")
 	exportCmd.Flags().StringVar(&outPath, "out", "", "Output JSON file")
 	exportCmd.Flags().StringVar(&sessionID, "session", "", "Optional session ID filter")
 	return exportCmd
@@ -1265,6 +1704,7 @@ func NewImportCmd() *cobra.Command {
 					"DELETE FROM messages",
 					"DELETE FROM memory_blocks",
 					"DELETE FROM task_results",
+					"DELETE FROM tool_calls",
 					"DELETE FROM sessions",
 				} {
 					if _, err := tx.Exec(stmt); err != nil {
@@ -1282,6 +1722,7 @@ func NewImportCmd() *cobra.Command {
 			messageCount := 0
 			memoryCount := 0
 			taskCount := 0
+			toolCallCount := 0
 			for _, s := range bundle.Sessions {
 				createdAt := parseExportedTime(s.CreatedAt)
 				if _, err := tx.Exec(sessionInsert, s.ID, createdAt, s.WorkingDir, s.Mode); err != nil {
@@ -1311,9 +1752,9 @@ func NewImportCmd() *cobra.Command {
 					memoryCount++
 				}
 
-				taskInsert := "INSERT OR REPLACE INTO task_results (id, session_id, agent_role, input, output, status, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)"
+				taskInsert := "INSERT OR REPLACE INTO task_results (id, session_id, agent_role, input, output, status, commit_sha, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
 				if merge {
-					taskInsert = "INSERT OR IGNORE INTO task_results (id, session_id, agent_role, input, output, status, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)"
+					taskInsert = "INSERT OR IGNORE INTO task_results (id, session_id, agent_role, input, output, status, commit_sha, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
 				}
 				for _, task := range s.TaskResults {
 					taskCreated := parseExportedTime(task.CreatedAt)
@@ -1323,20 +1764,30 @@ func NewImportCmd() *cobra.Command {
 					}
 					if _, err := tx.Exec(
 						taskInsert,
-						taskID, s.ID, task.AgentRole, task.Input, task.Output, task.Status, taskCreated,
+						taskID, s.ID, task.AgentRole, task.Input, task.Output, task.Status, task.CommitSHA, taskCreated,
 					); err != nil {
 						return fmt.Errorf("insert task result in session %s: %w", s.ID, err)
 					}
 					taskCount++
 				}
+
+				for _, call := range s.ToolCalls {
+					if _, err := tx.Exec(
+						"INSERT INTO tool_calls (session_id, run_id, task_id, role, tool, args, result, error, duration_ms, iteration, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+						s.ID, call.RunID, call.TaskID, call.Role, call.Tool, call.Args, call.Result, call.Error, call.DurationMS, call.Iteration, parseExportedTime(call.CreatedAt),
+					); err != nil {
+						return fmt.Errorf("insert tool call in session %s: %w", s.ID, err)
+					}
+					toolCallCount++
+				}
 			}
 
 			if err := tx.Commit(); err != nil {
 				return err
 			}
 
-			fmt.Printf("Imported %d session(s), %d message(s), %d memory block(s), %d task result(s) into %s\n",
-				sessionCount, messageCount, memoryCount, taskCount, dbPath)
+			fmt.Printf("Imported %d session(s), %d message(s), %d memory block(s), %d task result(s), %d tool call(s) into %s\n",
+				sessionCount, messageCount, memoryCount, taskCount, toolCallCount, dbPath)
 			if merge {
 				fmt.Println("Import mode: merge (session rows deduped, messages appended).")
 			} else {
@@ -1346,7 +1797,81 @@ func NewImportCmd() *cobra.Command {
 		},
 	}
 
-	importCmd.Flags().StringVar(&dbPath, "db", "orchestra.db", "Path to SQLite state database")
+	importCmd.Flags().StringVar(&dbPath, "db", defaultLocation().StateDB, "Path to SQLite state database")
 	importCmd.Flags().BoolVar(&merge, "merge", true, "Merge import into existing DB (false replaces existing state)")
 	return importCmd
 }
+
+func NewRewindCmd() *cobra.Command {
+	var dbPath string
+	var runID string
+	var taskID string
+	var workingDir string
+	var force bool
+
+	rewindCmd := &cobra.Command{
+		Use:   "rewind <session-id>",
+		Short: "Restore files changed by the latest run (or one task) of a session",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := state.Connect(dbPath)
+			if err != nil {
+				return fmt.Errorf("open db: %w", err)
+			}
+			defer db.Close()
+
+			ctx := context.Background()
+			session, err := db.GetSession(ctx, strings.TrimSpace(args[0]))
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(workingDir) == "" {
+				workingDir = session.WorkingDir
+			}
+
+			result, err := agent.RewindFiles(ctx, db, workingDir, session.ID, agent.RewindOptions{
+				RunID:  runID,
+				TaskID: taskID,
+				Force:  force,
+			})
+			if errors.Is(err, agent.ErrNoCheckpoints) {
+				fmt.Println("No checkpoints to rewind.")
+				return nil
+			}
+			if errors.Is(err, agent.ErrCheckpointConflict) {
+				for _, path := range result.Conflicts {
+					fmt.Printf("changed since checkpoint: %s\n", path)
+				}
+				return fmt.Errorf("%w; re-run with --force to overwrite", err)
+			}
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 2, 2, 2, ' ', 0)
+			fmt.Fprintln(w, "ACTION\tPATH")
+			for _, path := range result.Restored {
+				fmt.Fprintf(w, "restored\t%s\n", path)
+			}
+			for _, path := range result.Removed {
+				fmt.Fprintf(w, "removed\t%s\n", path)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			scope := "run " + result.RunID
+			if result.TaskID != "" {
+				scope += " task " + result.TaskID
+			}
+			fmt.Printf("Rewound %s.\n", scope)
+			return nil
+		},
+	}
+
+	rewindCmd.Flags().StringVar(&dbPath, "db", defaultLocation().StateDB, "Path to SQLite state database")
+	rewindCmd.Flags().StringVar(&runID, "run", "", "Run ID to rewind (defaults to the latest run)")
+	rewindCmd.Flags().StringVar(&taskID, "task", "", "Only rewind files written by this task")
+	rewindCmd.Flags().StringVar(&workingDir, "dir", "", "Working directory override (defaults to the session working dir)")
+	rewindCmd.Flags().BoolVar(&force, "force", false, "Overwrite files changed outside orchestra since the checkpoint")
+	return rewindCmd
+}
@@ -26,11 +26,39 @@ type Config struct {
 	} `toml:"providers"`
 	RAG struct {
 		Enabled      bool   `toml:"enabled"`
+		Backend      string `toml:"backend"`
 		Embedder     string `toml:"embedder"`
+		Dimensions   int    `toml:"dimensions"`
 		OllamaURL    string `toml:"ollama_url"`
+		BaseURL      string `toml:"base_url"`
+		KeyName      string `toml:"key_name"`
 		ChunkSize    int    `toml:"chunk_size"`
 		ChunkOverlap int    `toml:"chunk_overlap"`
+		BriefTokens  int    `toml:"brief_tokens"`
 	} `toml:"rag"`
+	Git struct {
+		Enabled   bool   `toml:"enabled"`
+		OnDirty   string `toml:"on_dirty"`
+		Worktrees bool   `toml:"worktrees"`
+	} `toml:"git"`
+	Review struct {
+		StageWrites bool `toml:"stage_writes"`
+	} `toml:"review"`
+	State struct {
+		Dir string `toml:"dir"`
+	} `toml:"state"`
+	TUI struct {
+		Layout string `toml:"layout"`
+		Theme  string `toml:"theme"`
+		// Colors overrides theme palette slots, e.g. accent = "#ff8800".
+		Colors map[string]string `toml:"colors"`
+		// Keys remaps actions to keys, e.g. history_prev = ["up", "ctrl+p"].
+		Keys map[string][]string `toml:"keys"`
+	} `toml:"tui"`
+	Verify struct {
+		Commands       []string `toml:"commands"`
+		TimeoutSeconds int      `toml:"timeout_seconds"`
+	} `toml:"verify"`
 }
 
 func GetConfigPath() string {
@@ -49,10 +77,16 @@ func Load() (*Config, error) {
 	cfg.Providers.OpenAI.DefaultModel = "gpt-4o"
 	cfg.Providers.OpenAI.BaseURL = "https://api.openai.com/v1"
 	cfg.RAG.Enabled = true
-	cfg.RAG.Embedder = "nomic-embed-text"
+	cfg.RAG.Backend = "ollama"
 	cfg.RAG.OllamaURL = "http://localhost:11434"
 	cfg.RAG.ChunkSize = 512
 	cfg.RAG.ChunkOverlap = 64
+	cfg.RAG.BriefTokens = 2000
+	cfg.Git.Enabled = false
+	cfg.Git.OnDirty = "refuse"
+	cfg.Verify.TimeoutSeconds = 300
+	cfg.TUI.Layout = "chat"
+	cfg.TUI.Theme = "dark"
 
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return &cfg, nil
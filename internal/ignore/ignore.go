@@ -0,0 +1,296 @@
+// Package ignore decides which workspace paths orchestra should skip, using
+// the gitignore pattern dialect. Rules come from built-in defaults, nested
+// .gitignore files and .orchestraignore files, with the usual precedence:
+// later and deeper rules win, and "!" re-includes a path.
+package ignore
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// SecretPatterns are always applied so credentials never reach a provider,
+// even in projects without a .gitignore. A .orchestraignore can re-include
+// a file with a "!" rule.
+var SecretPatterns = []string{
+	".env",
+	".env.*",
+	"!.env.example",
+	"!.env.sample",
+	"*.pem",
+	"*.key",
+	"*.p12",
+	"id_rsa",
+	"id_ed25519",
+	".netrc",
+}
+
+// IndexPatterns skip dependencies, build outputs, editor state, binaries
+// and orchestra's own data when indexing or summarizing a project.
+var IndexPatterns = []string{
+	".orchestra/",
+	"node_modules/",
+	"vendor/",
+	"dist/",
+	"build/",
+	".idea/",
+	".vscode/",
+	"*.exe",
+	"*.dll",
+	"*.so",
+	"*.dylib",
+	"*.sys",
+	"*.bin",
+	"orchestra.db*",
+	"orchestra_vec.db*",
+}
+
+var ignoreFiles = []string{".gitignore", ".orchestraignore"}
+
+type rule struct {
+	base    string
+	negate  bool
+	dirOnly bool
+	re      *regexp.Regexp
+}
+
+type Matcher struct {
+	root     string
+	defaults []rule
+
+	mu    sync.Mutex
+	rules map[string][]rule
+}
+
+// New returns a matcher for the workspace at root. Extra patterns apply at
+// the root ahead of any ignore file, so ignore files can override them.
+func New(root string, extra ...string) *Matcher {
+	m := &Matcher{root: root, rules: make(map[string][]rule)}
+	m.defaults = append(m.defaults, parseRules("", []string{".git/"})...)
+	m.defaults = append(m.defaults, parseRules("", SecretPatterns)...)
+	m.defaults = append(m.defaults, parseRules("", extra)...)
+	return m
+}
+
+// Reset drops cached ignore files, e.g. after one of them changed.
+func (m *Matcher) Reset() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.rules = make(map[string][]rule)
+	m.mu.Unlock()
+}
+
+// IsIgnoreFile reports whether name is a file the matcher reads rules from.
+func IsIgnoreFile(name string) bool {
+	base := filepath.Base(name)
+	for _, file := range ignoreFiles {
+		if base == file {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether relPath (relative to the root, either separator) is
+// ignored. A path inside an ignored directory is ignored too.
+func (m *Matcher) Match(relPath string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	relPath = strings.Trim(filepath.ToSlash(filepath.Clean(relPath)), "/")
+	if relPath == "" || relPath == "." || relPath == ".." || strings.HasPrefix(relPath, "../") {
+		return false
+	}
+	parts := strings.Split(relPath, "/")
+	for idx := 1; idx < len(parts); idx++ {
+		if m.matchOne(strings.Join(parts[:idx], "/"), true) {
+			return true
+		}
+	}
+	return m.matchOne(relPath, isDir)
+}
+
+// MatchAbs is Match for an absolute path or one relative to the process
+// working directory. Paths outside the root are never ignored.
+func (m *Matcher) MatchAbs(p string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	rootAbs, err := filepath.Abs(m.root)
+	if err != nil {
+		return false
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(rootAbs, abs)
+	if err != nil {
+		return false
+	}
+	return m.Match(rel, isDir)
+}
+
+func (m *Matcher) matchOne(relPath string, isDir bool) bool {
+	ignored := false
+	check := func(rules []rule) {
+		for _, r := range rules {
+			if r.matches(relPath, isDir) {
+				ignored = !r.negate
+			}
+		}
+	}
+	check(m.defaults)
+	dir := ""
+	check(m.dirRules(dir))
+	for _, part := range strings.Split(path.Dir(relPath), "/") {
+		if part == "." || part == "" {
+			break
+		}
+		if dir == "" {
+			dir = part
+		} else {
+			dir += "/" + part
+		}
+		check(m.dirRules(dir))
+	}
+	return ignored
+}
+
+func (m *Matcher) dirRules(dir string) []rule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if rules, ok := m.rules[dir]; ok {
+		return rules
+	}
+	var rules []rule
+	for _, name := range ignoreFiles {
+		rules = append(rules, parseRules(dir, readPatterns(filepath.Join(m.root, filepath.FromSlash(dir), name)))...)
+	}
+	m.rules[dir] = rules
+	return rules
+}
+
+func readPatterns(file string) []string {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+func parseRules(base string, lines []string) []rule {
+	var rules []rule
+	for _, line := range lines {
+		if r, ok := parseRule(base, line); ok {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+func parseRule(base, line string) (rule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule{}, false
+	}
+	r := rule{base: base}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule{}, false
+	}
+
+	// Patterns with an inner slash are anchored to the ignore file's
+	// directory; bare names match at any depth below it.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	expr := globToRegexp(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return rule{}, false
+	}
+	r.re = re
+	return r, true
+}
+
+func (r rule) matches(relPath string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		if !strings.HasPrefix(relPath, r.base+"/") {
+			return false
+		}
+		relPath = relPath[len(r.base)+1:]
+	}
+	return r.re.MatchString(relPath)
+}
+
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				switch {
+				case i+1 < len(glob) && glob[i+1] == '/':
+					i++
+					b.WriteString("(?:.*/)?")
+				default:
+					b.WriteString(".*")
+				}
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,100 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", rel, err)
+	}
+}
+
+func TestMatcherGitignoreDialect(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	writeFile(t, root, ".gitignore", "# build outputs\n/bin/\n*.log\n!keep.log\ndata/**/*.csv\ndocs/*.tmp\n")
+	writeFile(t, root, "web/.gitignore", "dist/\n/local.json\n")
+	writeFile(t, root, ".orchestraignore", "fixtures/\n!.env.test\n")
+
+	m := New(root, IndexPatterns...)
+	cases := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"bin", true, true},
+		{"bin/orchestra", false, true},
+		{"cmd/bin", true, false},
+		{"app.log", false, true},
+		{"logs/deep/app.log", false, true},
+		{"logs/keep.log", false, false},
+		{"data/a/b/c.csv", false, true},
+		{"data/c.csv", false, true},
+		{"data/c.json", false, false},
+		{"docs/x.tmp", false, true},
+		{"docs/sub/x.tmp", false, false},
+		{"web/dist/app.js", false, true},
+		{"web/local.json", false, true},
+		{"web/src/local.json", false, false},
+		{"dist/app.js", false, true},
+		{"fixtures/big.json", false, true},
+		{".env", false, true},
+		{"config/.env.production", false, true},
+		{".env.example", false, false},
+		{".env.test", false, false},
+		{"certs/server.pem", false, true},
+		{".git/HEAD", false, true},
+		{"node_modules/react/index.js", false, true},
+		{".orchestra/plans/p.md", false, true},
+		{"internal/agent/agent.go", false, false},
+	}
+	for _, tc := range cases {
+		if got := m.Match(tc.path, tc.isDir); got != tc.want {
+			t.Errorf("Match(%q, dir=%v) = %v, want %v", tc.path, tc.isDir, got, tc.want)
+		}
+	}
+}
+
+func TestMatcherIgnoreFileOverridesDefaultsAndReset(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	m := New(root, IndexPatterns...)
+	if !m.Match("vendor/lib/lib.go", false) {
+		t.Fatal("expected vendor to be ignored by default")
+	}
+
+	writeFile(t, root, ".orchestraignore", "!vendor/\n")
+	if !m.Match("vendor/lib/lib.go", false) {
+		t.Fatal("expected cached rules until Reset")
+	}
+	m.Reset()
+	if m.Match("vendor/lib/lib.go", false) {
+		t.Fatal("expected .orchestraignore to re-include vendor after Reset")
+	}
+}
+
+func TestMatcherWithoutExtrasOnlyHidesSecrets(t *testing.T) {
+	t.Parallel()
+	m := New(t.TempDir())
+	if m.Match("node_modules/x.js", false) || m.Match(".orchestra/plans/p.md", false) {
+		t.Fatal("expected index-only patterns to be opt-in")
+	}
+	if !m.Match(".env", false) || !m.Match("keys/id_rsa", false) {
+		t.Fatal("expected secret files to be ignored")
+	}
+	if m.Match("../outside/.env", false) {
+		t.Fatal("expected paths outside the root to never match")
+	}
+	var nilMatcher *Matcher
+	if nilMatcher.Match(".env", false) {
+		t.Fatal("expected nil matcher to ignore nothing")
+	}
+}
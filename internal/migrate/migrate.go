@@ -0,0 +1,241 @@
+// Package migrate applies ordered, versioned schema migrations to SQLite
+// databases. The applied versions are recorded in a schema_version table.
+package migrate
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrDowngrade   = errors.New("schema downgrades are not supported")
+	ErrNewerSchema = errors.New("database schema is newer than this build")
+)
+
+// Migration upgrades a schema from Version-1 to Version. Up runs in the same
+// transaction that records the version, so a failed migration leaves no
+// trace. Databases created before versioning have no schema_version table
+// and replay every migration, so migrations must tolerate objects that
+// already exist (CREATE ... IF NOT EXISTS, AddColumn).
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sql.Tx) error
+}
+
+// SQL returns an Up function that executes statements.
+func SQL(statements string) func(*sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(statements)
+		return err
+	}
+}
+
+// Applied is a migration recorded in schema_version.
+type Applied struct {
+	Version   int
+	Name      string
+	AppliedAt time.Time
+}
+
+type Status struct {
+	Current int
+	Latest  int
+	Applied []Applied
+	Pending []Migration
+}
+
+// Result describes a Run. Backup is empty when no backup was needed.
+type Result struct {
+	From    int
+	To      int
+	Applied []Migration
+	Backup  string
+}
+
+const versionSchema = `
+	CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME NOT NULL
+	);
+`
+
+func latest(migrations []Migration) int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+func validate(migrations []Migration) error {
+	for idx, m := range migrations {
+		if m.Version != idx+1 {
+			return fmt.Errorf("migration %q has version %d, want %d", m.Name, m.Version, idx+1)
+		}
+		if m.Up == nil {
+			return fmt.Errorf("migration %d (%s) has no Up", m.Version, m.Name)
+		}
+	}
+	return nil
+}
+
+// Current returns the highest applied version, or 0 for an unversioned
+// database.
+func Current(db *sql.DB) (int, error) {
+	var exists int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_version'").Scan(&exists); err != nil {
+		return 0, err
+	}
+	if exists == 0 {
+		return 0, nil
+	}
+	var version sql.NullInt64
+	if err := db.QueryRow("SELECT MAX(version) FROM schema_version").Scan(&version); err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+// GetStatus reports applied and pending migrations without changing db.
+func GetStatus(db *sql.DB, migrations []Migration) (Status, error) {
+	if err := validate(migrations); err != nil {
+		return Status{}, err
+	}
+	current, err := Current(db)
+	if err != nil {
+		return Status{}, err
+	}
+	status := Status{Current: current, Latest: latest(migrations)}
+	if current > 0 {
+		rows, err := db.Query("SELECT version, name, applied_at FROM schema_version ORDER BY version")
+		if err != nil {
+			return Status{}, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var applied Applied
+			if err := rows.Scan(&applied.Version, &applied.Name, &applied.AppliedAt); err != nil {
+				return Status{}, err
+			}
+			status.Applied = append(status.Applied, applied)
+		}
+		if err := rows.Err(); err != nil {
+			return Status{}, err
+		}
+	}
+	for _, m := range migrations {
+		if m.Version > current {
+			status.Pending = append(status.Pending, m)
+		}
+	}
+	return status, nil
+}
+
+// Run applies pending migrations up to target, or all of them when target
+// is 0. Before changing a database that already holds tables it is copied
+// to dbPath.v<current>.bak; pass an empty dbPath to skip the backup.
+func Run(db *sql.DB, dbPath string, migrations []Migration, target int) (Result, error) {
+	if err := validate(migrations); err != nil {
+		return Result{}, err
+	}
+	if target <= 0 {
+		target = latest(migrations)
+	}
+	if target > latest(migrations) {
+		return Result{}, fmt.Errorf("unknown schema version %d (latest is %d)", target, latest(migrations))
+	}
+	current, err := Current(db)
+	if err != nil {
+		return Result{}, err
+	}
+	result := Result{From: current, To: current}
+	if current > latest(migrations) {
+		return result, fmt.Errorf("%w: version %d, this build knows up to %d", ErrNewerSchema, current, latest(migrations))
+	}
+	if target < current {
+		return result, fmt.Errorf("%w: database is at version %d, asked for %d (restore a backup instead)", ErrDowngrade, current, target)
+	}
+	if target == current {
+		return result, nil
+	}
+
+	if result.Backup, err = backup(db, dbPath, current); err != nil {
+		return result, fmt.Errorf("back up before migrating: %w", err)
+	}
+	if _, err := db.Exec(versionSchema); err != nil {
+		return result, err
+	}
+	for _, m := range migrations[current:target] {
+		if err := apply(db, m); err != nil {
+			return result, fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		result.To = m.Version
+		result.Applied = append(result.Applied, m)
+	}
+	return result, nil
+}
+
+func apply(db *sql.DB, m Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// Another process may have applied it since Current was read.
+	var done int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM schema_version WHERE version = ?", m.Version).Scan(&done); err != nil {
+		return err
+	}
+	if done > 0 {
+		return nil
+	}
+	if err := m.Up(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)", m.Version, m.Name, time.Now().UTC()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// backup copies a database that already holds tables with VACUUM INTO and
+// returns the copy's path.
+func backup(db *sql.DB, dbPath string, version int) (string, error) {
+	dbPath = strings.TrimSpace(dbPath)
+	if dbPath == "" || dbPath == ":memory:" || strings.HasPrefix(dbPath, "file:") {
+		return "", nil
+	}
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'").Scan(&tables); err != nil {
+		return "", err
+	}
+	if tables == 0 {
+		return "", nil
+	}
+	path := fmt.Sprintf("%s.v%d.bak", dbPath, version)
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	if _, err := db.Exec("VACUUM INTO ?", path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// AddColumn adds a column unless the table already has it.
+func AddColumn(tx *sql.Tx, table, column, definition string) error {
+	var exists int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&exists); err != nil {
+		return err
+	}
+	if exists > 0 {
+		return nil
+	}
+	_, err := tx.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition)
+	return err
+}
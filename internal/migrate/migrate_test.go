@@ -0,0 +1,157 @@
+package migrate
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "items", Up: SQL(`CREATE TABLE IF NOT EXISTS items (id INTEGER PRIMARY KEY, name TEXT)`)},
+	{Version: 2, Name: "item size", Up: func(tx *sql.Tx) error {
+		return AddColumn(tx, "items", "size", "INTEGER")
+	}},
+	{Version: 3, Name: "tags", Up: SQL(`CREATE TABLE IF NOT EXISTS tags (item_id INTEGER, tag TEXT)`)},
+}
+
+func openTestDB(t *testing.T) (*sql.DB, string) {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, dbPath
+}
+
+func TestRunAppliesMigrationsInOrder(t *testing.T) {
+	t.Parallel()
+
+	db, dbPath := openTestDB(t)
+	result, err := Run(db, dbPath, testMigrations, 2)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if result.From != 0 || result.To != 2 || len(result.Applied) != 2 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if result.Backup != "" {
+		t.Fatalf("an empty database should not be backed up, got %q", result.Backup)
+	}
+
+	status, err := GetStatus(db, testMigrations)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if status.Current != 2 || status.Latest != 3 || len(status.Applied) != 2 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	if len(status.Pending) != 1 || status.Pending[0].Name != "tags" {
+		t.Fatalf("expected tags to be pending, got %+v", status.Pending)
+	}
+
+	result, err = Run(db, dbPath, testMigrations, 0)
+	if err != nil {
+		t.Fatalf("run to latest: %v", err)
+	}
+	if result.From != 2 || result.To != 3 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if result.Backup != dbPath+".v2.bak" {
+		t.Fatalf("expected backup at %s.v2.bak, got %q", dbPath, result.Backup)
+	}
+	backup, err := sql.Open("sqlite3", result.Backup)
+	if err != nil {
+		t.Fatalf("open backup: %v", err)
+	}
+	defer backup.Close()
+	if version, err := Current(backup); err != nil || version != 2 {
+		t.Fatalf("backup should hold version 2, got %d (%v)", version, err)
+	}
+
+	result, err = Run(db, dbPath, testMigrations, 0)
+	if err != nil || len(result.Applied) != 0 {
+		t.Fatalf("expected an up-to-date database to be left alone, got %+v (%v)", result, err)
+	}
+}
+
+func TestRunRollsBackAFailedMigration(t *testing.T) {
+	t.Parallel()
+
+	db, dbPath := openTestDB(t)
+	broken := append([]Migration{}, testMigrations[:2]...)
+	broken = append(broken, Migration{Version: 3, Name: "broken", Up: SQL(`
+		CREATE TABLE tags (item_id INTEGER, tag TEXT);
+		INSERT INTO missing VALUES (1);`)})
+
+	result, err := Run(db, dbPath, broken, 0)
+	if err == nil {
+		t.Fatal("expected the broken migration to fail")
+	}
+	if result.To != 2 {
+		t.Fatalf("expected to stop at version 2, got %d", result.To)
+	}
+	if version, err := Current(db); err != nil || version != 2 {
+		t.Fatalf("expected version 2 after the failure, got %d (%v)", version, err)
+	}
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'tags'").Scan(&tables); err != nil || tables != 0 {
+		t.Fatalf("expected the failed migration to be rolled back, found %d tags tables (%v)", tables, err)
+	}
+}
+
+func TestRunUpgradesUnversionedDatabase(t *testing.T) {
+	t.Parallel()
+
+	db, dbPath := openTestDB(t)
+	if _, err := db.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT, size INTEGER); INSERT INTO items (name, size) VALUES ('kept', 3)`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	result, err := Run(db, dbPath, testMigrations, 0)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if result.From != 0 || result.To != 3 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if _, err := os.Stat(dbPath + ".v0.bak"); err != nil {
+		t.Fatalf("expected a backup of the unversioned database: %v", err)
+	}
+	var name string
+	if err := db.QueryRow("SELECT name FROM items WHERE size = 3").Scan(&name); err != nil || name != "kept" {
+		t.Fatalf("existing row lost: %q (%v)", name, err)
+	}
+}
+
+func TestRunRefusesDowngradesAndNewerSchemas(t *testing.T) {
+	t.Parallel()
+
+	db, dbPath := openTestDB(t)
+	if _, err := Run(db, dbPath, testMigrations, 0); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if _, err := Run(db, dbPath, testMigrations, 1); !errors.Is(err, ErrDowngrade) {
+		t.Fatalf("expected ErrDowngrade, got %v", err)
+	}
+	if _, err := Run(db, dbPath, testMigrations[:2], 0); !errors.Is(err, ErrNewerSchema) {
+		t.Fatalf("expected ErrNewerSchema, got %v", err)
+	}
+	if _, err := Run(db, dbPath, testMigrations, 7); err == nil {
+		t.Fatal("expected an unknown target version to fail")
+	}
+}
+
+func TestValidateRequiresSequentialVersions(t *testing.T) {
+	t.Parallel()
+
+	db, dbPath := openTestDB(t)
+	gap := []Migration{testMigrations[0], testMigrations[2]}
+	if _, err := Run(db, dbPath, gap, 0); err == nil {
+		t.Fatal("expected a gap in versions to be rejected")
+	}
+}
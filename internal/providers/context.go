@@ -0,0 +1,42 @@
+package providers
+
+import "strings"
+
+// DefaultContextWindow is assumed for models missing from contextWindows.
+const DefaultContextWindow = 32000
+
+// contextWindows maps model ID prefixes to context sizes in tokens. The
+// longest matching prefix wins; provider prefixes such as "anthropic/" are
+// stripped first.
+var contextWindows = map[string]int{
+	"claude":        200000,
+	"gpt-4o":        128000,
+	"gpt-4-turbo":   128000,
+	"gpt-4.1":       1000000,
+	"gpt-5":         400000,
+	"o1":            200000,
+	"o3":            200000,
+	"o4":            200000,
+	"gemini-1.5":    1000000,
+	"gemini-2":      1000000,
+	"gemini":        1000000,
+	"llama-3":       128000,
+	"mistral-large": 128000,
+	"deepseek":      64000,
+	"qwen":          32000,
+}
+
+// ContextWindow returns the approximate context size of model in tokens.
+func ContextWindow(model string) int {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if idx := strings.LastIndex(model, "/"); idx >= 0 {
+		model = model[idx+1:]
+	}
+	best, size := 0, DefaultContextWindow
+	for prefix, window := range contextWindows {
+		if strings.HasPrefix(model, prefix) && len(prefix) > best {
+			best, size = len(prefix), window
+		}
+	}
+	return size
+}
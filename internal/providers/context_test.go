@@ -0,0 +1,20 @@
+package providers
+
+import "testing"
+
+func TestContextWindowMatchesLongestPrefix(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]int{
+		"claude-sonnet-4-20250514":      200000,
+		"anthropic/claude-3.5-haiku":    200000,
+		"gpt-4.1-mini":                  1000000,
+		"gpt-4o":                        128000,
+		"openrouter/unknown-model:free": DefaultContextWindow,
+	}
+	for model, want := range cases {
+		if got := ContextWindow(model); got != want {
+			t.Fatalf("ContextWindow(%q) = %d, want %d", model, got, want)
+		}
+	}
+}
@@ -1,61 +1,121 @@
 package rag
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io/fs"
+	"math"
+	"os"
+	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
+
+	"github.com/yubzen/orchestra/internal/ignore"
 )
 
 const (
-	maxBriefFiles    = 250
-	maxBriefPreview  = 80
-	maxBriefKeyFiles = 20
+	// DefaultBriefTokens is the brief size when BriefOptions leaves it unset.
+	DefaultBriefTokens = 2000
+	// maxBriefSources bounds how many source files feed the repo graph.
+	maxBriefSources     = 5000
+	maxBriefManifests   = 12
+	maxBriefPackages    = 15
+	maxBriefSymbols     = 8
+	maxBriefChurnCommit = 200
+	briefCacheVersion   = 1
 )
 
-func BuildProjectBrief(workDir string) (string, error) {
+// BriefOptions tunes BuildProjectBrief.
+type BriefOptions struct {
+	// TokenBudget caps the approximate size of the brief.
+	TokenBudget int
+}
+
+func (o BriefOptions) budget() int {
+	if o.TokenBudget <= 0 {
+		return DefaultBriefTokens
+	}
+	return o.TokenBudget
+}
+
+// manifestNames are build and project files that describe a project. They
+// are listed first and always rank high.
+var manifestNames = map[string]bool{
+	"go.mod":             true,
+	"go.work":            true,
+	"package.json":       true,
+	"cargo.toml":         true,
+	"pyproject.toml":     true,
+	"requirements.txt":   true,
+	"setup.py":           true,
+	"gemfile":            true,
+	"pom.xml":            true,
+	"build.gradle":       true,
+	"build.gradle.kts":   true,
+	"makefile":           true,
+	"dockerfile":         true,
+	"docker-compose.yml": true,
+	".goreleaser.yaml":   true,
+	"readme.md":          true,
+}
+
+// briefFile is one file with the inputs of its rank.
+type briefFile struct {
+	rel      string
+	refs     int
+	churn    int
+	manifest bool
+	score    float64
+}
+
+// BuildProjectBrief summarizes the project for agent prompts: detected
+// languages, manifests, the most depended-on packages with their exported
+// symbols, and files ranked by import-graph centrality, recent git churn and
+// whether they are manifests or entry points. Sections are cut to fit the
+// token budget, most important lines first.
+func BuildProjectBrief(workDir string, opts BriefOptions) (string, error) {
 	workDir = strings.TrimSpace(workDir)
 	if workDir == "" {
 		workDir = "."
 	}
 
 	var files []string
+	var sources []string
 	langCounts := make(map[string]int)
-	var keyFiles []string
 
+	matcher := ignore.New(workDir, ignore.IndexPatterns...)
 	err := filepath.WalkDir(workDir, func(path string, d fs.DirEntry, walkErr error) error {
 		if walkErr != nil {
 			return nil
 		}
-		name := d.Name()
-		if d.IsDir() {
-			switch name {
-			case ".git", "node_modules", "vendor", "dist", "build", ".idea", ".vscode":
-				return filepath.SkipDir
-			}
-			return nil
-		}
-
 		rel, err := filepath.Rel(workDir, path)
 		if err != nil {
 			rel = path
 		}
 		rel = filepath.ToSlash(strings.TrimSpace(rel))
-		if rel == "" || strings.HasPrefix(rel, "..") {
+		if matcher.Match(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
 			return nil
 		}
-
-		if len(files) < maxBriefFiles {
-			files = append(files, rel)
+		if d.IsDir() {
+			return nil
+		}
+		if rel == "" || strings.HasPrefix(rel, "..") {
+			return nil
 		}
 
-		lang := languageForFile(rel)
-		if lang != "" {
+		files = append(files, rel)
+		if lang := languageForFile(rel); lang != "" {
 			langCounts[lang]++
 		}
-		if isKeyProjectFile(rel) && len(keyFiles) < maxBriefKeyFiles {
-			keyFiles = append(keyFiles, rel)
+		if symbolLanguage(rel) != "" && len(sources) < maxBriefSources {
+			sources = append(sources, rel)
 		}
 		return nil
 	})
@@ -63,8 +123,9 @@ func BuildProjectBrief(workDir string) (string, error) {
 		return "", err
 	}
 
-	sort.Strings(files)
-	sort.Strings(keyFiles)
+	graph := buildRepoGraph(workDir, sources)
+	churn := gitChurn(workDir)
+	ranked := rankBriefFiles(files, graph, churn)
 
 	var languageSummary []string
 	for lang, count := range langCounts {
@@ -72,34 +133,288 @@ func BuildProjectBrief(workDir string) (string, error) {
 	}
 	sort.Strings(languageSummary)
 
-	var b strings.Builder
-	b.WriteString(fmt.Sprintf("Working directory: %s\n", filepath.Clean(workDir)))
+	brief := newBudgetWriter(opts.budget())
+	brief.line(fmt.Sprintf("Working directory: %s", filepath.Clean(workDir)))
 	if len(languageSummary) > 0 {
-		b.WriteString("Detected languages: " + strings.Join(languageSummary, ", ") + "\n")
+		brief.line("Detected languages: " + strings.Join(languageSummary, ", "))
 	} else {
-		b.WriteString("Detected languages: unknown\n")
+		brief.line("Detected languages: unknown")
+	}
+
+	var manifests []string
+	for _, file := range ranked {
+		if file.manifest && len(manifests) < maxBriefManifests {
+			manifests = append(manifests, "- "+describeManifest(workDir, file.rel))
+		}
+	}
+	brief.section("Manifests:", manifests, brief.remaining())
+
+	packages := rankBriefPackages(graph, churn)
+	var packageLines []string
+	for _, pkg := range packages {
+		packageLines = append(packageLines, "- "+describePackage(pkg))
+	}
+	// Packages may take half of what is left, so files always get a share.
+	brief.section("Packages (most depended on first):", packageLines, brief.remaining()/2)
+
+	var fileLines []string
+	for _, file := range ranked {
+		fileLines = append(fileLines, "- "+describeBriefFile(file))
+	}
+	brief.section("Files (most important first):", fileLines, brief.remaining())
+
+	return strings.TrimSpace(brief.String()), nil
+}
+
+// rankBriefFiles orders files by score, then by path.
+func rankBriefFiles(files []string, graph *repoGraph, churn map[string]int) []briefFile {
+	ranked := make([]briefFile, 0, len(files))
+	for _, rel := range files {
+		file := briefFile{
+			rel:      rel,
+			refs:     graph.fileRefs[rel],
+			churn:    churn[rel],
+			manifest: manifestNames[strings.ToLower(filepath.Base(rel))],
+		}
+		file.score = 3*math.Log1p(float64(file.refs)) + 2*math.Log1p(float64(file.churn))
+		if pkg := graph.packages[graph.fileDir[rel]]; pkg != nil {
+			file.score += 0.5 * float64(len(pkg.importers))
+			if pkg.name == "main" {
+				file.score += 1
+			}
+		}
+		if file.manifest {
+			// Nested manifests (vendored or example projects) matter less
+			// than the project's own.
+			file.score += 6 / float64(1+strings.Count(rel, "/"))
+		}
+		if strings.HasSuffix(rel, "_test.go") || strings.Contains(rel, "/testdata/") || strings.HasPrefix(rel, "testdata/") {
+			file.score *= 0.3
+		}
+		ranked = append(ranked, file)
+	}
+	sort.SliceStable(ranked, func(a, b int) bool {
+		if ranked[a].score != ranked[b].score {
+			return ranked[a].score > ranked[b].score
+		}
+		return ranked[a].rel < ranked[b].rel
+	})
+	return ranked
+}
+
+func rankBriefPackages(graph *repoGraph, churn map[string]int) []*repoPackage {
+	for rel, dir := range graph.fileDir {
+		graph.packages[dir].churn += churn[rel]
+	}
+	packages := make([]*repoPackage, 0, len(graph.packages))
+	for _, pkg := range graph.packages {
+		packages = append(packages, pkg)
+	}
+	score := func(pkg *repoPackage) float64 {
+		return 10*float64(len(pkg.importers)) + float64(pkg.refs) + 2*math.Log1p(float64(pkg.churn))
+	}
+	sort.Slice(packages, func(a, b int) bool {
+		if score(packages[a]) != score(packages[b]) {
+			return score(packages[a]) > score(packages[b])
+		}
+		return packages[a].dir < packages[b].dir
+	})
+	if len(packages) > maxBriefPackages {
+		packages = packages[:maxBriefPackages]
+	}
+	return packages
+}
+
+func describePackage(pkg *repoPackage) string {
+	line := fmt.Sprintf("%s [%s]", pkg.dir, pkg.name)
+	var notes []string
+	if len(pkg.importers) > 0 {
+		notes = append(notes, fmt.Sprintf("imported by %d", len(pkg.importers)))
+	}
+	if pkg.churn > 0 {
+		notes = append(notes, fmt.Sprintf("%d recent changes", pkg.churn))
+	}
+	if len(notes) > 0 {
+		line += " " + strings.Join(notes, ", ")
+	}
+	if names := topSymbols(pkg.symbols, maxBriefSymbols); len(names) > 0 {
+		line += ": " + strings.Join(names, ", ")
 	}
+	return line
+}
+
+func describeBriefFile(file briefFile) string {
+	var notes []string
+	if file.refs > 0 {
+		notes = append(notes, fmt.Sprintf("%d refs", file.refs))
+	}
+	if file.churn > 0 {
+		notes = append(notes, fmt.Sprintf("%d commits", file.churn))
+	}
+	if len(notes) == 0 {
+		return file.rel
+	}
+	return fmt.Sprintf("%s (%s)", file.rel, strings.Join(notes, ", "))
+}
 
-	if len(keyFiles) > 0 {
-		b.WriteString("Key files:\n")
-		for _, file := range keyFiles {
-			b.WriteString("- " + file + "\n")
+// describeManifest adds the module or package name for manifests that
+// declare one.
+func describeManifest(workDir, rel string) string {
+	path := filepath.Join(workDir, filepath.FromSlash(rel))
+	switch strings.ToLower(filepath.Base(rel)) {
+	case "go.mod":
+		module, goVersion := GoModulePath(filepath.Dir(path)), ""
+		if f, err := os.Open(path); err == nil {
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				if rest, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "go "); ok {
+					goVersion = strings.TrimSpace(rest)
+					break
+				}
+			}
+			f.Close()
+		}
+		if module != "" && goVersion != "" {
+			return fmt.Sprintf("%s: module %s (go %s)", rel, module, goVersion)
+		}
+		if module != "" {
+			return fmt.Sprintf("%s: module %s", rel, module)
+		}
+	case "package.json":
+		var pkg struct {
+			Name string `json:"name"`
+		}
+		if content, err := os.ReadFile(path); err == nil && json.Unmarshal(content, &pkg) == nil && pkg.Name != "" {
+			return fmt.Sprintf("%s: package %s", rel, pkg.Name)
 		}
 	}
+	return rel
+}
 
-	b.WriteString("File tree preview:\n")
-	preview := files
-	if len(preview) > maxBriefPreview {
-		preview = preview[:maxBriefPreview]
+// budgetWriter builds the brief line by line until the token budget is
+// spent.
+type budgetWriter struct {
+	b      strings.Builder
+	budget int
+	used   int
+}
+
+func newBudgetWriter(budget int) *budgetWriter {
+	return &budgetWriter{budget: budget}
+}
+
+func (w *budgetWriter) remaining() int {
+	return w.budget - w.used
+}
+
+func (w *budgetWriter) line(text string) {
+	w.b.WriteString(text + "\n")
+	w.used += EstimateTokens(text)
+}
+
+// section writes a heading and as many lines as fit in limit tokens, noting
+// how many were left out.
+func (w *budgetWriter) section(heading string, lines []string, limit int) {
+	if len(lines) == 0 || limit <= EstimateTokens(heading) {
+		return
+	}
+	w.line(heading)
+	spent := EstimateTokens(heading)
+	for idx, text := range lines {
+		cost := EstimateTokens(text)
+		if spent+cost > limit {
+			w.line(fmt.Sprintf("- ... (%d more)", len(lines)-idx))
+			return
+		}
+		w.line(text)
+		spent += cost
 	}
-	for _, file := range preview {
-		b.WriteString("- " + file + "\n")
+}
+
+func (w *budgetWriter) String() string {
+	return w.b.String()
+}
+
+// gitChurn counts, per file relative to workDir, the recent commits that
+// touched it. It returns an empty map outside a git repository.
+func gitChurn(workDir string) map[string]int {
+	churn := make(map[string]int)
+	out, err := gitOutput(workDir, "log", fmt.Sprintf("-n%d", maxBriefChurnCommit), "--name-only", "--relative", "--format=")
+	if err != nil {
+		return churn
 	}
-	if len(files) > len(preview) {
-		b.WriteString(fmt.Sprintf("- ... (%d more files)\n", len(files)-len(preview)))
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			churn[line]++
+		}
 	}
+	return churn
+}
 
-	return strings.TrimSpace(b.String()), nil
+// GitHead returns the commit checked out in workDir, or "" when workDir is
+// not in a git repository or has no commits.
+func GitHead(workDir string) string {
+	out, err := gitOutput(workDir, "rev-parse", "HEAD")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+func gitOutput(workDir string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", workDir}, args...)...)
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+type briefCache struct {
+	Version     int    `json:"version"`
+	Head        string `json:"head"`
+	TokenBudget int    `json:"token_budget"`
+	Brief       string `json:"brief"`
+}
+
+func briefCachePath(workDir string) string {
+	return filepath.Join(workDir, ".orchestra", "cache", "brief.json")
+}
+
+// CachedProjectBrief returns the brief for the current git HEAD, building it
+// only when HEAD or the options changed since it was cached under
+// .orchestra/cache. It also returns that HEAD, which is empty outside a git
+// repository, where the brief is always rebuilt.
+func CachedProjectBrief(workDir string, opts BriefOptions) (string, string, error) {
+	workDir = strings.TrimSpace(workDir)
+	if workDir == "" {
+		workDir = "."
+	}
+	head := GitHead(workDir)
+	if head == "" {
+		brief, err := BuildProjectBrief(workDir, opts)
+		return brief, "", err
+	}
+
+	path := briefCachePath(workDir)
+	if content, err := os.ReadFile(path); err == nil {
+		var cached briefCache
+		if json.Unmarshal(content, &cached) == nil && cached.Version == briefCacheVersion &&
+			cached.Head == head && cached.TokenBudget == opts.budget() && cached.Brief != "" {
+			return cached.Brief, head, nil
+		}
+	}
+
+	brief, err := BuildProjectBrief(workDir, opts)
+	if err != nil {
+		return "", "", err
+	}
+	// The cache only saves time; failing to write it is not an error.
+	if content, err := json.Marshal(briefCache{Version: briefCacheVersion, Head: head, TokenBudget: opts.budget(), Brief: brief}); err == nil {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err == nil {
+			_ = os.WriteFile(path, content, 0o644)
+		}
+	}
+	return brief, head, nil
 }
 
 func languageForFile(relPath string) string {
@@ -131,17 +446,3 @@ func languageForFile(relPath string) string {
 	}
 	return ""
 }
-
-func isKeyProjectFile(relPath string) bool {
-	lower := strings.ToLower(relPath)
-	if strings.HasSuffix(lower, "/go.mod") || strings.HasSuffix(lower, "/go.sum") {
-		return true
-	}
-	if strings.HasSuffix(lower, "/readme.md") || strings.HasSuffix(lower, "/makefile") {
-		return true
-	}
-	if strings.HasPrefix(lower, "cmd/") || strings.HasPrefix(lower, "internal/") {
-		return true
-	}
-	return false
-}
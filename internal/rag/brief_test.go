@@ -2,6 +2,7 @@ package rag
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -27,7 +28,7 @@ func TestBuildProjectBrief(t *testing.T) {
 	mustWrite("internal/app/service.go", "package app\n")
 	mustWrite("internal/app/service_test.go", "package app\n")
 
-	brief, err := BuildProjectBrief(root)
+	brief, err := BuildProjectBrief(root, BriefOptions{})
 	if err != nil {
 		t.Fatalf("build brief: %v", err)
 	}
@@ -45,3 +46,142 @@ func TestBuildProjectBrief(t *testing.T) {
 		t.Fatalf("expected file tree preview in brief: %q", brief)
 	}
 }
+
+func TestBuildProjectBriefRespectsIgnoreFiles(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	for rel, content := range map[string]string{
+		".gitignore":          "out/\n*.csv\n",
+		"web/.gitignore":      "generated.ts\n",
+		".orchestraignore":    "fixtures/\n",
+		".env":                "API_KEY=secret\n",
+		"main.go":             "package main\n",
+		"out/app":             "binary\n",
+		"data/rows.csv":       "a,b\n",
+		"web/generated.ts":    "export {}\n",
+		"web/app.ts":          "export {}\n",
+		"fixtures/large.json": "{}\n",
+	} {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	brief, err := BuildProjectBrief(root, BriefOptions{})
+	if err != nil {
+		t.Fatalf("build brief: %v", err)
+	}
+	for _, hidden := range []string{"- .env", "out/app", "rows.csv", "generated.ts", "fixtures/"} {
+		if strings.Contains(brief, hidden) {
+			t.Fatalf("expected %q to be ignored, got %q", hidden, brief)
+		}
+	}
+	for _, shown := range []string{"main.go", "web/app.ts"} {
+		if !strings.Contains(brief, shown) {
+			t.Fatalf("expected %q in brief, got %q", shown, brief)
+		}
+	}
+}
+
+func TestBuildProjectBriefRanksPackagesAndFiles(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeIndexerFile(t, root, "go.mod", "module example.com/app\n\ngo 1.24\n")
+	writeIndexerFile(t, root, "aaa/notes.txt", "unrelated\n")
+	writeIndexerFile(t, root, "store/store.go", symbolFixtureStore)
+	writeIndexerFile(t, root, "main.go", symbolFixtureMain)
+
+	brief, err := BuildProjectBrief(root, BriefOptions{})
+	if err != nil {
+		t.Fatalf("build brief: %v", err)
+	}
+	for _, want := range []string{
+		"- go.mod: module example.com/app (go 1.24)",
+		"- store [store] imported by 1: New, Store,",
+	} {
+		if !strings.Contains(brief, want) {
+			t.Fatalf("expected %q in brief: %q", want, brief)
+		}
+	}
+	order := []string{"- . [main]", "Files (most important first):", "- go.mod", "- store/store.go (1 refs)", "- main.go", "- aaa/notes.txt"}
+	rest := brief
+	for _, want := range order {
+		idx := strings.Index(rest, want)
+		if idx < 0 {
+			t.Fatalf("expected %q after the previous entries in brief: %q", want, brief)
+		}
+		rest = rest[idx+len(want):]
+	}
+}
+
+func TestBuildProjectBriefStaysWithinTokenBudget(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	for i := 0; i < 200; i++ {
+		writeIndexerFile(t, root, filepath.Join("docs", strings.Repeat("x", 20)+string(rune('a'+i%26))+strings.Repeat("y", i/26)+".md"), "# doc\n")
+	}
+
+	brief, err := BuildProjectBrief(root, BriefOptions{TokenBudget: 300})
+	if err != nil {
+		t.Fatalf("build brief: %v", err)
+	}
+	if tokens := EstimateTokens(brief); tokens > 330 {
+		t.Fatalf("brief uses ~%d tokens, want about 300: %q", tokens, brief)
+	}
+	if !strings.Contains(brief, "more)") {
+		t.Fatalf("expected a truncation note in brief: %q", brief)
+	}
+}
+
+func TestCachedProjectBriefIsKeyedByGitHead(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	root := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", root, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	writeIndexerFile(t, root, "main.go", "package main\n")
+	git("add", "-A")
+	git("commit", "-qm", "init")
+
+	brief, head, err := CachedProjectBrief(root, BriefOptions{})
+	if err != nil {
+		t.Fatalf("cached brief: %v", err)
+	}
+	if head == "" || !strings.Contains(brief, "main.go (1 commits)") {
+		t.Fatalf("unexpected brief for head %q: %q", head, brief)
+	}
+
+	// Until HEAD moves the cached brief is served, even if the tree changed.
+	writeIndexerFile(t, root, "extra.go", "package main\n")
+	cached, _, err := CachedProjectBrief(root, BriefOptions{})
+	if err != nil {
+		t.Fatalf("cached brief: %v", err)
+	}
+	if cached != brief {
+		t.Fatalf("expected the cached brief, got %q", cached)
+	}
+
+	git("add", "-A")
+	git("commit", "-qm", "extra")
+	rebuilt, newHead, err := CachedProjectBrief(root, BriefOptions{})
+	if err != nil {
+		t.Fatalf("cached brief: %v", err)
+	}
+	if newHead == head || !strings.Contains(rebuilt, "extra.go") {
+		t.Fatalf("expected a rebuilt brief for the new head, got %q", rebuilt)
+	}
+}
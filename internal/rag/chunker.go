@@ -0,0 +1,277 @@
+package rag
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultChunkSize    = 512
+	defaultChunkOverlap = 64
+)
+
+// ChunkOptions bounds chunk size in words. Overlap only applies when a unit
+// (a declaration, a section, or a plain file) has to be split.
+type ChunkOptions struct {
+	Size    int
+	Overlap int
+}
+
+func (o ChunkOptions) normalize() ChunkOptions {
+	if o.Size <= 0 {
+		o.Size = defaultChunkSize
+		if o.Overlap <= 0 {
+			o.Overlap = defaultChunkOverlap
+		}
+	}
+	if o.Overlap < 0 || o.Overlap >= o.Size {
+		o.Overlap = 0
+	}
+	return o
+}
+
+// Chunker splits one file into chunks carrying content, line range and
+// enclosing symbol. Filepath, ID and ChunkIndex are filled by the caller.
+type Chunker func(path, content string, opts ChunkOptions) ([]Chunk, error)
+
+var (
+	chunkersMu sync.RWMutex
+	chunkers   = map[string]Chunker{
+		"go":       chunkGo,
+		"markdown": chunkMarkdown,
+	}
+	chunkerExtensions = map[string]string{
+		".go":       "go",
+		".md":       "markdown",
+		".markdown": "markdown",
+	}
+)
+
+// RegisterChunker adds or replaces the chunker for a language and maps the
+// given file extensions to it.
+func RegisterChunker(language string, chunker Chunker, extensions ...string) {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language == "" || chunker == nil {
+		return
+	}
+	chunkersMu.Lock()
+	defer chunkersMu.Unlock()
+	chunkers[language] = chunker
+	for _, ext := range extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		chunkerExtensions[ext] = language
+	}
+}
+
+// ChunkFile picks the chunker registered for the file's language and falls
+// back to line-based chunking when there is none or it cannot parse the file.
+func ChunkFile(path, content string, opts ChunkOptions) []Chunk {
+	opts = opts.normalize()
+	chunkersMu.RLock()
+	chunker := chunkers[chunkerExtensions[strings.ToLower(filepath.Ext(path))]]
+	chunkersMu.RUnlock()
+
+	var chunks []Chunk
+	if chunker != nil {
+		if parsed, err := chunker(path, content, opts); err == nil {
+			chunks = parsed
+		}
+	}
+	if len(chunks) == 0 {
+		chunks = chunkLines(splitLines(content), 1, "", opts)
+	}
+	for idx := range chunks {
+		chunks[idx].ID = fmt.Sprintf("%s:%d", path, idx)
+		chunks[idx].Filepath = path
+		chunks[idx].ChunkIndex = idx
+	}
+	return chunks
+}
+
+func splitLines(content string) []string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	return strings.Split(strings.TrimRight(content, "\n"), "\n")
+}
+
+// chunkLines groups whole lines into chunks of roughly opts.Size words.
+// firstLine is the 1-based line number of lines[0].
+func chunkLines(lines []string, firstLine int, symbol string, opts ChunkOptions) []Chunk {
+	opts = opts.normalize()
+	words := make([]int, len(lines))
+	for idx, line := range lines {
+		words[idx] = len(strings.Fields(line))
+	}
+
+	var chunks []Chunk
+	start := 0
+	for start < len(lines) {
+		for start < len(lines) && words[start] == 0 {
+			start++
+		}
+		if start >= len(lines) {
+			break
+		}
+		end := start
+		count := 0
+		for end < len(lines) {
+			count += words[end]
+			end++
+			if count >= opts.Size {
+				break
+			}
+		}
+		chunks = append(chunks, newLineChunk(lines[start:end], firstLine+start, symbol))
+		if end >= len(lines) {
+			break
+		}
+
+		next := end
+		for overlap := 0; next > start+1 && overlap < opts.Overlap; {
+			next--
+			overlap += words[next]
+		}
+		start = next
+	}
+	return chunks
+}
+
+func newLineChunk(lines []string, startLine int, symbol string) Chunk {
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return Chunk{
+		Content:   strings.Join(lines, "\n"),
+		StartLine: startLine,
+		EndLine:   startLine + len(lines) - 1,
+		Symbol:    symbol,
+	}
+}
+
+// chunkGo emits one chunk per top-level declaration. Each chunk also takes
+// the comments and blank lines since the previous declaration, so doc
+// comments stay with their code and the package clause joins the first one.
+func chunkGo(path, content string, opts ChunkOptions) ([]Chunk, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	lines := splitLines(content)
+	if len(file.Decls) == 0 {
+		return chunkLines(lines, 1, "", opts), nil
+	}
+
+	var chunks []Chunk
+	startLine := 1
+	for idx, decl := range file.Decls {
+		endLine := fset.Position(decl.End()).Line
+		if idx == len(file.Decls)-1 {
+			endLine = len(lines)
+		}
+		chunks = append(chunks, chunkUnit(lines, startLine, endLine, goDeclSymbol(decl), opts)...)
+		startLine = endLine + 1
+	}
+	return chunks, nil
+}
+
+func goDeclSymbol(decl ast.Decl) string {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv == nil || len(d.Recv.List) == 0 {
+			return d.Name.Name
+		}
+		return goReceiverName(d.Recv.List[0].Type) + "." + d.Name.Name
+	case *ast.GenDecl:
+		if d.Tok == token.IMPORT {
+			return "imports"
+		}
+		var names []string
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				names = append(names, s.Name.Name)
+			case *ast.ValueSpec:
+				for _, name := range s.Names {
+					names = append(names, name.Name)
+				}
+			}
+		}
+		if len(names) > 3 {
+			names = append(names[:3], "...")
+		}
+		return strings.Join(names, ", ")
+	}
+	return ""
+}
+
+func goReceiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return goReceiverName(t.X)
+	case *ast.IndexExpr:
+		return goReceiverName(t.X)
+	case *ast.IndexListExpr:
+		return goReceiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+var markdownHeading = regexp.MustCompile(`^\s{0,3}#{1,6}\s+(.*?)\s*#*\s*$`)
+
+// chunkMarkdown emits one chunk per heading section; text before the first
+// heading forms its own section.
+func chunkMarkdown(_ string, content string, opts ChunkOptions) ([]Chunk, error) {
+	lines := splitLines(content)
+	var chunks []Chunk
+	startLine := 1
+	symbol := ""
+	inFence := false
+	for idx, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		match := markdownHeading.FindStringSubmatch(line)
+		if match == nil || idx == 0 {
+			if match != nil {
+				symbol = match[1]
+			}
+			continue
+		}
+		chunks = append(chunks, chunkUnit(lines, startLine, idx, symbol, opts)...)
+		startLine = idx + 1
+		symbol = match[1]
+	}
+	chunks = append(chunks, chunkUnit(lines, startLine, len(lines), symbol, opts)...)
+	return chunks, nil
+}
+
+// chunkUnit turns the 1-based inclusive line range into a single chunk, or
+// several when it is larger than opts.Size words.
+func chunkUnit(lines []string, startLine, endLine int, symbol string, opts ChunkOptions) []Chunk {
+	if endLine > len(lines) {
+		endLine = len(lines)
+	}
+	if startLine > endLine {
+		return nil
+	}
+	return chunkLines(lines[startLine-1:endLine], startLine, symbol, opts)
+}
@@ -0,0 +1,99 @@
+package rag
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const chunkerGoSource = `// Package demo is a test fixture.
+package demo
+
+import "fmt"
+
+// Greeter says hello.
+type Greeter struct {
+	Name string
+}
+
+// Greet returns a greeting.
+func (g *Greeter) Greet() string {
+	return fmt.Sprintf("hello %s", g.Name)
+}
+
+func helper() {}
+`
+
+func TestChunkFileSplitsGoByDeclaration(t *testing.T) {
+	chunks := ChunkFile("demo/demo.go", chunkerGoSource, ChunkOptions{Size: 512, Overlap: 64})
+	require.Len(t, chunks, 4)
+
+	assert.Equal(t, "imports", chunks[0].Symbol)
+	assert.Equal(t, 1, chunks[0].StartLine)
+	assert.Contains(t, chunks[0].Content, "package demo")
+
+	assert.Equal(t, "Greeter", chunks[1].Symbol)
+	assert.Equal(t, 6, chunks[1].StartLine)
+	assert.True(t, strings.HasPrefix(chunks[1].Content, "// Greeter says hello."))
+
+	assert.Equal(t, "Greeter.Greet", chunks[2].Symbol)
+	assert.Equal(t, 11, chunks[2].StartLine)
+	assert.Equal(t, 14, chunks[2].EndLine)
+	assert.Contains(t, chunks[2].Content, "return fmt.Sprintf")
+
+	assert.Equal(t, "helper", chunks[3].Symbol)
+	assert.Equal(t, "demo/demo.go:16", chunks[3].Location())
+	assert.Equal(t, "demo/demo.go:3", chunks[3].ID)
+	assert.Equal(t, 3, chunks[3].ChunkIndex)
+}
+
+func TestChunkFileSplitsOversizedGoDeclarationKeepingSymbol(t *testing.T) {
+	var body strings.Builder
+	body.WriteString("package demo\n\nfunc Big() {\n")
+	for i := 0; i < 40; i++ {
+		body.WriteString("\tprintln(\"one two three\")\n")
+	}
+	body.WriteString("}\n")
+
+	chunks := ChunkFile("big.go", body.String(), ChunkOptions{Size: 30, Overlap: 0})
+	require.Greater(t, len(chunks), 1)
+	for _, chunk := range chunks {
+		assert.Equal(t, "Big", chunk.Symbol)
+	}
+	assert.Equal(t, 44, chunks[len(chunks)-1].EndLine)
+}
+
+func TestChunkFileFallsBackOnUnparseableGo(t *testing.T) {
+	chunks := ChunkFile("broken.go", "package demo\n\nfunc {\n", ChunkOptions{})
+	require.Len(t, chunks, 1)
+	assert.Empty(t, chunks[0].Symbol)
+	assert.Equal(t, 1, chunks[0].StartLine)
+	assert.Equal(t, 3, chunks[0].EndLine)
+}
+
+func TestChunkFileSplitsMarkdownByHeading(t *testing.T) {
+	content := "Intro line.\n\n# Setup\nInstall it.\n\n```sh\n# not a heading\n```\n\n## Usage ##\nRun it.\n"
+	chunks := ChunkFile("README.md", content, ChunkOptions{})
+	require.Len(t, chunks, 3)
+
+	assert.Equal(t, "", chunks[0].Symbol)
+	assert.Equal(t, "Intro line.", chunks[0].Content)
+	assert.Equal(t, "Setup", chunks[1].Symbol)
+	assert.Equal(t, 3, chunks[1].StartLine)
+	assert.Equal(t, 8, chunks[1].EndLine)
+	assert.Contains(t, chunks[1].Content, "# not a heading")
+	assert.Equal(t, "Usage", chunks[2].Symbol)
+	assert.Equal(t, "README.md:10-11", chunks[2].Location())
+}
+
+func TestRegisterChunkerMapsExtensions(t *testing.T) {
+	RegisterChunker("fixture", func(_ string, content string, _ ChunkOptions) ([]Chunk, error) {
+		return []Chunk{{Content: content, StartLine: 1, EndLine: 1, Symbol: "whole"}}, nil
+	}, "fixture")
+
+	chunks := ChunkFile("a.fixture", "anything", ChunkOptions{})
+	require.Len(t, chunks, 1)
+	assert.Equal(t, "whole", chunks[0].Symbol)
+}
@@ -6,34 +6,56 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
+	"math"
 	"net/http"
 	"strings"
 )
 
-type Embedder struct {
-	URL    string
-	Model  string
-	Client *http.Client
+// EmbeddingProvider turns text into vectors for semantic search.
+type EmbeddingProvider interface {
+	// Name identifies the backend and model. The index is rebuilt when it
+	// changes, since vectors from different models are not comparable.
+	Name() string
+	EnsureReady(ctx context.Context) error
+	Embed(ctx context.Context, text string) ([]float32, error)
 }
 
 var ErrEmbedderNotReady = errors.New("rag embedder is not initialized")
 
-func NewEmbedder(url, model string) *Embedder {
+// OllamaEmbedder calls Ollama's /api/embeddings endpoint.
+type OllamaEmbedder struct {
+	URL   string
+	Model string
+	// Dimensions truncates vectors to this size when set, for models
+	// trained with Matryoshka representation learning.
+	Dimensions int
+	Client     *http.Client
+}
+
+func NewOllamaEmbedder(url, model string) *OllamaEmbedder {
 	if url == "" {
 		url = "http://localhost:11434"
 	}
 	if model == "" {
 		model = "nomic-embed-text"
 	}
-	return &Embedder{
+	return &OllamaEmbedder{
 		URL:    strings.TrimRight(url, "/"),
 		Model:  model,
 		Client: http.DefaultClient,
 	}
 }
 
-func (e *Embedder) ensureConfigured() error {
+func (e *OllamaEmbedder) Name() string {
+	if e == nil {
+		return ""
+	}
+	return "ollama:" + e.Model
+}
+
+func (e *OllamaEmbedder) ensureConfigured() error {
 	if e == nil {
 		return ErrEmbedderNotReady
 	}
@@ -46,14 +68,7 @@ func (e *Embedder) ensureConfigured() error {
 	return nil
 }
 
-func (e *Embedder) client() *http.Client {
-	if e != nil && e.Client != nil {
-		return e.Client
-	}
-	return http.DefaultClient
-}
-
-func (e *Embedder) EnsureReady(ctx context.Context) error {
+func (e *OllamaEmbedder) EnsureReady(ctx context.Context) error {
 	if err := e.ensureConfigured(); err != nil {
 		return err
 	}
@@ -66,7 +81,7 @@ func (e *Embedder) EnsureReady(ctx context.Context) error {
 		return fmt.Errorf("build ollama healthcheck request: %w", err)
 	}
 
-	resp, err := e.client().Do(req)
+	resp, err := httpClient(e.Client).Do(req)
 	if err != nil {
 		return fmt.Errorf("ollama embedder is unreachable at %s: %w", e.URL, err)
 	}
@@ -80,7 +95,7 @@ func (e *Embedder) EnsureReady(ctx context.Context) error {
 	return nil
 }
 
-func (e *Embedder) Embed(ctx context.Context, text string) ([]float32, error) {
+func (e *OllamaEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
 	if err := e.ensureConfigured(); err != nil {
 		return nil, err
 	}
@@ -102,7 +117,7 @@ func (e *Embedder) Embed(ctx context.Context, text string) ([]float32, error) {
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := e.client().Do(req)
+	resp, err := httpClient(e.Client).Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -120,5 +135,240 @@ func (e *Embedder) Embed(ctx context.Context, text string) ([]float32, error) {
 		return nil, err
 	}
 
-	return res.Embedding, nil
+	return truncateEmbedding(res.Embedding, e.Dimensions)
+}
+
+// OpenAIEmbedder calls an OpenAI-compatible /embeddings endpoint. APIKey may
+// be empty for local servers that do not check it.
+type OpenAIEmbedder struct {
+	BaseURL    string
+	Model      string
+	APIKey     string
+	Dimensions int
+	Client     *http.Client
+}
+
+func NewOpenAIEmbedder(baseURL, model, apiKey string) *OpenAIEmbedder {
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	if model == "" {
+		model = "text-embedding-3-small"
+	}
+	return &OpenAIEmbedder{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Model:   model,
+		APIKey:  strings.TrimSpace(apiKey),
+		Client:  http.DefaultClient,
+	}
+}
+
+func (e *OpenAIEmbedder) Name() string {
+	if e == nil {
+		return ""
+	}
+	return "openai:" + e.Model
+}
+
+// EnsureReady lists the server's models instead of embedding, so a health
+// check is not billed. Servers without a /models endpoint get a test embed.
+func (e *OpenAIEmbedder) EnsureReady(ctx context.Context) error {
+	if e == nil {
+		return ErrEmbedderNotReady
+	}
+	if strings.TrimSpace(e.BaseURL) == "" {
+		return errors.New("rag embedder URL is empty")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.BaseURL+"/models", nil)
+	if err != nil {
+		return fmt.Errorf("build models request: %w", err)
+	}
+	if e.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.APIKey)
+	}
+	resp, err := httpClient(e.Client).Do(req)
+	if err != nil {
+		return fmt.Errorf("embeddings endpoint is unreachable at %s: %w", e.BaseURL, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		_, err := e.Embed(ctx, "ping")
+		return err
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("models healthcheck failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+func (e *OpenAIEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	if e == nil {
+		return nil, ErrEmbedderNotReady
+	}
+	if strings.TrimSpace(e.BaseURL) == "" {
+		return nil, errors.New("rag embedder URL is empty")
+	}
+	if strings.TrimSpace(e.Model) == "" {
+		return nil, errors.New("rag embedder model is empty")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	payload := map[string]any{"model": e.Model, "input": text}
+	if e.Dimensions > 0 {
+		payload["dimensions"] = e.Dimensions
+	}
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal embeddings request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.BaseURL+"/embeddings", bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.APIKey)
+	}
+
+	resp, err := httpClient(e.Client).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embeddings endpoint is unreachable at %s: %w", e.BaseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("embeddings error: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var res struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	if len(res.Data) == 0 {
+		return nil, errors.New("embeddings response has no data")
+	}
+	return truncateEmbedding(res.Data[0].Embedding, e.Dimensions)
+}
+
+const defaultHashingDimensions = 256
+
+// HashingEmbedder is an offline embedder that hashes words and identifier
+// parts into a fixed number of buckets. It needs no model server and is
+// deterministic, at the cost of only matching shared vocabulary.
+type HashingEmbedder struct {
+	Dimensions int
+}
+
+func NewHashingEmbedder(dimensions int) *HashingEmbedder {
+	if dimensions <= 0 {
+		dimensions = defaultHashingDimensions
+	}
+	return &HashingEmbedder{Dimensions: dimensions}
+}
+
+func (e *HashingEmbedder) Name() string {
+	return "hashing"
+}
+
+func (e *HashingEmbedder) EnsureReady(context.Context) error {
+	if e == nil || e.Dimensions <= 0 {
+		return ErrEmbedderNotReady
+	}
+	return nil
+}
+
+func (e *HashingEmbedder) Embed(_ context.Context, text string) ([]float32, error) {
+	if e == nil || e.Dimensions <= 0 {
+		return nil, ErrEmbedderNotReady
+	}
+	counts := make(map[string]int)
+	for _, term := range lexicalTerms(text) {
+		counts[term]++
+	}
+	vec := make([]float32, e.Dimensions)
+	for term, count := range counts {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(term))
+		sum := h.Sum64()
+		// The top bit picks a sign so that colliding terms tend to cancel
+		// instead of piling up in one bucket.
+		weight := float32(1 + math.Log(float64(count)))
+		if sum>>63 == 1 {
+			weight = -weight
+		}
+		vec[sum%uint64(e.Dimensions)] += weight
+	}
+	return normalizeEmbedding(vec), nil
+}
+
+func httpClient(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return http.DefaultClient
+}
+
+// truncateEmbedding shortens vec to dims and renormalizes it. A vector that
+// is already shorter than dims is an error, as it cannot be padded usefully.
+func truncateEmbedding(vec []float32, dims int) ([]float32, error) {
+	if dims <= 0 || len(vec) == dims {
+		return vec, nil
+	}
+	if len(vec) < dims {
+		return nil, fmt.Errorf("embedding has %d dimensions, configured %d", len(vec), dims)
+	}
+	return normalizeEmbedding(vec[:dims]), nil
+}
+
+func normalizeEmbedding(vec []float32) []float32 {
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		return vec
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for idx := range vec {
+		vec[idx] *= scale
+	}
+	return vec
+}
+
+// EmbedderConfig selects and configures an embedding backend.
+type EmbedderConfig struct {
+	// Backend is "ollama" (the default), "openai" or "hashing".
+	Backend    string
+	Model      string
+	URL        string
+	APIKey     string
+	Dimensions int
+}
+
+func NewEmbeddingProvider(cfg EmbedderConfig) (EmbeddingProvider, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Backend)) {
+	case "", "ollama":
+		embedder := NewOllamaEmbedder(strings.TrimSpace(cfg.URL), strings.TrimSpace(cfg.Model))
+		embedder.Dimensions = cfg.Dimensions
+		return embedder, nil
+	case "openai":
+		embedder := NewOpenAIEmbedder(strings.TrimSpace(cfg.URL), strings.TrimSpace(cfg.Model), cfg.APIKey)
+		embedder.Dimensions = cfg.Dimensions
+		return embedder, nil
+	case "hashing":
+		return NewHashingEmbedder(cfg.Dimensions), nil
+	}
+	return nil, fmt.Errorf("unknown rag backend %q (want ollama, openai or hashing)", cfg.Backend)
 }
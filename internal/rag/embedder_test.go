@@ -2,13 +2,17 @@ package rag
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEmbedderGuardOnNilReceiver(t *testing.T) {
-	var embedder *Embedder
+	var embedder *OllamaEmbedder
 
 	assert.ErrorIs(t, embedder.EnsureReady(context.Background()), ErrEmbedderNotReady)
 
@@ -17,7 +21,7 @@ func TestEmbedderGuardOnNilReceiver(t *testing.T) {
 }
 
 func TestEmbedderRequiresConfig(t *testing.T) {
-	embedder := &Embedder{}
+	embedder := &OllamaEmbedder{}
 
 	assert.ErrorContains(t, embedder.EnsureReady(context.Background()), "URL is empty")
 
@@ -25,9 +29,105 @@ func TestEmbedderRequiresConfig(t *testing.T) {
 	assert.ErrorContains(t, err, "URL is empty")
 }
 
-func TestNewEmbedderDefaults(t *testing.T) {
-	embedder := NewEmbedder("http://localhost:11434/", "")
+func TestNewOllamaEmbedderDefaults(t *testing.T) {
+	embedder := NewOllamaEmbedder("http://localhost:11434/", "")
 
 	assert.Equal(t, "http://localhost:11434", embedder.URL)
 	assert.Equal(t, "nomic-embed-text", embedder.Model)
 }
+
+func TestOpenAIEmbedderSendsDimensionsAndKey(t *testing.T) {
+	var got struct {
+		Model      string `json:"model"`
+		Input      string `json:"input"`
+		Dimensions int    `json:"dimensions"`
+	}
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		auth = r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": []map[string]any{{"embedding": []float32{3, 4, 0}}}})
+	}))
+	defer server.Close()
+
+	embedder := NewOpenAIEmbedder(server.URL+"/v1/", "", "sk-test")
+	embedder.Dimensions = 2
+	vec, err := embedder.Embed(context.Background(), "hello")
+	require.NoError(t, err)
+
+	assert.Equal(t, "text-embedding-3-small", got.Model)
+	assert.Equal(t, "hello", got.Input)
+	assert.Equal(t, 2, got.Dimensions)
+	assert.Equal(t, "Bearer sk-test", auth)
+	// Servers that ignore dimensions get truncated and renormalized.
+	assert.InDeltaSlice(t, []float32{0.6, 0.8}, vec, 1e-6)
+	assert.Equal(t, "openai:text-embedding-3-small", embedder.Name())
+}
+
+func TestOpenAIEmbedderEnsureReadyListsModels(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/v1/models" {
+			assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+			_, _ = w.Write([]byte(`{"data":[]}`))
+			return
+		}
+		if r.URL.Path == "/v2/models" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": []map[string]any{{"embedding": []float32{1}}}})
+	}))
+	defer server.Close()
+
+	require.NoError(t, NewOpenAIEmbedder(server.URL+"/v1", "", "sk-test").EnsureReady(context.Background()))
+	assert.Equal(t, []string{"/v1/models"}, paths)
+
+	// Servers without a models endpoint are checked with a test embed.
+	paths = nil
+	require.NoError(t, NewOpenAIEmbedder(server.URL+"/v2", "", "").EnsureReady(context.Background()))
+	assert.Equal(t, []string{"/v2/models", "/v2/embeddings"}, paths)
+}
+
+func TestHashingEmbedderIsDeterministicAndMatchesSharedTerms(t *testing.T) {
+	embedder := NewHashingEmbedder(0)
+	require.NoError(t, embedder.EnsureReady(context.Background()))
+
+	a, err := embedder.Embed(context.Background(), "func writePlanLock(path string)")
+	require.NoError(t, err)
+	again, err := embedder.Embed(context.Background(), "func writePlanLock(path string)")
+	require.NoError(t, err)
+	related, err := embedder.Embed(context.Background(), "where is the plan lock written")
+	require.NoError(t, err)
+	unrelated, err := embedder.Embed(context.Background(), "docker compose deployment")
+	require.NoError(t, err)
+
+	assert.Len(t, a, defaultHashingDimensions)
+	assert.Equal(t, a, again)
+	near, err := cosineDistance(a, related)
+	require.NoError(t, err)
+	far, err := cosineDistance(a, unrelated)
+	require.NoError(t, err)
+	assert.Less(t, near, far)
+}
+
+func TestNewEmbeddingProviderSelectsBackend(t *testing.T) {
+	provider, err := NewEmbeddingProvider(EmbedderConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, "ollama:nomic-embed-text", provider.Name())
+
+	provider, err = NewEmbeddingProvider(EmbedderConfig{Backend: "openai"})
+	require.NoError(t, err)
+	assert.Equal(t, "openai:text-embedding-3-small", provider.Name())
+
+	provider, err = NewEmbeddingProvider(EmbedderConfig{Backend: "Hashing", Dimensions: 32})
+	require.NoError(t, err)
+	vec, err := provider.Embed(context.Background(), "hello world")
+	require.NoError(t, err)
+	assert.Len(t, vec, 32)
+
+	_, err = NewEmbeddingProvider(EmbedderConfig{Backend: "word2vec"})
+	assert.ErrorContains(t, err, "unknown rag backend")
+}
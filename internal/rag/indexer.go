@@ -7,36 +7,113 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+
+	"github.com/yubzen/orchestra/internal/ignore"
+)
+
+// indexFormatVersion is bumped whenever chunking or stored chunk fields
+// change in a way that makes existing chunks stale.
+const indexFormatVersion = 1
+
+const (
+	metaEmbedderModel = "embedder_model"
+	metaEmbedderDims  = "embedder_dims"
+	// metaPathKeys records that stored paths are keyed relative to the
+	// working directory; older indexes stored the paths walked.
+	metaPathKeys = "path_keys"
+)
+
+// While degraded, a watching indexer probes the embedder again after
+// embedderRetryMin, doubling the wait up to embedderRetryMax.
+const (
+	embedderRetryMin = 30 * time.Second
+	embedderRetryMax = 10 * time.Minute
 )
 
+type IndexPhase string
+
+const (
+	IndexPhaseScanned  IndexPhase = "scanned"
+	IndexPhaseIndexing IndexPhase = "indexing"
+	IndexPhaseIdle     IndexPhase = "idle"
+)
+
+// IndexProgress reports indexer activity. A scanned event carries the
+// startup scan totals, an indexing event covers one file, and an idle event
+// closes each batch.
+type IndexProgress struct {
+	Phase   IndexPhase
+	Path    string
+	Done    int
+	Total   int
+	Skipped int
+	Purged  int
+	Failed  int
+	Rebuild bool
+	// Degraded is set while the embedder is unreachable and new chunks are
+	// only searchable by keyword.
+	Degraded bool
+	Err      error
+}
+
 type Indexer struct {
 	Store      *Store
-	Embedder   *Embedder
+	Embedder   EmbeddingProvider
 	WorkingDir string
+	Chunking   ChunkOptions
+	Ignore     *ignore.Matcher
 	Done       chan struct{}
+	// Progress receives IndexProgress events. Sends never block; events are
+	// dropped when nobody keeps up.
+	Progress chan IndexProgress
 
+	// mu guards the fields below; model, version and degraded are set by
+	// prepare and read by the watcher goroutine.
 	mu         sync.Mutex
 	dirtyFiles map[string]bool
+	// symbolDirs holds directories whose symbols need re-extracting besides
+	// those of the files in the next batch; refreshSymbols is set when the
+	// stored symbols predate symbolFormatVersion.
+	symbolDirs     map[string]bool
+	refreshSymbols bool
+	model          string
+	version        string
+	degraded       bool
+	pathsKeyed     bool
 }
 
 var ErrIndexerNotReady = errors.New("rag indexer is not initialized")
 
-func NewIndexer(store *Store, embedder *Embedder, workingDir string) *Indexer {
+func NewIndexer(store *Store, embedder EmbeddingProvider, workingDir string) *Indexer {
 	return &Indexer{
 		Store:      store,
 		Embedder:   embedder,
 		WorkingDir: workingDir,
+		Ignore:     ignore.New(workingDir, ignore.IndexPatterns...),
 		Done:       make(chan struct{}),
+		Progress:   make(chan IndexProgress, 64),
 		dirtyFiles: make(map[string]bool),
+		symbolDirs: make(map[string]bool),
+	}
+}
+
+func (i *Indexer) ensureDependencies(ctx context.Context) error {
+	if err := i.checkDependencies(ctx); err != nil {
+		return err
 	}
+	return i.keyPaths(ctx)
 }
 
-func (i *Indexer) ensureDependencies(ctx context.Context, checkEmbedderHealth bool) error {
+// checkDependencies is ensureDependencies without moving an older index to
+// relative keys, for callers that only read the index.
+func (i *Indexer) checkDependencies(ctx context.Context) error {
 	if i == nil {
 		return ErrIndexerNotReady
 	}
@@ -49,100 +126,421 @@ func (i *Indexer) ensureDependencies(ctx context.Context, checkEmbedderHealth bo
 	if i.Embedder == nil {
 		return ErrEmbedderNotReady
 	}
-	if checkEmbedderHealth {
-		healthCtx := ctx
-		if healthCtx == nil {
-			healthCtx = context.Background()
+	return nil
+}
+
+// indexKey returns the key path is stored under: its path relative to
+// WorkingDir in slash form, whichever way WorkingDir is spelled.
+func (i *Indexer) indexKey(path string) string {
+	absRoot, err := filepath.Abs(i.WorkingDir)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	rel, err := filepath.Rel(absRoot, absPath)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// diskPath returns the file stored under key.
+func (i *Indexer) diskPath(key string) string {
+	return filepath.Join(i.WorkingDir, filepath.FromSlash(key))
+}
+
+// keyPaths moves an index that stored walked paths to relative keys, once.
+// Relative paths were walked from the process directory, so they are
+// resolved against it. Entries outside WorkingDir, or that now share a key
+// with another entry, are dropped and indexed again. Symbols are cheap to
+// extract, so they are cleared rather than rewritten.
+func (i *Indexer) keyPaths(ctx context.Context) error {
+	i.mu.Lock()
+	keyed := i.pathsKeyed
+	i.mu.Unlock()
+	if keyed {
+		return nil
+	}
+	if value, err := i.Store.IndexMeta(ctx, metaPathKeys); err != nil || value == "relative" {
+		if err == nil {
+			i.mu.Lock()
+			i.pathsKeyed = true
+			i.mu.Unlock()
+		}
+		return err
+	}
+	paths, err := i.Store.IndexedPaths(ctx)
+	if err != nil {
+		return err
+	}
+	// Paths already in key form keep their key; the others are moved to it.
+	taken := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if i.indexKey(path) == path {
+			taken[path] = true
 		}
-		if _, hasDeadline := healthCtx.Deadline(); !hasDeadline {
-			var cancel context.CancelFunc
-			healthCtx, cancel = context.WithTimeout(healthCtx, 5*time.Second)
-			defer cancel()
+	}
+	for _, path := range paths {
+		key := i.indexKey(path)
+		switch {
+		case key == path:
+			continue
+		case key == ".." || strings.HasPrefix(key, "../") || taken[key]:
+			err = i.Store.ClearFile(ctx, path)
+		default:
+			err = i.Store.RekeyFile(ctx, path, key)
+			taken[key] = true
 		}
-		if err := i.Embedder.EnsureReady(healthCtx); err != nil {
-			return fmt.Errorf("rag embedder not ready: %w", err)
+		if err != nil {
+			return fmt.Errorf("rekey %s: %w", path, err)
 		}
 	}
+	if err := i.Store.ClearSymbols(ctx); err != nil {
+		return err
+	}
+	if err := i.Store.SetIndexMeta(ctx, metaPathKeys, "relative"); err != nil {
+		return err
+	}
+	i.mu.Lock()
+	i.pathsKeyed = true
+	i.mu.Unlock()
+	return nil
+}
+
+func (i *Indexer) checkEmbedder(ctx context.Context) error {
+	healthCtx := ctx
+	if healthCtx == nil {
+		healthCtx = context.Background()
+	}
+	if _, hasDeadline := healthCtx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		healthCtx, cancel = context.WithTimeout(healthCtx, 5*time.Second)
+		defer cancel()
+	}
+	if err := i.Embedder.EnsureReady(healthCtx); err != nil {
+		return fmt.Errorf("rag embedder not ready: %w", err)
+	}
 	return nil
 }
 
-func chunkText(text string, chunkSize, overlap int) []string {
-	words := strings.Fields(text)
-	var chunks []string
+func (i *Indexer) isIgnored(path string, info fs.FileInfo) bool {
+	return i.Ignore.MatchAbs(path, info.IsDir())
+}
+
+func (i *Indexer) emitProgress(progress IndexProgress) {
+	if i == nil || i.Progress == nil {
+		return
+	}
+	progress.Degraded = i.Degraded()
+	select {
+	case i.Progress <- progress:
+	default:
+	}
+}
 
-	if len(words) == 0 {
-		return chunks
+// Degraded reports whether the embedder was unreachable when last probed.
+// Files are then indexed for lexical search only and queries skip vector
+// search.
+func (i *Indexer) Degraded() bool {
+	if i == nil {
+		return false
 	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.degraded
+}
+
+func (i *Indexer) embedState() (model, version string, degraded bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.model, i.version, i.degraded
+}
+
+func (i *Indexer) setEmbedState(model, version string, degraded bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.model, i.version, i.degraded = model, version, degraded
+}
+
+// entryCurrent reports whether a manifest entry needs no re-embedding. In
+// degraded mode any entry counts, so embeddings from a healthy run are kept
+// until the embedder is back.
+func (i *Indexer) entryCurrent(entry ManifestEntry) bool {
+	model, version, degraded := i.embedState()
+	return degraded || entry.current(model, version)
+}
 
-	for i := 0; i < len(words); i += (chunkSize - overlap) {
-		end := i + chunkSize
-		if end > len(words) {
-			end = len(words)
+// prepare records the embedder model and vector size in the store and wipes
+// the index when either changed, since old vectors are not comparable. When
+// the embedder is unreachable the indexer switches to degraded mode instead.
+func (i *Indexer) prepare(ctx context.Context) (bool, error) {
+	opts := i.Chunking.normalize()
+	if err := i.checkEmbedder(ctx); err != nil {
+		if !i.Degraded() {
+			fmt.Fprintf(os.Stderr, "warning: %v; indexing for keyword search only\n", err)
 		}
-		chunks = append(chunks, strings.Join(words[i:end], " "))
-		if end == len(words) {
-			break
+		i.setEmbedState("", fmt.Sprintf("v%d lexical chunk=%d/%d", indexFormatVersion, opts.Size, opts.Overlap), true)
+		return false, nil
+	}
+
+	probe, err := i.Embedder.Embed(ctx, "orchestra index probe")
+	if err != nil {
+		return false, fmt.Errorf("probe embedder: %w", err)
+	}
+	if len(probe) == 0 {
+		return false, errors.New("probe embedder: empty embedding")
+	}
+	model := strings.TrimSpace(i.Embedder.Name())
+	dims := strconv.Itoa(len(probe))
+
+	storedModel, err := i.Store.IndexMeta(ctx, metaEmbedderModel)
+	if err != nil {
+		return false, err
+	}
+	storedDims, err := i.Store.IndexMeta(ctx, metaEmbedderDims)
+	if err != nil {
+		return false, err
+	}
+	rebuild := (storedModel != "" && storedModel != model) || (storedDims != "" && storedDims != dims)
+	if rebuild {
+		if err := i.Store.ResetIndex(ctx); err != nil {
+			return false, fmt.Errorf("reset index: %w", err)
 		}
 	}
-	return chunks
+	if err := i.Store.ConfigureVectors(ctx, len(probe)); err != nil {
+		return false, fmt.Errorf("configure vectors: %w", err)
+	}
+	if err := i.Store.SetIndexMeta(ctx, metaEmbedderModel, model); err != nil {
+		return false, err
+	}
+	if err := i.Store.SetIndexMeta(ctx, metaEmbedderDims, dims); err != nil {
+		return false, err
+	}
+
+	i.setEmbedState(model, fmt.Sprintf("v%d dims=%s chunk=%d/%d", indexFormatVersion, dims, opts.Size, opts.Overlap), false)
+	return rebuild, nil
+}
+
+// recoverEmbedder probes a degraded indexer's embedder again and, once it
+// answers, embeds the files indexed for keyword search only. It reports
+// whether the embedder is back.
+func (i *Indexer) recoverEmbedder(ctx context.Context) bool {
+	rebuild, err := i.prepare(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "indexer embedder probe error: %v\n", err)
+		return false
+	}
+	if i.Degraded() {
+		return false
+	}
+	dirty, skipped, purged, err := i.scan(ctx, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "indexer scan error: %v\n", err)
+		return true
+	}
+	i.emitProgress(IndexProgress{Phase: IndexPhaseScanned, Total: len(dirty), Skipped: skipped, Purged: purged, Rebuild: rebuild})
+	i.processBatch(ctx, dirty)
+	return true
 }
 
-func isIgnored(path string, info fs.FileInfo) bool {
-	if info.IsDir() {
-		name := info.Name()
-		if name == ".git" || name == "node_modules" || name == "vendor" {
-			return true
+// scan walks the working tree and returns the files whose size, mtime or
+// index version differ from the manifest. Manifest entries for files that
+// no longer exist, or are now ignored, are purged. Directories are added to
+// watcher when it is not nil. Every directory is queued for symbol
+// extraction when the stored symbols are out of date.
+func (i *Indexer) scan(ctx context.Context, watcher *fsnotify.Watcher) ([]string, int, int, error) {
+	entries, err := i.Store.ListManifest(ctx)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	manifest := make(map[string]ManifestEntry, len(entries))
+	for _, entry := range entries {
+		manifest[entry.Path] = entry
+	}
+
+	var dirty []string
+	skipped := 0
+	// seen maps the key of each indexable file to its path.
+	seen := make(map[string]string)
+	err = filepath.Walk(i.WorkingDir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return nil
 		}
-	} else {
-		ext := filepath.Ext(path)
-		if ext == ".exe" || ext == ".dll" || ext == ".so" || ext == ".dylib" || ext == ".sys" || ext == ".bin" {
-			return true
+		if ctx.Err() != nil {
+			return ctx.Err()
 		}
+		if i.isIgnored(path, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			if watcher != nil {
+				return watcher.Add(path)
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		key := i.indexKey(path)
+		seen[key] = path
+		entry, ok := manifest[key]
+		if ok && i.entryCurrent(entry) && entry.Size == info.Size() && entry.ModTime.Equal(info.ModTime()) {
+			skipped++
+			return nil
+		}
+		dirty = append(dirty, path)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	purged := 0
+	var removed []string
+	for key := range manifest {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		if err := i.Store.ClearFile(ctx, key); err != nil {
+			return nil, 0, 0, fmt.Errorf("purge %s: %w", key, err)
+		}
+		removed = append(removed, i.diskPath(key))
+		purged++
+	}
+	i.markSymbolDirs(removed...)
+
+	symbolsVersion, err := i.Store.IndexMeta(ctx, metaSymbolsVersion)
+	if err != nil {
+		return nil, 0, 0, err
 	}
-	return false
+	if symbolsVersion != symbolFormatVersion {
+		paths := make([]string, 0, len(seen))
+		for _, path := range seen {
+			paths = append(paths, path)
+		}
+		i.markSymbolDirs(paths...)
+		i.mu.Lock()
+		i.refreshSymbols = true
+		i.mu.Unlock()
+	}
+	return dirty, skipped, purged, nil
 }
 
+// processFile re-embeds one file unless its content hash still matches the
+// manifest, in which case only the recorded size and mtime are refreshed.
 func (i *Indexer) processFile(ctx context.Context, path string) error {
-	if err := i.ensureDependencies(ctx, false); err != nil {
+	if err := i.ensureDependencies(ctx); err != nil {
 		return err
 	}
 
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
 	contentBytes, err := os.ReadFile(path)
 	if err != nil {
 		return err
 	}
-	content := string(contentBytes)
+	hash := contentHash(contentBytes)
+	key := i.indexKey(path)
 
-	if err := i.Store.ClearFile(ctx, path); err != nil {
+	entry, ok, err := i.Store.ManifestEntry(ctx, key)
+	if err != nil {
 		return err
 	}
+	model, version, degraded := i.embedState()
+	if ok && entry.Hash == hash && (degraded || entry.current(model, version)) {
+		entry.Size = info.Size()
+		entry.ModTime = info.ModTime()
+		return i.Store.SaveManifestEntry(ctx, entry)
+	}
 
-	chunks := chunkText(content, 512, 64)
-	for idx, chunkStr := range chunks {
-		embedding, err := i.Embedder.Embed(ctx, chunkStr)
-		if err != nil {
-			return fmt.Errorf("embed error: %w", err)
-		}
+	if err := i.Store.ClearFile(ctx, key); err != nil {
+		return err
+	}
 
-		chk := Chunk{
-			ID:         fmt.Sprintf("%s:%d", path, idx),
-			Filepath:   path,
-			ChunkIndex: idx,
-			Content:    chunkStr,
+	for _, chk := range ChunkFile(key, string(contentBytes), i.Chunking) {
+		var embedding []float32
+		if !degraded {
+			embedding, err = i.Embedder.Embed(ctx, chk.Content)
+			if err != nil {
+				return fmt.Errorf("embed error: %w", err)
+			}
 		}
-
 		if err := i.Store.SaveChunk(ctx, chk, embedding); err != nil {
 			return err
 		}
 	}
-	return nil
+	return i.Store.SaveManifestEntry(ctx, ManifestEntry{
+		Path:    key,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Hash:    hash,
+		Model:   model,
+		Version: version,
+	})
+}
+
+// processBatch indexes files in path order and returns how many failed.
+func (i *Indexer) processBatch(ctx context.Context, files []string) int {
+	sort.Strings(files)
+	failed := 0
+	for idx, f := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		err := i.processFile(ctx, f)
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "indexer process error for %s: %v\n", f, err)
+		}
+		i.emitProgress(IndexProgress{Phase: IndexPhaseIndexing, Path: f, Done: idx + 1, Total: len(files), Err: err})
+	}
+	if err := i.flushSymbols(ctx, files); err != nil {
+		fmt.Fprintf(os.Stderr, "indexer symbol error: %v\n", err)
+	}
+	i.emitProgress(IndexProgress{Phase: IndexPhaseIdle, Done: len(files), Total: len(files), Failed: failed})
+	return failed
+}
+
+// Sync brings the index up to date with the working tree once, without
+// watching for further changes.
+func (i *Indexer) Sync(ctx context.Context) error {
+	ctx = normalizeContext(ctx)
+	if err := i.ensureDependencies(ctx); err != nil {
+		return err
+	}
+	rebuild, err := i.prepare(ctx)
+	if err != nil {
+		return err
+	}
+	dirty, skipped, purged, err := i.scan(ctx, nil)
+	if err != nil {
+		return err
+	}
+	i.emitProgress(IndexProgress{Phase: IndexPhaseScanned, Total: len(dirty), Skipped: skipped, Purged: purged, Rebuild: rebuild})
+	if failed := i.processBatch(ctx, dirty); failed > 0 {
+		return fmt.Errorf("%d of %d files failed to index", failed, len(dirty))
+	}
+	return ctx.Err()
 }
 
 func (i *Indexer) Start(ctx context.Context) error {
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	if err := i.ensureDependencies(ctx, true); err != nil {
+	if err := i.ensureDependencies(ctx); err != nil {
+		return err
+	}
+	rebuild, err := i.prepare(ctx)
+	if err != nil {
 		return err
 	}
 
@@ -151,30 +549,17 @@ func (i *Indexer) Start(ctx context.Context) error {
 		return err
 	}
 
-	err = filepath.Walk(i.WorkingDir, func(path string, info fs.FileInfo, err error) error {
-		if err != nil {
-			return nil
-		}
-		if isIgnored(path, info) {
-			if info.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-		if info.IsDir() {
-			err = watcher.Add(path)
-			return err
-		}
-
-		i.mu.Lock()
-		i.dirtyFiles[path] = true
-		i.mu.Unlock()
-		return nil
-	})
+	dirty, skipped, purged, err := i.scan(ctx, watcher)
 	if err != nil {
 		watcher.Close()
 		return err
 	}
+	i.mu.Lock()
+	for _, path := range dirty {
+		i.dirtyFiles[path] = true
+	}
+	i.mu.Unlock()
+	i.emitProgress(IndexProgress{Phase: IndexPhaseScanned, Total: len(dirty), Skipped: skipped, Purged: purged, Rebuild: rebuild})
 
 	go func() {
 		defer watcher.Close()
@@ -182,6 +567,11 @@ func (i *Indexer) Start(ctx context.Context) error {
 
 		debounceTimer := time.NewTimer(2 * time.Second)
 		defer debounceTimer.Stop()
+		retryDelay := embedderRetryMin
+		var retry <-chan time.Time
+		if i.Degraded() {
+			retry = time.After(retryDelay)
+		}
 		for {
 			select {
 			case <-ctx.Done():
@@ -190,13 +580,18 @@ func (i *Indexer) Start(ctx context.Context) error {
 				if !ok {
 					return
 				}
+				if ignore.IsIgnoreFile(event.Name) {
+					i.Ignore.Reset()
+				}
 				if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
 					info, err := os.Stat(event.Name)
 					if err == nil {
 						if info.IsDir() {
-							_ = watcher.Add(event.Name)
+							if !i.isIgnored(event.Name, info) {
+								_ = watcher.Add(event.Name)
+							}
 						} else {
-							if !isIgnored(event.Name, info) {
+							if !i.isIgnored(event.Name, info) {
 								i.mu.Lock()
 								i.dirtyFiles[event.Name] = true
 								i.mu.Unlock()
@@ -204,9 +599,10 @@ func (i *Indexer) Start(ctx context.Context) error {
 						}
 					}
 				} else if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
-					if err := i.Store.ClearFile(ctx, event.Name); err != nil {
+					if err := i.Store.ClearFile(ctx, i.indexKey(event.Name)); err != nil {
 						fmt.Fprintf(os.Stderr, "indexer clear error for %s: %v\n", event.Name, err)
 					}
+					i.markSymbolDirs(event.Name)
 				}
 
 				if !debounceTimer.Stop() {
@@ -223,16 +619,25 @@ func (i *Indexer) Start(ctx context.Context) error {
 				}
 				fmt.Fprintf(os.Stderr, "watcher error: %v\n", err)
 
+			case <-retry:
+				retry = nil
+				if !i.recoverEmbedder(ctx) {
+					retryDelay = min(retryDelay*2, embedderRetryMax)
+					retry = time.After(retryDelay)
+				}
+
 			case <-debounceTimer.C:
 				i.mu.Lock()
-				filesToProcess := i.dirtyFiles
+				filesToProcess := make([]string, 0, len(i.dirtyFiles))
+				for f := range i.dirtyFiles {
+					filesToProcess = append(filesToProcess, f)
+				}
 				i.dirtyFiles = make(map[string]bool)
+				pendingSymbols := len(i.symbolDirs) > 0
 				i.mu.Unlock()
 
-				for f := range filesToProcess {
-					if err := i.processFile(ctx, f); err != nil {
-						fmt.Fprintf(os.Stderr, "indexer process error for %s: %v\n", f, err)
-					}
+				if len(filesToProcess) > 0 || pendingSymbols {
+					i.processBatch(ctx, filesToProcess)
 				}
 			}
 		}
@@ -241,14 +646,43 @@ func (i *Indexer) Start(ctx context.Context) error {
 	return nil
 }
 
-func (i *Indexer) Query(ctx context.Context, prompt string) ([]Chunk, error) {
-	if err := i.ensureDependencies(ctx, false); err != nil {
-		return nil, err
+// markSymbolDirs queues the directories of paths for symbol extraction.
+func (i *Indexer) markSymbolDirs(paths ...string) {
+	dirs := symbolDirs(paths)
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.symbolDirs == nil {
+		i.symbolDirs = make(map[string]bool)
 	}
+	for dir := range dirs {
+		i.symbolDirs[dir] = true
+	}
+}
 
-	emb, err := i.Embedder.Embed(ctx, prompt)
-	if err != nil {
-		return nil, err
+// flushSymbols re-extracts symbols for the directories of files and any
+// queued ones. It needs no embedder, so it also runs in degraded mode.
+func (i *Indexer) flushSymbols(ctx context.Context, files []string) error {
+	dirs := symbolDirs(files)
+	i.mu.Lock()
+	for dir := range i.symbolDirs {
+		dirs[dir] = true
 	}
-	return i.Store.Search(ctx, emb, 5)
+	i.symbolDirs = make(map[string]bool)
+	refresh := i.refreshSymbols
+	i.refreshSymbols = false
+	i.mu.Unlock()
+
+	if err := i.indexSymbols(ctx, dirs); err != nil {
+		i.mu.Lock()
+		for dir := range dirs {
+			i.symbolDirs[dir] = true
+		}
+		i.refreshSymbols = i.refreshSymbols || refresh
+		i.mu.Unlock()
+		return err
+	}
+	if refresh {
+		return i.Store.SetIndexMeta(ctx, metaSymbolsVersion, symbolFormatVersion)
+	}
+	return nil
 }
@@ -1,13 +1,23 @@
 package rag
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-// TestChunkText verifies that chunkText successfully splits and overlaps
-func TestChunkText(t *testing.T) {
+// TestChunkLines verifies that chunkLines splits on whole lines and overlaps
+func TestChunkLines(t *testing.T) {
 	tests := []struct {
 		name          string
 		input         string
@@ -24,24 +34,277 @@ func TestChunkText(t *testing.T) {
 		},
 		{
 			name:          "exactly one chunk",
-			input:         "word1 word2 word3 word4 word5",
+			input:         "word1 word2\nword3 word4 word5",
 			chunkSize:     5,
 			overlap:       1,
 			expectedCount: 1,
 		},
 		{
 			name:          "two chunks with overlap",
-			input:         "w1 w2 w3 w4 w5",
+			input:         "w1 w2\nw3 w4\nw5",
 			chunkSize:     4,
 			overlap:       2,
-			expectedCount: 2, // "w1 w2 w3 w4", "w3 w4 w5"
+			expectedCount: 2, // lines 1-2, lines 2-3
+		},
+		{
+			name:          "blank input",
+			input:         "\n\n",
+			chunkSize:     4,
+			overlap:       0,
+			expectedCount: 0,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			chunks := chunkText(tt.input, tt.chunkSize, tt.overlap)
+			chunks := chunkLines(splitLines(tt.input), 1, "", ChunkOptions{Size: tt.chunkSize, Overlap: tt.overlap})
 			assert.Equal(t, tt.expectedCount, len(chunks))
 		})
 	}
 }
+
+func TestChunkLinesTracksLineNumbers(t *testing.T) {
+	chunks := chunkLines(splitLines("w1 w2\nw3 w4\nw5"), 10, "sym", ChunkOptions{Size: 4, Overlap: 2})
+	assert.Len(t, chunks, 2)
+	assert.Equal(t, 10, chunks[0].StartLine)
+	assert.Equal(t, 11, chunks[0].EndLine)
+	assert.Equal(t, 11, chunks[1].StartLine)
+	assert.Equal(t, 12, chunks[1].EndLine)
+	assert.Equal(t, "w3 w4\nw5", chunks[1].Content)
+	assert.Equal(t, "sym", chunks[1].Symbol)
+	assert.True(t, strings.HasPrefix(chunks[0].Content, "w1 w2"))
+}
+
+// newCountingEmbedServer returns fixed-size vectors and counts embedding
+// requests other than the startup probe.
+func newCountingEmbedServer(t *testing.T, dims int, calls *atomic.Int64) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/tags" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		var req struct {
+			Prompt string `json:"prompt"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Prompt != "orchestra index probe" {
+			calls.Add(1)
+		}
+		vec := make([]float32, dims)
+		vec[len(req.Prompt)%dims] = 1
+		_ = json.NewEncoder(w).Encode(map[string]any{"embedding": vec})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func writeIndexerFile(t *testing.T, root, rel, content string) string {
+	t.Helper()
+	path := filepath.Join(root, rel)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestSyncSkipsUnchangedFilesAndPurgesDeleted(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	dbPath := filepath.Join(t.TempDir(), "rag.db")
+	var calls atomic.Int64
+	server := newCountingEmbedServer(t, 8, &calls)
+
+	writeIndexerFile(t, root, "keep.md", "# Keep\n\nstable content")
+	touched := writeIndexerFile(t, root, "touched.md", "# Touched\n\nsame bytes")
+	gone := writeIndexerFile(t, root, "gone.md", "# Gone\n\nremoved while stopped")
+
+	sync := func() (*Store, []IndexProgress) {
+		store, err := NewStore(dbPath)
+		require.NoError(t, err)
+		indexer := NewIndexer(store, NewOllamaEmbedder(server.URL, "test"), root)
+		require.NoError(t, indexer.Sync(ctx))
+		var events []IndexProgress
+		for len(indexer.Progress) > 0 {
+			events = append(events, <-indexer.Progress)
+		}
+		return store, events
+	}
+
+	store, events := sync()
+	require.NoError(t, store.Close())
+	assert.Equal(t, int64(3), calls.Load())
+	require.NotEmpty(t, events)
+	assert.Equal(t, IndexProgress{Phase: IndexPhaseScanned, Total: 3}, events[0])
+	assert.Equal(t, IndexPhaseIdle, events[len(events)-1].Phase)
+
+	// A restart with nothing changed embeds nothing. A new mtime with the
+	// same bytes is settled by the content hash, and deleted files are purged.
+	require.NoError(t, os.Remove(gone))
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(touched, later, later))
+	calls.Store(0)
+
+	store, events = sync()
+	defer store.Close()
+	assert.Zero(t, calls.Load())
+	assert.Equal(t, IndexProgress{Phase: IndexPhaseScanned, Total: 1, Skipped: 1, Purged: 1}, events[0])
+
+	entries, err := store.ListManifest(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "keep.md", entries[0].Path)
+	assert.Equal(t, "touched.md", entries[1].Path)
+	assert.True(t, entries[1].ModTime.Equal(later))
+
+	lexical, err := store.SearchLexical(ctx, "removed while stopped", 5)
+	require.NoError(t, err)
+	for _, chunk := range lexical {
+		assert.NotEqual(t, "gone.md", chunk.Filepath)
+	}
+}
+
+func TestSyncRebuildsWhenEmbedderChanges(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	dbPath := filepath.Join(t.TempDir(), "rag.db")
+	writeIndexerFile(t, root, "a.md", "# A\n\nalpha")
+	writeIndexerFile(t, root, "b.md", "# B\n\nbeta")
+
+	run := func(model string, dims int) (int64, IndexProgress) {
+		var calls atomic.Int64
+		server := newCountingEmbedServer(t, dims, &calls)
+		store, err := NewStore(dbPath)
+		require.NoError(t, err)
+		defer store.Close()
+		indexer := NewIndexer(store, NewOllamaEmbedder(server.URL, model), root)
+		require.NoError(t, indexer.Sync(ctx))
+		return calls.Load(), <-indexer.Progress
+	}
+
+	calls, scanned := run("model-a", 8)
+	assert.Equal(t, int64(2), calls)
+	assert.False(t, scanned.Rebuild)
+
+	calls, scanned = run("model-a", 8)
+	assert.Zero(t, calls)
+
+	calls, scanned = run("model-a", 16)
+	assert.Equal(t, int64(2), calls)
+	assert.True(t, scanned.Rebuild)
+
+	calls, scanned = run("model-b", 16)
+	assert.Equal(t, int64(2), calls)
+	assert.True(t, scanned.Rebuild)
+	assert.Equal(t, 2, scanned.Total)
+}
+
+func TestSyncDegradesToLexicalWhenEmbedderIsDown(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	dbPath := filepath.Join(t.TempDir(), "rag.db")
+	writeIndexerFile(t, root, "plans/lock.go", "package plans\n\nfunc writePlanLock() {}\n")
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := down.URL
+	down.Close()
+
+	store, err := NewStore(dbPath)
+	require.NoError(t, err)
+	indexer := NewIndexer(store, NewOllamaEmbedder(downURL, "test"), root)
+	require.NoError(t, indexer.Sync(ctx))
+	assert.True(t, indexer.Degraded())
+	assert.True(t, (<-indexer.Progress).Degraded)
+
+	chunks, err := indexer.Query(ctx, "writePlanLock", QueryOptions{})
+	require.NoError(t, err)
+	require.NotEmpty(t, chunks)
+	assert.Contains(t, chunks[0].Content, "writePlanLock")
+	_, err = indexer.Query(ctx, "writePlanLock", QueryOptions{Mode: QueryModeVector})
+	assert.Error(t, err)
+	require.NoError(t, store.Close())
+
+	// Once the embedder is back, keyword-only files are embedded properly.
+	var calls atomic.Int64
+	store, err = NewStore(dbPath)
+	require.NoError(t, err)
+	defer store.Close()
+	indexer = NewIndexer(store, NewOllamaEmbedder(newCountingEmbedServer(t, 8, &calls).URL, "test"), root)
+	require.NoError(t, indexer.Sync(ctx))
+	assert.False(t, indexer.Degraded())
+	assert.Equal(t, int64(1), calls.Load())
+}
+
+func TestDegradedIndexerRecoversWhenEmbedderReturns(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	writeIndexerFile(t, root, "plans/lock.go", "package plans\n\nfunc writePlanLock() {}\n")
+
+	var up atomic.Bool
+	var calls atomic.Int64
+	healthy := newCountingEmbedServer(t, 8, &calls)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		healthy.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	store, err := NewStore(filepath.Join(t.TempDir(), "rag.db"))
+	require.NoError(t, err)
+	defer store.Close()
+	indexer := NewIndexer(store, NewOllamaEmbedder(server.URL, "test"), root)
+	require.NoError(t, indexer.Sync(ctx))
+	require.True(t, indexer.Degraded())
+
+	assert.False(t, indexer.recoverEmbedder(ctx))
+	assert.Zero(t, calls.Load())
+
+	up.Store(true)
+	assert.True(t, indexer.recoverEmbedder(ctx))
+	assert.False(t, indexer.Degraded())
+	assert.Equal(t, int64(1), calls.Load())
+	_, err = indexer.Query(ctx, "writePlanLock", QueryOptions{Mode: QueryModeVector})
+	assert.NoError(t, err)
+}
+
+func TestIndexKeysDoNotDependOnWorkingDirSpelling(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	dbPath := filepath.Join(t.TempDir(), "rag.db")
+	var calls atomic.Int64
+	server := newCountingEmbedServer(t, 8, &calls)
+	abs := writeIndexerFile(t, root, "docs/a.md", "# A\n\nalpha")
+
+	sync := func(workingDir string) *Store {
+		store, err := NewStore(dbPath)
+		require.NoError(t, err)
+		require.NoError(t, NewIndexer(store, NewOllamaEmbedder(server.URL, "test"), workingDir).Sync(ctx))
+		return store
+	}
+	require.NoError(t, sync(root).Close())
+	assert.Equal(t, int64(1), calls.Load())
+
+	// An index written with walked paths is moved to relative keys without
+	// re-embedding.
+	store, err := NewStore(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, store.RekeyFile(ctx, "docs/a.md", abs))
+	require.NoError(t, store.SetIndexMeta(ctx, metaPathKeys, ""))
+	require.NoError(t, store.Close())
+
+	t.Chdir(root)
+	calls.Store(0)
+	store = sync(".")
+	defer store.Close()
+	assert.Zero(t, calls.Load())
+	entries, err := store.ListManifest(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "docs/a.md", entries[0].Path)
+	chunks, err := store.SearchLexical(ctx, "alpha", 5)
+	require.NoError(t, err)
+	require.NotEmpty(t, chunks)
+	assert.Equal(t, "docs/a.md", chunks[0].Filepath)
+}
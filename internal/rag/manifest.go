@@ -0,0 +1,207 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ManifestEntry records what was indexed for a file, so unchanged files can
+// be skipped on the next start.
+type ManifestEntry struct {
+	Path      string
+	Size      int64
+	ModTime   time.Time
+	Hash      string
+	Model     string
+	Version   string
+	IndexedAt time.Time
+}
+
+// current reports whether the entry was indexed with the given embedder model
+// and index version.
+func (e ManifestEntry) current(model, version string) bool {
+	return e.Model == model && e.Version == version
+}
+
+func (s *Store) ManifestEntry(ctx context.Context, path string) (ManifestEntry, bool, error) {
+	ctx = normalizeContext(ctx)
+	if err := s.EnsureReady(ctx); err != nil {
+		return ManifestEntry{}, false, err
+	}
+	var entry ManifestEntry
+	var mtimeNS int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT filepath, size, mtime_ns, content_hash, embedder_model, index_version, indexed_at
+		FROM file_manifest WHERE filepath = ?
+	`, path).Scan(&entry.Path, &entry.Size, &mtimeNS, &entry.Hash, &entry.Model, &entry.Version, &entry.IndexedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ManifestEntry{}, false, nil
+	}
+	if err != nil {
+		return ManifestEntry{}, false, err
+	}
+	entry.ModTime = time.Unix(0, mtimeNS)
+	return entry, true, nil
+}
+
+// ListManifest returns every manifest entry ordered by path.
+func (s *Store) ListManifest(ctx context.Context) ([]ManifestEntry, error) {
+	ctx = normalizeContext(ctx)
+	if err := s.EnsureReady(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT filepath, size, mtime_ns, content_hash, embedder_model, index_version, indexed_at
+		FROM file_manifest ORDER BY filepath ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ManifestEntry
+	for rows.Next() {
+		var entry ManifestEntry
+		var mtimeNS int64
+		if err := rows.Scan(&entry.Path, &entry.Size, &mtimeNS, &entry.Hash, &entry.Model, &entry.Version, &entry.IndexedAt); err != nil {
+			return nil, err
+		}
+		entry.ModTime = time.Unix(0, mtimeNS)
+		out = append(out, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *Store) SaveManifestEntry(ctx context.Context, entry ManifestEntry) error {
+	ctx = normalizeContext(ctx)
+	if err := s.EnsureReady(ctx); err != nil {
+		return err
+	}
+	if strings.TrimSpace(entry.Path) == "" {
+		return errors.New("manifest entry requires a path")
+	}
+	if entry.IndexedAt.IsZero() {
+		entry.IndexedAt = time.Now().UTC()
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO file_manifest (filepath, size, mtime_ns, content_hash, embedder_model, index_version, indexed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, entry.Path, entry.Size, entry.ModTime.UnixNano(), entry.Hash, entry.Model, entry.Version, entry.IndexedAt)
+	return err
+}
+
+// IndexMeta returns a stored index setting, or "" when it was never set.
+func (s *Store) IndexMeta(ctx context.Context, key string) (string, error) {
+	ctx = normalizeContext(ctx)
+	if err := s.EnsureReady(ctx); err != nil {
+		return "", err
+	}
+	var value string
+	err := s.db.QueryRowContext(ctx, "SELECT value FROM index_meta WHERE key = ?", key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return value, err
+}
+
+func (s *Store) SetIndexMeta(ctx context.Context, key, value string) error {
+	ctx = normalizeContext(ctx)
+	if err := s.EnsureReady(ctx); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, "INSERT OR REPLACE INTO index_meta (key, value) VALUES (?, ?)", key, value)
+	return err
+}
+
+// ResetIndex drops every chunk, vector, manifest entry and symbol. Index
+// settings in index_meta are kept, except the symbol version, so that the
+// next scan extracts symbols again.
+func (s *Store) ResetIndex(ctx context.Context) error {
+	ctx = normalizeContext(ctx)
+	if err := s.EnsureReady(ctx); err != nil {
+		return err
+	}
+	statements := []string{
+		"DELETE FROM file_chunks",
+		"DELETE FROM file_manifest",
+		"DELETE FROM symbols",
+		"DELETE FROM symbol_refs",
+		"DELETE FROM symbol_imports",
+		"DELETE FROM index_meta WHERE key = '" + metaSymbolsVersion + "'",
+	}
+	if s.isVecEnabled() {
+		statements = append([]string{"DELETE FROM vec_chunks"}, statements...)
+	}
+	if s.isFTSEnabled() {
+		statements = append([]string{"DELETE FROM fts_chunks"}, statements...)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RekeyFile moves the chunks and manifest entry stored under from to the
+// key to, keeping their embeddings.
+func (s *Store) RekeyFile(ctx context.Context, from, to string) error {
+	ctx = normalizeContext(ctx)
+	if err := s.EnsureReady(ctx); err != nil {
+		return err
+	}
+	statements := []string{
+		"UPDATE file_manifest SET filepath = ? WHERE filepath = ?",
+		"UPDATE file_chunks SET filepath = ?1, id = ?1 || ':' || chunk_index WHERE filepath = ?2",
+	}
+	if s.isFTSEnabled() {
+		statements = append([]string{"UPDATE fts_chunks SET filepath = ?1 WHERE rowid IN (SELECT rowid FROM file_chunks WHERE filepath = ?2)"}, statements...)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement, to, from); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ClearSymbols drops every symbol, reference and import, and the symbol
+// version, so that the next scan extracts symbols again.
+func (s *Store) ClearSymbols(ctx context.Context) error {
+	ctx = normalizeContext(ctx)
+	if err := s.EnsureReady(ctx); err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, statement := range []string{
+		"DELETE FROM symbols",
+		"DELETE FROM symbol_refs",
+		"DELETE FROM symbol_imports",
+		"DELETE FROM index_meta WHERE key = '" + metaSymbolsVersion + "'",
+	} {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,109 @@
+package rag
+
+import (
+	"database/sql"
+
+	"github.com/yubzen/orchestra/internal/migrate"
+)
+
+// migrations is the RAG schema history. Append new versions; never edit one
+// that has shipped. The sqlite-vec and FTS5 virtual tables depend on the
+// build and the embedder, so NewStore and ConfigureVectors manage them.
+var migrations = []migrate.Migration{
+	{Version: 1, Name: "initial schema", Up: migrate.SQL(`
+		CREATE TABLE IF NOT EXISTS file_chunks (
+			rowid INTEGER PRIMARY KEY AUTOINCREMENT,
+			id TEXT UNIQUE,
+			filepath TEXT NOT NULL,
+			chunk_index INTEGER NOT NULL,
+			content TEXT NOT NULL,
+			embedding TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_file_chunks_filepath ON file_chunks(filepath);`)},
+	{Version: 2, Name: "chunk lines and symbols", Up: func(tx *sql.Tx) error {
+		for _, column := range []struct{ name, definition string }{
+			{"start_line", "INTEGER NOT NULL DEFAULT 0"},
+			{"end_line", "INTEGER NOT NULL DEFAULT 0"},
+			{"symbol", "TEXT NOT NULL DEFAULT ''"},
+		} {
+			if err := migrate.AddColumn(tx, "file_chunks", column.name, column.definition); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{Version: 3, Name: "file manifest", Up: migrate.SQL(`
+		CREATE TABLE IF NOT EXISTS file_manifest (
+			filepath TEXT PRIMARY KEY,
+			size INTEGER NOT NULL,
+			mtime_ns INTEGER NOT NULL,
+			content_hash TEXT NOT NULL,
+			embedder_model TEXT NOT NULL,
+			index_version TEXT NOT NULL,
+			indexed_at DATETIME NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS index_meta (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL
+		);`)},
+	{Version: 4, Name: "symbol graph", Up: migrate.SQL(`
+		CREATE TABLE IF NOT EXISTS symbols (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			dir TEXT NOT NULL,
+			filepath TEXT NOT NULL,
+			package TEXT NOT NULL,
+			package_name TEXT NOT NULL,
+			name TEXT NOT NULL,
+			recv TEXT NOT NULL DEFAULT '',
+			kind TEXT NOT NULL,
+			line INTEGER NOT NULL,
+			end_line INTEGER NOT NULL,
+			exported INTEGER NOT NULL DEFAULT 0,
+			signature TEXT NOT NULL DEFAULT ''
+		);
+		CREATE INDEX IF NOT EXISTS idx_symbols_name ON symbols(name);
+		CREATE INDEX IF NOT EXISTS idx_symbols_dir ON symbols(dir);
+		CREATE TABLE IF NOT EXISTS symbol_refs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			dir TEXT NOT NULL,
+			filepath TEXT NOT NULL,
+			line INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			target TEXT NOT NULL DEFAULT '',
+			caller TEXT NOT NULL DEFAULT ''
+		);
+		CREATE INDEX IF NOT EXISTS idx_symbol_refs_name ON symbol_refs(name);
+		CREATE INDEX IF NOT EXISTS idx_symbol_refs_dir ON symbol_refs(dir);
+		CREATE TABLE IF NOT EXISTS symbol_imports (
+			dir TEXT NOT NULL,
+			filepath TEXT NOT NULL,
+			package TEXT NOT NULL,
+			import_path TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_symbol_imports_dir ON symbol_imports(dir);
+		CREATE INDEX IF NOT EXISTS idx_symbol_imports_path ON symbol_imports(import_path);`)},
+}
+
+// MigrationStatus reports the schema version of the RAG database at dbPath
+// without migrating it.
+func MigrationStatus(dbPath string) (migrate.Status, error) {
+	enableSQLiteVec()
+	conn, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return migrate.Status{}, err
+	}
+	defer conn.Close()
+	return migrate.GetStatus(conn, migrations)
+}
+
+// MigrateTo upgrades the RAG database at dbPath to target, or to the latest
+// version when target is 0.
+func MigrateTo(dbPath string, target int) (migrate.Result, error) {
+	enableSQLiteVec()
+	conn, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return migrate.Result{}, err
+	}
+	defer conn.Close()
+	return migrate.Run(conn, dbPath, migrations, target)
+}
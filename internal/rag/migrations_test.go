@@ -0,0 +1,91 @@
+package rag
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationsUpgradeFromEveryVersion(t *testing.T) {
+	want := ragSchemaOf(t, filepath.Join(t.TempDir(), "fresh.db"))
+
+	for version := 0; version < len(migrations); version++ {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			dbPath := filepath.Join(t.TempDir(), "rag.db")
+			if version == 0 {
+				legacy, err := sql.Open("sqlite3", dbPath)
+				require.NoError(t, err)
+				_, err = legacy.Exec(`CREATE TABLE file_chunks (
+					rowid INTEGER PRIMARY KEY AUTOINCREMENT,
+					id TEXT UNIQUE,
+					filepath TEXT NOT NULL,
+					chunk_index INTEGER NOT NULL,
+					content TEXT NOT NULL,
+					embedding TEXT NOT NULL
+				)`)
+				require.NoError(t, err)
+				require.NoError(t, legacy.Close())
+			} else {
+				_, err := MigrateTo(dbPath, version)
+				require.NoError(t, err)
+			}
+
+			conn, err := sql.Open("sqlite3", dbPath)
+			require.NoError(t, err)
+			_, err = conn.Exec(`INSERT INTO file_chunks (id, filepath, chunk_index, content, embedding) VALUES ('a.go:0', 'a.go', 0, 'func A() {}', '[1,0,0]')`)
+			require.NoError(t, err)
+			require.NoError(t, conn.Close())
+
+			status, err := MigrationStatus(dbPath)
+			require.NoError(t, err)
+			assert.Equal(t, version, status.Current)
+			assert.Len(t, status.Pending, len(migrations)-version)
+
+			assert.Equal(t, want, ragSchemaOf(t, dbPath))
+			assert.FileExists(t, fmt.Sprintf("%s.v%d.bak", dbPath, version))
+
+			store, err := NewStore(dbPath)
+			require.NoError(t, err)
+			defer store.Close()
+			var content string
+			require.NoError(t, store.db.QueryRow("SELECT content FROM file_chunks WHERE id = 'a.go:0'").Scan(&content))
+			assert.Equal(t, "func A() {}", content)
+		})
+	}
+}
+
+func TestMigrateToRefusesDowngrade(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "rag.db")
+	store, err := NewStore(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	_, err = MigrateTo(dbPath, 1)
+	assert.Error(t, err)
+}
+
+// ragSchemaOf opens dbPath through NewStore and lists the columns of each
+// migrated table.
+func ragSchemaOf(t *testing.T, dbPath string) map[string][]string {
+	t.Helper()
+	store, err := NewStore(dbPath)
+	require.NoError(t, err)
+	defer store.Close()
+
+	schema := make(map[string][]string)
+	for _, table := range []string{"file_chunks", "file_manifest", "index_meta", "symbols", "symbol_refs", "symbol_imports"} {
+		rows, err := store.db.Query("SELECT name, type FROM pragma_table_info(?) ORDER BY name", table)
+		require.NoError(t, err)
+		for rows.Next() {
+			var name, typ string
+			require.NoError(t, rows.Scan(&name, &typ))
+			schema[table] = append(schema[table], name+" "+typ)
+		}
+		require.NoError(t, rows.Close())
+	}
+	return schema
+}
@@ -0,0 +1,140 @@
+package rag
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// repoPackage is one package of the repo graph with its rank inputs.
+type repoPackage struct {
+	dir       string
+	path      string
+	name      string
+	importers map[string]bool
+	// refs counts references from other packages.
+	refs    int
+	churn   int
+	symbols map[string]int
+}
+
+// repoGraph holds the import and reference graph of a project's sources.
+type repoGraph struct {
+	packages map[string]*repoPackage
+	// fileRefs counts references into each file from other files.
+	fileRefs map[string]int
+	fileDir  map[string]string
+}
+
+// buildRepoGraph extracts symbols from the given source files (relative to
+// workDir) and counts, per package, the packages importing it and the
+// references from other packages, and per file the references from other
+// files. Test files are left out.
+func buildRepoGraph(workDir string, sourceFiles []string) *repoGraph {
+	graph := &repoGraph{
+		packages: make(map[string]*repoPackage),
+		fileRefs: make(map[string]int),
+		fileDir:  make(map[string]string),
+	}
+	byDir := make(map[string][]string)
+	for _, rel := range sourceFiles {
+		if strings.HasSuffix(rel, "_test.go") || symbolLanguage(rel) == "" {
+			continue
+		}
+		dir := filepath.ToSlash(filepath.Dir(rel))
+		byDir[dir] = append(byDir[dir], rel)
+		graph.fileDir[rel] = dir
+	}
+
+	module := GoModulePath(workDir)
+	byPath := make(map[string]*repoPackage)
+	extracted := make(map[string]map[string]FileSymbols)
+	definedIn := make(map[string]string)
+	for dir, rels := range byDir {
+		files := make(map[string][]byte, len(rels))
+		for _, rel := range rels {
+			content, err := os.ReadFile(filepath.Join(workDir, filepath.FromSlash(rel)))
+			if err == nil {
+				files[rel] = content
+			}
+		}
+		pkg := &repoPackage{dir: dir, path: packagePath(module, dir), importers: make(map[string]bool), symbols: make(map[string]int)}
+		extracted[dir] = ExtractSymbols(pkg.path, files)
+		for rel, fileSymbols := range extracted[dir] {
+			if pkg.name == "" {
+				pkg.name = fileSymbols.PackageName
+			}
+			for _, sym := range fileSymbols.Symbols {
+				definedIn[pkg.path+"."+sym.QualifiedName()] = rel
+				if sym.Exported {
+					pkg.symbols[sym.QualifiedName()] = 0
+				}
+			}
+		}
+		graph.packages[dir] = pkg
+		byPath[pkg.path] = pkg
+	}
+
+	for dir, files := range extracted {
+		from := graph.packages[dir].path
+		for rel, fileSymbols := range files {
+			for _, imp := range fileSymbols.Imports {
+				if target, ok := byPath[imp]; ok && imp != from {
+					target.importers[from] = true
+				}
+			}
+			for _, ref := range fileSymbols.Refs {
+				if file, ok := definedIn[ref.Target]; ok && file != rel {
+					graph.fileRefs[file]++
+				}
+				pkgPath, name, ok := splitRefTarget(ref.Target, byPath)
+				if !ok || pkgPath == from {
+					continue
+				}
+				target := byPath[pkgPath]
+				target.refs++
+				if _, exported := target.symbols[name]; exported {
+					target.symbols[name]++
+				}
+			}
+		}
+	}
+	return graph
+}
+
+// splitRefTarget splits a resolved reference target into a known package
+// path and the symbol's qualified name.
+func splitRefTarget(target string, packages map[string]*repoPackage) (string, string, bool) {
+	if target == "" {
+		return "", "", false
+	}
+	slash := strings.LastIndex(target, "/")
+	dot := strings.Index(target[slash+1:], ".")
+	if dot < 0 {
+		return "", "", false
+	}
+	pkgPath := target[:slash+1+dot]
+	if _, ok := packages[pkgPath]; !ok {
+		return "", "", false
+	}
+	return pkgPath, target[slash+2+dot:], true
+}
+
+// topSymbols returns up to limit names, most referenced first.
+func topSymbols(counts map[string]int, limit int) []string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(a, b int) bool {
+		if counts[names[a]] != counts[names[b]] {
+			return counts[names[a]] > counts[names[b]]
+		}
+		return names[a] < names[b]
+	})
+	if len(names) > limit {
+		names = names[:limit]
+	}
+	return names
+}
@@ -0,0 +1,284 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type QueryMode string
+
+const (
+	QueryModeHybrid  QueryMode = "hybrid"
+	QueryModeVector  QueryMode = "vector"
+	QueryModeLexical QueryMode = "lexical"
+)
+
+const (
+	defaultTokenBudget = 2000
+	defaultCandidates  = 20
+	defaultMaxPerFile  = 1
+	// rrfK dampens the weight of top ranks so that a chunk found by both
+	// retrievers beats one that only tops a single list.
+	rrfK = 60
+)
+
+// QueryOptions controls Indexer.Query. Zero values select hybrid retrieval,
+// one chunk per file, a 2000-token budget and no cap on the result count.
+type QueryOptions struct {
+	Mode        QueryMode
+	TokenBudget int
+	MaxPerFile  int
+	Candidates  int
+	Limit       int
+}
+
+func (o QueryOptions) normalize() QueryOptions {
+	switch QueryMode(strings.ToLower(strings.TrimSpace(string(o.Mode)))) {
+	case QueryModeVector:
+		o.Mode = QueryModeVector
+	case QueryModeLexical:
+		o.Mode = QueryModeLexical
+	default:
+		o.Mode = QueryModeHybrid
+	}
+	if o.TokenBudget <= 0 {
+		o.TokenBudget = defaultTokenBudget
+	}
+	if o.MaxPerFile <= 0 {
+		o.MaxPerFile = defaultMaxPerFile
+	}
+	if o.Candidates <= 0 {
+		o.Candidates = defaultCandidates
+	}
+	return o
+}
+
+func (i *Indexer) Query(ctx context.Context, prompt string, opts QueryOptions) ([]Chunk, error) {
+	ctx = normalizeContext(ctx)
+	opts = opts.normalize()
+	if i == nil {
+		return nil, ErrIndexerNotReady
+	}
+	if err := i.Store.EnsureReady(ctx); err != nil {
+		return nil, err
+	}
+
+	var ranked [][]Chunk
+	if opts.Mode == QueryModeVector && i.Degraded() {
+		return nil, errors.New("vector search is unavailable while the embedder is unreachable")
+	}
+	if opts.Mode != QueryModeLexical && !i.Degraded() {
+		if err := i.ensureDependencies(ctx); err != nil {
+			return nil, err
+		}
+		emb, err := i.Embedder.Embed(ctx, prompt)
+		switch {
+		case err == nil:
+			vector, err := i.Store.Search(ctx, emb, opts.Candidates)
+			if err != nil {
+				return nil, err
+			}
+			ranked = append(ranked, vector)
+		case opts.Mode == QueryModeVector || ctx.Err() != nil:
+			return nil, err
+		}
+		// In hybrid mode an embedder failure still leaves lexical results.
+	}
+	if opts.Mode != QueryModeVector {
+		lexical, err := i.Store.SearchLexical(ctx, prompt, opts.Candidates)
+		if err != nil {
+			return nil, err
+		}
+		ranked = append(ranked, lexical)
+	}
+	return selectChunks(fuseReciprocalRank(ranked...), opts), nil
+}
+
+// fuseReciprocalRank merges ranked lists by summing 1/(k+rank) per chunk.
+func fuseReciprocalRank(lists ...[]Chunk) []Chunk {
+	scores := make(map[string]float64)
+	chunks := make(map[string]Chunk)
+	for _, list := range lists {
+		for rank, chunk := range list {
+			scores[chunk.ID] += 1 / float64(rrfK+rank+1)
+			chunks[chunk.ID] = chunk
+		}
+	}
+	fused := make([]Chunk, 0, len(chunks))
+	for id, chunk := range chunks {
+		chunk.Score = scores[id]
+		fused = append(fused, chunk)
+	}
+	sort.Slice(fused, func(a, b int) bool {
+		if scores[fused[a].ID] != scores[fused[b].ID] {
+			return scores[fused[a].ID] > scores[fused[b].ID]
+		}
+		return fused[a].ID < fused[b].ID
+	})
+	return fused
+}
+
+// selectChunks keeps at most MaxPerFile chunks per file, drops chunks that
+// overlap an already selected range, and fills the token budget greedily in
+// rank order.
+func selectChunks(ranked []Chunk, opts QueryOptions) []Chunk {
+	perFile := make(map[string][]Chunk)
+	used := 0
+	var out []Chunk
+	for _, chunk := range ranked {
+		if opts.Limit > 0 && len(out) >= opts.Limit {
+			break
+		}
+		picked := perFile[chunk.Filepath]
+		if len(picked) >= opts.MaxPerFile || overlapsAny(chunk, picked) {
+			continue
+		}
+		cost := EstimateTokens(chunk.Content)
+		if used+cost > opts.TokenBudget && len(out) > 0 {
+			continue
+		}
+		used += cost
+		perFile[chunk.Filepath] = append(picked, chunk)
+		out = append(out, chunk)
+	}
+	return out
+}
+
+func overlapsAny(chunk Chunk, picked []Chunk) bool {
+	if chunk.StartLine <= 0 {
+		return false
+	}
+	for _, other := range picked {
+		if other.StartLine > 0 && chunk.StartLine <= other.EndLine && other.StartLine <= chunk.EndLine {
+			return true
+		}
+	}
+	return false
+}
+
+// EstimateTokens uses the common four-characters-per-token approximation.
+func EstimateTokens(text string) int {
+	return EstimateTokensForLen(len(text))
+}
+
+// EstimateTokensForLen is EstimateTokens for a text of n bytes, for callers
+// that count streamed text without keeping it.
+func EstimateTokensForLen(n int) int {
+	return n/4 + 1
+}
+
+var lexicalStopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "does": true, "for": true,
+	"how": true, "in": true, "is": true, "it": true, "of": true, "on": true,
+	"or": true, "the": true, "to": true, "what": true, "where": true, "which": true,
+	"who": true, "why": true, "with": true,
+}
+
+// lexicalTerms lowercases identifiers and also emits their camelCase and
+// snake_case parts, so "writePlanLock" matches both itself and "plan lock".
+func lexicalTerms(text string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}) {
+		lower := strings.ToLower(word)
+		if len(lower) < 2 || lexicalStopwords[lower] {
+			continue
+		}
+		terms = append(terms, lower)
+		parts := splitIdentifier(word)
+		if len(parts) < 2 {
+			continue
+		}
+		for _, part := range parts {
+			if part = strings.ToLower(part); len(part) >= 2 && !lexicalStopwords[part] {
+				terms = append(terms, part)
+			}
+		}
+	}
+	return terms
+}
+
+func splitIdentifier(word string) []string {
+	var parts []string
+	var current []rune
+	runes := []rune(word)
+	flush := func() {
+		if len(current) > 0 {
+			parts = append(parts, string(current))
+			current = nil
+		}
+	}
+	for idx, r := range runes {
+		switch {
+		case r == '_':
+			flush()
+			continue
+		case unicode.IsUpper(r) && idx > 0:
+			prev := runes[idx-1]
+			nextLower := idx+1 < len(runes) && unicode.IsLower(runes[idx+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return parts
+}
+
+// bm25Ranker scores documents in memory. It backs lexical search when the
+// SQLite build has no FTS5.
+type bm25Ranker struct {
+	docs   [][]string
+	df     map[string]int
+	avgLen float64
+}
+
+func newBM25Ranker(docs [][]string) *bm25Ranker {
+	r := &bm25Ranker{docs: docs, df: make(map[string]int)}
+	total := 0
+	for _, doc := range docs {
+		total += len(doc)
+		seen := make(map[string]bool)
+		for _, term := range doc {
+			if !seen[term] {
+				seen[term] = true
+				r.df[term]++
+			}
+		}
+	}
+	if len(docs) > 0 {
+		r.avgLen = float64(total) / float64(len(docs))
+	}
+	return r
+}
+
+func (r *bm25Ranker) score(docIdx int, query []string) float64 {
+	const k1, b = 1.2, 0.75
+	doc := r.docs[docIdx]
+	tf := make(map[string]int)
+	for _, term := range doc {
+		tf[term]++
+	}
+	n := float64(len(r.docs))
+	var score float64
+	for _, term := range query {
+		freq := float64(tf[term])
+		if freq == 0 {
+			continue
+		}
+		df := float64(r.df[term])
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		norm := 1 - b
+		if r.avgLen > 0 {
+			norm += b * float64(len(doc)) / r.avgLen
+		}
+		score += idf * freq * (k1 + 1) / (freq + k1*norm)
+	}
+	return score
+}
@@ -0,0 +1,162 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newHashingEmbedServer imitates the Ollama embeddings API with a bag of
+// hashed words, which is enough to give similar texts similar vectors.
+func newHashingEmbedServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/tags" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		var req struct {
+			Prompt string `json:"prompt"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		vec := make([]float32, 64)
+		for _, word := range strings.Fields(strings.ToLower(req.Prompt)) {
+			word = strings.Trim(word, ".,;:()[]{}\"'`?!*&")
+			if len(word) < 3 {
+				continue
+			}
+			h := fnv.New32a()
+			_, _ = h.Write([]byte(word))
+			vec[h.Sum32()%64]++
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"embedding": vec})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newGoldenIndexer(t *testing.T) (*Indexer, string) {
+	t.Helper()
+	root, err := filepath.Abs(filepath.Join("testdata", "golden"))
+	require.NoError(t, err)
+	store, err := NewStore(filepath.Join(t.TempDir(), "rag.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	indexer := NewIndexer(store, NewOllamaEmbedder(newHashingEmbedServer(t).URL, "test"), root)
+	ctx := context.Background()
+	require.NoError(t, filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		return indexer.processFile(ctx, path)
+	}))
+	return indexer, root
+}
+
+func TestQueryGoldenRecall(t *testing.T) {
+	indexer, _ := newGoldenIndexer(t)
+	golden := []struct {
+		query string
+		want  string
+	}{
+		{"where is writePlanLock?", "plans/lock.go"},
+		{"plan lock file", "plans/lock.go"},
+		{"refresh an expired access token", "auth/session.go"},
+		{"LRU eviction when over capacity", "cache/lru.go"},
+		{"register HTTP handlers on the mux", "server/http.go"},
+		{"deploying with docker", "docs/deploy.md"},
+	}
+
+	recall := func(mode QueryMode) (hits int, firstHits int) {
+		for _, g := range golden {
+			chunks, err := indexer.Query(context.Background(), g.query, QueryOptions{Mode: mode, TokenBudget: 100000})
+			require.NoError(t, err)
+			for rank, chunk := range chunks {
+				if rank >= 3 {
+					break
+				}
+				if chunk.Filepath == g.want {
+					hits++
+					if rank == 0 {
+						firstHits++
+					}
+					break
+				}
+			}
+		}
+		return hits, firstHits
+	}
+
+	hybridHits, hybridFirst := recall(QueryModeHybrid)
+	vectorHits, _ := recall(QueryModeVector)
+	lexicalHits, _ := recall(QueryModeLexical)
+	t.Logf("recall@3: hybrid=%d vector=%d lexical=%d of %d (hybrid top-1=%d)", hybridHits, vectorHits, lexicalHits, len(golden), hybridFirst)
+
+	assert.Equal(t, len(golden), hybridHits, "hybrid retrieval should find every golden file in the top 3")
+	assert.GreaterOrEqual(t, hybridFirst, len(golden)-1)
+	assert.GreaterOrEqual(t, hybridHits, vectorHits)
+	assert.GreaterOrEqual(t, hybridHits, lexicalHits)
+}
+
+func TestQueryFindsExactIdentifierFirst(t *testing.T) {
+	indexer, _ := newGoldenIndexer(t)
+
+	chunks, err := indexer.Query(context.Background(), "where is writePlanLock?", QueryOptions{Mode: QueryModeLexical})
+	require.NoError(t, err)
+	require.NotEmpty(t, chunks)
+	assert.Equal(t, "writePlanLock", chunks[0].Symbol)
+}
+
+func TestSelectChunksDedupesPerFileAndRespectsBudget(t *testing.T) {
+	ranked := []Chunk{
+		{ID: "a:0", Filepath: "a.go", Content: strings.Repeat("a", 400), StartLine: 1, EndLine: 10},
+		{ID: "a:1", Filepath: "a.go", Content: strings.Repeat("a", 40), StartLine: 5, EndLine: 12},
+		{ID: "a:2", Filepath: "a.go", Content: strings.Repeat("a", 40), StartLine: 20, EndLine: 30},
+		{ID: "b:0", Filepath: "b.go", Content: strings.Repeat("b", 4000)},
+		{ID: "c:0", Filepath: "c.go", Content: strings.Repeat("c", 40)},
+	}
+
+	got := selectChunks(ranked, QueryOptions{MaxPerFile: 2, TokenBudget: 150})
+	ids := make([]string, 0, len(got))
+	for _, chunk := range got {
+		ids = append(ids, chunk.ID)
+	}
+	assert.Equal(t, []string{"a:0", "a:2", "c:0"}, ids)
+
+	got = selectChunks(ranked, QueryOptions{}.normalize())
+	ids = ids[:0]
+	for _, chunk := range got {
+		ids = append(ids, chunk.ID)
+	}
+	assert.Equal(t, []string{"a:0", "b:0", "c:0"}, ids)
+}
+
+func TestFuseReciprocalRankPrefersAgreement(t *testing.T) {
+	a := Chunk{ID: "a"}
+	b := Chunk{ID: "b"}
+	c := Chunk{ID: "c"}
+
+	fused := fuseReciprocalRank([]Chunk{a, b}, []Chunk{c, b})
+	require.Len(t, fused, 3)
+	assert.Equal(t, "b", fused[0].ID)
+}
+
+func TestLexicalTermsSplitsIdentifiers(t *testing.T) {
+	assert.Equal(t,
+		[]string{"writeplanlock", "write", "plan", "lock", "http_server", "http", "server", "parsehttpheader", "parse", "http", "header"},
+		lexicalTerms("where is writePlanLock? http_server parseHTTPHeader"),
+	)
+}
@@ -0,0 +1,287 @@
+package rag
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IndexStatus describes the index against the current working tree.
+type IndexStatus struct {
+	Files  int
+	Chunks int
+	// Stale lists files that are new or changed since they were indexed,
+	// relative to the working directory like every stored path.
+	Stale []string
+	// Missing lists indexed files that were deleted or are now ignored.
+	Missing []string
+	// Embedder is the configured backend; IndexedWith is the one that
+	// produced the stored vectors. They differ until the next rebuild.
+	Embedder    string
+	IndexedWith string
+	Dimensions  int
+	VecEnabled  bool
+	FTSEnabled  bool
+	// EmbedderErr is set when the configured embedder is unreachable.
+	EmbedderErr error
+}
+
+// Stats counts manifest entries and stored chunks.
+func (s *Store) Stats(ctx context.Context) (files, chunks int, err error) {
+	ctx = normalizeContext(ctx)
+	if err := s.EnsureReady(ctx); err != nil {
+		return 0, 0, err
+	}
+	err = s.db.QueryRowContext(ctx, "SELECT (SELECT COUNT(*) FROM file_manifest), (SELECT COUNT(*) FROM file_chunks)").Scan(&files, &chunks)
+	return files, chunks, err
+}
+
+// IndexedPaths returns every stored path with chunks or a manifest entry.
+func (s *Store) IndexedPaths(ctx context.Context) ([]string, error) {
+	ctx = normalizeContext(ctx)
+	if err := s.EnsureReady(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, "SELECT filepath FROM file_chunks UNION SELECT filepath FROM file_manifest ORDER BY 1")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		out = append(out, path)
+	}
+	return out, rows.Err()
+}
+
+// VectorSearch reports whether sqlite-vec backs vector search, and its size.
+func (s *Store) VectorSearch() (bool, int) {
+	if s == nil {
+		return false, 0
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.vecEnabled, s.vecDims
+}
+
+// LexicalSearch reports whether FTS5 backs lexical search.
+func (s *Store) LexicalSearch() bool {
+	return s != nil && s.isFTSEnabled()
+}
+
+// Status compares the index with the working tree without changing either.
+// An index that still stores walked paths is compared under the keys it
+// would move to, and is left for the next sync to rewrite.
+func (i *Indexer) Status(ctx context.Context) (IndexStatus, error) {
+	ctx = normalizeContext(ctx)
+	if err := i.checkDependencies(ctx); err != nil {
+		return IndexStatus{}, err
+	}
+	var status IndexStatus
+	var err error
+	if status.Files, status.Chunks, err = i.Store.Stats(ctx); err != nil {
+		return IndexStatus{}, err
+	}
+	status.Embedder = i.Embedder.Name()
+	status.EmbedderErr = i.checkEmbedder(ctx)
+	if status.IndexedWith, err = i.Store.IndexMeta(ctx, metaEmbedderModel); err != nil {
+		return IndexStatus{}, err
+	}
+	if dims, err := i.Store.IndexMeta(ctx, metaEmbedderDims); err == nil {
+		status.Dimensions, _ = strconv.Atoi(dims)
+	}
+	status.VecEnabled, _ = i.Store.VectorSearch()
+	status.FTSEnabled = i.Store.LexicalSearch()
+
+	entries, err := i.Store.ListManifest(ctx)
+	if err != nil {
+		return IndexStatus{}, err
+	}
+	keys, err := i.Store.IndexMeta(ctx, metaPathKeys)
+	if err != nil {
+		return IndexStatus{}, err
+	}
+	manifest := make(map[string]ManifestEntry, len(entries))
+	for idx := range entries {
+		if keys != "relative" {
+			entries[idx].Path = i.indexKey(entries[idx].Path)
+		}
+		manifest[entries[idx].Path] = entries[idx]
+	}
+	seen := make(map[string]bool)
+	err = i.walkIndexable(ctx, i.WorkingDir, func(path string, info fs.FileInfo) {
+		key := i.indexKey(path)
+		seen[key] = true
+		entry, ok := manifest[key]
+		if ok && entry.Size == info.Size() && entry.ModTime.Equal(info.ModTime()) {
+			return
+		}
+		if ok && entry.Hash == fileHash(path) {
+			return
+		}
+		status.Stale = append(status.Stale, key)
+	})
+	if err != nil {
+		return IndexStatus{}, err
+	}
+	for _, entry := range entries {
+		if !seen[entry.Path] {
+			status.Missing = append(status.Missing, entry.Path)
+		}
+	}
+	return status, nil
+}
+
+// Prune removes index data for files that were deleted or are now ignored,
+// including chunks left without a manifest entry by older versions.
+func (i *Indexer) Prune(ctx context.Context) ([]string, error) {
+	ctx = normalizeContext(ctx)
+	if err := i.ensureDependencies(ctx); err != nil {
+		return nil, err
+	}
+	paths, err := i.Store.IndexedPaths(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pruned []string
+	for _, key := range paths {
+		path := i.diskPath(key)
+		info, err := os.Stat(path)
+		if err == nil && info.Mode().IsRegular() && !i.isIgnored(path, info) {
+			continue
+		}
+		if err := i.Store.ClearFile(ctx, key); err != nil {
+			return pruned, fmt.Errorf("prune %s: %w", key, err)
+		}
+		pruned = append(pruned, key)
+	}
+	return pruned, nil
+}
+
+// Reindex re-embeds the files under the given paths, or wipes and rebuilds
+// the whole index when no path is given.
+func (i *Indexer) Reindex(ctx context.Context, paths ...string) error {
+	ctx = normalizeContext(ctx)
+	if err := i.ensureDependencies(ctx); err != nil {
+		return err
+	}
+	// Never wipe good vectors for a keyword-only rebuild.
+	if err := i.checkEmbedder(ctx); err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		if err := i.Store.ResetIndex(ctx); err != nil {
+			return err
+		}
+		return i.Sync(ctx)
+	}
+
+	if _, err := i.prepare(ctx); err != nil {
+		return err
+	}
+	var files []string
+	for _, path := range paths {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return err
+		}
+		if err := i.walkIndexable(ctx, i.indexPath(path), func(file string, _ fs.FileInfo) {
+			files = append(files, file)
+		}); err != nil {
+			return err
+		}
+	}
+	for _, file := range files {
+		// Clearing first drops the manifest entry, so processFile cannot
+		// skip the file on a matching hash.
+		if err := i.Store.ClearFile(ctx, i.indexKey(file)); err != nil {
+			return err
+		}
+	}
+	i.emitProgress(IndexProgress{Phase: IndexPhaseScanned, Total: len(files)})
+	if failed := i.processBatch(ctx, files); failed > 0 {
+		return fmt.Errorf("%d of %d files failed to index", failed, len(files))
+	}
+	return ctx.Err()
+}
+
+// indexPath rewrites p the way walking WorkingDir would spell it, so that it
+// matches stored paths whether WorkingDir is relative or absolute.
+func (i *Indexer) indexPath(p string) string {
+	absPath, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+	if filepath.IsAbs(i.WorkingDir) {
+		return absPath
+	}
+	absRoot, err := filepath.Abs(i.WorkingDir)
+	if err != nil {
+		return p
+	}
+	rel, err := filepath.Rel(absRoot, absPath)
+	if err != nil {
+		return p
+	}
+	return filepath.Join(i.WorkingDir, rel)
+}
+
+// walkIndexable calls visit for every regular, non-ignored file under root.
+func (i *Indexer) walkIndexable(ctx context.Context, root string, visit func(path string, info fs.FileInfo)) error {
+	var files []string
+	infos := make(map[string]fs.FileInfo)
+	err := filepath.Walk(root, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if i.isIgnored(path, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			files = append(files, path)
+			infos[path] = info
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	for _, path := range files {
+		visit(path, infos[path])
+	}
+	return nil
+}
+
+func fileHash(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return contentHash(content)
+}
+
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,93 @@
+package rag
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusPruneAndReindex(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	var calls atomic.Int64
+	store, err := NewStore(filepath.Join(t.TempDir(), "rag.db"))
+	require.NoError(t, err)
+	defer store.Close()
+	indexer := NewIndexer(store, NewOllamaEmbedder(newCountingEmbedServer(t, 8, &calls).URL, "test"), root)
+
+	a := writeIndexerFile(t, root, "a.md", "# A\n\nalpha")
+	b := writeIndexerFile(t, root, "docs/b.md", "# B\n\nbeta")
+	require.NoError(t, indexer.Sync(ctx))
+
+	status, err := indexer.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, status.Files)
+	assert.Equal(t, "ollama:test", status.IndexedWith)
+	assert.Equal(t, 8, status.Dimensions)
+	assert.NoError(t, status.EmbedderErr)
+	assert.Empty(t, status.Stale)
+	assert.Empty(t, status.Missing)
+
+	writeIndexerFile(t, root, "c.md", "# C\n\ngamma")
+	require.NoError(t, os.Remove(b))
+	status, err = indexer.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c.md"}, status.Stale)
+	assert.Equal(t, []string{"docs/b.md"}, status.Missing)
+
+	pruned, err := indexer.Prune(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docs/b.md"}, pruned)
+
+	// Reindexing a path re-embeds it even though its content is unchanged.
+	calls.Store(0)
+	require.NoError(t, indexer.Reindex(ctx, a))
+	assert.Equal(t, int64(1), calls.Load())
+
+	calls.Store(0)
+	require.NoError(t, indexer.Reindex(ctx))
+	assert.Equal(t, int64(2), calls.Load())
+	files, _, err := store.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, files)
+}
+
+func TestStatusLeavesOlderPathKeysAlone(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	var calls atomic.Int64
+	store, err := NewStore(filepath.Join(t.TempDir(), "rag.db"))
+	require.NoError(t, err)
+	defer store.Close()
+	abs := writeIndexerFile(t, root, "docs/a.md", "# A\n\nalpha")
+	require.NoError(t, NewIndexer(store, NewOllamaEmbedder(newCountingEmbedServer(t, 8, &calls).URL, "test"), root).Sync(ctx))
+
+	// An index written before relative keys stored walked paths.
+	require.NoError(t, store.RekeyFile(ctx, "docs/a.md", abs))
+	require.NoError(t, store.SetIndexMeta(ctx, metaPathKeys, ""))
+
+	indexer := NewIndexer(store, NewOllamaEmbedder(newCountingEmbedServer(t, 8, &calls).URL, "test"), root)
+	status, err := indexer.Status(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, status.Stale)
+	assert.Empty(t, status.Missing)
+	entries, err := store.ListManifest(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, abs, entries[0].Path)
+}
+
+func TestSelectChunksHonoursLimit(t *testing.T) {
+	ranked := []Chunk{
+		{ID: "a:0", Filepath: "a", Content: "x"},
+		{ID: "b:0", Filepath: "b", Content: "x"},
+		{ID: "c:0", Filepath: "c", Content: "x"},
+	}
+	got := selectChunks(ranked, QueryOptions{Limit: 2}.normalize())
+	assert.Len(t, got, 2)
+}
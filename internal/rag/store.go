@@ -8,11 +8,14 @@ import (
 	"fmt"
 	"math"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
 	sqlite_vec "github.com/asg017/sqlite-vec-go-bindings/cgo"
 	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/yubzen/orchestra/internal/migrate"
 )
 
 type Store struct {
@@ -20,6 +23,8 @@ type Store struct {
 
 	mu         sync.RWMutex
 	vecEnabled bool
+	vecDims    int
+	ftsEnabled bool
 }
 
 var ErrStoreNotReady = errors.New("rag store is not initialized")
@@ -27,7 +32,10 @@ var ErrStoreNotReady = errors.New("rag store is not initialized")
 const (
 	defaultSearchLimit       = 5
 	defaultDistanceThreshold = 0.85
-	sqliteVecDimensions      = 768
+	// legacyVecDimensions is the vec_chunks size used before dimensions
+	// were recorded in index_meta.
+	legacyVecDimensions = 768
+	metaVecDims         = "vec_dims"
 )
 
 var sqliteVecAutoOnce sync.Once
@@ -37,6 +45,22 @@ type Chunk struct {
 	Filepath   string
 	ChunkIndex int
 	Content    string
+	StartLine  int
+	EndLine    int
+	Symbol     string
+	// Score is the fused retrieval score set by Indexer.Query.
+	Score float64
+}
+
+// Location renders the chunk as path:start-end for citations.
+func (c Chunk) Location() string {
+	if c.StartLine <= 0 {
+		return c.Filepath
+	}
+	if c.EndLine <= c.StartLine {
+		return fmt.Sprintf("%s:%d", c.Filepath, c.StartLine)
+	}
+	return fmt.Sprintf("%s:%d-%d", c.Filepath, c.StartLine, c.EndLine)
 }
 
 func enableSQLiteVec() {
@@ -52,12 +76,31 @@ func (s *Store) isVecEnabled() bool {
 	return s.vecEnabled
 }
 
+// vecFits reports whether an embedding can go through sqlite-vec.
+func (s *Store) vecFits(embedding []float32) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.vecEnabled && len(embedding) == s.vecDims
+}
+
 func (s *Store) setVecEnabled(enabled bool) {
 	s.mu.Lock()
 	s.vecEnabled = enabled
 	s.mu.Unlock()
 }
 
+func (s *Store) isFTSEnabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ftsEnabled
+}
+
+func (s *Store) setFTSEnabled(enabled bool) {
+	s.mu.Lock()
+	s.ftsEnabled = enabled
+	s.mu.Unlock()
+}
+
 func NewStore(dbPath string) (*Store, error) {
 	enableSQLiteVec()
 
@@ -71,18 +114,7 @@ func NewStore(dbPath string) (*Store, error) {
 		return nil, fmt.Errorf("connect rag store %q: %w", dbPath, err)
 	}
 
-	schema := `
-	CREATE TABLE IF NOT EXISTS file_chunks (
-		rowid INTEGER PRIMARY KEY AUTOINCREMENT,
-		id TEXT UNIQUE,
-		filepath TEXT NOT NULL,
-		chunk_index INTEGER NOT NULL,
-		content TEXT NOT NULL,
-		embedding TEXT NOT NULL
-	);
-	CREATE INDEX IF NOT EXISTS idx_file_chunks_filepath ON file_chunks(filepath);
-	`
-	if _, err := db.Exec(schema); err != nil {
+	if _, err := migrate.Run(db, dbPath, migrations, 0); err != nil {
 		_ = db.Close()
 		return nil, fmt.Errorf("initialize rag schema: %w", err)
 	}
@@ -90,14 +122,80 @@ func NewStore(dbPath string) (*Store, error) {
 	store := &Store{db: db}
 
 	// Prefer sqlite-vec when available; keep fallback path if vec initialization fails.
-	vecSchema := fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS vec_chunks USING vec0(embedding float[%d]);", sqliteVecDimensions)
-	if _, err := db.Exec(vecSchema); err == nil {
-		store.setVecEnabled(true)
+	// The vector table is created once the embedder's size is known.
+	store.openVec(context.Background())
+	// FTS5 needs the sqlite_fts5 build tag; lexical search scans file_chunks
+	// without it. terms holds identifier parts (see lexicalTerms).
+	if _, err := db.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS fts_chunks USING fts5(content, symbol, filepath, terms);"); err == nil {
+		store.setFTSEnabled(true)
+		store.backfillFTS(context.Background())
 	}
 
 	return store, nil
 }
 
+func (s *Store) openVec(ctx context.Context) {
+	var version string
+	if err := s.db.QueryRowContext(ctx, "SELECT vec_version()").Scan(&version); err != nil {
+		return
+	}
+	dims := 0
+	if value, err := s.IndexMeta(ctx, metaVecDims); err == nil {
+		dims, _ = strconv.Atoi(value)
+	}
+	if dims <= 0 {
+		var exists int
+		if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE name = 'vec_chunks'").Scan(&exists); err != nil || exists == 0 {
+			return
+		}
+		dims = legacyVecDimensions
+	}
+	s.mu.Lock()
+	s.vecEnabled = true
+	s.vecDims = dims
+	s.mu.Unlock()
+}
+
+// ConfigureVectors sizes the sqlite-vec table for dims-wide embeddings,
+// recreating it when the size changed. Without sqlite-vec it is a no-op and
+// vector search scans file_chunks.
+func (s *Store) ConfigureVectors(ctx context.Context, dims int) error {
+	ctx = normalizeContext(ctx)
+	if err := s.EnsureReady(ctx); err != nil {
+		return err
+	}
+	if dims <= 0 {
+		return errors.New("vector dimensions must be positive")
+	}
+	s.mu.RLock()
+	current := s.vecEnabled && s.vecDims == dims
+	s.mu.RUnlock()
+	if current {
+		return nil
+	}
+	var version string
+	if err := s.db.QueryRowContext(ctx, "SELECT vec_version()").Scan(&version); err != nil {
+		return nil
+	}
+
+	if _, err := s.db.ExecContext(ctx, "DROP TABLE IF EXISTS vec_chunks"); err != nil {
+		return err
+	}
+	vecSchema := fmt.Sprintf("CREATE VIRTUAL TABLE vec_chunks USING vec0(embedding float[%d]);", dims)
+	if _, err := s.db.ExecContext(ctx, vecSchema); err != nil {
+		s.setVecEnabled(false)
+		return nil
+	}
+	if err := s.SetIndexMeta(ctx, metaVecDims, strconv.Itoa(dims)); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.vecEnabled = true
+	s.vecDims = dims
+	s.mu.Unlock()
+	return nil
+}
+
 func normalizeContext(ctx context.Context) context.Context {
 	if ctx == nil {
 		return context.Background()
@@ -135,32 +233,89 @@ func (s *Store) SaveChunk(ctx context.Context, chunk Chunk, embedding []float32)
 	if strings.TrimSpace(chunk.ID) == "" {
 		return errors.New("chunk id is required")
 	}
-	if len(embedding) == 0 {
-		return errors.New("embedding is required")
+	// An empty embedding stores the chunk for lexical search only.
+	if embedding == nil {
+		embedding = []float32{}
 	}
-
 	embJSON, err := json.Marshal(embedding)
 	if err != nil {
 		return fmt.Errorf("marshal embedding: %w", err)
 	}
+	s.deleteFTSRows(ctx, "id = ?", chunk.ID)
 
-	if s.isVecEnabled() && len(embedding) == sqliteVecDimensions {
+	if s.vecFits(embedding) {
 		if err := s.saveChunkWithVec(ctx, chunk, embedding, string(embJSON)); err == nil {
+			s.indexFTSChunk(ctx, chunk)
 			return nil
 		}
 		s.setVecEnabled(false)
 	}
 
 	_, err = s.db.ExecContext(ctx,
-		"INSERT OR REPLACE INTO file_chunks (id, filepath, chunk_index, content, embedding) VALUES (?, ?, ?, ?, ?)",
-		chunk.ID, chunk.Filepath, chunk.ChunkIndex, chunk.Content, string(embJSON),
+		"INSERT OR REPLACE INTO file_chunks (id, filepath, chunk_index, content, embedding, start_line, end_line, symbol) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		chunk.ID, chunk.Filepath, chunk.ChunkIndex, chunk.Content, string(embJSON), chunk.StartLine, chunk.EndLine, chunk.Symbol,
 	)
 	if err != nil {
 		return err
 	}
+	s.indexFTSChunk(ctx, chunk)
 	return nil
 }
 
+// deleteFTSRows drops the full-text rows of the file_chunks rows matching
+// where. FTS failures disable lexical indexing instead of failing writes.
+func (s *Store) deleteFTSRows(ctx context.Context, where string, arg any) {
+	if !s.isFTSEnabled() {
+		return
+	}
+	_, err := s.db.ExecContext(ctx, "DELETE FROM fts_chunks WHERE rowid IN (SELECT rowid FROM file_chunks WHERE "+where+")", arg)
+	if err != nil {
+		s.setFTSEnabled(false)
+	}
+}
+
+// backfillFTS indexes chunks stored before the full-text table existed.
+func (s *Store) backfillFTS(ctx context.Context) {
+	var indexed, stored int
+	if err := s.db.QueryRowContext(ctx, "SELECT (SELECT COUNT(*) FROM fts_chunks), (SELECT COUNT(*) FROM file_chunks)").Scan(&indexed, &stored); err != nil {
+		s.setFTSEnabled(false)
+		return
+	}
+	if indexed > 0 || stored == 0 {
+		return
+	}
+	rows, err := s.db.QueryContext(ctx, "SELECT id, content, symbol FROM file_chunks")
+	if err != nil {
+		s.setFTSEnabled(false)
+		return
+	}
+	var chunks []Chunk
+	for rows.Next() {
+		var c Chunk
+		if err := rows.Scan(&c.ID, &c.Content, &c.Symbol); err != nil {
+			break
+		}
+		chunks = append(chunks, c)
+	}
+	rows.Close()
+	for _, chunk := range chunks {
+		s.indexFTSChunk(ctx, chunk)
+	}
+}
+
+func (s *Store) indexFTSChunk(ctx context.Context, chunk Chunk) {
+	if !s.isFTSEnabled() {
+		return
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO fts_chunks (rowid, content, symbol, filepath, terms)
+		SELECT rowid, content, symbol, filepath, ? FROM file_chunks WHERE id = ?
+	`, strings.Join(lexicalTerms(chunk.Content+" "+chunk.Symbol), " "), chunk.ID)
+	if err != nil {
+		s.setFTSEnabled(false)
+	}
+}
+
 func (s *Store) saveChunkWithVec(ctx context.Context, chunk Chunk, embedding []float32, embJSON string) error {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -180,8 +335,8 @@ func (s *Store) saveChunkWithVec(ctx context.Context, chunk Chunk, embedding []f
 	}
 
 	res, err := tx.ExecContext(ctx,
-		"INSERT INTO file_chunks (id, filepath, chunk_index, content, embedding) VALUES (?, ?, ?, ?, ?)",
-		chunk.ID, chunk.Filepath, chunk.ChunkIndex, chunk.Content, embJSON,
+		"INSERT INTO file_chunks (id, filepath, chunk_index, content, embedding, start_line, end_line, symbol) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		chunk.ID, chunk.Filepath, chunk.ChunkIndex, chunk.Content, embJSON, chunk.StartLine, chunk.EndLine, chunk.Symbol,
 	)
 	if err != nil {
 		return err
@@ -210,6 +365,12 @@ func (s *Store) ClearFile(ctx context.Context, filepath string) error {
 		return err
 	}
 
+	// Dropping the manifest row first means an interrupted clear is redone
+	// on the next start.
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM file_manifest WHERE filepath = ?", filepath); err != nil {
+		return err
+	}
+	s.deleteFTSRows(ctx, "filepath = ?", filepath)
 	if s.isVecEnabled() {
 		if err := s.clearFileWithVec(ctx, filepath); err == nil {
 			return nil
@@ -258,7 +419,7 @@ func (s *Store) Search(ctx context.Context, embedding []float32, limit int) ([]C
 		limit = defaultSearchLimit
 	}
 
-	if s.isVecEnabled() && len(embedding) == sqliteVecDimensions {
+	if s.vecFits(embedding) {
 		if chunks, err := s.searchWithVec(ctx, embedding, limit); err == nil {
 			return chunks, nil
 		}
@@ -268,6 +429,120 @@ func (s *Store) Search(ctx context.Context, embedding []float32, limit int) ([]C
 	return s.searchFallback(ctx, embedding, limit)
 }
 
+// SearchLexical ranks chunks by BM25 over identifiers and words in the
+// query, using FTS5 when available.
+func (s *Store) SearchLexical(ctx context.Context, query string, limit int) ([]Chunk, error) {
+	ctx = normalizeContext(ctx)
+
+	if err := s.EnsureReady(ctx); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	terms := uniqueTerms(lexicalTerms(query))
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	if s.isFTSEnabled() {
+		if chunks, err := s.searchFTS(ctx, terms, limit); err == nil {
+			return chunks, nil
+		}
+		s.setFTSEnabled(false)
+	}
+	return s.searchLexicalFallback(ctx, terms, limit)
+}
+
+func (s *Store) searchFTS(ctx context.Context, terms []string, limit int) ([]Chunk, error) {
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		quoted = append(quoted, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT f.id, f.filepath, f.chunk_index, f.content, f.start_line, f.end_line, f.symbol
+		FROM fts_chunks
+		JOIN file_chunks f ON f.rowid = fts_chunks.rowid
+		WHERE fts_chunks MATCH ?
+		ORDER BY bm25(fts_chunks) ASC
+		LIMIT ?
+	`, strings.Join(quoted, " OR "), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chunks := make([]Chunk, 0, limit)
+	for rows.Next() {
+		var c Chunk
+		if err := rows.Scan(&c.ID, &c.Filepath, &c.ChunkIndex, &c.Content, &c.StartLine, &c.EndLine, &c.Symbol); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+func (s *Store) searchLexicalFallback(ctx context.Context, terms []string, limit int) ([]Chunk, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, filepath, chunk_index, content, start_line, end_line, symbol FROM file_chunks")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chunks []Chunk
+	var docs [][]string
+	for rows.Next() {
+		var c Chunk
+		if err := rows.Scan(&c.ID, &c.Filepath, &c.ChunkIndex, &c.Content, &c.StartLine, &c.EndLine, &c.Symbol); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, c)
+		docs = append(docs, lexicalTerms(c.Content+" "+c.Symbol+" "+c.Filepath))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ranker := newBM25Ranker(docs)
+	type scoredChunk struct {
+		chunk Chunk
+		score float64
+	}
+	var scored []scoredChunk
+	for idx, chunk := range chunks {
+		if score := ranker.score(idx, terms); score > 0 {
+			scored = append(scored, scoredChunk{chunk: chunk, score: score})
+		}
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+	if len(scored) > limit {
+		scored = scored[:limit]
+	}
+	out := make([]Chunk, 0, len(scored))
+	for _, item := range scored {
+		out = append(out, item.chunk)
+	}
+	return out, nil
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	out := terms[:0]
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			out = append(out, term)
+		}
+	}
+	return out
+}
+
 func (s *Store) searchWithVec(ctx context.Context, embedding []float32, limit int) ([]Chunk, error) {
 	vecBlob, err := sqlite_vec.SerializeFloat32(embedding)
 	if err != nil {
@@ -275,13 +550,16 @@ func (s *Store) searchWithVec(ctx context.Context, embedding []float32, limit in
 	}
 
 	query := `
-	SELECT id, filepath, chunk_index, content
+	SELECT id, filepath, chunk_index, content, start_line, end_line, symbol
 	FROM (
 		SELECT
 			f.id AS id,
 			f.filepath AS filepath,
 			f.chunk_index AS chunk_index,
 			f.content AS content,
+			f.start_line AS start_line,
+			f.end_line AS end_line,
+			f.symbol AS symbol,
 			distance AS distance
 		FROM vec_chunks
 		JOIN file_chunks f ON f.rowid = vec_chunks.rowid
@@ -301,7 +579,7 @@ func (s *Store) searchWithVec(ctx context.Context, embedding []float32, limit in
 	chunks := make([]Chunk, 0, limit)
 	for rows.Next() {
 		var c Chunk
-		if err := rows.Scan(&c.ID, &c.Filepath, &c.ChunkIndex, &c.Content); err != nil {
+		if err := rows.Scan(&c.ID, &c.Filepath, &c.ChunkIndex, &c.Content, &c.StartLine, &c.EndLine, &c.Symbol); err != nil {
 			return nil, err
 		}
 		chunks = append(chunks, c)
@@ -313,7 +591,7 @@ func (s *Store) searchWithVec(ctx context.Context, embedding []float32, limit in
 }
 
 func (s *Store) searchFallback(ctx context.Context, embedding []float32, limit int) ([]Chunk, error) {
-	rows, err := s.db.QueryContext(ctx, "SELECT id, filepath, chunk_index, content, embedding FROM file_chunks")
+	rows, err := s.db.QueryContext(ctx, "SELECT id, filepath, chunk_index, content, start_line, end_line, symbol, embedding FROM file_chunks")
 	if err != nil {
 		return nil, err
 	}
@@ -327,7 +605,7 @@ func (s *Store) searchFallback(ctx context.Context, embedding []float32, limit i
 	for rows.Next() {
 		var c Chunk
 		var embeddingJSON string
-		if err := rows.Scan(&c.ID, &c.Filepath, &c.ChunkIndex, &c.Content, &embeddingJSON); err != nil {
+		if err := rows.Scan(&c.ID, &c.Filepath, &c.ChunkIndex, &c.Content, &c.StartLine, &c.EndLine, &c.Symbol, &embeddingJSON); err != nil {
 			return nil, err
 		}
 		var candidate []float32
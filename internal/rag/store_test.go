@@ -2,6 +2,7 @@ package rag
 
 import (
 	"context"
+	"database/sql"
 	"path/filepath"
 	"testing"
 
@@ -92,3 +93,66 @@ func TestSQLiteVecIsRegistered(t *testing.T) {
 	require.NoError(t, err)
 	assert.NotEmpty(t, vecVersion)
 }
+
+func TestStoreKeepsChunkLinesAndSymbolOnLegacyDB(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "rag.db")
+	legacy, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`CREATE TABLE file_chunks (
+		rowid INTEGER PRIMARY KEY AUTOINCREMENT,
+		id TEXT UNIQUE,
+		filepath TEXT NOT NULL,
+		chunk_index INTEGER NOT NULL,
+		content TEXT NOT NULL,
+		embedding TEXT NOT NULL
+	)`)
+	require.NoError(t, err)
+	require.NoError(t, legacy.Close())
+
+	store, err := NewStore(dbPath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+
+	ctx := context.Background()
+	require.NoError(t, store.SaveChunk(ctx, Chunk{
+		ID:        "a.go:0",
+		Filepath:  "a.go",
+		Content:   "func A() {}",
+		StartLine: 3,
+		EndLine:   5,
+		Symbol:    "A",
+	}, []float32{1, 0, 0}))
+
+	matches, err := store.Search(ctx, []float32{1, 0, 0}, 5)
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "A", matches[0].Symbol)
+	assert.Equal(t, "a.go:3-5", matches[0].Location())
+}
+
+func TestConfigureVectorsUsesEmbedderDimensions(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "rag.db")
+	store, err := NewStore(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, store.ConfigureVectors(ctx, 4))
+	if !store.isVecEnabled() {
+		t.Skip("sqlite-vec is not available")
+	}
+	assert.True(t, store.vecFits([]float32{1, 0, 0, 0}))
+	assert.False(t, store.vecFits(make([]float32, legacyVecDimensions)))
+
+	require.NoError(t, store.SaveChunk(ctx, Chunk{ID: "a.go:0", Filepath: "a.go", Content: "alpha"}, []float32{1, 0, 0, 0}))
+	found, err := store.Search(ctx, []float32{1, 0, 0, 0}, 1)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.NoError(t, store.Close())
+
+	// The size survives a reopen.
+	store, err = NewStore(dbPath)
+	require.NoError(t, err)
+	defer store.Close()
+	assert.True(t, store.vecFits([]float32{1, 0, 0, 0}))
+}
@@ -0,0 +1,467 @@
+package rag
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// symbolFormatVersion is bumped when extraction changes, so that existing
+// indexes re-extract every package once.
+const symbolFormatVersion = "1"
+
+const metaSymbolsVersion = "symbols_version"
+
+// Symbol is a top-level declaration. Methods carry their receiver type.
+type Symbol struct {
+	Name        string
+	Recv        string
+	Kind        string
+	Package     string
+	PackageName string
+	File        string
+	Line        int
+	EndLine     int
+	Exported    bool
+	Signature   string
+}
+
+// QualifiedName is Recv.Name for methods and Name otherwise.
+func (s Symbol) QualifiedName() string {
+	if s.Recv != "" {
+		return s.Recv + "." + s.Name
+	}
+	return s.Name
+}
+
+// SymbolRef is a use of a symbol. Target is the resolved "pkgpath.Name" or
+// "pkgpath.Recv.Name"; it is empty when the extractor only knows the name.
+type SymbolRef struct {
+	Name   string
+	Target string
+	File   string
+	Line   int
+	Caller string
+}
+
+// FileSymbols is what an extractor found in one file.
+type FileSymbols struct {
+	Package     string
+	PackageName string
+	Symbols     []Symbol
+	Refs        []SymbolRef
+	Imports     []string
+}
+
+// SymbolExtractor extracts symbols from the files of one directory, keyed by
+// path. pkgPath identifies the package (for Go, its import path); Go needs
+// every file of a package to resolve references between them.
+type SymbolExtractor func(pkgPath string, files map[string][]byte) (map[string]FileSymbols, error)
+
+var (
+	symbolExtractorsMu sync.RWMutex
+	symbolExtractors   = map[string]SymbolExtractor{
+		"go": extractGoSymbols,
+	}
+	symbolExtensions = map[string]string{
+		".go": "go",
+	}
+)
+
+// RegisterSymbolExtractor adds or replaces the extractor for a language and
+// maps the given file extensions to it.
+func RegisterSymbolExtractor(language string, extractor SymbolExtractor, extensions ...string) {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language == "" || extractor == nil {
+		return
+	}
+	symbolExtractorsMu.Lock()
+	defer symbolExtractorsMu.Unlock()
+	symbolExtractors[language] = extractor
+	for _, ext := range extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		symbolExtensions[ext] = language
+	}
+}
+
+func symbolLanguage(path string) string {
+	symbolExtractorsMu.RLock()
+	defer symbolExtractorsMu.RUnlock()
+	return symbolExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// ExtractSymbols runs the registered extractors over files that all live in
+// one directory. pkgPath is passed through to the extractors.
+func ExtractSymbols(pkgPath string, files map[string][]byte) map[string]FileSymbols {
+	byLanguage := make(map[string]map[string][]byte)
+	for path, content := range files {
+		lang := symbolLanguage(path)
+		if lang == "" {
+			continue
+		}
+		if byLanguage[lang] == nil {
+			byLanguage[lang] = make(map[string][]byte)
+		}
+		byLanguage[lang][path] = content
+	}
+	out := make(map[string]FileSymbols)
+	for lang, group := range byLanguage {
+		symbolExtractorsMu.RLock()
+		extractor := symbolExtractors[lang]
+		symbolExtractorsMu.RUnlock()
+		extracted, err := extractor(pkgPath, group)
+		if err != nil {
+			continue
+		}
+		for path, symbols := range extracted {
+			out[path] = symbols
+		}
+	}
+	return out
+}
+
+// GoModulePath reads the module path from root/go.mod, or returns "".
+func GoModulePath(root string) string {
+	f, err := os.Open(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if rest, ok := strings.CutPrefix(line, "module"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			return strings.Trim(strings.TrimSpace(rest), `"`)
+		}
+	}
+	return ""
+}
+
+// packagePath names the package in dir (relative to the project root, slash
+// separated) after the module path when there is one.
+func packagePath(module, relDir string) string {
+	relDir = strings.Trim(path.Clean(filepath.ToSlash(relDir)), "/")
+	if relDir == "." {
+		relDir = ""
+	}
+	switch {
+	case module == "":
+		if relDir == "" {
+			return "."
+		}
+		return relDir
+	case relDir == "":
+		return module
+	default:
+		return module + "/" + relDir
+	}
+}
+
+// ReplaceSymbols swaps the stored symbols, references and imports of every
+// file in dir for the given ones.
+func (s *Store) ReplaceSymbols(ctx context.Context, dir string, files map[string]FileSymbols) error {
+	ctx = normalizeContext(ctx)
+	if err := s.EnsureReady(ctx); err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, table := range []string{"symbols", "symbol_refs", "symbol_imports"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE dir = ?", dir); err != nil {
+			return err
+		}
+	}
+	for file, fileSymbols := range files {
+		for _, sym := range fileSymbols.Symbols {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO symbols (dir, filepath, package, package_name, name, recv, kind, line, end_line, exported, signature)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`, dir, file, fileSymbols.Package, fileSymbols.PackageName, sym.Name, sym.Recv, sym.Kind, sym.Line, sym.EndLine, sym.Exported, sym.Signature); err != nil {
+				return err
+			}
+		}
+		for _, ref := range fileSymbols.Refs {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO symbol_refs (dir, filepath, line, name, target, caller) VALUES (?, ?, ?, ?, ?, ?)
+			`, dir, file, ref.Line, ref.Name, ref.Target, ref.Caller); err != nil {
+				return err
+			}
+		}
+		for _, imp := range fileSymbols.Imports {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO symbol_imports (dir, filepath, package, import_path) VALUES (?, ?, ?, ?)
+			`, dir, file, fileSymbols.Package, imp); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *Store) querySymbols(ctx context.Context, where string, args ...any) ([]Symbol, error) {
+	ctx = normalizeContext(ctx)
+	if err := s.EnsureReady(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT name, recv, kind, package, package_name, filepath, line, end_line, exported, signature
+		FROM symbols WHERE `+where+` ORDER BY filepath, line LIMIT 200`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Symbol
+	for rows.Next() {
+		var sym Symbol
+		if err := rows.Scan(&sym.Name, &sym.Recv, &sym.Kind, &sym.Package, &sym.PackageName, &sym.File, &sym.Line, &sym.EndLine, &sym.Exported, &sym.Signature); err != nil {
+			return nil, err
+		}
+		out = append(out, sym)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) queryRefs(ctx context.Context, where string, args ...any) ([]SymbolRef, error) {
+	ctx = normalizeContext(ctx)
+	if err := s.EnsureReady(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT name, target, filepath, line, caller
+		FROM symbol_refs WHERE `+where+` ORDER BY filepath, line LIMIT 500`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []SymbolRef
+	for rows.Next() {
+		var ref SymbolRef
+		if err := rows.Scan(&ref.Name, &ref.Target, &ref.File, &ref.Line, &ref.Caller); err != nil {
+			return nil, err
+		}
+		out = append(out, ref)
+	}
+	return out, rows.Err()
+}
+
+// splitQualified splits "pkg.Recv.Name" style queries into the qualifier and
+// the bare name.
+func splitQualified(name string) (string, string) {
+	name = strings.TrimSpace(name)
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		return name[:idx], name[idx+1:]
+	}
+	return "", name
+}
+
+// FindSymbols looks up definitions by name. The name may be qualified by a
+// receiver type ("Indexer.Query") or a package name or path ("rag.NewStore").
+// Without exact matches it falls back to a case-insensitive substring match.
+func (i *Indexer) FindSymbols(ctx context.Context, name string) ([]Symbol, error) {
+	if i == nil {
+		return nil, ErrIndexerNotReady
+	}
+	qualifier, base := splitQualified(name)
+	if base == "" {
+		return nil, fmt.Errorf("symbol name is required")
+	}
+	symbols, err := i.Store.querySymbols(ctx, "name = ?", base)
+	if err != nil {
+		return nil, err
+	}
+	if qualifier != "" {
+		filtered := symbols[:0]
+		for _, sym := range symbols {
+			if sym.Recv == qualifier || sym.PackageName == qualifier || sym.Package == qualifier || strings.HasSuffix(sym.Package, "/"+qualifier) {
+				filtered = append(filtered, sym)
+			}
+		}
+		symbols = filtered
+	}
+	if len(symbols) == 0 {
+		symbols, err = i.Store.querySymbols(ctx, "name LIKE ? ESCAPE '\\'", "%"+escapeLike(base)+"%")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return symbols, nil
+}
+
+// FindReferences lists uses of a symbol, qualified the same way as in
+// FindSymbols. Unqualified queries also return references whose target
+// could not be resolved.
+func (i *Indexer) FindReferences(ctx context.Context, name string) ([]SymbolRef, error) {
+	if i == nil {
+		return nil, ErrIndexerNotReady
+	}
+	qualifier, base := splitQualified(name)
+	if base == "" {
+		return nil, fmt.Errorf("symbol name is required")
+	}
+	refs, err := i.Store.queryRefs(ctx, "name = ?", base)
+	if err != nil {
+		return nil, err
+	}
+	filtered := refs[:0]
+	for _, ref := range refs {
+		switch {
+		case qualifier == "":
+			filtered = append(filtered, ref)
+		case ref.Target == "":
+		case strings.HasSuffix(ref.Target, "/"+qualifier+"."+base),
+			strings.HasSuffix(ref.Target, "."+qualifier+"."+base),
+			ref.Target == qualifier+"."+base:
+			filtered = append(filtered, ref)
+		}
+	}
+	return filtered, nil
+}
+
+// PackageInfo summarizes one package of the symbol graph.
+type PackageInfo struct {
+	Dir        string
+	Path       string
+	Name       string
+	Files      []string
+	Symbols    []Symbol
+	Imports    []string
+	ImportedBy []string
+}
+
+// ListPackage describes the package in dir, given relative to the working
+// directory or as an import path.
+func (i *Indexer) ListPackage(ctx context.Context, dir string) (PackageInfo, error) {
+	if i == nil {
+		return PackageInfo{}, ErrIndexerNotReady
+	}
+	module := GoModulePath(i.WorkingDir)
+	dir = strings.Trim(filepath.ToSlash(strings.TrimSpace(dir)), "/")
+	if module != "" && (dir == module || strings.HasPrefix(dir, module+"/")) {
+		dir = strings.TrimPrefix(strings.TrimPrefix(dir, module), "/")
+	}
+	if dir == "" {
+		dir = "."
+	}
+	symbols, err := i.Store.querySymbols(ctx, "dir = ?", dir)
+	if err != nil {
+		return PackageInfo{}, err
+	}
+	info := PackageInfo{Dir: dir, Path: packagePath(module, dir)}
+	files := make(map[string]bool)
+	for idx := range symbols {
+		files[symbols[idx].File] = true
+		if info.Name == "" || !strings.HasSuffix(symbols[idx].PackageName, "_test") {
+			info.Name = symbols[idx].PackageName
+		}
+	}
+	info.Symbols = symbols
+
+	rows, err := i.Store.db.QueryContext(normalizeContext(ctx), "SELECT DISTINCT filepath, import_path FROM symbol_imports WHERE dir = ? ORDER BY import_path", dir)
+	if err != nil {
+		return PackageInfo{}, err
+	}
+	imports := make(map[string]bool)
+	for rows.Next() {
+		var file, imp string
+		if err := rows.Scan(&file, &imp); err != nil {
+			rows.Close()
+			return PackageInfo{}, err
+		}
+		files[file] = true
+		imports[imp] = true
+	}
+	rows.Close()
+	info.Imports = sortedKeys(imports)
+	info.Files = sortedKeys(files)
+	if len(info.Files) == 0 {
+		return PackageInfo{}, fmt.Errorf("no indexed package in %s", dir)
+	}
+
+	rows, err = i.Store.db.QueryContext(normalizeContext(ctx), "SELECT DISTINCT dir FROM symbol_imports WHERE import_path = ? ORDER BY dir", info.Path)
+	if err != nil {
+		return PackageInfo{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var importer string
+		if err := rows.Scan(&importer); err != nil {
+			return PackageInfo{}, err
+		}
+		info.ImportedBy = append(info.ImportedBy, importer)
+	}
+	return info, rows.Err()
+}
+
+// indexSymbols re-extracts the symbols of every directory in dirs.
+func (i *Indexer) indexSymbols(ctx context.Context, dirs map[string]bool) error {
+	module := GoModulePath(i.WorkingDir)
+	for _, dir := range sortedKeys(dirs) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		files := make(map[string][]byte)
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			p := filepath.Join(dir, entry.Name())
+			if !entry.Type().IsRegular() || symbolLanguage(p) == "" {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil || i.isIgnored(p, info) {
+				continue
+			}
+			if content, err := os.ReadFile(p); err == nil {
+				files[p] = content
+			}
+		}
+		relDir := i.indexKey(dir)
+		extracted := ExtractSymbols(packagePath(module, relDir), files)
+		keyed := make(map[string]FileSymbols, len(extracted))
+		for path, fileSymbols := range extracted {
+			keyed[i.indexKey(path)] = fileSymbols
+		}
+		if err := i.Store.ReplaceSymbols(ctx, relDir, keyed); err != nil {
+			return fmt.Errorf("index symbols in %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// symbolDirs returns the directories of files that have a symbol extractor.
+func symbolDirs(files []string) map[string]bool {
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		if symbolLanguage(file) != "" {
+			dirs[filepath.Dir(file)] = true
+		}
+	}
+	return dirs
+}
+
+func sortedKeys(set map[string]bool) []string {
+	out := make([]string, 0, len(set))
+	for key := range set {
+		out = append(out, key)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,301 @@
+package rag
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maxSignatureLen = 160
+
+// extractGoSymbols type-checks the files of one Go package directory. Imports
+// resolve to empty stub packages, so uses inside the package are resolved
+// through go/types while selectors on imported packages resolve through the
+// import path. Files that do not parse are skipped.
+func extractGoSymbols(pkgPath string, files map[string][]byte) (map[string]FileSymbols, error) {
+	fset := token.NewFileSet()
+	groups := make(map[string][]*ast.File)
+	names := make(map[*ast.File]string)
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		file, err := parser.ParseFile(fset, p, files[p], parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		groups[file.Name.Name] = append(groups[file.Name.Name], file)
+		names[file] = p
+	}
+
+	out := make(map[string]FileSymbols)
+	for pkgName, astFiles := range groups {
+		groupPath := pkgPath
+		// External test packages share the directory but not the package.
+		if strings.HasSuffix(pkgName, "_test") && len(groups) > 1 {
+			groupPath += "_test"
+		}
+		info := &types.Info{
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		}
+		conf := types.Config{
+			Importer:    stubImporter{},
+			FakeImportC: true,
+			Error:       func(error) {},
+		}
+		pkg, _ := conf.Check(groupPath, fset, astFiles, info)
+		for _, file := range astFiles {
+			out[names[file]] = goFileSymbols(fset, file, pkg, info, groupPath)
+		}
+	}
+	return out, nil
+}
+
+func goFileSymbols(fset *token.FileSet, file *ast.File, pkg *types.Package, info *types.Info, pkgPath string) FileSymbols {
+	result := FileSymbols{Package: pkgPath, PackageName: file.Name.Name}
+
+	imports := make(map[string]string)
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		result.Imports = append(result.Imports, importPath)
+		name := guessPackageName(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = importPath
+	}
+
+	for _, decl := range file.Decls {
+		caller := ""
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			sym := Symbol{
+				Name:      d.Name.Name,
+				Kind:      "func",
+				Recv:      goFuncReceiver(d),
+				Signature: goFuncSignature(fset, d),
+				Exported:  d.Name.IsExported(),
+			}
+			if sym.Recv != "" {
+				sym.Kind = "method"
+			}
+			result.Symbols = append(result.Symbols, withPosition(fset, sym, d))
+			caller = sym.QualifiedName()
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				for _, sym := range goSpecSymbols(fset, d.Tok, spec) {
+					result.Symbols = append(result.Symbols, sym)
+					if caller == "" {
+						caller = sym.Name
+					}
+				}
+			}
+		}
+		result.Refs = append(result.Refs, goDeclRefs(fset, decl, caller, pkg, info, imports, pkgPath)...)
+	}
+	for idx := range result.Symbols {
+		result.Symbols[idx].Package = pkgPath
+		result.Symbols[idx].PackageName = file.Name.Name
+		result.Symbols[idx].File = fset.Position(file.Pos()).Filename
+	}
+	for idx := range result.Refs {
+		result.Refs[idx].File = fset.Position(file.Pos()).Filename
+	}
+	return result
+}
+
+func withPosition(fset *token.FileSet, sym Symbol, node ast.Node) Symbol {
+	sym.Line = fset.Position(node.Pos()).Line
+	sym.EndLine = fset.Position(node.End()).Line
+	return sym
+}
+
+func goSpecSymbols(fset *token.FileSet, tok token.Token, spec ast.Spec) []Symbol {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		signature := "type " + s.Name.Name
+		switch s.Type.(type) {
+		case *ast.StructType:
+			signature += " struct"
+		case *ast.InterfaceType:
+			signature += " interface"
+		default:
+			signature = "type " + goNodeString(fset, s)
+		}
+		return []Symbol{withPosition(fset, Symbol{
+			Name:      s.Name.Name,
+			Kind:      "type",
+			Signature: shortSignature(signature),
+			Exported:  s.Name.IsExported(),
+		}, s)}
+	case *ast.ValueSpec:
+		kind := "var"
+		if tok == token.CONST {
+			kind = "const"
+		}
+		var out []Symbol
+		for _, name := range s.Names {
+			if name.Name == "_" {
+				continue
+			}
+			out = append(out, withPosition(fset, Symbol{
+				Name:      name.Name,
+				Kind:      kind,
+				Signature: shortSignature(kind + " " + goNodeString(fset, s)),
+				Exported:  name.IsExported(),
+			}, s))
+		}
+		return out
+	}
+	return nil
+}
+
+// goDeclRefs records uses of package-level objects and of selectors on
+// imported packages within one top-level declaration.
+func goDeclRefs(fset *token.FileSet, decl ast.Decl, caller string, pkg *types.Package, info *types.Info, imports map[string]string, pkgPath string) []SymbolRef {
+	var refs []SymbolRef
+	seen := make(map[string]bool)
+	add := func(pos token.Pos, name, target string) {
+		ref := SymbolRef{Name: name, Target: target, Line: fset.Position(pos).Line, Caller: caller}
+		key := strconv.Itoa(ref.Line) + "\x00" + name + "\x00" + target
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		refs = append(refs, ref)
+	}
+
+	ast.Inspect(decl, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.SelectorExpr:
+			if x, ok := n.X.(*ast.Ident); ok {
+				if importPath, ok := goImportedName(x, info, imports); ok {
+					add(n.Sel.Pos(), n.Sel.Name, importPath+"."+n.Sel.Name)
+					return false
+				}
+			}
+			if sel, ok := info.Selections[n]; ok {
+				if fn, ok := sel.Obj().(*types.Func); ok && fn.Pkg() == pkg {
+					if recv := goRecvTypeName(fn); recv != "" {
+						add(n.Sel.Pos(), n.Sel.Name, pkgPath+"."+recv+"."+n.Sel.Name)
+					}
+				}
+				return true
+			}
+			// Methods on types from other packages cannot be resolved
+			// without their source; keep the name so lookups still work.
+			if n.Sel.IsExported() && info.Uses[n.Sel] == nil {
+				add(n.Sel.Pos(), n.Sel.Name, "")
+			}
+		case *ast.Ident:
+			obj := info.Uses[n]
+			if obj == nil || pkg == nil || obj.Pkg() != pkg || obj.Parent() != pkg.Scope() {
+				return true
+			}
+			add(n.Pos(), n.Name, pkgPath+"."+n.Name)
+		}
+		return true
+	})
+	return refs
+}
+
+func goImportedName(ident *ast.Ident, info *types.Info, imports map[string]string) (string, bool) {
+	if obj, ok := info.Uses[ident].(*types.PkgName); ok {
+		return obj.Imported().Path(), true
+	}
+	if info.Uses[ident] != nil {
+		return "", false
+	}
+	importPath, ok := imports[ident.Name]
+	return importPath, ok
+}
+
+func goRecvTypeName(fn *types.Func) string {
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok || sig.Recv() == nil {
+		return ""
+	}
+	typ := sig.Recv().Type()
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	if named, ok := typ.(*types.Named); ok {
+		return named.Obj().Name()
+	}
+	return ""
+}
+
+func goFuncReceiver(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return ""
+	}
+	return goReceiverName(fn.Recv.List[0].Type)
+}
+
+func goFuncSignature(fset *token.FileSet, fn *ast.FuncDecl) string {
+	header := *fn
+	header.Body = nil
+	header.Doc = nil
+	return shortSignature(goNodeString(fset, &header))
+}
+
+func goNodeString(fset *token.FileSet, node ast.Node) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, node); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+var whitespaceRun = regexp.MustCompile(`\s+`)
+
+func shortSignature(signature string) string {
+	signature = strings.TrimSpace(whitespaceRun.ReplaceAllString(signature, " "))
+	if len(signature) > maxSignatureLen {
+		signature = signature[:maxSignatureLen-3] + "..."
+	}
+	return signature
+}
+
+var majorVersionSuffix = regexp.MustCompile(`^v[0-9]+$`)
+
+// guessPackageName derives the conventional package name from an import
+// path, e.g. "github.com/mattn/go-sqlite3" is "sqlite3" and
+// "gopkg.in/yaml.v3" is "yaml".
+func guessPackageName(importPath string) string {
+	name := path.Base(importPath)
+	if majorVersionSuffix.MatchString(name) {
+		name = path.Base(path.Dir(importPath))
+	}
+	if idx := strings.Index(name, ".v"); idx > 0 {
+		name = name[:idx]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	name = strings.TrimSuffix(name, "-go")
+	return strings.NewReplacer("-", "_", ".", "_").Replace(name)
+}
+
+// stubImporter resolves every import to an empty package, so type checking
+// needs neither the module cache nor compiled export data.
+type stubImporter struct{}
+
+func (stubImporter) Import(importPath string) (*types.Package, error) {
+	pkg := types.NewPackage(importPath, guessPackageName(importPath))
+	pkg.MarkComplete()
+	return pkg, nil
+}
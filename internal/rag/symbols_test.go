@@ -0,0 +1,132 @@
+package rag
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const symbolFixtureStore = `package store
+
+import "strings"
+
+type Store struct{ name string }
+
+func New(name string) *Store {
+	return &Store{name: strings.TrimSpace(name)}
+}
+
+func (s *Store) Name() string { return s.name }
+
+func (s *Store) Greeting() string { return "hello " + s.Name() }
+`
+
+const symbolFixtureMain = `package main
+
+import (
+	"fmt"
+
+	"example.com/app/store"
+)
+
+func main() {
+	s := store.New("demo")
+	fmt.Println(s.Greeting())
+}
+`
+
+func TestExtractGoSymbolsResolvesLocalAndImportedUses(t *testing.T) {
+	extracted := ExtractSymbols("example.com/app/store", map[string][]byte{
+		"store/store.go": []byte(symbolFixtureStore),
+	})
+	fileSymbols := extracted["store/store.go"]
+	assert.Equal(t, "store", fileSymbols.PackageName)
+	assert.Equal(t, []string{"strings"}, fileSymbols.Imports)
+
+	var names []string
+	for _, sym := range fileSymbols.Symbols {
+		names = append(names, sym.Kind+" "+sym.QualifiedName())
+	}
+	assert.Equal(t, []string{"type Store", "func New", "method Store.Name", "method Store.Greeting"}, names)
+	assert.Equal(t, "func New(name string) *Store", fileSymbols.Symbols[1].Signature)
+
+	targets := make(map[string]string)
+	for _, ref := range fileSymbols.Refs {
+		targets[ref.Target] = ref.Caller
+	}
+	assert.Equal(t, "New", targets["strings.TrimSpace"])
+	assert.Equal(t, "Store.Greeting", targets["example.com/app/store.Store.Name"])
+	assert.Contains(t, targets, "example.com/app/store.Store")
+}
+
+func TestIndexerSymbolQueries(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	store, err := NewStore(filepath.Join(t.TempDir(), "rag.db"))
+	require.NoError(t, err)
+	defer store.Close()
+	indexer := NewIndexer(store, NewHashingEmbedder(16), root)
+
+	writeIndexerFile(t, root, "go.mod", "module example.com/app\n")
+	writeIndexerFile(t, root, "store/store.go", symbolFixtureStore)
+	mainPath := writeIndexerFile(t, root, "main.go", symbolFixtureMain)
+	require.NoError(t, indexer.Sync(ctx))
+
+	symbols, err := indexer.FindSymbols(ctx, "store.New")
+	require.NoError(t, err)
+	require.Len(t, symbols, 1)
+	assert.Equal(t, "store/store.go", symbols[0].File)
+	assert.Equal(t, 7, symbols[0].Line)
+
+	refs, err := indexer.FindReferences(ctx, "store.New")
+	require.NoError(t, err)
+	require.Len(t, refs, 1)
+	assert.Equal(t, "main.go", refs[0].File)
+	assert.Equal(t, "main", refs[0].Caller)
+
+	refs, err = indexer.FindReferences(ctx, "Store.Name")
+	require.NoError(t, err)
+	require.Len(t, refs, 1)
+	assert.Equal(t, "Store.Greeting", refs[0].Caller)
+
+	pkg, err := indexer.ListPackage(ctx, "store")
+	require.NoError(t, err)
+	assert.Equal(t, "example.com/app/store", pkg.Path)
+	assert.Equal(t, []string{"store/store.go"}, pkg.Files)
+	assert.Equal(t, []string{"strings"}, pkg.Imports)
+	assert.Equal(t, []string{"."}, pkg.ImportedBy)
+
+	// Deleting the only caller drops its references on the next sync.
+	require.NoError(t, os.Remove(mainPath))
+	require.NoError(t, indexer.Sync(ctx))
+	refs, err = indexer.FindReferences(ctx, "store.New")
+	require.NoError(t, err)
+	assert.Empty(t, refs)
+}
+
+func TestSyncExtractsSymbolsForExistingIndexes(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	store, err := NewStore(filepath.Join(t.TempDir(), "rag.db"))
+	require.NoError(t, err)
+	defer store.Close()
+	indexer := NewIndexer(store, NewHashingEmbedder(16), root)
+
+	writeIndexerFile(t, root, "store/store.go", symbolFixtureStore)
+	require.NoError(t, indexer.Sync(ctx))
+
+	// An index built before symbols existed has no symbol version; the next
+	// sync extracts every package even though no file changed.
+	require.NoError(t, store.ReplaceSymbols(ctx, filepath.Join(root, "store"), nil))
+	require.NoError(t, store.SetIndexMeta(ctx, metaSymbolsVersion, ""))
+	require.NoError(t, indexer.Sync(ctx))
+
+	symbols, err := indexer.FindSymbols(ctx, "Store.Greeting")
+	require.NoError(t, err)
+	require.Len(t, symbols, 1)
+	assert.Equal(t, "method", symbols[0].Kind)
+}
@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrTokenExpired = errors.New("token expired")
+
+type Token struct {
+	Value     string
+	ExpiresAt time.Time
+}
+
+// Refresh exchanges an expired access token for a new one using the stored
+// refresh credential. Tokens still valid for a minute are returned unchanged.
+func Refresh(tok Token, exchange func() (Token, error)) (Token, error) {
+	if time.Until(tok.ExpiresAt) > time.Minute {
+		return tok, nil
+	}
+	fresh, err := exchange()
+	if err != nil {
+		return Token{}, ErrTokenExpired
+	}
+	return fresh, nil
+}
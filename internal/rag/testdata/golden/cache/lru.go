@@ -0,0 +1,31 @@
+package cache
+
+import "container/list"
+
+// LRU is a least-recently-used cache with a fixed capacity.
+type LRU struct {
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+type entry struct {
+	key   string
+	value any
+}
+
+// Put stores a value and evicts the least recently used entry when the
+// cache is over capacity.
+func (c *LRU) Put(key string, value any) {
+	if el, ok := c.items[key]; ok {
+		c.order.MoveToFront(el)
+		el.Value.(*entry).value = value
+		return
+	}
+	c.items[key] = c.order.PushFront(&entry{key: key, value: value})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*entry).key)
+	}
+}
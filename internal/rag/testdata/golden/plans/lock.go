@@ -0,0 +1,21 @@
+package plans
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// writePlanLock records the approved plan hash next to the plan file so that
+// a second orchestrator does not execute the same plan concurrently.
+func writePlanLock(planPath, hash string) error {
+	lockPath := planPath + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(lockPath, []byte(hash), 0o644)
+}
+
+// removePlanLock deletes the lock once the plan has finished.
+func removePlanLock(planPath string) error {
+	return os.Remove(planPath + ".lock")
+}
@@ -0,0 +1,18 @@
+package server
+
+import "net/http"
+
+// Routes registers every HTTP handler on the mux.
+func Routes(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", health)
+	mux.HandleFunc("/api/plans", listPlans)
+}
+
+func health(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func listPlans(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte("[]"))
+}
@@ -0,0 +1,128 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+type FileCheckpoint struct {
+	ID        int64
+	SessionID string
+	RunID     string
+	TaskID    string
+	Path      string
+	Existed   bool
+	// Mode is the file's permission bits before the write; 0 when the file
+	// did not exist or was checkpointed before modes were recorded.
+	Mode      fs.FileMode
+	Before    []byte
+	AfterHash string
+	CreatedAt time.Time
+}
+
+func (db *DB) SaveFileCheckpoint(ctx context.Context, cp FileCheckpoint) error {
+	cp.SessionID = strings.TrimSpace(cp.SessionID)
+	cp.RunID = strings.TrimSpace(cp.RunID)
+	cp.Path = strings.TrimSpace(cp.Path)
+	if cp.SessionID == "" || cp.RunID == "" || cp.Path == "" {
+		return errors.New("checkpoint requires session, run, and path")
+	}
+	if cp.CreatedAt.IsZero() {
+		cp.CreatedAt = time.Now().UTC()
+	}
+	existed := 0
+	if cp.Existed {
+		existed = 1
+	}
+	_, err := db.conn.ExecContext(ctx, `
+		INSERT INTO file_checkpoints (session_id, run_id, task_id, path, existed, mode, before_content, after_hash, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, cp.SessionID, cp.RunID, strings.TrimSpace(cp.TaskID), cp.Path, existed, uint32(cp.Mode.Perm()), cp.Before, cp.AfterHash, cp.CreatedAt)
+	return err
+}
+
+// ListFileCheckpoints returns checkpoints in write order. Empty runID or
+// taskID match every run or task of the session.
+func (db *DB) ListFileCheckpoints(ctx context.Context, sessionID, runID, taskID string) ([]FileCheckpoint, error) {
+	query := `
+		SELECT id, session_id, run_id, task_id, path, existed, COALESCE(mode, 0), before_content, after_hash, created_at
+		FROM file_checkpoints
+		WHERE session_id = ?`
+	args := []any{strings.TrimSpace(sessionID)}
+	if runID = strings.TrimSpace(runID); runID != "" {
+		query += " AND run_id = ?"
+		args = append(args, runID)
+	}
+	if taskID = strings.TrimSpace(taskID); taskID != "" {
+		query += " AND task_id = ?"
+		args = append(args, taskID)
+	}
+	query += " ORDER BY id ASC"
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []FileCheckpoint
+	for rows.Next() {
+		var cp FileCheckpoint
+		var existed int
+		var mode uint32
+		if err := rows.Scan(&cp.ID, &cp.SessionID, &cp.RunID, &cp.TaskID, &cp.Path, &existed, &mode, &cp.Before, &cp.AfterHash, &cp.CreatedAt); err != nil {
+			return nil, err
+		}
+		cp.Existed = existed != 0
+		cp.Mode = fs.FileMode(mode)
+		out = append(out, cp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LatestCheckpoint returns the most recent checkpoint of a session, or nil
+// when nothing has been recorded.
+func (db *DB) LatestCheckpoint(ctx context.Context, sessionID, taskID string) (*FileCheckpoint, error) {
+	query := "SELECT run_id, task_id FROM file_checkpoints WHERE session_id = ?"
+	args := []any{strings.TrimSpace(sessionID)}
+	if taskID = strings.TrimSpace(taskID); taskID != "" {
+		query += " AND task_id = ?"
+		args = append(args, taskID)
+	}
+	query += " ORDER BY id DESC LIMIT 1"
+
+	var cp FileCheckpoint
+	err := db.conn.QueryRowContext(ctx, query, args...).Scan(&cp.RunID, &cp.TaskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cp.SessionID = strings.TrimSpace(sessionID)
+	return &cp, nil
+}
+
+func (db *DB) DeleteFileCheckpoints(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, id := range ids {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM file_checkpoints WHERE id = ?", id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
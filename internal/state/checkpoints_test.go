@@ -0,0 +1,67 @@
+package state
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFileCheckpointsListLatestAndDelete(t *testing.T) {
+	t.Parallel()
+
+	db, err := Connect(":memory:")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	for _, cp := range []FileCheckpoint{
+		{SessionID: "s1", RunID: "run-a", TaskID: "t1", Path: "a.go", Existed: true, Mode: 0o755, Before: []byte("old"), AfterHash: "h1"},
+		{SessionID: "s1", RunID: "run-b", TaskID: "t1", Path: "b.go", AfterHash: "h2"},
+		{SessionID: "s1", RunID: "run-b", TaskID: "t2", Path: "c.go", AfterHash: "h3"},
+	} {
+		if err := db.SaveFileCheckpoint(ctx, cp); err != nil {
+			t.Fatalf("save checkpoint: %v", err)
+		}
+	}
+
+	latest, err := db.LatestCheckpoint(ctx, "s1", "")
+	if err != nil {
+		t.Fatalf("latest: %v", err)
+	}
+	if latest == nil || latest.RunID != "run-b" || latest.TaskID != "t2" {
+		t.Fatalf("unexpected latest checkpoint: %+v", latest)
+	}
+
+	runA, err := db.ListFileCheckpoints(ctx, "s1", "run-a", "")
+	if err != nil {
+		t.Fatalf("list run-a: %v", err)
+	}
+	if len(runA) != 1 || !runA[0].Existed || runA[0].Mode != 0o755 || string(runA[0].Before) != "old" {
+		t.Fatalf("unexpected run-a checkpoints: %+v", runA)
+	}
+
+	task, err := db.ListFileCheckpoints(ctx, "s1", "run-b", "t1")
+	if err != nil {
+		t.Fatalf("list task: %v", err)
+	}
+	if len(task) != 1 || task[0].Path != "b.go" {
+		t.Fatalf("unexpected task checkpoints: %+v", task)
+	}
+
+	if err := db.DeleteFileCheckpoints(ctx, []int64{task[0].ID}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	remaining, err := db.ListFileCheckpoints(ctx, "s1", "", "")
+	if err != nil {
+		t.Fatalf("list remaining: %v", err)
+	}
+	if len(remaining) != 2 {
+		t.Fatalf("expected 2 remaining checkpoints, got %d", len(remaining))
+	}
+
+	none, err := db.LatestCheckpoint(ctx, "other", "")
+	if err != nil || none != nil {
+		t.Fatalf("expected no checkpoint for unknown session, got %+v err=%v", none, err)
+	}
+}
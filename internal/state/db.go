@@ -12,73 +12,13 @@ type DB struct {
 }
 
 func Connect(dbPath string) (*DB, error) {
-	conn, err := sql.Open("sqlite3", dbPath)
+	conn, err := Open(dbPath)
 	if err != nil {
 		return nil, err
 	}
-
-	if err := migrate(conn); err != nil {
-		return nil, err
-	}
-
 	return &DB{conn: conn}, nil
 }
 
-func migrate(db *sql.DB) error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS sessions (
-		id TEXT PRIMARY KEY,
-		created_at DATETIME,
-		working_dir TEXT,
-		mode TEXT
-	);
-	CREATE TABLE IF NOT EXISTS session_settings (
-		session_id TEXT PRIMARY KEY,
-		execution_mode TEXT NOT NULL DEFAULT 'fast',
-		updated_at DATETIME
-	);
-	CREATE TABLE IF NOT EXISTS messages (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		session_id TEXT,
-		role TEXT,
-		agent_role TEXT,
-		content TEXT,
-		tokens_used INTEGER,
-		created_at DATETIME
-	);
-	CREATE TABLE IF NOT EXISTS memory_blocks (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		session_id TEXT,
-		summary TEXT,
-		created_at DATETIME
-	);
-	CREATE TABLE IF NOT EXISTS task_results (
-		id TEXT PRIMARY KEY,
-		session_id TEXT,
-		agent_role TEXT,
-		input TEXT,
-		output TEXT,
-		status TEXT,
-		created_at DATETIME
-	);
-	CREATE TABLE IF NOT EXISTS session_model_selections (
-		session_id TEXT NOT NULL,
-		role TEXT NOT NULL,
-		provider_key TEXT NOT NULL,
-		model_id TEXT NOT NULL,
-		updated_at DATETIME,
-		PRIMARY KEY (session_id, role)
-	);
-	CREATE TABLE IF NOT EXISTS session_input_history (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		session_id TEXT NOT NULL,
-		content TEXT NOT NULL,
-		created_at DATETIME
-	);`
-	_, err := db.Exec(schema)
-	return err
-}
-
 func (db *DB) Close() error {
 	return db.conn.Close()
 }
@@ -89,6 +29,7 @@ type Session struct {
 	WorkingDir    string
 	Mode          string
 	ExecutionMode string
+	Name          string
 }
 
 const (
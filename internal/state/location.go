@@ -0,0 +1,198 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// StateDirEnv overrides where a project's databases live.
+	StateDirEnv = "ORCHESTRA_STATE_DIR"
+
+	StateDBName = "orchestra.db"
+	RAGDBName   = "orchestra_vec.db"
+)
+
+// projectMarkers identify a project root. The nearest ancestor of the working
+// directory holding one of them keeps its state in <root>/.orchestra.
+var projectMarkers = []string{".orchestra", ".git", "go.mod", "package.json", "Cargo.toml", "pyproject.toml"}
+
+// Location is the directory holding a project's state and RAG databases.
+type Location struct {
+	Dir     string
+	StateDB string
+	RAGDB   string
+	// Source tells how Dir was chosen: "env", "config", "project" or "global".
+	Source string
+}
+
+// ResolveLocation picks the state directory for workingDir. $ORCHESTRA_STATE_DIR
+// wins over configured (the [state] dir setting); otherwise a project root
+// found above workingDir keeps its state in <root>/.orchestra, and anything
+// else falls back to $XDG_DATA_HOME/orchestra/<project-hash>.
+func ResolveLocation(workingDir, configured string) (Location, error) {
+	if strings.TrimSpace(workingDir) == "" {
+		workingDir = "."
+	}
+	abs, err := filepath.Abs(workingDir)
+	if err != nil {
+		return Location{}, err
+	}
+
+	if dir := strings.TrimSpace(os.Getenv(StateDirEnv)); dir != "" {
+		return newLocation(expandDir(dir, abs), "env"), nil
+	}
+	if dir := strings.TrimSpace(configured); dir != "" {
+		return newLocation(expandDir(dir, abs), "config"), nil
+	}
+	if root := ProjectRoot(abs); root != "" {
+		return newLocation(filepath.Join(root, ".orchestra"), "project"), nil
+	}
+	dataHome := strings.TrimSpace(os.Getenv("XDG_DATA_HOME"))
+	if dataHome == "" || !filepath.IsAbs(dataHome) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return Location{}, fmt.Errorf("resolve state dir: %w", err)
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return newLocation(filepath.Join(dataHome, "orchestra", hex.EncodeToString(sum[:8])), "global"), nil
+}
+
+func newLocation(dir, source string) Location {
+	return Location{
+		Dir:     dir,
+		StateDB: filepath.Join(dir, StateDBName),
+		RAGDB:   filepath.Join(dir, RAGDBName),
+		Source:  source,
+	}
+}
+
+// expandDir resolves ~ and paths relative to the working directory.
+func expandDir(dir, workingDir string) string {
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, strings.TrimPrefix(dir, "~"))
+		}
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(workingDir, dir)
+	}
+	return filepath.Clean(dir)
+}
+
+// ProjectRoot returns the nearest directory at or above dir that holds a
+// project marker, or "" when there is none.
+func ProjectRoot(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		for _, marker := range projectMarkers {
+			if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+				return dir
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Prepare creates the state directory and moves databases left in
+// legacyDirs by older releases, which kept them in the current directory.
+// A database already present in the state directory is never overwritten.
+// It returns the databases it moved.
+func (l Location) Prepare(legacyDirs ...string) ([]string, error) {
+	return l.PrepareExcept(nil, legacyDirs...)
+}
+
+// PrepareExcept is Prepare leaving the databases at the paths in inUse where
+// they are, for commands told explicitly to open a legacy database.
+func (l Location) PrepareExcept(inUse []string, legacyDirs ...string) ([]string, error) {
+	if err := os.MkdirAll(l.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create state dir: %w", err)
+	}
+	if l.Source == "project" {
+		// Keep the databases out of commits while plans stay reviewable.
+		ignorePath := filepath.Join(l.Dir, ".gitignore")
+		if _, err := os.Stat(ignorePath); errors.Is(err, os.ErrNotExist) {
+			_ = os.WriteFile(ignorePath, []byte(StateDBName+"*\n"+RAGDBName+"*\n"), 0o644)
+		}
+	}
+
+	keep := map[string]bool{}
+	for _, path := range inUse {
+		if abs, err := filepath.Abs(path); err == nil {
+			keep[abs] = true
+		}
+	}
+	var moved []string
+	seen := map[string]bool{}
+	for _, dir := range legacyDirs {
+		abs, err := filepath.Abs(dir)
+		if err != nil || seen[abs] || abs == filepath.Clean(l.Dir) {
+			continue
+		}
+		seen[abs] = true
+		for _, target := range []string{l.StateDB, l.RAGDB} {
+			from := filepath.Join(abs, filepath.Base(target))
+			if keep[from] {
+				continue
+			}
+			if _, err := os.Stat(from); err != nil {
+				continue
+			}
+			if _, err := os.Stat(target); err == nil {
+				continue
+			}
+			// The WAL and journal hold committed pages, so they move along.
+			for _, suffix := range []string{"-wal", "-shm", "-journal", ""} {
+				if _, err := os.Stat(from + suffix); err != nil {
+					continue
+				}
+				if err := moveFile(from+suffix, target+suffix); err != nil {
+					return moved, fmt.Errorf("move %s: %w", from+suffix, err)
+				}
+			}
+			moved = append(moved, from)
+		}
+	}
+	return moved, nil
+}
+
+// moveFile renames src to dst, copying when they are on different devices.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		_ = os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(dst)
+		return err
+	}
+	return os.Remove(src)
+}
@@ -0,0 +1,160 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveLocationPrefersEnvThenConfig(t *testing.T) {
+	workDir := t.TempDir()
+
+	t.Setenv(StateDirEnv, "")
+	loc, err := ResolveLocation(workDir, "state")
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if loc.Source != "config" || loc.Dir != filepath.Join(workDir, "state") {
+		t.Fatalf("expected the configured dir relative to the working dir, got %+v", loc)
+	}
+	if loc.StateDB != filepath.Join(workDir, "state", StateDBName) || loc.RAGDB != filepath.Join(workDir, "state", RAGDBName) {
+		t.Fatalf("unexpected database paths: %+v", loc)
+	}
+
+	envDir := t.TempDir()
+	t.Setenv(StateDirEnv, envDir)
+	loc, err = ResolveLocation(workDir, "state")
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if loc.Source != "env" || loc.Dir != envDir {
+		t.Fatalf("expected $%s to win, got %+v", StateDirEnv, loc)
+	}
+}
+
+func TestResolveLocationUsesProjectRoot(t *testing.T) {
+	t.Setenv(StateDirEnv, "")
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	nested := filepath.Join(root, "internal", "pkg")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	loc, err := ResolveLocation(nested, "")
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if loc.Source != "project" || loc.Dir != filepath.Join(root, ".orchestra") {
+		t.Fatalf("expected <root>/.orchestra, got %+v", loc)
+	}
+}
+
+func TestResolveLocationFallsBackToXDGDataHome(t *testing.T) {
+	workDir := t.TempDir()
+	if ProjectRoot(workDir) != "" {
+		t.Skip("temp dir is inside a project")
+	}
+	dataHome := t.TempDir()
+	t.Setenv(StateDirEnv, "")
+	t.Setenv("XDG_DATA_HOME", dataHome)
+
+	loc, err := ResolveLocation(workDir, "")
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if loc.Source != "global" || filepath.Dir(loc.Dir) != filepath.Join(dataHome, "orchestra") {
+		t.Fatalf("expected a dir under $XDG_DATA_HOME/orchestra, got %+v", loc)
+	}
+	again, err := ResolveLocation(workDir, "")
+	if err != nil || again.Dir != loc.Dir {
+		t.Fatalf("expected a stable per-project dir, got %q then %q (%v)", loc.Dir, again.Dir, err)
+	}
+	other, err := ResolveLocation(t.TempDir(), "")
+	if err != nil || other.Dir == loc.Dir {
+		t.Fatalf("expected another project to get its own dir, got %q (%v)", other.Dir, err)
+	}
+}
+
+func TestPrepareMovesLegacyDatabases(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		StateDBName:          "state",
+		StateDBName + "-wal": "wal",
+		RAGDBName:            "rag",
+	} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	loc := newLocation(filepath.Join(root, ".orchestra"), "project")
+
+	moved, err := loc.Prepare(root, root)
+	if err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	if len(moved) != 2 {
+		t.Fatalf("expected both databases to move, got %v", moved)
+	}
+	for path, want := range map[string]string{
+		loc.StateDB:          "state",
+		loc.StateDB + "-wal": "wal",
+		loc.RAGDB:            "rag",
+	} {
+		got, err := os.ReadFile(path)
+		if err != nil || string(got) != want {
+			t.Fatalf("%s: got %q (%v), want %q", path, got, err, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, StateDBName)); !os.IsNotExist(err) {
+		t.Fatalf("expected the legacy database to be gone, got %v", err)
+	}
+	ignore, err := os.ReadFile(filepath.Join(loc.Dir, ".gitignore"))
+	if err != nil || !strings.Contains(string(ignore), StateDBName) {
+		t.Fatalf("expected a .gitignore for the databases, got %q (%v)", ignore, err)
+	}
+
+	// A database already in the state dir is never replaced.
+	if err := os.WriteFile(filepath.Join(root, StateDBName), []byte("stale"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	moved, err = loc.Prepare(root)
+	if err != nil || len(moved) != 0 {
+		t.Fatalf("expected nothing to move, got %v (%v)", moved, err)
+	}
+	if got, _ := os.ReadFile(loc.StateDB); string(got) != "state" {
+		t.Fatalf("existing database was overwritten with %q", got)
+	}
+}
+
+func TestPrepareKeepsDatabasesInUse(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	for name, content := range map[string]string{StateDBName: "state", RAGDBName: "rag"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	loc := newLocation(filepath.Join(root, ".orchestra"), "project")
+
+	// A command opening ./orchestra.db with --db keeps it in place.
+	moved, err := loc.PrepareExcept([]string{filepath.Join(root, StateDBName)}, root)
+	if err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	if len(moved) != 1 || moved[0] != filepath.Join(root, RAGDBName) {
+		t.Fatalf("expected only the RAG database to move, got %v", moved)
+	}
+	if got, err := os.ReadFile(filepath.Join(root, StateDBName)); err != nil || string(got) != "state" {
+		t.Fatalf("expected the database in use to stay, got %q (%v)", got, err)
+	}
+	if _, err := os.Stat(loc.StateDB); !os.IsNotExist(err) {
+		t.Fatalf("expected no state database in the state dir, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package state
 
 import (
 	"context"
+	"strings"
 	"time"
 )
 
@@ -12,15 +13,48 @@ type TaskResult struct {
 	Input     string
 	Output    string
 	Status    string
+	CommitSHA string
 	CreatedAt time.Time
 }
 
 func (db *DB) SaveTaskResult(ctx context.Context, tr TaskResult) error {
-	_, err := db.conn.ExecContext(ctx, "INSERT INTO task_results (id, session_id, agent_role, input, output, status, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
-		tr.ID, tr.SessionID, tr.AgentRole, tr.Input, tr.Output, tr.Status, tr.CreatedAt)
+	if strings.TrimSpace(tr.ID) == "" {
+		tr.ID = genID()
+	}
+	if tr.CreatedAt.IsZero() {
+		tr.CreatedAt = time.Now().UTC()
+	}
+	_, err := db.conn.ExecContext(ctx, "INSERT INTO task_results (id, session_id, agent_role, input, output, status, commit_sha, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		tr.ID, tr.SessionID, tr.AgentRole, tr.Input, tr.Output, tr.Status, tr.CommitSHA, tr.CreatedAt)
 	return err
 }
 
+func (db *DB) GetTaskResults(ctx context.Context, sessionID string) ([]TaskResult, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT id, session_id, agent_role, input, output, status, COALESCE(commit_sha, ''), created_at
+		FROM task_results
+		WHERE session_id = ?
+		ORDER BY created_at ASC
+	`, strings.TrimSpace(sessionID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []TaskResult
+	for rows.Next() {
+		var tr TaskResult
+		if err := rows.Scan(&tr.ID, &tr.SessionID, &tr.AgentRole, &tr.Input, &tr.Output, &tr.Status, &tr.CommitSHA, &tr.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, tr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (db *DB) SaveMemoryBlock(ctx context.Context, sessionID, summary string) error {
 	_, err := db.conn.ExecContext(ctx, "INSERT INTO memory_blocks (session_id, summary, created_at) VALUES (?, ?, ?)",
 		sessionID, summary, time.Now())
@@ -0,0 +1,61 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveTaskResultStoresCommitSHA(t *testing.T) {
+	t.Parallel()
+
+	db, err := Connect(":memory:")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if err := db.SaveTaskResult(ctx, TaskResult{SessionID: "s1", AgentRole: "coder", Status: "approved", CommitSHA: "abc123"}); err != nil {
+		t.Fatalf("save task result: %v", err)
+	}
+	results, err := db.GetTaskResults(ctx, "s1")
+	if err != nil {
+		t.Fatalf("get task results: %v", err)
+	}
+	if len(results) != 1 || results[0].ID == "" || results[0].CommitSHA != "abc123" {
+		t.Fatalf("unexpected task results: %+v", results)
+	}
+}
+
+func TestConnectAddsCommitSHAColumnToExistingDB(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	legacy, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("open legacy db: %v", err)
+	}
+	if _, err := legacy.Exec(`CREATE TABLE task_results (
+		id TEXT PRIMARY KEY,
+		session_id TEXT,
+		agent_role TEXT,
+		input TEXT,
+		output TEXT,
+		status TEXT,
+		created_at DATETIME
+	)`); err != nil {
+		t.Fatalf("create legacy table: %v", err)
+	}
+	_ = legacy.Close()
+
+	db, err := Connect(path)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+	if err := db.SaveTaskResult(context.Background(), TaskResult{SessionID: "s1", CommitSHA: "def456"}); err != nil {
+		t.Fatalf("save task result on migrated db: %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/yubzen/orchestra/internal/migrate"
+)
+
+// migrations is the state schema history. Append new versions; never edit
+// one that has shipped.
+var migrations = []migrate.Migration{
+	{Version: 1, Name: "initial schema", Up: migrate.SQL(`
+		CREATE TABLE IF NOT EXISTS sessions (
+			id TEXT PRIMARY KEY,
+			created_at DATETIME,
+			working_dir TEXT,
+			mode TEXT
+		);
+		CREATE TABLE IF NOT EXISTS session_settings (
+			session_id TEXT PRIMARY KEY,
+			execution_mode TEXT NOT NULL DEFAULT 'fast',
+			updated_at DATETIME
+		);
+		CREATE TABLE IF NOT EXISTS messages (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_id TEXT,
+			role TEXT,
+			agent_role TEXT,
+			content TEXT,
+			tokens_used INTEGER,
+			created_at DATETIME
+		);
+		CREATE TABLE IF NOT EXISTS memory_blocks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_id TEXT,
+			summary TEXT,
+			created_at DATETIME
+		);
+		CREATE TABLE IF NOT EXISTS task_results (
+			id TEXT PRIMARY KEY,
+			session_id TEXT,
+			agent_role TEXT,
+			input TEXT,
+			output TEXT,
+			status TEXT,
+			created_at DATETIME
+		);
+		CREATE TABLE IF NOT EXISTS session_model_selections (
+			session_id TEXT NOT NULL,
+			role TEXT NOT NULL,
+			provider_key TEXT NOT NULL,
+			model_id TEXT NOT NULL,
+			updated_at DATETIME,
+			PRIMARY KEY (session_id, role)
+		);
+		CREATE TABLE IF NOT EXISTS session_input_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_id TEXT NOT NULL,
+			content TEXT NOT NULL,
+			created_at DATETIME
+		);`)},
+	{Version: 2, Name: "file checkpoints", Up: migrate.SQL(`
+		CREATE TABLE IF NOT EXISTS file_checkpoints (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_id TEXT NOT NULL,
+			run_id TEXT NOT NULL,
+			task_id TEXT,
+			path TEXT NOT NULL,
+			existed INTEGER NOT NULL DEFAULT 0,
+			before_content BLOB,
+			after_hash TEXT,
+			created_at DATETIME
+		);
+		CREATE INDEX IF NOT EXISTS idx_file_checkpoints_session_run ON file_checkpoints (session_id, run_id);`)},
+	{Version: 3, Name: "task commit sha", Up: func(tx *sql.Tx) error {
+		return migrate.AddColumn(tx, "task_results", "commit_sha", "TEXT")
+	}},
+	{Version: 4, Name: "tool calls", Up: migrate.SQL(`
+		CREATE TABLE IF NOT EXISTS tool_calls (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_id TEXT NOT NULL,
+			run_id TEXT,
+			task_id TEXT,
+			role TEXT,
+			tool TEXT NOT NULL,
+			args TEXT,
+			result TEXT,
+			error TEXT,
+			duration_ms INTEGER,
+			iteration INTEGER,
+			created_at DATETIME
+		);
+		CREATE INDEX IF NOT EXISTS idx_tool_calls_session ON tool_calls (session_id, task_id);`)},
+	{Version: 5, Name: "session names", Up: func(tx *sql.Tx) error {
+		return migrate.AddColumn(tx, "sessions", "name", "TEXT")
+	}},
+	{Version: 6, Name: "trace spans", Up: migrate.SQL(`
+		CREATE TABLE IF NOT EXISTS trace_spans (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_id TEXT NOT NULL,
+			run_id TEXT NOT NULL,
+			parent_id INTEGER,
+			kind TEXT NOT NULL,
+			name TEXT,
+			role TEXT,
+			task_id TEXT,
+			attempt INTEGER,
+			status TEXT NOT NULL,
+			input TEXT,
+			output TEXT,
+			error TEXT,
+			started_at DATETIME,
+			ended_at DATETIME,
+			duration_ms INTEGER
+		);
+		CREATE INDEX IF NOT EXISTS idx_trace_spans_session_run ON trace_spans (session_id, run_id);`)},
+	{Version: 7, Name: "run state", Up: migrate.SQL(`
+		CREATE TABLE IF NOT EXISTS runs (
+			id TEXT PRIMARY KEY,
+			session_id TEXT NOT NULL,
+			prompt TEXT NOT NULL,
+			status TEXT NOT NULL,
+			role TEXT,
+			execution_mode TEXT,
+			plan_id TEXT,
+			plan_path TEXT,
+			plan_yaml TEXT,
+			error TEXT,
+			created_at DATETIME,
+			updated_at DATETIME
+		);
+		CREATE INDEX IF NOT EXISTS idx_runs_status ON runs (status);
+		CREATE TABLE IF NOT EXISTS run_tasks (
+			run_id TEXT NOT NULL,
+			session_id TEXT NOT NULL,
+			task_id TEXT NOT NULL,
+			position INTEGER NOT NULL,
+			status TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			findings TEXT,
+			updated_at DATETIME,
+			PRIMARY KEY (run_id, task_id)
+		);`)},
+	{Version: 8, Name: "checkpoint file modes", Up: func(tx *sql.Tx) error {
+		return migrate.AddColumn(tx, "file_checkpoints", "mode", "INTEGER")
+	}},
+	{Version: 9, Name: "run owners", Up: func(tx *sql.Tx) error {
+		if err := migrate.AddColumn(tx, "runs", "owner_pid", "INTEGER"); err != nil {
+			return err
+		}
+		return migrate.AddColumn(tx, "runs", "heartbeat_at", "DATETIME")
+	}},
+}
+
+// Open returns a connection to the state database with every migration
+// applied, for callers that query it directly. Writes wait for a lock held
+// by another process instead of failing, so concurrent run claims resolve to
+// one winner.
+func Open(dbPath string) (*sql.DB, error) {
+	conn, err := sql.Open("sqlite3", dbPath+"?_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := migrate.Run(conn, dbPath, migrations, 0); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("migrate state db: %w", err)
+	}
+	return conn, nil
+}
+
+// MigrationStatus reports the schema version of the state database at
+// dbPath without migrating it.
+func MigrationStatus(dbPath string) (migrate.Status, error) {
+	conn, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return migrate.Status{}, err
+	}
+	defer conn.Close()
+	return migrate.GetStatus(conn, migrations)
+}
+
+// MigrateTo upgrades the state database at dbPath to target, or to the
+// latest version when target is 0.
+func MigrateTo(dbPath string, target int) (migrate.Result, error) {
+	conn, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return migrate.Result{}, err
+	}
+	defer conn.Close()
+	return migrate.Run(conn, dbPath, migrations, target)
+}
@@ -0,0 +1,170 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestMigrationsUpgradeLegacyFixtures(t *testing.T) {
+	t.Parallel()
+
+	want := freshSchema(t)
+	for _, fixture := range []string{"legacy_baseline.sql", "legacy_tool_calls.sql"} {
+		t.Run(fixture, func(t *testing.T) {
+			t.Parallel()
+
+			dbPath := filepath.Join(t.TempDir(), "orchestra.db")
+			script, err := os.ReadFile(filepath.Join("testdata", fixture))
+			if err != nil {
+				t.Fatalf("read fixture: %v", err)
+			}
+			conn, err := sql.Open("sqlite3", dbPath)
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			if _, err := conn.Exec(string(script)); err != nil {
+				t.Fatalf("load fixture: %v", err)
+			}
+			conn.Close()
+
+			result, err := MigrateTo(dbPath, 0)
+			if err != nil {
+				t.Fatalf("migrate: %v", err)
+			}
+			if result.From != 0 || result.To != len(migrations) {
+				t.Fatalf("migrated from %d to %d, want 0 to %d", result.From, result.To, len(migrations))
+			}
+			if _, err := os.Stat(dbPath + ".v0.bak"); err != nil {
+				t.Fatalf("expected a backup before migrating: %v", err)
+			}
+
+			db, err := Connect(dbPath)
+			if err != nil {
+				t.Fatalf("connect: %v", err)
+			}
+			defer db.Close()
+			if got := schemaOf(t, db.conn); !reflect.DeepEqual(got, want) {
+				t.Fatalf("schema after upgrade differs from a fresh database:\ngot  %v\nwant %v", got, want)
+			}
+			var workingDir string
+			if err := db.conn.QueryRow("SELECT working_dir FROM sessions WHERE id = 'legacy'").Scan(&workingDir); err != nil || workingDir != "/work" {
+				t.Fatalf("legacy session lost: %q, %v", workingDir, err)
+			}
+		})
+	}
+}
+
+func TestMigrationsUpgradeFromEveryVersion(t *testing.T) {
+	t.Parallel()
+
+	want := freshSchema(t)
+	for version := 1; version < len(migrations); version++ {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			t.Parallel()
+
+			dbPath := filepath.Join(t.TempDir(), "orchestra.db")
+			if _, err := MigrateTo(dbPath, version); err != nil {
+				t.Fatalf("migrate to v%d: %v", version, err)
+			}
+			status, err := MigrationStatus(dbPath)
+			if err != nil {
+				t.Fatalf("status: %v", err)
+			}
+			if status.Current != version || len(status.Pending) != len(migrations)-version {
+				t.Fatalf("unexpected status at v%d: %+v", version, status)
+			}
+			conn, err := sql.Open("sqlite3", dbPath)
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			if _, err := conn.Exec("INSERT INTO sessions (id, created_at, working_dir, mode) VALUES ('old', CURRENT_TIMESTAMP, '/work', 'solo')"); err != nil {
+				t.Fatalf("seed: %v", err)
+			}
+			conn.Close()
+
+			db, err := Connect(dbPath)
+			if err != nil {
+				t.Fatalf("connect: %v", err)
+			}
+			defer db.Close()
+			if got := schemaOf(t, db.conn); !reflect.DeepEqual(got, want) {
+				t.Fatalf("schema after upgrade from v%d differs from a fresh database:\ngot  %v\nwant %v", version, got, want)
+			}
+			if _, err := db.GetSession(context.Background(), "old"); err != nil {
+				t.Fatalf("session lost in upgrade: %v", err)
+			}
+			if _, err := os.Stat(fmt.Sprintf("%s.v%d.bak", dbPath, version)); err != nil {
+				t.Fatalf("expected a backup before migrating: %v", err)
+			}
+		})
+	}
+}
+
+func TestMigrateToRefusesDowngrade(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(t.TempDir(), "orchestra.db")
+	if _, err := MigrateTo(dbPath, 0); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if _, err := MigrateTo(dbPath, 1); err == nil {
+		t.Fatal("expected migrating to an older version to fail")
+	}
+}
+
+func freshSchema(t *testing.T) map[string][]string {
+	t.Helper()
+	db, err := Connect(filepath.Join(t.TempDir(), "fresh.db"))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+	return schemaOf(t, db.conn)
+}
+
+// schemaOf lists each table's columns with their types, and each index.
+func schemaOf(t *testing.T, conn *sql.DB) map[string][]string {
+	t.Helper()
+	rows, err := conn.Query("SELECT type, name FROM sqlite_master WHERE name NOT LIKE 'sqlite_%' AND name != 'schema_version' ORDER BY name")
+	if err != nil {
+		t.Fatalf("read schema: %v", err)
+	}
+	var tables []string
+	schema := make(map[string][]string)
+	for rows.Next() {
+		var kind, name string
+		if err := rows.Scan(&kind, &name); err != nil {
+			t.Fatalf("scan schema: %v", err)
+		}
+		if kind == "table" {
+			tables = append(tables, name)
+		} else {
+			schema[kind+":"+name] = nil
+		}
+	}
+	rows.Close()
+	for _, table := range tables {
+		cols, err := conn.Query("SELECT name, type FROM pragma_table_info(?)", table)
+		if err != nil {
+			t.Fatalf("read columns: %v", err)
+		}
+		var columns []string
+		for cols.Next() {
+			var name, typ string
+			if err := cols.Scan(&name, &typ); err != nil {
+				t.Fatalf("scan columns: %v", err)
+			}
+			columns = append(columns, name+" "+typ)
+		}
+		cols.Close()
+		sort.Strings(columns)
+		schema[table] = columns
+	}
+	return schema
+}
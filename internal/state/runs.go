@@ -0,0 +1,362 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Run statuses. A run is saved as running before its first task and set to
+// completed, paused, cancelled or failed when it returns. While it runs its
+// process refreshes a heartbeat, so one still marked running whose heartbeat
+// went stale was interrupted.
+const (
+	RunStatusRunning   = "running"
+	RunStatusPaused    = "paused"
+	RunStatusCompleted = "completed"
+	RunStatusFailed    = "failed"
+	RunStatusCancelled = "cancelled"
+)
+
+// RunHeartbeatInterval is how often the process running a run refreshes its
+// heartbeat. A running run whose heartbeat is older than RunStaleAfter is
+// taken to have lost its process.
+const (
+	RunHeartbeatInterval = 10 * time.Second
+	RunStaleAfter        = 3 * RunHeartbeatInterval
+)
+
+// ErrRunLive is returned when claiming a run another process is running.
+var ErrRunLive = errors.New("run is still running")
+
+// Task statuses within a run.
+const (
+	RunTaskPending = "pending"
+	RunTaskRunning = "running"
+	RunTaskDone    = "done"
+	RunTaskBlocked = "blocked"
+)
+
+// Run is the persisted state of one planned orchestration: the prompt and
+// choices it was started with, its plan and the progress of each task.
+type Run struct {
+	ID            string
+	SessionID     string
+	Prompt        string
+	Status        string
+	Role          string
+	ExecutionMode string
+	PlanID        string
+	PlanPath      string
+	PlanYAML      string
+	Error         string
+	OwnerPID      int
+	HeartbeatAt   time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Tasks         []RunTask
+}
+
+// RunTask is one plan task of a run. Findings holds the reviewer's latest
+// requested changes.
+type RunTask struct {
+	RunID     string
+	TaskID    string
+	Position  int
+	Status    string
+	Attempts  int
+	Findings  string
+	UpdatedAt time.Time
+}
+
+// Resumable reports whether the run has work left to continue.
+func (r Run) Resumable() bool {
+	return r.Status != RunStatusCompleted && strings.TrimSpace(r.PlanYAML) != ""
+}
+
+// Live reports whether the run is marked running and its owner's heartbeat
+// is recent as of now. A running run without an owner was saved before
+// owners were recorded and is never live.
+func (r Run) Live(now time.Time) bool {
+	return r.Status == RunStatusRunning && r.OwnerPID != 0 && now.Sub(r.HeartbeatAt) < RunStaleAfter
+}
+
+// Interrupted reports whether the run is marked running but its process
+// stopped without recording how it ended.
+func (r Run) Interrupted(now time.Time) bool {
+	return r.Status == RunStatusRunning && !r.Live(now)
+}
+
+// DoneTasks counts the tasks that finished.
+func (r Run) DoneTasks() int {
+	done := 0
+	for _, task := range r.Tasks {
+		if task.Status == RunTaskDone {
+			done++
+		}
+	}
+	return done
+}
+
+// CreateRun saves a run and its tasks, in plan order. A run with an
+// OwnerPID starts with a fresh heartbeat.
+func (db *DB) CreateRun(ctx context.Context, run Run) error {
+	run.ID = strings.TrimSpace(run.ID)
+	run.SessionID = strings.TrimSpace(run.SessionID)
+	if run.ID == "" || run.SessionID == "" {
+		return errors.New("run requires an id and a session")
+	}
+	if run.Status == "" {
+		run.Status = RunStatusRunning
+	}
+	now := time.Now().UTC()
+	var heartbeat any
+	if run.OwnerPID != 0 {
+		heartbeat = now
+	}
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO runs (id, session_id, prompt, status, role, execution_mode, plan_id, plan_path, plan_yaml, error, owner_pid, heartbeat_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, '', ?, ?, ?, ?)
+	`,
+		run.ID,
+		run.SessionID,
+		run.Prompt,
+		run.Status,
+		strings.TrimSpace(run.Role),
+		strings.TrimSpace(run.ExecutionMode),
+		strings.TrimSpace(run.PlanID),
+		strings.TrimSpace(run.PlanPath),
+		run.PlanYAML,
+		run.OwnerPID,
+		heartbeat,
+		now,
+		now,
+	); err != nil {
+		return err
+	}
+	for i, task := range run.Tasks {
+		status := task.Status
+		if status == "" {
+			status = RunTaskPending
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO run_tasks (run_id, session_id, task_id, position, status, attempts, findings, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, run.ID, run.SessionID, strings.TrimSpace(task.TaskID), i, status, task.Attempts, task.Findings, now); err != nil {
+			return fmt.Errorf("save run task %s: %w", task.TaskID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// SetRunStatus moves a run to status, recording errMsg for failed runs.
+func (db *DB) SetRunStatus(ctx context.Context, id, status, errMsg string) error {
+	res, err := db.conn.ExecContext(ctx,
+		"UPDATE runs SET status = ?, error = ?, updated_at = ? WHERE id = ?",
+		status, errMsg, time.Now().UTC(), strings.TrimSpace(id),
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("run %q not found", id)
+	}
+	return nil
+}
+
+// ClaimRun marks a run running in process pid with a fresh heartbeat. It
+// returns ErrRunLive, and leaves the run alone, while another process's
+// heartbeat on it is recent. The check and the claim are one statement, so
+// of two processes claiming the same run only one succeeds.
+func (db *DB) ClaimRun(ctx context.Context, id string, pid int) error {
+	id = strings.TrimSpace(id)
+	now := time.Now().UTC()
+	// Heartbeats are all written in UTC by the same driver, so they compare
+	// in time order as text.
+	res, err := db.conn.ExecContext(ctx, `
+		UPDATE runs
+		SET status = ?, error = '', owner_pid = ?, heartbeat_at = ?, updated_at = ?
+		WHERE id = ? AND NOT (
+			status = ? AND COALESCE(owner_pid, 0) != 0 AND heartbeat_at IS NOT NULL AND heartbeat_at > ?
+		)
+	`, RunStatusRunning, pid, now, now, id, RunStatusRunning, now.Add(-RunStaleAfter))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	run, err := db.GetRun(ctx, id)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w in process %d", ErrRunLive, run.OwnerPID)
+}
+
+// HeartbeatRun refreshes the heartbeat of a run owned by process pid. It
+// does nothing once another process has claimed the run.
+func (db *DB) HeartbeatRun(ctx context.Context, id string, pid int) error {
+	_, err := db.conn.ExecContext(ctx,
+		"UPDATE runs SET heartbeat_at = ? WHERE id = ? AND owner_pid = ?",
+		time.Now().UTC(), strings.TrimSpace(id), pid,
+	)
+	return err
+}
+
+// SaveRunTask records a task's status, attempt count and findings.
+func (db *DB) SaveRunTask(ctx context.Context, task RunTask) error {
+	now := time.Now().UTC()
+	res, err := db.conn.ExecContext(ctx, `
+		UPDATE run_tasks
+		SET status = ?, attempts = ?, findings = ?, updated_at = ?
+		WHERE run_id = ? AND task_id = ?
+	`, task.Status, task.Attempts, task.Findings, now, strings.TrimSpace(task.RunID), strings.TrimSpace(task.TaskID))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("task %q of run %q not found", task.TaskID, task.RunID)
+	}
+	_, err = db.conn.ExecContext(ctx, "UPDATE runs SET updated_at = ? WHERE id = ?", now, strings.TrimSpace(task.RunID))
+	return err
+}
+
+// GetRun returns a run with its tasks.
+func (db *DB) GetRun(ctx context.Context, id string) (*Run, error) {
+	id = strings.TrimSpace(id)
+	runs, err := db.queryRuns(ctx, "WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("run %q not found", id)
+	}
+	return &runs[0], nil
+}
+
+// ListRuns returns the runs in any of statuses, or every run when none are
+// given, most recently updated first.
+func (db *DB) ListRuns(ctx context.Context, statuses ...string) ([]Run, error) {
+	if len(statuses) == 0 {
+		return db.queryRuns(ctx, "ORDER BY updated_at DESC")
+	}
+	args := make([]any, 0, len(statuses))
+	for _, status := range statuses {
+		args = append(args, status)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(statuses)), ", ")
+	return db.queryRuns(ctx, "WHERE status IN ("+placeholders+") ORDER BY updated_at DESC", args...)
+}
+
+// ListInterruptedRuns returns the paused runs and the running ones whose
+// process stopped, that is every run a process left unfinished. Runs another
+// process is still running are left out.
+func (db *DB) ListInterruptedRuns(ctx context.Context) ([]Run, error) {
+	runs, err := db.ListRuns(ctx, RunStatusRunning, RunStatusPaused)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	out := runs[:0]
+	for _, run := range runs {
+		if !run.Live(now) {
+			out = append(out, run)
+		}
+	}
+	return out, nil
+}
+
+// ListResumableRuns returns the runs with work left to continue, most
+// recently active first. Runs another process is still running are left
+// out.
+func (db *DB) ListResumableRuns(ctx context.Context) ([]Run, error) {
+	runs, err := db.ListRuns(ctx, RunStatusRunning, RunStatusPaused, RunStatusCancelled, RunStatusFailed)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	out := runs[:0]
+	for _, run := range runs {
+		if run.Resumable() && !run.Live(now) {
+			out = append(out, run)
+		}
+	}
+	return out, nil
+}
+
+func (db *DB) queryRuns(ctx context.Context, where string, args ...any) ([]Run, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT id, session_id, prompt, status, COALESCE(role, ''), COALESCE(execution_mode, ''),
+			COALESCE(plan_id, ''), COALESCE(plan_path, ''), COALESCE(plan_yaml, ''), COALESCE(error, ''),
+			COALESCE(owner_pid, 0), heartbeat_at, created_at, updated_at
+		FROM runs `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	var out []Run
+	for rows.Next() {
+		var run Run
+		var heartbeat sql.NullTime
+		if err := rows.Scan(
+			&run.ID, &run.SessionID, &run.Prompt, &run.Status, &run.Role, &run.ExecutionMode,
+			&run.PlanID, &run.PlanPath, &run.PlanYAML, &run.Error,
+			&run.OwnerPID, &heartbeat, &run.CreatedAt, &run.UpdatedAt,
+		); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		run.HeartbeatAt = heartbeat.Time
+		out = append(out, run)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range out {
+		tasks, err := db.listRunTasks(ctx, out[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		out[i].Tasks = tasks
+	}
+	return out, nil
+}
+
+func (db *DB) listRunTasks(ctx context.Context, runID string) ([]RunTask, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT run_id, task_id, position, status, attempts, COALESCE(findings, ''), updated_at
+		FROM run_tasks
+		WHERE run_id = ?
+		ORDER BY position ASC
+	`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []RunTask
+	for rows.Next() {
+		var (
+			task      RunTask
+			updatedAt sql.NullTime
+		)
+		if err := rows.Scan(&task.RunID, &task.TaskID, &task.Position, &task.Status, &task.Attempts, &task.Findings, &updatedAt); err != nil {
+			return nil, err
+		}
+		task.UpdatedAt = updatedAt.Time
+		out = append(out, task)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,190 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunStateTransitionsAndInterruptedRuns(t *testing.T) {
+	t.Parallel()
+
+	db, err := Connect(":memory:")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	session, err := db.CreateSession(ctx, ".", "orchestrated")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	for _, run := range []Run{
+		{ID: "run-a", SessionID: session.ID, Prompt: "add a cache", ExecutionMode: "plan", PlanID: "task_1", PlanYAML: "tasks: []", Tasks: []RunTask{{TaskID: "t1"}, {TaskID: "t2"}}},
+		{ID: "run-b", SessionID: session.ID, Prompt: "fix the build", PlanYAML: "tasks: []", Tasks: []RunTask{{TaskID: "t1"}}},
+	} {
+		if err := db.CreateRun(ctx, run); err != nil {
+			t.Fatalf("create %s: %v", run.ID, err)
+		}
+	}
+
+	if err := db.SaveRunTask(ctx, RunTask{RunID: "run-a", TaskID: "t1", Status: RunTaskDone, Attempts: 2, Findings: "add a test"}); err != nil {
+		t.Fatalf("save task: %v", err)
+	}
+	if err := db.SaveRunTask(ctx, RunTask{RunID: "run-a", TaskID: "t9", Status: RunTaskDone}); err == nil {
+		t.Fatal("expected an unknown task to be rejected")
+	}
+	if err := db.SetRunStatus(ctx, "run-b", RunStatusCompleted, ""); err != nil {
+		t.Fatalf("complete run-b: %v", err)
+	}
+
+	run, err := db.GetRun(ctx, "run-a")
+	if err != nil {
+		t.Fatalf("get run: %v", err)
+	}
+	if run.Status != RunStatusRunning || run.ExecutionMode != "plan" || len(run.Tasks) != 2 {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+	if first := run.Tasks[0]; first.TaskID != "t1" || first.Status != RunTaskDone || first.Attempts != 2 || first.Findings != "add a test" {
+		t.Fatalf("unexpected first task: %+v", first)
+	}
+	if run.Tasks[1].Status != RunTaskPending || run.DoneTasks() != 1 || !run.Resumable() {
+		t.Fatalf("expected one pending task left, got %+v", run.Tasks)
+	}
+
+	interrupted, err := db.ListInterruptedRuns(ctx)
+	if err != nil {
+		t.Fatalf("list interrupted: %v", err)
+	}
+	if len(interrupted) != 1 || interrupted[0].ID != "run-a" {
+		t.Fatalf("expected only run-a to be interrupted, got %+v", interrupted)
+	}
+
+	if err := db.SetRunStatus(ctx, "run-a", RunStatusPaused, ""); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	if interrupted, _ := db.ListInterruptedRuns(ctx); len(interrupted) != 1 || interrupted[0].Status != RunStatusPaused {
+		t.Fatalf("expected the paused run to stay resumable, got %+v", interrupted)
+	}
+	if resumable, _ := db.ListResumableRuns(ctx); len(resumable) != 1 || resumable[0].ID != "run-a" {
+		t.Fatalf("expected the completed run to be left out, got %+v", resumable)
+	}
+	if err := db.SetRunStatus(ctx, "missing", RunStatusFailed, "boom"); err == nil {
+		t.Fatal("expected an unknown run to be rejected")
+	}
+	if _, err := db.GetRun(ctx, "missing"); err == nil {
+		t.Fatal("expected an error for an unknown run")
+	}
+
+	if err := db.DeleteSession(ctx, session.ID); err != nil {
+		t.Fatalf("delete session: %v", err)
+	}
+	if runs, _ := db.ListRuns(ctx); len(runs) != 0 {
+		t.Fatalf("expected runs to be deleted with their session, got %+v", runs)
+	}
+}
+
+func TestLiveRunsAreNotInterrupted(t *testing.T) {
+	t.Parallel()
+
+	db, err := Connect(":memory:")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	session, err := db.CreateSession(ctx, ".", "orchestrated")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	if err := db.CreateRun(ctx, Run{ID: "run-live", SessionID: session.ID, Prompt: "add a cache", PlanYAML: "tasks: []", OwnerPID: 4242}); err != nil {
+		t.Fatalf("create run: %v", err)
+	}
+
+	run, err := db.GetRun(ctx, "run-live")
+	if err != nil {
+		t.Fatalf("get run: %v", err)
+	}
+	if run.OwnerPID != 4242 || !run.Live(time.Now()) || run.Interrupted(time.Now()) {
+		t.Fatalf("expected a live run, got %+v", run)
+	}
+	if interrupted, _ := db.ListInterruptedRuns(ctx); len(interrupted) != 0 {
+		t.Fatalf("expected a live run not to be interrupted, got %+v", interrupted)
+	}
+	if resumable, _ := db.ListResumableRuns(ctx); len(resumable) != 0 {
+		t.Fatalf("expected a live run not to be resumable, got %+v", resumable)
+	}
+	if err := db.ClaimRun(ctx, "run-live", 7); !errors.Is(err, ErrRunLive) {
+		t.Fatalf("expected claiming a live run to fail, got %v", err)
+	}
+
+	// The owner stops refreshing its heartbeat.
+	stale := time.Now().UTC().Add(-2 * RunStaleAfter)
+	if _, err := db.conn.ExecContext(ctx, "UPDATE runs SET heartbeat_at = ? WHERE id = ?", stale, "run-live"); err != nil {
+		t.Fatalf("age heartbeat: %v", err)
+	}
+	if interrupted, _ := db.ListInterruptedRuns(ctx); len(interrupted) != 1 || !interrupted[0].Interrupted(time.Now()) {
+		t.Fatalf("expected a stale run to be interrupted, got %+v", interrupted)
+	}
+	if err := db.ClaimRun(ctx, "run-live", 7); err != nil {
+		t.Fatalf("claim stale run: %v", err)
+	}
+	if err := db.HeartbeatRun(ctx, "run-live", 4242); err != nil {
+		t.Fatalf("heartbeat: %v", err)
+	}
+	run, _ = db.GetRun(ctx, "run-live")
+	if run.OwnerPID != 7 || !run.Live(time.Now()) {
+		t.Fatalf("expected the claiming process to own the run, got %+v", run)
+	}
+	if err := db.ClaimRun(ctx, "missing", 7); err == nil {
+		t.Fatal("expected an unknown run to be rejected")
+	}
+}
+
+func TestConcurrentClaimsHaveOneWinner(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(t.TempDir(), "orchestra.db")
+	ctx := context.Background()
+	first, err := Connect(dbPath)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer first.Close()
+	session, err := first.CreateSession(ctx, ".", "orchestrated")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	if err := first.CreateRun(ctx, Run{ID: "run-a", SessionID: session.ID, Prompt: "add a cache", PlanYAML: "tasks: []", Status: RunStatusPaused}); err != nil {
+		t.Fatalf("create run: %v", err)
+	}
+	second, err := Connect(dbPath)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer second.Close()
+
+	// Two processes resuming the same paused run at once.
+	errs := make(chan error, 2)
+	for pid, db := range map[int]*DB{101: first, 202: second} {
+		go func() { errs <- db.ClaimRun(ctx, "run-a", pid) }()
+	}
+	var won, live int
+	for range 2 {
+		switch err := <-errs; {
+		case err == nil:
+			won++
+		case errors.Is(err, ErrRunLive):
+			live++
+		default:
+			t.Fatalf("unexpected claim error: %v", err)
+		}
+	}
+	if won != 1 || live != 1 {
+		t.Fatalf("expected one claim to win and one to see a live run, got %d and %d", won, live)
+	}
+}
@@ -44,9 +44,9 @@ func (db *DB) CreateSession(ctx context.Context, workingDir, mode string) (*Sess
 func (db *DB) GetSession(ctx context.Context, id string) (*Session, error) {
 	var s Session
 	if err := db.conn.QueryRowContext(ctx,
-		"SELECT id, created_at, working_dir, mode FROM sessions WHERE id = ?",
+		"SELECT id, created_at, working_dir, mode, COALESCE(name, '') FROM sessions WHERE id = ?",
 		id,
-	).Scan(&s.ID, &s.CreatedAt, &s.WorkingDir, &s.Mode); err != nil {
+	).Scan(&s.ID, &s.CreatedAt, &s.WorkingDir, &s.Mode, &s.Name); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("session %q not found", id)
 		}
@@ -0,0 +1,285 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SessionSummary is one row of the session browser.
+type SessionSummary struct {
+	Session
+	FirstPrompt  string
+	MessageCount int
+	LastActivity time.Time
+}
+
+// sessionTables lists every table keyed by session_id, in the order
+// DeleteSession clears them.
+var sessionTables = []string{
+	"session_settings",
+	"messages",
+	"memory_blocks",
+	"task_results",
+	"session_model_selections",
+	"session_input_history",
+	"file_checkpoints",
+	"tool_calls",
+	"trace_spans",
+	"run_tasks",
+	"runs",
+}
+
+// ListSessionSummaries returns every session, most recently active first.
+func (db *DB) ListSessionSummaries(ctx context.Context) ([]SessionSummary, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT id, created_at, working_dir, mode, COALESCE(name, '')
+		FROM sessions
+	`)
+	if err != nil {
+		return nil, err
+	}
+	var out []SessionSummary
+	for rows.Next() {
+		var s SessionSummary
+		if err := rows.Scan(&s.ID, &s.CreatedAt, &s.WorkingDir, &s.Mode, &s.Name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+
+	for idx := range out {
+		if err := db.fillSessionSummary(ctx, &out[idx]); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].LastActivity.After(out[j].LastActivity)
+	})
+	return out, nil
+}
+
+func (db *DB) fillSessionSummary(ctx context.Context, s *SessionSummary) error {
+	executionMode, err := db.GetSessionExecutionMode(ctx, s.ID)
+	if err != nil {
+		return err
+	}
+	s.ExecutionMode = executionMode
+	s.LastActivity = s.CreatedAt
+
+	if err := db.conn.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM messages WHERE session_id = ?", s.ID,
+	).Scan(&s.MessageCount); err != nil {
+		return err
+	}
+	for _, query := range []string{
+		"SELECT created_at FROM messages WHERE session_id = ? ORDER BY id DESC LIMIT 1",
+		"SELECT created_at FROM session_input_history WHERE session_id = ? ORDER BY id DESC LIMIT 1",
+	} {
+		var at time.Time
+		err := db.conn.QueryRowContext(ctx, query, s.ID).Scan(&at)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if at.After(s.LastActivity) {
+			s.LastActivity = at
+		}
+	}
+
+	// The input history keeps what the user typed, so it is preferred over
+	// messages, which may hold expanded prompts; slash commands are skipped.
+	err = db.conn.QueryRowContext(ctx, `
+		SELECT content FROM session_input_history
+		WHERE session_id = ? AND content NOT LIKE '/%'
+		ORDER BY id ASC LIMIT 1
+	`, s.ID).Scan(&s.FirstPrompt)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = db.conn.QueryRowContext(ctx, `
+			SELECT content FROM messages
+			WHERE session_id = ? AND role = 'user'
+			ORDER BY id ASC LIMIT 1
+		`, s.ID).Scan(&s.FirstPrompt)
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	s.FirstPrompt = strings.TrimSpace(s.FirstPrompt)
+	return nil
+}
+
+// SessionSearchText returns each session's messages and typed inputs joined
+// into one string, keyed by session ID, for searching message content.
+func (db *DB) SessionSearchText(ctx context.Context) (map[string]string, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT session_id, content FROM (
+			SELECT session_id, content, 0 AS src, id FROM messages
+			UNION ALL
+			SELECT session_id, content, 1 AS src, id FROM session_input_history
+		)
+		ORDER BY session_id, src, id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	parts := make(map[string][]string)
+	for rows.Next() {
+		var sessionID, content string
+		if err := rows.Scan(&sessionID, &content); err != nil {
+			return nil, err
+		}
+		if content = strings.TrimSpace(content); content != "" {
+			parts[sessionID] = append(parts[sessionID], content)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(parts))
+	for id, texts := range parts {
+		out[id] = strings.Join(texts, "\n")
+	}
+	return out, nil
+}
+
+// RenameSession sets a session's display name; an empty name clears it.
+func (db *DB) RenameSession(ctx context.Context, id, name string) error {
+	var value any
+	if name = strings.TrimSpace(name); name != "" {
+		value = name
+	}
+	res, err := db.conn.ExecContext(ctx, "UPDATE sessions SET name = ? WHERE id = ?", value, strings.TrimSpace(id))
+	if err != nil {
+		return err
+	}
+	return requireSessionRow(res, id)
+}
+
+// DeleteSession removes a session and everything recorded for it.
+func (db *DB) DeleteSession(ctx context.Context, id string) error {
+	id = strings.TrimSpace(id)
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM sessions WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if err := requireSessionRow(res, id); err != nil {
+		return err
+	}
+	for _, table := range sessionTables {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE session_id = ?", id); err != nil {
+			return fmt.Errorf("delete %s: %w", table, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ForkSession copies a session's conversation up to and including the
+// message throughMessageID into a new session, along with its settings,
+// model selections and input history. A throughMessageID of 0 copies every
+// message.
+func (db *DB) ForkSession(ctx context.Context, sourceID string, throughMessageID int64) (*Session, error) {
+	source, err := db.GetSession(ctx, strings.TrimSpace(sourceID))
+	if err != nil {
+		return nil, err
+	}
+	label := source.Name
+	if label == "" {
+		label = shortSessionID(source.ID)
+	}
+	fork := &Session{
+		ID:            genID(),
+		CreatedAt:     time.Now(),
+		WorkingDir:    source.WorkingDir,
+		Mode:          source.Mode,
+		ExecutionMode: source.ExecutionMode,
+		Name:          "fork of " + label,
+	}
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO sessions (id, created_at, working_dir, mode, name) VALUES (?, ?, ?, ?, ?)",
+		fork.ID, fork.CreatedAt, fork.WorkingDir, fork.Mode, fork.Name,
+	); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO session_settings (session_id, execution_mode, updated_at)
+		VALUES (?, ?, ?)
+	`, fork.ID, fork.ExecutionMode, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+
+	messages := `
+		INSERT INTO messages (session_id, role, agent_role, content, tokens_used, created_at)
+		SELECT ?, role, agent_role, content, tokens_used, created_at
+		FROM messages WHERE session_id = ?`
+	args := []any{fork.ID, source.ID}
+	if throughMessageID > 0 {
+		messages += " AND id <= ?"
+		args = append(args, throughMessageID)
+	}
+	if _, err := tx.ExecContext(ctx, messages+" ORDER BY id", args...); err != nil {
+		return nil, fmt.Errorf("copy messages: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO session_model_selections (session_id, role, provider_key, model_id, updated_at)
+		SELECT ?, role, provider_key, model_id, updated_at
+		FROM session_model_selections WHERE session_id = ?
+	`, fork.ID, source.ID); err != nil {
+		return nil, fmt.Errorf("copy model selections: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO session_input_history (session_id, content, created_at)
+		SELECT ?, content, created_at
+		FROM session_input_history WHERE session_id = ? ORDER BY id
+	`, fork.ID, source.ID); err != nil {
+		return nil, fmt.Errorf("copy input history: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return fork, nil
+}
+
+func requireSessionRow(res sql.Result, id string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("session %q not found", id)
+	}
+	return nil
+}
+
+func shortSessionID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,171 @@
+package state
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// connectFile opens a file-backed database; transactions may run on a pooled
+// connection that would see a separate :memory: database.
+func connectFile(t *testing.T) *DB {
+	t.Helper()
+	db, err := Connect(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestListSessionSummariesOrdersByActivity(t *testing.T) {
+	t.Parallel()
+
+	db := connectFile(t)
+	ctx := context.Background()
+	older, err := db.CreateSession(ctx, "/work/a", "orchestrated")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	newer, err := db.CreateSession(ctx, "/work/b", "orchestrated")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	if err := db.AppendSessionInputHistory(ctx, older.ID, "/models"); err != nil {
+		t.Fatalf("append history: %v", err)
+	}
+	if err := db.AppendSessionInputHistory(ctx, older.ID, "fix the login bug"); err != nil {
+		t.Fatalf("append history: %v", err)
+	}
+	for _, content := range []string{"fix the login bug", "done"} {
+		if err := db.SaveMessage(ctx, older.ID, "user", "", content, 0); err != nil {
+			t.Fatalf("save message: %v", err)
+		}
+	}
+
+	summaries, err := db.ListSessionSummaries(ctx)
+	if err != nil {
+		t.Fatalf("list summaries: %v", err)
+	}
+	if len(summaries) != 2 {
+		t.Fatalf("expected 2 summaries, got %d", len(summaries))
+	}
+	first := summaries[0]
+	if first.ID != older.ID {
+		t.Fatalf("expected most recently active session %s first, got %s", older.ID, first.ID)
+	}
+	if first.FirstPrompt != "fix the login bug" || first.MessageCount != 2 || first.WorkingDir != "/work/a" {
+		t.Fatalf("unexpected summary: %+v", first)
+	}
+	if summaries[1].ID != newer.ID || summaries[1].MessageCount != 0 {
+		t.Fatalf("unexpected second summary: %+v", summaries[1])
+	}
+
+	text, err := db.SessionSearchText(ctx)
+	if err != nil {
+		t.Fatalf("search text: %v", err)
+	}
+	if !strings.Contains(text[older.ID], "done") || text[newer.ID] != "" {
+		t.Fatalf("unexpected search text: %#v", text)
+	}
+}
+
+func TestForkSessionCopiesThroughMessage(t *testing.T) {
+	t.Parallel()
+
+	db := connectFile(t)
+	ctx := context.Background()
+	source, err := db.CreateSession(ctx, "/work", "orchestrated")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	if err := db.SetSessionExecutionMode(ctx, source.ID, ExecutionModePlan); err != nil {
+		t.Fatalf("set mode: %v", err)
+	}
+	if err := db.SaveSessionModelSelection(ctx, source.ID, "coder", "openai", "gpt"); err != nil {
+		t.Fatalf("save selection: %v", err)
+	}
+	for _, content := range []string{"one", "two", "three"} {
+		if err := db.SaveMessage(ctx, source.ID, "user", "", content, 0); err != nil {
+			t.Fatalf("save message: %v", err)
+		}
+	}
+	msgs, err := db.GetMessages(ctx, source.ID)
+	if err != nil {
+		t.Fatalf("get messages: %v", err)
+	}
+
+	fork, err := db.ForkSession(ctx, source.ID, msgs[1].ID)
+	if err != nil {
+		t.Fatalf("fork: %v", err)
+	}
+	if fork.ID == source.ID || fork.WorkingDir != "/work" || !strings.HasPrefix(fork.Name, "fork of ") {
+		t.Fatalf("unexpected fork: %+v", fork)
+	}
+	reloaded, err := db.GetSession(ctx, fork.ID)
+	if err != nil {
+		t.Fatalf("get fork: %v", err)
+	}
+	if reloaded.ExecutionMode != ExecutionModePlan || reloaded.Name != fork.Name {
+		t.Fatalf("fork lost settings: %+v", reloaded)
+	}
+	forked, err := db.GetMessages(ctx, fork.ID)
+	if err != nil {
+		t.Fatalf("get fork messages: %v", err)
+	}
+	if len(forked) != 2 || forked[0].Content != "one" || forked[1].Content != "two" {
+		t.Fatalf("unexpected fork messages: %+v", forked)
+	}
+	selections, err := db.GetSessionModelSelections(ctx, fork.ID)
+	if err != nil {
+		t.Fatalf("get selections: %v", err)
+	}
+	if selections["CODER"].ModelID != "gpt" {
+		t.Fatalf("expected copied model selection, got %+v", selections)
+	}
+}
+
+func TestRenameAndDeleteSession(t *testing.T) {
+	t.Parallel()
+
+	db := connectFile(t)
+	ctx := context.Background()
+	session, err := db.CreateSession(ctx, "/work", "orchestrated")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	if err := db.SaveMessage(ctx, session.ID, "user", "", "hello", 0); err != nil {
+		t.Fatalf("save message: %v", err)
+	}
+	if err := db.RenameSession(ctx, session.ID, "  login fix  "); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	reloaded, err := db.GetSession(ctx, session.ID)
+	if err != nil {
+		t.Fatalf("get session: %v", err)
+	}
+	if reloaded.Name != "login fix" {
+		t.Fatalf("expected renamed session, got %q", reloaded.Name)
+	}
+
+	if err := db.DeleteSession(ctx, session.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := db.GetSession(ctx, session.ID); err == nil {
+		t.Fatal("expected deleted session to be gone")
+	}
+	msgs, err := db.GetMessages(ctx, session.ID)
+	if err != nil {
+		t.Fatalf("get messages: %v", err)
+	}
+	if len(msgs) != 0 {
+		t.Fatalf("expected messages to be deleted, got %d", len(msgs))
+	}
+	if err := db.DeleteSession(ctx, session.ID); err == nil {
+		t.Fatal("expected deleting a missing session to fail")
+	}
+	if err := db.RenameSession(ctx, "missing", "x"); err == nil {
+		t.Fatal("expected renaming a missing session to fail")
+	}
+}
@@ -0,0 +1,54 @@
+-- State database as created before schema versioning (first release).
+CREATE TABLE IF NOT EXISTS sessions (
+	id TEXT PRIMARY KEY,
+	created_at DATETIME,
+	working_dir TEXT,
+	mode TEXT
+);
+CREATE TABLE IF NOT EXISTS session_settings (
+	session_id TEXT PRIMARY KEY,
+	execution_mode TEXT NOT NULL DEFAULT 'fast',
+	updated_at DATETIME
+);
+CREATE TABLE IF NOT EXISTS messages (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id TEXT,
+	role TEXT,
+	agent_role TEXT,
+	content TEXT,
+	tokens_used INTEGER,
+	created_at DATETIME
+);
+CREATE TABLE IF NOT EXISTS memory_blocks (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id TEXT,
+	summary TEXT,
+	created_at DATETIME
+);
+CREATE TABLE IF NOT EXISTS task_results (
+	id TEXT PRIMARY KEY,
+	session_id TEXT,
+	agent_role TEXT,
+	input TEXT,
+	output TEXT,
+	status TEXT,
+	created_at DATETIME
+);
+CREATE TABLE IF NOT EXISTS session_model_selections (
+	session_id TEXT NOT NULL,
+	role TEXT NOT NULL,
+	provider_key TEXT NOT NULL,
+	model_id TEXT NOT NULL,
+	updated_at DATETIME,
+	PRIMARY KEY (session_id, role)
+);
+CREATE TABLE IF NOT EXISTS session_input_history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id TEXT NOT NULL,
+	content TEXT NOT NULL,
+	created_at DATETIME
+);
+
+INSERT INTO sessions (id, created_at, working_dir, mode) VALUES ('legacy', '2026-01-02 03:04:05', '/work', 'solo');
+INSERT INTO messages (session_id, role, agent_role, content, tokens_used, created_at) VALUES ('legacy', 'user', 'planner', 'hello', 3, '2026-01-02 03:04:05');
+INSERT INTO task_results (id, session_id, agent_role, input, output, status, created_at) VALUES ('t1', 'legacy', 'coder', 'in', 'out', 'done', '2026-01-02 03:04:05');
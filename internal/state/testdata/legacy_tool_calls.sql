@@ -0,0 +1,82 @@
+-- State database as created by the last release before schema versioning.
+CREATE TABLE IF NOT EXISTS sessions (
+	id TEXT PRIMARY KEY,
+	created_at DATETIME,
+	working_dir TEXT,
+	mode TEXT
+);
+CREATE TABLE IF NOT EXISTS session_settings (
+	session_id TEXT PRIMARY KEY,
+	execution_mode TEXT NOT NULL DEFAULT 'fast',
+	updated_at DATETIME
+);
+CREATE TABLE IF NOT EXISTS messages (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id TEXT,
+	role TEXT,
+	agent_role TEXT,
+	content TEXT,
+	tokens_used INTEGER,
+	created_at DATETIME
+);
+CREATE TABLE IF NOT EXISTS memory_blocks (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id TEXT,
+	summary TEXT,
+	created_at DATETIME
+);
+CREATE TABLE IF NOT EXISTS task_results (
+	id TEXT PRIMARY KEY,
+	session_id TEXT,
+	agent_role TEXT,
+	input TEXT,
+	output TEXT,
+	status TEXT,
+	commit_sha TEXT,
+	created_at DATETIME
+);
+CREATE TABLE IF NOT EXISTS session_model_selections (
+	session_id TEXT NOT NULL,
+	role TEXT NOT NULL,
+	provider_key TEXT NOT NULL,
+	model_id TEXT NOT NULL,
+	updated_at DATETIME,
+	PRIMARY KEY (session_id, role)
+);
+CREATE TABLE IF NOT EXISTS session_input_history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id TEXT NOT NULL,
+	content TEXT NOT NULL,
+	created_at DATETIME
+);
+CREATE TABLE IF NOT EXISTS file_checkpoints (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id TEXT NOT NULL,
+	run_id TEXT NOT NULL,
+	task_id TEXT,
+	path TEXT NOT NULL,
+	existed INTEGER NOT NULL DEFAULT 0,
+	before_content BLOB,
+	after_hash TEXT,
+	created_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_file_checkpoints_session_run ON file_checkpoints (session_id, run_id);
+CREATE TABLE IF NOT EXISTS tool_calls (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id TEXT NOT NULL,
+	run_id TEXT,
+	task_id TEXT,
+	role TEXT,
+	tool TEXT NOT NULL,
+	args TEXT,
+	result TEXT,
+	error TEXT,
+	duration_ms INTEGER,
+	iteration INTEGER,
+	created_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_tool_calls_session ON tool_calls (session_id, task_id);
+
+INSERT INTO sessions (id, created_at, working_dir, mode) VALUES ('legacy', '2026-01-02 03:04:05', '/work', 'solo');
+INSERT INTO task_results (id, session_id, agent_role, input, output, status, commit_sha, created_at) VALUES ('t1', 'legacy', 'coder', 'in', 'out', 'done', 'abc123', '2026-01-02 03:04:05');
+INSERT INTO tool_calls (session_id, run_id, task_id, role, tool, args, result, duration_ms, iteration, created_at) VALUES ('legacy', 'r1', 't1', 'coder', 'read_file', '{}', 'ok', 5, 1, '2026-01-02 03:04:05');
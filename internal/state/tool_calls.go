@@ -0,0 +1,109 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxToolCallResultChars bounds the stored tool arguments and output; full
+// file writes, reads and command logs would otherwise dominate the database.
+const MaxToolCallResultChars = 2000
+
+type ToolCall struct {
+	ID        int64
+	SessionID string
+	RunID     string
+	TaskID    string
+	Role      string
+	Tool      string
+	Args      string
+	Result    string
+	Error     string
+	Duration  time.Duration
+	Iteration int
+	CreatedAt time.Time
+}
+
+func (db *DB) SaveToolCall(ctx context.Context, call ToolCall) error {
+	call.SessionID = strings.TrimSpace(call.SessionID)
+	call.Tool = strings.TrimSpace(call.Tool)
+	if call.SessionID == "" || call.Tool == "" {
+		return errors.New("tool call requires session and tool")
+	}
+	if call.CreatedAt.IsZero() {
+		call.CreatedAt = time.Now().UTC()
+	}
+	_, err := db.conn.ExecContext(ctx, `
+		INSERT INTO tool_calls (session_id, run_id, task_id, role, tool, args, result, error, duration_ms, iteration, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		call.SessionID,
+		strings.TrimSpace(call.RunID),
+		strings.TrimSpace(call.TaskID),
+		strings.TrimSpace(call.Role),
+		call.Tool,
+		TruncateToolResult(call.Args),
+		TruncateToolResult(call.Result),
+		call.Error,
+		call.Duration.Milliseconds(),
+		call.Iteration,
+		call.CreatedAt,
+	)
+	return err
+}
+
+// ListToolCalls returns a session's tool calls in invocation order. An empty
+// taskID matches every task.
+func (db *DB) ListToolCalls(ctx context.Context, sessionID, taskID string) ([]ToolCall, error) {
+	query := `
+		SELECT id, session_id, COALESCE(run_id, ''), COALESCE(task_id, ''), COALESCE(role, ''), tool,
+			COALESCE(args, ''), COALESCE(result, ''), COALESCE(error, ''), COALESCE(duration_ms, 0), COALESCE(iteration, 0), created_at
+		FROM tool_calls
+		WHERE session_id = ?`
+	args := []any{strings.TrimSpace(sessionID)}
+	if taskID = strings.TrimSpace(taskID); taskID != "" {
+		query += " AND task_id = ?"
+		args = append(args, taskID)
+	}
+	query += " ORDER BY id ASC"
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ToolCall
+	for rows.Next() {
+		var call ToolCall
+		var durationMS int64
+		if err := rows.Scan(
+			&call.ID, &call.SessionID, &call.RunID, &call.TaskID, &call.Role, &call.Tool,
+			&call.Args, &call.Result, &call.Error, &durationMS, &call.Iteration, &call.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		call.Duration = time.Duration(durationMS) * time.Millisecond
+		out = append(out, call)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TruncateToolResult cuts result to at most MaxToolCallResultChars bytes,
+// backing up to a rune boundary so multi-byte characters are not split.
+func TruncateToolResult(result string) string {
+	if len(result) <= MaxToolCallResultChars {
+		return result
+	}
+	cut := MaxToolCallResultChars
+	for cut > 0 && !utf8.RuneStart(result[cut]) {
+		cut--
+	}
+	return result[:cut] + "\n... (truncated)"
+}
@@ -0,0 +1,73 @@
+package state
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestToolCallsSaveListAndTruncate(t *testing.T) {
+	t.Parallel()
+
+	db, err := Connect(":memory:")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	for _, call := range []ToolCall{
+		{SessionID: "s1", RunID: "run-a", TaskID: "t1", Role: "coder", Tool: "read_file", Args: `{"path":"a.go"}`, Result: strings.Repeat("x", MaxToolCallResultChars+50), Duration: 1500 * time.Millisecond, Iteration: 1},
+		{SessionID: "s1", RunID: "run-a", TaskID: "t2", Role: "coder", Tool: "run_command", Args: `{"command":"false"}`, Error: "exit status 1", Iteration: 2},
+		{SessionID: "s2", Tool: "list_files"},
+		{SessionID: "s2", Tool: "write_file", Args: `{"content":"` + strings.Repeat("y", MaxToolCallResultChars) + `"}`},
+	} {
+		if err := db.SaveToolCall(ctx, call); err != nil {
+			t.Fatalf("save tool call: %v", err)
+		}
+	}
+	if err := db.SaveToolCall(ctx, ToolCall{SessionID: "s1"}); err == nil {
+		t.Fatal("expected tool call without a tool name to be rejected")
+	}
+
+	calls, err := db.ListToolCalls(ctx, "s1", "")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(calls) != 2 || calls[0].Tool != "read_file" || calls[1].Tool != "run_command" {
+		t.Fatalf("unexpected calls: %+v", calls)
+	}
+	if calls[0].Duration != 1500*time.Millisecond || calls[0].Iteration != 1 {
+		t.Fatalf("expected duration and iteration to round-trip, got %+v", calls[0])
+	}
+	if !strings.HasSuffix(calls[0].Result, "(truncated)") || len(calls[0].Result) > MaxToolCallResultChars+32 {
+		t.Fatalf("expected truncated result, got %d chars", len(calls[0].Result))
+	}
+
+	if s2, _ := db.ListToolCalls(ctx, "s2", ""); len(s2) != 2 || !strings.HasSuffix(s2[1].Args, "(truncated)") {
+		t.Fatalf("expected large args to be truncated, got %+v", s2)
+	}
+
+	t2, err := db.ListToolCalls(ctx, "s1", "t2")
+	if err != nil {
+		t.Fatalf("list t2: %v", err)
+	}
+	if len(t2) != 1 || t2[0].Error != "exit status 1" {
+		t.Fatalf("unexpected task filter result: %+v", t2)
+	}
+}
+
+func TestTruncateToolResultKeepsRunesWhole(t *testing.T) {
+	t.Parallel()
+
+	result := strings.Repeat("x", MaxToolCallResultChars-1) + strings.Repeat("é", 10)
+	got := TruncateToolResult(result)
+	if !utf8.ValidString(got) {
+		t.Fatalf("expected valid UTF-8 after truncation, got %q", got[len(got)-24:])
+	}
+	if want := strings.Repeat("x", MaxToolCallResultChars-1) + "\n... (truncated)"; got != want {
+		t.Fatalf("expected the split rune to be dropped, got %q", got[MaxToolCallResultChars-8:])
+	}
+}
@@ -0,0 +1,173 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Span kinds, outermost first. A run holds tasks, a task holds attempts, an
+// attempt holds LLM calls and an LLM call holds the tool calls it requested.
+const (
+	SpanKindRun     = "run"
+	SpanKindTask    = "task"
+	SpanKindAttempt = "attempt"
+	SpanKindLLM     = "llm"
+	SpanKindTool    = "tool"
+)
+
+const (
+	SpanStatusRunning   = "running"
+	SpanStatusOK        = "ok"
+	SpanStatusError     = "error"
+	SpanStatusCancelled = "cancelled"
+)
+
+// MaxTraceTextChars bounds each stored span input and output. It is far
+// larger than MaxToolCallResultChars because the trace exists to show the
+// exact prompts a model saw.
+const MaxTraceTextChars = 64000
+
+// TraceSpan is one timed node of a run trace.
+type TraceSpan struct {
+	ID        int64
+	ParentID  int64
+	SessionID string
+	RunID     string
+	Kind      string
+	Name      string
+	Role      string
+	TaskID    string
+	Attempt   int
+	Status    string
+	Input     string
+	Output    string
+	Error     string
+	StartedAt time.Time
+	EndedAt   time.Time
+	Duration  time.Duration
+}
+
+// StartSpan records a running span and returns its ID for FinishSpan.
+func (db *DB) StartSpan(ctx context.Context, span TraceSpan) (int64, error) {
+	span.SessionID = strings.TrimSpace(span.SessionID)
+	span.RunID = strings.TrimSpace(span.RunID)
+	span.Kind = strings.TrimSpace(span.Kind)
+	if span.SessionID == "" || span.RunID == "" || span.Kind == "" {
+		return 0, errors.New("trace span requires session, run and kind")
+	}
+	if span.StartedAt.IsZero() {
+		span.StartedAt = time.Now().UTC()
+	}
+	if span.Status == "" {
+		span.Status = SpanStatusRunning
+	}
+	var parent any
+	if span.ParentID > 0 {
+		parent = span.ParentID
+	}
+	res, err := db.conn.ExecContext(ctx, `
+		INSERT INTO trace_spans (session_id, run_id, parent_id, kind, name, role, task_id, attempt, status, input, started_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		span.SessionID,
+		span.RunID,
+		parent,
+		span.Kind,
+		strings.TrimSpace(span.Name),
+		strings.TrimSpace(span.Role),
+		strings.TrimSpace(span.TaskID),
+		span.Attempt,
+		span.Status,
+		TruncateTraceText(span.Input),
+		span.StartedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// FinishSpan closes a span with its final status, output and error.
+func (db *DB) FinishSpan(ctx context.Context, id int64, status, output, errMsg string) error {
+	if id <= 0 {
+		return errors.New("trace span id is required")
+	}
+	var startedAt time.Time
+	if err := db.conn.QueryRowContext(ctx, "SELECT started_at FROM trace_spans WHERE id = ?", id).Scan(&startedAt); err != nil {
+		return err
+	}
+	endedAt := time.Now().UTC()
+	_, err := db.conn.ExecContext(ctx, `
+		UPDATE trace_spans
+		SET status = ?, output = ?, error = ?, ended_at = ?, duration_ms = ?
+		WHERE id = ?
+	`,
+		status,
+		TruncateTraceText(output),
+		errMsg,
+		endedAt,
+		endedAt.Sub(startedAt).Milliseconds(),
+		id,
+	)
+	return err
+}
+
+// ListTraceRuns returns a session's run spans, newest first.
+func (db *DB) ListTraceRuns(ctx context.Context, sessionID string) ([]TraceSpan, error) {
+	return db.queryTraceSpans(ctx, `
+		WHERE session_id = ? AND kind = ?
+		ORDER BY id DESC`, strings.TrimSpace(sessionID), SpanKindRun)
+}
+
+// ListTraceSpans returns every span of one run in the order they started.
+func (db *DB) ListTraceSpans(ctx context.Context, sessionID, runID string) ([]TraceSpan, error) {
+	return db.queryTraceSpans(ctx, `
+		WHERE session_id = ? AND run_id = ?
+		ORDER BY id ASC`, strings.TrimSpace(sessionID), strings.TrimSpace(runID))
+}
+
+func (db *DB) queryTraceSpans(ctx context.Context, where string, args ...any) ([]TraceSpan, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT id, COALESCE(parent_id, 0), session_id, run_id, kind, COALESCE(name, ''), COALESCE(role, ''),
+			COALESCE(task_id, ''), COALESCE(attempt, 0), status, COALESCE(input, ''), COALESCE(output, ''),
+			COALESCE(error, ''), started_at, ended_at, COALESCE(duration_ms, 0)
+		FROM trace_spans`+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []TraceSpan
+	for rows.Next() {
+		var (
+			span       TraceSpan
+			endedAt    *time.Time
+			durationMS int64
+		)
+		if err := rows.Scan(
+			&span.ID, &span.ParentID, &span.SessionID, &span.RunID, &span.Kind, &span.Name, &span.Role,
+			&span.TaskID, &span.Attempt, &span.Status, &span.Input, &span.Output,
+			&span.Error, &span.StartedAt, &endedAt, &durationMS,
+		); err != nil {
+			return nil, err
+		}
+		if endedAt != nil {
+			span.EndedAt = *endedAt
+		}
+		span.Duration = time.Duration(durationMS) * time.Millisecond
+		out = append(out, span)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func TruncateTraceText(text string) string {
+	if len(text) <= MaxTraceTextChars {
+		return text
+	}
+	return text[:MaxTraceTextChars] + "\n... (truncated)"
+}
@@ -0,0 +1,71 @@
+package state
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestTraceSpansStartFinishAndList(t *testing.T) {
+	t.Parallel()
+
+	db, err := Connect(":memory:")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	start := func(span TraceSpan) int64 {
+		t.Helper()
+		id, err := db.StartSpan(ctx, span)
+		if err != nil {
+			t.Fatalf("start %s span: %v", span.Kind, err)
+		}
+		return id
+	}
+	run := start(TraceSpan{SessionID: "s1", RunID: "run-a", Kind: SpanKindRun, Name: "add tests", Input: "add tests"})
+	task := start(TraceSpan{SessionID: "s1", RunID: "run-a", ParentID: run, Kind: SpanKindTask, TaskID: "t1", Role: "coder"})
+	attempt := start(TraceSpan{SessionID: "s1", RunID: "run-a", ParentID: task, Kind: SpanKindAttempt, TaskID: "t1", Attempt: 1})
+	llm := start(TraceSpan{SessionID: "s1", RunID: "run-a", ParentID: attempt, Kind: SpanKindLLM, Input: strings.Repeat("p", MaxTraceTextChars+10)})
+	start(TraceSpan{SessionID: "s1", RunID: "run-b", Kind: SpanKindRun})
+	start(TraceSpan{SessionID: "s2", RunID: "run-c", Kind: SpanKindRun})
+	if _, err := db.StartSpan(ctx, TraceSpan{SessionID: "s1", Kind: SpanKindRun}); err == nil {
+		t.Fatal("expected a span without a run to be rejected")
+	}
+
+	if err := db.FinishSpan(ctx, llm, SpanStatusOK, "done", ""); err != nil {
+		t.Fatalf("finish llm: %v", err)
+	}
+	if err := db.FinishSpan(ctx, attempt, SpanStatusError, "", "coder did not read the plan file"); err != nil {
+		t.Fatalf("finish attempt: %v", err)
+	}
+
+	runs, err := db.ListTraceRuns(ctx, "s1")
+	if err != nil {
+		t.Fatalf("list runs: %v", err)
+	}
+	if len(runs) != 2 || runs[0].RunID != "run-b" || runs[1].RunID != "run-a" {
+		t.Fatalf("expected s1 runs newest first, got %+v", runs)
+	}
+
+	spans, err := db.ListTraceSpans(ctx, "s1", "run-a")
+	if err != nil {
+		t.Fatalf("list spans: %v", err)
+	}
+	if len(spans) != 4 {
+		t.Fatalf("expected 4 spans, got %d", len(spans))
+	}
+	if spans[0].ParentID != 0 || spans[1].ParentID != run || spans[3].ParentID != attempt {
+		t.Fatalf("unexpected parents: %+v", spans)
+	}
+	if spans[0].Status != SpanStatusRunning || !spans[0].EndedAt.IsZero() {
+		t.Fatalf("expected the run to still be running, got %+v", spans[0])
+	}
+	if spans[2].Status != SpanStatusError || spans[2].Error != "coder did not read the plan file" || spans[2].EndedAt.IsZero() {
+		t.Fatalf("expected the attempt to be finished with an error, got %+v", spans[2])
+	}
+	if spans[3].Output != "done" || !strings.HasSuffix(spans[3].Input, "(truncated)") {
+		t.Fatalf("expected truncated input and stored output, got %d chars / %q", len(spans[3].Input), spans[3].Output)
+	}
+}
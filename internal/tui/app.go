@@ -2,6 +2,7 @@ package tui
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -16,6 +17,7 @@ import (
 	"github.com/yubzen/orchestra/internal/agent"
 	"github.com/yubzen/orchestra/internal/config"
 	"github.com/yubzen/orchestra/internal/providers"
+	"github.com/yubzen/orchestra/internal/rag"
 	"github.com/yubzen/orchestra/internal/state"
 )
 
@@ -50,6 +52,10 @@ type AppModel struct {
 	statusbar         *StatusBarModel
 	modelsModal       *ModelsModal
 	planModal         *PlanReviewModal
+	diffModal         *DiffReviewModal
+	sessionsModal     *SessionsModal
+	panes             *PaneView
+	paneLayout        bool
 	rolesModal        *SelectModal
 	connectModal      *SelectModal
 	authMethodModal   *SelectModal
@@ -80,6 +86,13 @@ type AppModel struct {
 	repoPath          string
 	repoDisplayPath   string
 	restoreIssues     map[string]string
+	indexProgress     chan rag.IndexProgress
+	promptCommands    []agent.PromptCommand
+	keys              *KeyMap
+	helpModal         *HelpModal
+	traceModal        *TraceModal
+	// traceSessionID is the session the trace modal is showing.
+	traceSessionID string
 }
 
 func NewAppModel(cfg *config.Config, db *state.DB, session *state.Session, orc *agent.Orchestrator) *AppModel {
@@ -117,6 +130,12 @@ func NewAppModel(cfg *config.Config, db *state.DB, session *state.Session, orc *
 		statusbar:         sb,
 		modelsModal:       modelsModal,
 		planModal:         planModal,
+		diffModal:         NewDiffReviewModal(),
+		sessionsModal:     NewSessionsModal(),
+		helpModal:         NewHelpModal(),
+		traceModal:        NewTraceModal(),
+		panes:             NewPaneView(),
+		paneLayout:        cfg != nil && strings.EqualFold(strings.TrimSpace(cfg.TUI.Layout), "panes"),
 		rolesModal:        roleModal,
 		connectModal:      NewSelectModal("Select Provider", "up/down: navigate  enter: select  esc: close"),
 		authMethodModal:   NewSelectModal("Select auth method", "up/down: navigate  enter: select  esc: close"),
@@ -136,6 +155,23 @@ func NewAppModel(cfg *config.Config, db *state.DB, session *state.Session, orc *
 		restoreIssues:     make(map[string]string),
 	}
 	model.statusbar.SetRepoPath(repoDisplayPath)
+	model.chat.SetMentionSource(func() []string {
+		return agent.MentionCandidates(context.Background(), model.workspaceRoot())
+	})
+	var keyOverrides map[string][]string
+	if cfg != nil {
+		keyOverrides = cfg.TUI.Keys
+	}
+	keys, keyErrs := NewKeyMap(keyOverrides)
+	model.keys = keys
+	model.chat.SetInterruptKeys(strings.Trim(keys.Label(keyCancelRun)+"/"+keys.Label(keyQuit), "/"))
+	for _, err := range keyErrs {
+		model.chat.AddMessage("System", fmt.Sprintf("Ignored key binding: %v", err))
+	}
+	for _, err := range model.loadPromptCommands() {
+		model.chat.AddMessage("System", fmt.Sprintf("Skipped prompt command: %v", err))
+	}
+	model.noticeInterruptedRuns()
 	model.loadPersistedSessionSelections()
 	model.collectMissingCredentialIssues()
 	model.updateRestoreHint()
@@ -158,6 +194,9 @@ func (m *AppModel) Init() tea.Cmd {
 	if m.orc != nil && m.orc.EventChan != nil {
 		cmds = append(cmds, waitForAgentEvent(m.orc.EventChan))
 	}
+	if m.indexProgress != nil {
+		cmds = append(cmds, waitForIndexProgress(m.indexProgress))
+	}
 	if m.chat.IsLoading() {
 		cmds = append(cmds, loadingTickCmd())
 	}
@@ -169,12 +208,21 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		if m.planModal != nil && m.planModal.Visible {
+		if m.keys.Matches(msg, keyQuit) {
+			return m.handleCtrlC()
+		}
+		if m.helpModal != nil && m.helpModal.Visible {
 			switch msg.String() {
-			case "ctrl+c":
-				return m.handleCtrlC()
+			case "esc", "?", "q", "enter":
+				m.helpModal.Close()
+			case "up", "k":
+				m.helpModal.Scroll(-1)
+			case "down", "j":
+				m.helpModal.Scroll(1)
 			}
-
+			return m, nil
+		}
+		if m.planModal != nil && m.planModal.Visible {
 			action, cmd := m.planModal.Update(msg)
 			if action.DecisionMade {
 				if m.orc != nil {
@@ -199,10 +247,39 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, cmd
 		}
 
+		if m.diffModal != nil && m.diffModal.Visible {
+			action, cmd := m.diffModal.Update(msg)
+			if action.DecisionMade {
+				if m.orc != nil {
+					m.orc.SubmitDiffReview(action.Review)
+				}
+				m.diffModal.Close()
+				msgText := summarizeDiffReview(action.Review)
+				return m, func() tea.Msg {
+					return CommandResultMsg{Msg: msgText}
+				}
+			}
+			return m, cmd
+		}
+
+		if m.sessionsModal != nil && m.sessionsModal.Visible {
+			action, cmd := m.sessionsModal.Update(msg)
+			if action.Kind != SessionsActionNone {
+				m.handleSessionsAction(action)
+			}
+			return m, cmd
+		}
+
+		if m.traceModal != nil && m.traceModal.Visible {
+			action, cmd := m.traceModal.Update(msg)
+			if action.Kind == TraceActionLoadRun {
+				m.loadTraceRun(action.RunID)
+			}
+			return m, cmd
+		}
+
 		if m.apiKeyModal != nil && m.apiKeyModal.Visible {
 			switch msg.String() {
-			case "ctrl+c":
-				return m.handleCtrlC()
 			case "esc":
 				m.activeConnectReq = 0
 				m.modelsModal.ClearLoading()
@@ -255,8 +332,6 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		if m.authMethodModal != nil && m.authMethodModal.Visible {
 			switch msg.String() {
-			case "ctrl+c":
-				return m.handleCtrlC()
 			case "esc":
 				m.authMethodModal.Close()
 				return m, nil
@@ -286,8 +361,6 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		if m.connectModal != nil && m.connectModal.Visible {
 			switch msg.String() {
-			case "ctrl+c":
-				return m.handleCtrlC()
 			case "esc":
 				m.connectModal.Close()
 				return m, nil
@@ -327,8 +400,6 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		if m.rolesModal != nil && m.rolesModal.Visible {
 			switch msg.String() {
-			case "ctrl+c":
-				return m.handleCtrlC()
 			case "esc":
 				m.rolesModal.Close()
 				return m, nil
@@ -351,8 +422,6 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		if m.modelsModal != nil && m.modelsModal.Visible {
 			switch msg.String() {
-			case "ctrl+c":
-				return m.handleCtrlC()
 			case "esc":
 				m.modelsModal.Close()
 				return m, nil
@@ -374,10 +443,12 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
-		switch msg.String() {
-		case "ctrl+c":
-			return m.handleCtrlC()
-		case "esc":
+		if m.paneLayout && m.handlePaneKey(msg) {
+			return m, nil
+		}
+
+		switch m.keys.Action(msg) {
+		case keyCancelRun:
 			if strings.TrimSpace(m.chat.GetInputValue()) == "" && m.agentRunActive {
 				if !m.cancelRequested {
 					m.cancelRequested = true
@@ -393,22 +464,30 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				return m, nil
 			}
-		case "p":
+		case keyToggleModeIdle:
 			if strings.TrimSpace(m.chat.GetInputValue()) == "" {
 				modeMsg := m.toggleExecutionMode()
 				return m, func() tea.Msg {
 					return CommandResultMsg{Msg: modeMsg}
 				}
 			}
-		case "shift+tab":
+		case keyToggleMode:
 			modeMsg := m.toggleExecutionMode()
 			return m, func() tea.Msg {
 				return CommandResultMsg{Msg: modeMsg}
 			}
-		case "tab":
+		case keyComplete:
 			if m.chat.ApplyTopSlashSuggestion() {
 				return m, nil
 			}
+		case keyAttachments:
+			m.chat.ToggleAttachments()
+			return m, nil
+		case keyHelp:
+			if strings.TrimSpace(m.chat.GetInputValue()) == "" {
+				m.helpModal.Open(m.keys)
+				return m, nil
+			}
 		}
 		if shouldResetHistoryNavigation(msg) {
 			m.resetInputHistoryNavigation()
@@ -418,7 +497,7 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 		m.statusbar.SetWidth(msg.Width)
-		m.chat.SetSize(msg.Width, msg.Height-1)
+		m.applyLayout()
 		modalWidth := msg.Width - 4
 		if modalWidth < 32 {
 			modalWidth = 32
@@ -438,6 +517,18 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.planModal != nil {
 			m.planModal.SetSize(msg.Width, msg.Height)
 		}
+		if m.diffModal != nil {
+			m.diffModal.SetSize(msg.Width, msg.Height)
+		}
+		if m.sessionsModal != nil {
+			m.sessionsModal.SetSize(msg.Width, msg.Height)
+		}
+		if m.helpModal != nil {
+			m.helpModal.SetSize(msg.Width, msg.Height)
+		}
+		if m.traceModal != nil {
+			m.traceModal.SetSize(msg.Width, msg.Height)
+		}
 
 	case agent.StepUpdate:
 		if strings.EqualFold(strings.TrimSpace(msg.Status), "plan_ready") && m.planModal != nil {
@@ -446,39 +537,73 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				planText = strings.TrimSpace(msg.Msg)
 			}
 			m.chat.AddMessage("System", fmt.Sprintf("[%s] %s", msg.StepID, msg.Msg))
+			m.planModal.WorkingDir = m.workspaceRoot()
 			m.planModal.Open(msg.PlanID, planText)
+		} else if strings.EqualFold(strings.TrimSpace(msg.Status), "review_ready") && m.diffModal != nil {
+			m.chat.AddMessage("System", fmt.Sprintf("[%s] %s", msg.StepID, msg.Msg))
+			m.diffModal.Open(msg.StepID, msg.Staged)
 		} else {
 			m.chat.AddMessage("System", fmt.Sprintf("[%s] %s: %s", msg.StepID, msg.Status, msg.Msg))
 		}
+		m.refreshTraceModal()
 		if m.orc != nil && m.orc.UpdateChan != nil {
 			cmds = append(cmds, waitForStepUpdate(m.orc.UpdateChan))
 		}
 
 	case agent.AgentEvent:
 		m.handleAgentEvent(msg)
+		m.refreshTraceModal()
 		if m.orc != nil && m.orc.EventChan != nil {
 			cmds = append(cmds, waitForAgentEvent(m.orc.EventChan))
 		}
 
+	case rag.IndexProgress:
+		if m.statusbar != nil {
+			m.statusbar.SetIndexStatus(formatIndexProgress(msg))
+		}
+		if m.indexProgress != nil {
+			cmds = append(cmds, waitForIndexProgress(m.indexProgress))
+		}
+
 	case AgentRunResultMsg:
+		if m.diffModal != nil {
+			m.diffModal.Close()
+		}
 		m.chat.SetLoading(false, "")
 		m.clearAgentRunState()
+		m.refreshTraceModal()
 		if m.statusbar != nil {
 			m.statusbar.ResetTeamActivity()
 		}
 
+		undelivered := m.orc.TakeUndeliveredSteering()
+		runID := m.orc.LastRunID()
 		shouldDrain := true
 		if msg.Err != nil {
-			if agent.IsUserCancelled(msg.Err) {
+			if agent.IsUserCancelled(msg.Err) || errors.Is(msg.Err, agent.ErrRunPaused) {
 				shouldDrain = false
 				m.pendingMessages = nil
-				m.chat.AddMessage("System", "✓ Run cancelled by user. Input is ready.")
+				stopped := "cancel"
+				if errors.Is(msg.Err, agent.ErrRunPaused) {
+					stopped = "pause"
+					m.chat.AddMessage("System", fmt.Sprintf("⏸ Run paused. Continue it with /resume %s.", runID))
+				} else if runID != "" {
+					m.chat.AddMessage("System", fmt.Sprintf("✓ Run cancelled by user. Input is ready; continue it later with /resume %s.", runID))
+				} else {
+					m.chat.AddMessage("System", "✓ Run cancelled by user. Input is ready.")
+				}
+				if len(undelivered) > 0 {
+					m.chat.AddMessage("System", fmt.Sprintf("%d steering note(s) were not delivered before the %s.", len(undelivered), stopped))
+				}
 			} else {
 				roleLabel := strings.ToLower(strings.TrimSpace(msg.Role))
 				if roleLabel == "" {
 					roleLabel = "agent"
 				}
 				m.chat.AddMessage("System", fmt.Sprintf("%s error: %v", roleLabel, msg.Err))
+				if runID != "" {
+					m.chat.AddMessage("System", fmt.Sprintf("Retry from the unfinished task with /resume %s.", runID))
+				}
 			}
 		} else if strings.TrimSpace(msg.Reply) != "" {
 			roleLabel := strings.ToUpper(strings.TrimSpace(msg.Role))
@@ -487,6 +612,12 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			m.chat.AddMessage(roleLabel, msg.Reply)
 		}
+		if shouldDrain && len(undelivered) > 0 {
+			// The run ended before an agent picked these up; send them as
+			// follow-up prompts ahead of anything queued later.
+			m.pendingMessages = append(undelivered, m.pendingMessages...)
+			m.chat.AddMessage("System", fmt.Sprintf("Run finished before %d steering note(s) were delivered; sending them as follow-up prompts.", len(undelivered)))
+		}
 
 		if shouldDrain && len(m.pendingMessages) > 0 {
 			next := m.pendingMessages[0]
@@ -510,7 +641,17 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, m.runAgentCmd(runCtx, next), loadingTickCmd())
 		}
 
+	case PaneLayoutMsg:
+		m.paneLayout = msg.Enabled
+		m.applyLayout()
+		if msg.Enabled {
+			m.chat.AddMessage("System", "Split-pane view on. alt+←/→ or alt+1-9 focus a pane, alt+z maximizes it, alt+↑/↓ and alt+pgup/pgdown scroll it.")
+		} else {
+			m.chat.AddMessage("System", "Split-pane view off.")
+		}
+
 	case LoadingTickMsg:
+		m.syncRoleUsage()
 		if m.chat.IsLoading() {
 			cmds = append(cmds, loadingTickCmd())
 		}
@@ -521,6 +662,27 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case OpenRolesModalMsg:
 		m.openModelRolePicker()
 
+	case OpenSessionsModalMsg:
+		m.openSessionsModal()
+
+	case OpenTraceModalMsg:
+		m.openTraceModal(msg.Session)
+
+	case PromptCommandMsg:
+		if cmd := m.startPromptCommand(msg); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+
+	case MentionsExpandedMsg:
+		if cmd := m.sendExpandedPrompt(msg); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+
+	case ResumeRunMsg:
+		if cmd := m.startResumeRun(msg.RunID); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+
 	case OpenConnectModalMsg:
 		if m.connectModal != nil {
 			m.refreshConnectOptions()
@@ -593,7 +755,11 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
-	if msgKey, ok := msg.(tea.KeyMsg); ok && msgKey.String() == "enter" {
+	if msgKey, ok := msg.(tea.KeyMsg); ok && m.keys.Matches(msgKey, keySubmit) {
+		if m.chat.MentionActive() {
+			m.chat.ApplyTopSlashSuggestion()
+			return m, tea.Batch(cmds...)
+		}
 		if selected, ok := m.chat.SelectedSlashSuggestion(); ok {
 			m.appendInputHistory(selected.Name)
 			cmds = append(cmds, handleSlashCommand(selected.Name, m))
@@ -614,22 +780,12 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.chat.AddMessage("System", "Planner model is not configured. Run /connect, then /models and assign Planner before sending prompts.")
 					return m, tea.Batch(cmds...)
 				}
-				// Expand @file references and show original in chat.
-				expanded := expandFileReferences(trimmedInput)
 				m.appendInputHistory(trimmedInput)
-				m.chat.AddMessage("User", trimmedInput)
 				m.chat.ClearInput()
 				m.resetInputHistoryNavigation()
-
-				if m.chat.IsLoading() {
-					// Queue the message if the agent is still processing.
-					m.pendingMessages = append(m.pendingMessages, expanded)
-					m.chat.AddMessage("System", "⏳ Queued — will send after current response.")
-				} else {
-					runCtx := m.startAgentRunContext()
-					m.chat.SetLoading(true, "ORCHESTRATOR")
-					cmds = append(cmds, m.runAgentCmd(runCtx, expanded), loadingTickCmd())
-				}
+				// Attach @mentions off the update loop; the prompt is sent
+				// once they are resolved.
+				cmds = append(cmds, m.expandMentionsCmd(MentionsExpandedMsg{Input: trimmedInput}))
 			}
 		}
 	} else {
@@ -671,6 +827,10 @@ func (m *AppModel) startAgentRunContext() context.Context {
 		m.agentRunCancel()
 	}
 	runCtx, cancel := context.WithCancel(context.Background())
+	m.panes.Reset()
+	if m.statusbar != nil {
+		m.statusbar.ResetRoleUsage()
+	}
 	m.agentRunActive = true
 	m.agentRunCancel = cancel
 	m.cancelRequested = false
@@ -687,6 +847,10 @@ func (m *AppModel) clearAgentRunState() {
 }
 
 func (m *AppModel) runAgentCmd(runCtx context.Context, prompt string) tea.Cmd {
+	return m.runAgentCmdWithOverrides(runCtx, prompt, agent.RunOverrides{})
+}
+
+func (m *AppModel) runAgentCmdWithOverrides(runCtx context.Context, prompt string, overrides agent.RunOverrides) tea.Cmd {
 	return func() tea.Msg {
 		if runCtx == nil {
 			runCtx = context.Background()
@@ -704,7 +868,7 @@ func (m *AppModel) runAgentCmd(runCtx context.Context, prompt string) tea.Cmd {
 			}
 		}
 
-		err := m.orc.Run(runCtx, prompt)
+		err := m.orc.RunWithOverrides(runCtx, prompt, overrides)
 		if agent.IsUserCancelled(err) {
 			err = agent.ErrUserCancelled
 		}
@@ -715,6 +879,17 @@ func (m *AppModel) runAgentCmd(runCtx context.Context, prompt string) tea.Cmd {
 	}
 }
 
+// MentionsExpandedMsg carries a prompt whose @mentions have been resolved,
+// ready to send. Command is the typed prompt command that rendered Input,
+// empty for a plain prompt.
+type MentionsExpandedMsg struct {
+	Input       string
+	Expanded    string
+	Attachments []agent.Attachment
+	Command     string
+	Overrides   agent.RunOverrides
+}
+
 type AgentRunResultMsg struct {
 	Reply string
 	Role  string
@@ -733,11 +908,53 @@ func waitForAgentEvent(ch chan agent.AgentEvent) tea.Cmd {
 	}
 }
 
+// SetIndexProgress subscribes the status bar to RAG indexer progress. Call
+// it before the program starts.
+func (m *AppModel) SetIndexProgress(ch chan rag.IndexProgress) {
+	m.indexProgress = ch
+}
+
+func waitForIndexProgress(ch chan rag.IndexProgress) tea.Cmd {
+	return func() tea.Msg {
+		return <-ch
+	}
+}
+
+func formatIndexProgress(progress rag.IndexProgress) string {
+	status := indexPhaseStatus(progress)
+	if status != "" && progress.Degraded {
+		status += " (lexical)"
+	}
+	return status
+}
+
+func indexPhaseStatus(progress rag.IndexProgress) string {
+	switch progress.Phase {
+	case rag.IndexPhaseScanned:
+		if progress.Total == 0 {
+			return "up to date"
+		}
+		if progress.Rebuild {
+			return fmt.Sprintf("rebuild 0/%d", progress.Total)
+		}
+		return fmt.Sprintf("0/%d", progress.Total)
+	case rag.IndexPhaseIndexing:
+		return fmt.Sprintf("%d/%d", progress.Done, progress.Total)
+	case rag.IndexPhaseIdle:
+		if progress.Failed > 0 {
+			return fmt.Sprintf("%d failed", progress.Failed)
+		}
+		return "up to date"
+	}
+	return ""
+}
+
 func (m *AppModel) View() string {
-	view := lipgloss.JoinVertical(lipgloss.Left,
-		m.chat.View(),
-		m.statusbar.View(),
-	)
+	parts := []string{m.chat.View(), m.statusbar.View()}
+	if m.paneLayout && m.panes != nil {
+		parts = append([]string{m.panes.View()}, parts...)
+	}
+	view := lipgloss.JoinVertical(lipgloss.Left, parts...)
 	base := appStyle.Render(view)
 
 	if m.apiKeyModal != nil && m.apiKeyModal.Visible {
@@ -768,6 +985,34 @@ func (m *AppModel) View() string {
 		}
 		return overlay
 	}
+	if m.diffModal != nil && m.diffModal.Visible {
+		overlay := m.diffModal.View()
+		if m.width > 0 && m.height > 0 {
+			return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, overlay)
+		}
+		return overlay
+	}
+	if m.helpModal != nil && m.helpModal.Visible {
+		overlay := m.helpModal.View()
+		if m.width > 0 && m.height > 0 {
+			return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, overlay)
+		}
+		return overlay
+	}
+	if m.sessionsModal != nil && m.sessionsModal.Visible {
+		overlay := m.sessionsModal.View()
+		if m.width > 0 && m.height > 0 {
+			return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, overlay)
+		}
+		return overlay
+	}
+	if m.traceModal != nil && m.traceModal.Visible {
+		overlay := m.traceModal.View()
+		if m.width > 0 && m.height > 0 {
+			return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, overlay)
+		}
+		return overlay
+	}
 	if m.modelsModal != nil && m.modelsModal.Visible {
 		overlay := m.modelsModal.View()
 		if m.width > 0 && m.height > 0 {
@@ -1347,7 +1592,7 @@ func (m *AppModel) navigateInputHistory(delta int) bool {
 func (m *AppModel) dispatchUpDownKey(msg tea.KeyMsg) (bool, tea.Cmd) {
 	delta, ok := upDownDelta(msg)
 	if !ok {
-		return false, nil
+		return m.dispatchHistoryKey(msg)
 	}
 
 	// Modal navigation always has highest priority.
@@ -1370,6 +1615,32 @@ func (m *AppModel) dispatchUpDownKey(msg tea.KeyMsg) (bool, tea.Cmd) {
 		return true, nil
 	}
 
+	return m.dispatchHistoryKey(msg)
+}
+
+// dispatchHistoryKey moves through suggestions or input history with the
+// history_prev/history_next bindings. Modals keep the arrow keys.
+func (m *AppModel) dispatchHistoryKey(msg tea.KeyMsg) (bool, tea.Cmd) {
+	var delta int
+	switch m.keys.Action(msg) {
+	case keyHistoryPrev:
+		delta = -1
+	case keyHistoryNext:
+		delta = 1
+	default:
+		return false, nil
+	}
+	for _, visible := range []bool{
+		m.modelsModal != nil && m.modelsModal.Visible,
+		m.rolesModal != nil && m.rolesModal.Visible,
+		m.connectModal != nil && m.connectModal.Visible,
+		m.authMethodModal != nil && m.authMethodModal.Visible,
+	} {
+		if visible {
+			return false, nil
+		}
+	}
+
 	// Command suggestions win over input history.
 	if m.chat != nil && m.chat.HasVisibleSuggestions() {
 		m.chat.MoveSlashSelection(delta)
@@ -1466,11 +1737,251 @@ func (m *AppModel) toggleExecutionMode() string {
 	return fmt.Sprintf("Execution mode set to %s", strings.ToUpper(next))
 }
 
+func (m *AppModel) openSessionsModal() {
+	if m.sessionsModal == nil {
+		return
+	}
+	if m.db == nil {
+		m.chat.AddMessage("System", "Session browser unavailable: no session state.")
+		return
+	}
+	summaries, text, err := m.loadSessionSummaries()
+	if err != nil {
+		m.chat.AddMessage("System", fmt.Sprintf("failed to load sessions: %v", err))
+		return
+	}
+	if m.session != nil {
+		m.sessionsModal.CurrentID = m.session.ID
+	}
+	m.sessionsModal.Open(summaries, text)
+}
+
+func (m *AppModel) loadSessionSummaries() ([]state.SessionSummary, map[string]string, error) {
+	summaries, err := m.db.ListSessionSummaries(context.Background())
+	if err != nil {
+		return nil, nil, err
+	}
+	text, err := m.db.SessionSearchText(context.Background())
+	if err != nil {
+		return nil, nil, err
+	}
+	return summaries, text, nil
+}
+
+func (m *AppModel) reloadSessionsModal(notice string) {
+	summaries, text, err := m.loadSessionSummaries()
+	if err != nil {
+		notice = fmt.Sprintf("failed to reload sessions: %v", err)
+	} else {
+		m.sessionsModal.SetSessions(summaries, text)
+	}
+	m.sessionsModal.SetNotice(notice)
+}
+
+func (m *AppModel) handleSessionsAction(action SessionsAction) {
+	if m.db == nil {
+		return
+	}
+	ctx := context.Background()
+	switch action.Kind {
+	case SessionsActionResume:
+		if err := m.resumeSession(action.SessionID); err != nil {
+			m.sessionsModal.SetNotice(err.Error())
+			return
+		}
+		m.sessionsModal.Close()
+	case SessionsActionLoadMessages:
+		messages, err := m.db.GetMessages(ctx, action.SessionID)
+		if err != nil {
+			m.sessionsModal.SetNotice(fmt.Sprintf("failed to load messages: %v", err))
+			return
+		}
+		m.sessionsModal.ShowMessages(messages)
+	case SessionsActionFork:
+		fork, err := m.db.ForkSession(ctx, action.SessionID, action.MessageID)
+		if err != nil {
+			m.sessionsModal.SetNotice(fmt.Sprintf("fork failed: %v", err))
+			return
+		}
+		if err := m.resumeSession(fork.ID); err != nil {
+			m.reloadSessionsModal(fmt.Sprintf("Forked into %s; %v", fork.Name, err))
+			return
+		}
+		m.sessionsModal.Close()
+	case SessionsActionRename:
+		if err := m.db.RenameSession(ctx, action.SessionID, action.Name); err != nil {
+			m.sessionsModal.SetNotice(fmt.Sprintf("rename failed: %v", err))
+			return
+		}
+		if m.session != nil && m.session.ID == action.SessionID {
+			m.session.Name = strings.TrimSpace(action.Name)
+		}
+		m.reloadSessionsModal("Session renamed.")
+	case SessionsActionDelete:
+		if m.session != nil && m.session.ID == action.SessionID {
+			m.sessionsModal.SetNotice("The current session cannot be deleted.")
+			return
+		}
+		if err := m.db.DeleteSession(ctx, action.SessionID); err != nil {
+			m.sessionsModal.SetNotice(fmt.Sprintf("delete failed: %v", err))
+			return
+		}
+		m.reloadSessionsModal("Session deleted.")
+	}
+}
+
+// openTraceModal shows the traced runs of the current session, or of the
+// saved session whose ID prefix or name matches query.
+func (m *AppModel) openTraceModal(query string) {
+	if m.traceModal == nil {
+		return
+	}
+	if m.db == nil {
+		m.chat.AddMessage("System", "Trace unavailable: no session state.")
+		return
+	}
+	ctx := context.Background()
+	var target state.SessionSummary
+	if m.session != nil {
+		target.Session = *m.session
+	}
+	if query = strings.TrimSpace(query); query != "" {
+		summaries, err := m.db.ListSessionSummaries(ctx)
+		if err != nil {
+			m.chat.AddMessage("System", fmt.Sprintf("failed to load sessions: %v", err))
+			return
+		}
+		var matches []state.SessionSummary
+		for _, summary := range summaries {
+			if strings.HasPrefix(summary.ID, query) || strings.EqualFold(strings.TrimSpace(summary.Name), query) {
+				matches = append(matches, summary)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			m.chat.AddMessage("System", fmt.Sprintf("No session matches %q. Use /sessions to find one.", query))
+			return
+		case 1:
+			target = matches[0]
+		default:
+			m.chat.AddMessage("System", fmt.Sprintf("%d sessions match %q; use a longer ID prefix.", len(matches), query))
+			return
+		}
+	}
+	if target.ID == "" {
+		m.chat.AddMessage("System", "Trace unavailable: no active session.")
+		return
+	}
+	runs, err := m.db.ListTraceRuns(ctx, target.ID)
+	if err != nil {
+		m.chat.AddMessage("System", fmt.Sprintf("failed to load trace: %v", err))
+		return
+	}
+	m.traceSessionID = target.ID
+	m.traceModal.Open(sessionLabel(target), runs)
+}
+
+func (m *AppModel) loadTraceRun(runID string) {
+	spans, err := m.db.ListTraceSpans(context.Background(), m.traceSessionID, runID)
+	if err != nil {
+		m.traceModal.SetNotice(fmt.Sprintf("failed to load run: %v", err))
+		return
+	}
+	m.traceModal.ShowRun(runID, spans)
+}
+
+// refreshTraceModal reloads an open trace so a running orchestration can be
+// followed live.
+func (m *AppModel) refreshTraceModal() {
+	if m.traceModal == nil || !m.traceModal.Visible || m.db == nil {
+		return
+	}
+	if runID := m.traceModal.RunID(); runID != "" {
+		m.loadTraceRun(runID)
+		return
+	}
+	if runs, err := m.db.ListTraceRuns(context.Background(), m.traceSessionID); err == nil {
+		m.traceModal.SetRuns(runs)
+	}
+}
+
+// resumeSession switches the running app to another session: the transcript,
+// input history, model selections and execution mode are reloaded from it.
+// The tools and index are bound to the startup directory, so sessions from
+// elsewhere still need a restart.
+func (m *AppModel) resumeSession(id string) error {
+	if m.agentRunActive {
+		return errors.New("cannot switch sessions while a run is active")
+	}
+	ctx := context.Background()
+	next, err := m.db.GetSession(ctx, id)
+	if err != nil {
+		return err
+	}
+	if m.session != nil && (!sameSessionDir(next.WorkingDir, m.session.WorkingDir) || !strings.EqualFold(next.Mode, m.session.Mode)) {
+		return fmt.Errorf("session was started in %s (%s); restart with: orchestra -s %s", displaySessionDir(next.WorkingDir), next.Mode, next.ID)
+	}
+	messages, err := m.db.GetMessages(ctx, next.ID)
+	if err != nil {
+		return err
+	}
+
+	m.session = next
+	if m.orc != nil {
+		m.orc.Session = next
+	}
+	m.chat.ClearMessages()
+	for _, msg := range messages {
+		sender := "User"
+		if !strings.EqualFold(msg.Role, "user") {
+			sender = strings.ToUpper(strings.TrimSpace(msg.AgentRole))
+			if sender == "" {
+				sender = "ORCHESTRATOR"
+			}
+		}
+		m.chat.AddMessage(sender, msg.Content)
+	}
+	m.pendingMessages = nil
+	m.inputHistory = nil
+	m.loadPersistedInputHistory()
+	m.resetInputHistoryNavigation()
+	m.loadPersistedSessionSelections()
+	m.panes.Reset()
+	if m.statusbar != nil {
+		m.statusbar.ResetRoleUsage()
+	}
+	m.syncRoleAndModelDisplay()
+	m.chat.AddMessage("System", fmt.Sprintf("Resumed session %s (%d messages).", sessionLabel(state.SessionSummary{Session: *next}), len(messages)))
+	return nil
+}
+
+func sameSessionDir(a, b string) bool {
+	clean := func(dir string) string {
+		dir = strings.TrimSpace(dir)
+		if dir == "" {
+			dir = "."
+		}
+		if abs, err := filepath.Abs(dir); err == nil {
+			return abs
+		}
+		return filepath.Clean(dir)
+	}
+	return clean(a) == clean(b)
+}
+
 func (m *AppModel) handleAgentEvent(event agent.AgentEvent) {
 	if m == nil || m.chat == nil {
 		return
 	}
+	if event.Type == agent.EventSteer {
+		m.chat.AddMessage("System", fmt.Sprintf("» %s %s", formatAgentRoleLabel(event.Role), strings.TrimSpace(event.Detail)))
+		return
+	}
 	m.updateActivityLine(event)
+	if m.panes != nil {
+		m.panes.HandleEvent(event)
+		m.syncRoleUsage()
+	}
 	if m.statusbar != nil {
 		roleName := strings.ToUpper(strings.TrimSpace(string(event.Role)))
 		if roleName != "" {
@@ -1543,6 +2054,65 @@ func (m *AppModel) handleAgentEvent(event agent.AgentEvent) {
 	}
 }
 
+// applyLayout sizes the chat and, in the split-pane layout, the role panes
+// above it.
+func (m *AppModel) applyLayout() {
+	if m.width <= 0 || m.height <= 0 {
+		return
+	}
+	available := m.height - 1
+	if !m.paneLayout || m.panes == nil {
+		m.chat.SetSize(m.width, available)
+		return
+	}
+	paneHeight := available * 3 / 5
+	m.panes.SetSize(m.width, paneHeight)
+	m.chat.SetSize(m.width, available-paneHeight)
+}
+
+// handlePaneKey focuses, maximizes and scrolls role panes. It reports
+// whether the key was used.
+func (m *AppModel) handlePaneKey(msg tea.KeyMsg) bool {
+	if m.panes == nil {
+		return false
+	}
+	switch m.keys.Action(msg) {
+	case keyPaneNext:
+		m.panes.FocusNext(1)
+	case keyPanePrev:
+		m.panes.FocusNext(-1)
+	case keyPaneMaximize:
+		m.panes.ToggleMaximize()
+	case keyPaneScrollUp:
+		m.panes.Scroll(-1)
+	case keyPaneScrollDown:
+		m.panes.Scroll(1)
+	case keyPanePageUp:
+		m.panes.Scroll(-m.panes.PageSize())
+	case keyPanePageDown:
+		m.panes.Scroll(m.panes.PageSize())
+	default:
+		key := msg.String()
+		if len(key) == 5 && strings.HasPrefix(key, "alt+") && key[4] >= '1' && key[4] <= '9' {
+			m.panes.FocusIndex(int(key[4] - '1'))
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// syncRoleUsage copies per-role elapsed time and token estimates from the
+// panes to the status bar.
+func (m *AppModel) syncRoleUsage() {
+	if m.panes == nil || m.statusbar == nil {
+		return
+	}
+	for role, usage := range m.panes.Usage() {
+		m.statusbar.SetRoleUsage(string(role), usage)
+	}
+}
+
 func formatAgentRoleLabel(role agent.Role) string {
 	raw := strings.TrimSpace(string(role))
 	if raw == "" {
@@ -1893,73 +2463,206 @@ func isInternalOrchestraPath(text string) bool {
 	return strings.Contains(strings.ToLower(strings.TrimSpace(text)), ".orchestra/")
 }
 
-// expandFileReferences scans input for @path/to/file patterns, reads the
-// referenced files or directory listings, and injects their content as context.
-func expandFileReferences(input string) string {
-	words := strings.Fields(input)
-	var refs []string
-	var nonRefs []string
-	for _, w := range words {
-		if strings.HasPrefix(w, "@") && len(w) > 1 {
-			refs = append(refs, w[1:])
-		} else {
-			nonRefs = append(nonRefs, w)
+// expandMentionsCmd resolves msg.Input's @mentions against the workspace,
+// budgeted to a share of the planner model's context window. Reading the
+// mentioned files happens in the command, never in Update.
+func (m *AppModel) expandMentionsCmd(msg MentionsExpandedMsg) tea.Cmd {
+	opts := agent.MentionOptions{
+		WorkingDir:  m.workspaceRoot(),
+		TokenBudget: agent.MentionBudget(m.roleModels["PLANNER"]),
+	}
+	if m.orc != nil && m.orc.Planner != nil {
+		opts.Index = m.orc.Planner.Indexer
+	}
+	return func() tea.Msg {
+		msg.Expanded, msg.Attachments = agent.ExpandMentions(context.Background(), msg.Input, opts)
+		return msg
+	}
+}
+
+// sendExpandedPrompt shows the original prompt with its attachments and
+// sends the expanded one: steering an active run, queued behind a pending
+// response, or as a new run.
+func (m *AppModel) sendExpandedPrompt(msg MentionsExpandedMsg) tea.Cmd {
+	if msg.Command != "" {
+		// A run may have started while the mentions were resolving.
+		if m.agentRunActive || m.chat.IsLoading() {
+			m.chat.AddMessage("System", fmt.Sprintf("%s not started: a run is active. Send it again when it finishes.", msg.Command))
+			return nil
 		}
+		m.chat.AddUserMessage(msg.Input, msg.Attachments)
+		runCtx := m.startAgentRunContext()
+		m.chat.SetLoading(true, "ORCHESTRATOR")
+		return tea.Batch(m.runAgentCmdWithOverrides(runCtx, msg.Expanded, msg.Overrides), loadingTickCmd())
 	}
-	if len(refs) == 0 {
-		return input
+
+	// During a run, input steers the active agent instead of waiting for
+	// the run to finish.
+	if m.agentRunActive && m.orc != nil && m.orc.Steer(msg.Expanded) == nil {
+		m.chat.AddSteerMessage(msg.Input, msg.Attachments)
+		return nil
 	}
+	m.chat.AddUserMessage(msg.Input, msg.Attachments)
+	if m.chat.IsLoading() {
+		// Queue the message if the agent is still processing.
+		m.pendingMessages = append(m.pendingMessages, msg.Expanded)
+		m.chat.AddMessage("System", "⏳ Queued — will send after current response.")
+		return nil
+	}
+	runCtx := m.startAgentRunContext()
+	m.chat.SetLoading(true, "ORCHESTRATOR")
+	return tea.Batch(m.runAgentCmd(runCtx, msg.Expanded), loadingTickCmd())
+}
 
-	var contextParts []string
-	for _, ref := range refs {
-		path := ref
-		if !filepath.IsAbs(path) {
-			cwd, err := os.Getwd()
-			if err != nil {
-				continue
-			}
-			path = filepath.Join(cwd, path)
+// workspaceRoot is the directory the agents' tools work in.
+func (m *AppModel) workspaceRoot() string {
+	if m.orc != nil && strings.TrimSpace(m.orc.WorkingDir) != "" {
+		return m.orc.WorkingDir
+	}
+	return m.repoPath
+}
+
+// loadPromptCommands reloads the user-defined commands and offers them in
+// the "/" suggestions after the built-ins. Commands named like a built-in
+// are skipped.
+func (m *AppModel) loadPromptCommands() []error {
+	loaded, errs := agent.LoadPromptCommands(m.workspaceRoot(), agent.UserCommandsDir())
+	m.promptCommands = m.promptCommands[:0]
+	for _, cmd := range loaded {
+		if isBuiltinSlashCommand("/" + cmd.Name) {
+			errs = append(errs, fmt.Errorf("%s: /%s is a built-in command", cmd.Path, cmd.Name))
+			continue
 		}
+		m.promptCommands = append(m.promptCommands, cmd)
+	}
+	m.chat.SetSlashCommands(m.slashCommandList())
+	return errs
+}
+
+// slashCommandList is every command offered after "/": built-ins first,
+// then prompt commands.
+func (m *AppModel) slashCommandList() []slashCommand {
+	if m == nil || len(m.promptCommands) == 0 {
+		return slashCommands
+	}
+	list := append([]slashCommand(nil), slashCommands...)
+	for _, cmd := range m.promptCommands {
+		desc := cmd.Description
+		if desc == "" {
+			desc = cmd.Usage()
+		}
+		list = append(list, slashCommand{Name: "/" + cmd.Name, Description: fmt.Sprintf("%s (%s)", desc, cmd.Scope)})
+	}
+	return list
+}
+
+// startResumeRun continues a saved run, the most recently active resumable
+// one when runID is empty. A run from another session switches to that
+// session first so the transcript and the run stay together.
+func (m *AppModel) startResumeRun(runID string) tea.Cmd {
+	if m.agentRunActive || m.chat.IsLoading() {
+		m.chat.AddMessage("System", "A run is active. Pause or cancel it before resuming another.")
+		return nil
+	}
+	if m.db == nil || m.orc == nil {
+		m.chat.AddMessage("System", "Resume unavailable: no session state.")
+		return nil
+	}
+	if !m.hasPlannerModelSelected() {
+		m.chat.AddMessage("System", "Planner model is not configured. Run /connect, then /models and assign Planner before resuming.")
+		return nil
+	}
 
-		info, err := os.Stat(path)
+	ctx := context.Background()
+	var run *state.Run
+	if runID = strings.TrimSpace(runID); runID == "" {
+		runs, err := m.db.ListResumableRuns(ctx)
 		if err != nil {
-			contextParts = append(contextParts, fmt.Sprintf("--- @%s (not found) ---", ref))
-			continue
+			m.chat.AddMessage("System", fmt.Sprintf("Resume failed: %v", err))
+			return nil
+		}
+		if len(runs) == 0 {
+			m.chat.AddMessage("System", "No runs to resume.")
+			return nil
+		}
+		run = &runs[0]
+	} else {
+		found, err := m.db.GetRun(ctx, runID)
+		if err != nil {
+			m.chat.AddMessage("System", fmt.Sprintf("Resume failed: %v", err))
+			return nil
+		}
+		run = found
+	}
+	if run.Status == state.RunStatusCompleted {
+		m.chat.AddMessage("System", fmt.Sprintf("Run %s already completed.", run.ID))
+		return nil
+	}
+	if run.Live(time.Now()) {
+		m.chat.AddMessage("System", fmt.Sprintf("Run %s is still running in process %d.", run.ID, run.OwnerPID))
+		return nil
+	}
+	if m.session == nil || run.SessionID != m.session.ID {
+		if err := m.resumeSession(run.SessionID); err != nil {
+			m.chat.AddMessage("System", fmt.Sprintf("Resume failed: %v", err))
+			return nil
 		}
+	}
 
-		if info.IsDir() {
-			entries, err := os.ReadDir(path)
-			if err != nil {
-				contextParts = append(contextParts, fmt.Sprintf("--- @%s (cannot read directory) ---", ref))
-				continue
-			}
-			var listing []string
-			for _, e := range entries {
-				name := e.Name()
-				if e.IsDir() {
-					name += "/"
-				}
-				listing = append(listing, name)
-			}
-			contextParts = append(contextParts, fmt.Sprintf("--- @%s (directory listing) ---\n%s", ref, strings.Join(listing, "\n")))
-		} else {
-			data, err := os.ReadFile(path)
-			if err != nil {
-				contextParts = append(contextParts, fmt.Sprintf("--- @%s (cannot read) ---", ref))
-				continue
-			}
-			content := string(data)
-			// Cap at ~32KB to avoid blowing up context.
-			if len(content) > 32*1024 {
-				content = content[:32*1024] + "\n... (truncated)"
-			}
-			contextParts = append(contextParts, fmt.Sprintf("--- @%s ---\n%s", ref, content))
+	m.chat.AddMessage("System", "↻ Resuming "+formatResumableRun(*run))
+	runCtx := m.startAgentRunContext()
+	m.chat.SetLoading(true, "ORCHESTRATOR")
+	orc, id := m.orc, run.ID
+	return tea.Batch(func() tea.Msg {
+		err := orc.ResumeRun(runCtx, id)
+		if agent.IsUserCancelled(err) {
+			err = agent.ErrUserCancelled
+		}
+		return AgentRunResultMsg{Role: "ORCHESTRATOR", Err: err}
+	}, loadingTickCmd())
+}
+
+// noticeInterruptedRuns offers to resume the runs an earlier process left
+// running, because it was killed, or paused. Runs another process is still
+// running are not offered.
+func (m *AppModel) noticeInterruptedRuns() {
+	if m.db == nil {
+		return
+	}
+	runs, err := m.db.ListInterruptedRuns(context.Background())
+	if err != nil {
+		return
+	}
+	var lines []string
+	for _, run := range runs {
+		if run.Resumable() {
+			lines = append(lines, "  "+formatResumableRun(run))
 		}
 	}
+	if len(lines) == 0 {
+		return
+	}
+	m.chat.AddMessage("System", fmt.Sprintf("Found %d unfinished run(s):\n%s\nContinue one with /resume <run-id>, or the latest with /resume.", len(lines), strings.Join(lines, "\n")))
+}
 
-	prompt := strings.Join(nonRefs, " ")
-	if len(contextParts) > 0 {
-		prompt = prompt + "\n\n" + strings.Join(contextParts, "\n\n")
+func formatResumableRun(run state.Run) string {
+	status := run.Status
+	if run.Interrupted(time.Now()) {
+		status = "interrupted"
+	}
+	return fmt.Sprintf("%s  %s  %d/%d tasks  %s", run.ID, status, run.DoneTasks(), len(run.Tasks), agent.PromptPreview(run.Prompt))
+}
+
+// startPromptCommand sends a rendered prompt command like a typed prompt,
+// with the command's role, dispatch and execution mode.
+func (m *AppModel) startPromptCommand(msg PromptCommandMsg) tea.Cmd {
+	if m.agentRunActive || m.chat.IsLoading() {
+		m.chat.AddMessage("System", fmt.Sprintf("%s not started: a run is active. Send it again when it finishes.", msg.Input))
+		return nil
+	}
+	if !m.hasPlannerModelSelected() {
+		m.chat.AddMessage("System", "Planner model is not configured. Run /connect, then /models and assign Planner before sending prompts.")
+		return nil
 	}
-	return prompt
+	return m.expandMentionsCmd(MentionsExpandedMsg{Input: msg.Prompt, Command: msg.Input, Overrides: msg.Overrides})
 }
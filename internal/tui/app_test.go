@@ -11,6 +11,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/yubzen/orchestra/internal/agent"
 	"github.com/yubzen/orchestra/internal/providers"
+	"github.com/yubzen/orchestra/internal/rag"
 	"github.com/yubzen/orchestra/internal/state"
 )
 
@@ -745,3 +746,25 @@ func TestHandleAgentEventSkipsInternalOrchestraActivityLine(t *testing.T) {
 		t.Fatalf("expected previous visible activity to remain, got %q", view)
 	}
 }
+
+func TestAppShowsIndexProgressInStatusBar(t *testing.T) {
+	t.Parallel()
+
+	app := NewAppModel(nil, nil, nil, nil)
+	progress := make(chan rag.IndexProgress, 1)
+	app.SetIndexProgress(progress)
+	app.statusbar.SetWidth(240)
+
+	_, cmd := app.Update(rag.IndexProgress{Phase: rag.IndexPhaseIndexing, Path: "a.go", Done: 3, Total: 12})
+	if cmd == nil {
+		t.Fatal("expected a command that waits for the next progress event")
+	}
+	if view := app.statusbar.View(); !strings.Contains(view, "[IDX: 3/12]") {
+		t.Fatalf("expected indexing progress in status bar, got %q", view)
+	}
+
+	app.Update(rag.IndexProgress{Phase: rag.IndexPhaseIdle, Done: 12, Total: 12})
+	if view := app.statusbar.View(); !strings.Contains(view, "[IDX: up to date]") {
+		t.Fatalf("expected idle index status, got %q", view)
+	}
+}
@@ -9,48 +9,86 @@ import (
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-)
 
-var splashCardBG = lipgloss.Color("236")
+	"github.com/yubzen/orchestra/internal/agent"
+)
 
 var (
-	chatViewportStyle = lipgloss.NewStyle().Border(lipgloss.NormalBorder(), false, false, true, false).BorderForeground(lipgloss.Color("238"))
-	userInputStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("86")).Bold(true)
-	assistantStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
-	systemStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Italic(true)
-	activityStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("45")).Bold(true).Padding(0, 1)
-	activityMetaStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
-	diffBoxStyle      = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("240")).Padding(0, 1)
-	diffHeaderStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("81")).Bold(true)
-	diffAddStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
-	diffDelStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
-	diffCtxStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
-	suggestBoxStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("250"))
-	suggestNameStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("39")).Bold(true)
-	suggestDescStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
-	suggestSelStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("51")).Bold(true)
-	splashLogoBlue    = lipgloss.NewStyle().Foreground(lipgloss.Color("39")).Bold(true)
-	splashLogoWhite   = lipgloss.NewStyle().Foreground(lipgloss.Color("255")).Bold(true)
-	splashLogoYellow  = lipgloss.NewStyle().Foreground(lipgloss.Color("220")).Bold(true)
-	splashCardStyle   = lipgloss.NewStyle().
-				Border(lipgloss.NormalBorder(), false, false, false, true).
-				BorderForeground(lipgloss.Color("39")).
-				Padding(1, 2).
-				Background(splashCardBG)
-	splashPromptStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("244")).Background(splashCardBG)
-	splashHintStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("245")).Background(splashCardBG)
-	splashCursorStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("255")).Background(splashCardBG).Bold(true)
-	splashPlaceholderStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Background(splashCardBG).Italic(true)
-	splashPromptIndicator  = lipgloss.NewStyle().Foreground(lipgloss.Color("86")).Background(splashCardBG).Bold(true)
-	splashTipStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("220"))
-	loadingStyle           = lipgloss.NewStyle().Foreground(lipgloss.Color("220")).Bold(true)
-	loadingTimerStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
-	placeholderStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Italic(true)
-	roleLabelCoderStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("212")).Bold(true)
-	roleLabelOtherStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("39")).Bold(true)
-	promptIndicator        = lipgloss.NewStyle().Foreground(lipgloss.Color("86")).Bold(true)
+	splashCardBG           lipgloss.Color
+	chatViewportStyle      lipgloss.Style
+	userInputStyle         lipgloss.Style
+	assistantStyle         lipgloss.Style
+	systemStyle            lipgloss.Style
+	activityStyle          lipgloss.Style
+	activityMetaStyle      lipgloss.Style
+	diffBoxStyle           lipgloss.Style
+	diffHeaderStyle        lipgloss.Style
+	diffAddStyle           lipgloss.Style
+	diffDelStyle           lipgloss.Style
+	diffCtxStyle           lipgloss.Style
+	suggestBoxStyle        lipgloss.Style
+	suggestNameStyle       lipgloss.Style
+	suggestDescStyle       lipgloss.Style
+	suggestSelStyle        lipgloss.Style
+	splashLogoBlue         lipgloss.Style
+	splashLogoWhite        lipgloss.Style
+	splashLogoYellow       lipgloss.Style
+	splashCardStyle        lipgloss.Style
+	splashPromptStyle      lipgloss.Style
+	splashHintStyle        lipgloss.Style
+	splashCursorStyle      lipgloss.Style
+	splashPlaceholderStyle lipgloss.Style
+	splashPromptIndicator  lipgloss.Style
+	splashTipStyle         lipgloss.Style
+	loadingStyle           lipgloss.Style
+	loadingTimerStyle      lipgloss.Style
+	placeholderStyle       lipgloss.Style
+	roleLabelCoderStyle    lipgloss.Style
+	roleLabelOtherStyle    lipgloss.Style
+	promptIndicator        lipgloss.Style
+	steerIndicator         lipgloss.Style
 )
 
+func applyChatStyles(p Palette) {
+	splashCardBG = p.SurfaceAlt
+	chatViewportStyle = lipgloss.NewStyle().Border(lipgloss.NormalBorder(), false, false, true, false).BorderForeground(p.Border)
+	userInputStyle = lipgloss.NewStyle().Foreground(p.Prompt).Bold(true)
+	assistantStyle = lipgloss.NewStyle().Foreground(p.Assistant)
+	systemStyle = lipgloss.NewStyle().Foreground(p.Faint).Italic(true)
+	activityStyle = lipgloss.NewStyle().Foreground(p.Active).Bold(true).Padding(0, 1)
+	activityMetaStyle = lipgloss.NewStyle().Foreground(p.Muted)
+	diffBoxStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(p.Faint).Padding(0, 1)
+	diffHeaderStyle = lipgloss.NewStyle().Foreground(p.Title).Bold(true)
+	diffAddStyle = lipgloss.NewStyle().Foreground(p.Success)
+	diffDelStyle = lipgloss.NewStyle().Foreground(p.Error)
+	diffCtxStyle = lipgloss.NewStyle().Foreground(p.Muted)
+	suggestBoxStyle = lipgloss.NewStyle().Foreground(p.Subtle)
+	suggestNameStyle = lipgloss.NewStyle().Foreground(p.Accent).Bold(true)
+	suggestDescStyle = lipgloss.NewStyle().Foreground(p.Muted)
+	suggestSelStyle = lipgloss.NewStyle().Foreground(p.Selected).Bold(true)
+	splashLogoBlue = lipgloss.NewStyle().Foreground(p.Accent).Bold(true)
+	splashLogoWhite = lipgloss.NewStyle().Foreground(p.Bright).Bold(true)
+	splashLogoYellow = lipgloss.NewStyle().Foreground(p.Warning).Bold(true)
+	splashCardStyle = lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder(), false, false, false, true).
+		BorderForeground(p.Accent).
+		Padding(1, 2).
+		Background(splashCardBG)
+	splashPromptStyle = lipgloss.NewStyle().Foreground(p.Muted).Background(splashCardBG)
+	splashHintStyle = lipgloss.NewStyle().Foreground(p.Muted).Background(splashCardBG)
+	splashCursorStyle = lipgloss.NewStyle().Foreground(p.Bright).Background(splashCardBG).Bold(true)
+	splashPlaceholderStyle = lipgloss.NewStyle().Foreground(p.Faint).Background(splashCardBG).Italic(true)
+	splashPromptIndicator = lipgloss.NewStyle().Foreground(p.Prompt).Background(splashCardBG).Bold(true)
+	splashTipStyle = lipgloss.NewStyle().Foreground(p.Warning)
+	loadingStyle = lipgloss.NewStyle().Foreground(p.Warning).Bold(true)
+	loadingTimerStyle = lipgloss.NewStyle().Foreground(p.Muted)
+	placeholderStyle = lipgloss.NewStyle().Foreground(p.Faint).Italic(true)
+	roleLabelCoderStyle = lipgloss.NewStyle().Foreground(p.Assistant).Bold(true)
+	roleLabelOtherStyle = lipgloss.NewStyle().Foreground(p.Accent).Bold(true)
+	promptIndicator = lipgloss.NewStyle().Foreground(p.Prompt).Bold(true)
+	steerIndicator = lipgloss.NewStyle().Foreground(p.Caution).Bold(true)
+}
+
 var orchestraBlockGlyphs = map[rune][]string{
 	'O': {
 		" ####### ",
@@ -114,9 +152,10 @@ var orchestraBlockGlyphs = map[rune][]string{
 type LoadingTickMsg struct{}
 
 type ChatMessage struct {
-	Sender  string
-	Content string
-	Diff    *FileDiffMessage
+	Sender      string
+	Content     string
+	Diff        *FileDiffMessage
+	Attachments []agent.Attachment
 }
 
 type FileDiffMessage struct {
@@ -151,6 +190,17 @@ type ChatModel struct {
 	stickToBottom    bool
 	activity         ActivityLine
 	activityVisible  bool
+	// mentionSource lists the workspace paths offered after "@"; it is
+	// called once each time a mention starts.
+	mentionSource func() []string
+	// interruptKeys is shown in the activity line; empty means the defaults.
+	interruptKeys string
+	// commands are offered after "/"; nil means the built-ins alone.
+	commands            []slashCommand
+	mentionCandidates   []string
+	mentionActive       bool
+	mentionStart        int
+	attachmentsExpanded bool
 }
 
 func NewChatModel() *ChatModel {
@@ -245,6 +295,58 @@ func (m *ChatModel) AddMessage(sender, content string) {
 	m.renderMessages()
 }
 
+// AddUserMessage shows a prompt with the attachments its @mentions
+// resolved to.
+func (m *ChatModel) AddUserMessage(content string, attachments []agent.Attachment) {
+	m.messages = append(m.messages, ChatMessage{Sender: "User", Content: content, Attachments: attachments})
+	m.renderMessages()
+}
+
+// AddSteerMessage shows input sent to a running orchestration, marked so
+// it reads as a mid-run correction rather than a new prompt.
+func (m *ChatModel) AddSteerMessage(content string, attachments []agent.Attachment) {
+	m.messages = append(m.messages, ChatMessage{Sender: "Steer", Content: content, Attachments: attachments})
+	m.renderMessages()
+}
+
+// ToggleAttachments expands or collapses every attachment preview.
+func (m *ChatModel) ToggleAttachments() bool {
+	m.attachmentsExpanded = !m.attachmentsExpanded
+	m.renderMessages()
+	return m.attachmentsExpanded
+}
+
+// SetInterruptKeys sets the keys the activity line offers for interrupting
+// a run.
+func (m *ChatModel) SetInterruptKeys(keys string) {
+	m.interruptKeys = keys
+}
+
+// SetSlashCommands replaces the commands offered after "/".
+func (m *ChatModel) SetSlashCommands(commands []slashCommand) {
+	m.commands = commands
+}
+
+// SetMentionSource sets where the @ picker gets its paths from.
+func (m *ChatModel) SetMentionSource(source func() []string) {
+	m.mentionSource = source
+}
+
+// MentionActive reports whether the suggestions are @mention paths rather
+// than slash commands.
+func (m *ChatModel) MentionActive() bool {
+	return m.mentionActive && len(m.slashSuggestions) > 0
+}
+
+// ClearMessages empties the transcript, as when switching sessions.
+func (m *ChatModel) ClearMessages() {
+	m.messages = nil
+	m.keyedMessages = make(map[string]int)
+	m.stickToBottom = true
+	m.ClearActivity()
+	m.renderMessages()
+}
+
 func (m *ChatModel) AddFileDiff(path string, oldLines, newLines []string) {
 	path = strings.TrimSpace(path)
 	if path == "" {
@@ -309,6 +411,15 @@ func (m *ChatModel) renderMessages() {
 		case "User":
 			indicator := promptIndicator.Render("> ")
 			block = indicator + wrapToWidth(content, contentWidth-2)
+			if len(msg.Attachments) > 0 {
+				block += "\n" + renderAttachments(msg.Attachments, m.attachmentsExpanded, contentWidth)
+			}
+		case "Steer":
+			indicator := steerIndicator.Render("» steer ")
+			block = indicator + wrapToWidth(content, contentWidth-lipgloss.Width(indicator))
+			if len(msg.Attachments) > 0 {
+				block += "\n" + renderAttachments(msg.Attachments, m.attachmentsExpanded, contentWidth)
+			}
 		case "System":
 			block = systemStyle.Render(wrapToWidth(content, contentWidth))
 		default:
@@ -427,7 +538,11 @@ func (m *ChatModel) renderActivityLine() string {
 	if target != "" {
 		label += "  ·  " + target
 	}
-	meta := fmt.Sprintf("(%s · esc/ctrl+c to interrupt)", formatActivityElapsed(elapsed))
+	keys := m.interruptKeys
+	if keys == "" {
+		keys = "esc/ctrl+c"
+	}
+	meta := fmt.Sprintf("(%s · %s to interrupt)", formatActivityElapsed(elapsed), keys)
 	return activityStyle.Render(spinner+" "+label) + "  " + activityMetaStyle.Render(meta)
 }
 
@@ -608,6 +723,10 @@ func (m *ChatModel) ApplyTopSlashSuggestion() bool {
 	if !ok {
 		return false
 	}
+	if m.mentionActive {
+		m.applyMention(suggestion.Name)
+		return true
+	}
 	m.textInput.SetValue(suggestion.Name)
 	// SetValue keeps the previous cursor in some cases; force the cursor to
 	// command end so continued typing appends after autocomplete.
@@ -649,6 +768,21 @@ func (m *ChatModel) MoveSlashSelection(delta int) bool {
 	return true
 }
 
+// applyMention replaces the @token being typed with mention. Files get a
+// trailing space; directories stay open so the path can be narrowed.
+func (m *ChatModel) applyMention(mention string) {
+	value := []rune(m.textInput.Value())
+	pos := min(m.textInput.Position(), len(value))
+	start := min(m.mentionStart, pos)
+	if !strings.HasSuffix(mention, "/") {
+		mention += " "
+	}
+	next := string(value[:start]) + mention + string(value[pos:])
+	m.textInput.SetValue(next)
+	m.textInput.SetCursor(start + len([]rune(mention)))
+	m.updateSlashSuggestions()
+}
+
 func (m *ChatModel) HasVisibleSuggestions() bool {
 	if m == nil || !m.inputEnabled {
 		return false
@@ -669,7 +803,29 @@ func (m *ChatModel) updateSlashSuggestions() {
 	inputChanged := input != m.lastSuggestInput
 	m.lastSuggestInput = input
 
-	m.slashSuggestions = filterSlashCommands(input, 6)
+	if start, query, ok := mentionToken([]rune(input), m.textInput.Position()); ok && m.mentionSource != nil {
+		if !m.mentionActive {
+			m.mentionCandidates = m.mentionSource()
+		}
+		m.mentionActive = true
+		m.mentionStart = start
+		m.slashSuggestions = nil
+		for _, candidate := range rankMentionCandidates(query, m.mentionCandidates, 6) {
+			desc := "file"
+			if strings.HasSuffix(candidate, "/") {
+				desc = "directory"
+			}
+			m.slashSuggestions = append(m.slashSuggestions, slashCommand{Name: "@" + candidate, Description: desc})
+		}
+	} else {
+		m.mentionActive = false
+		m.mentionCandidates = nil
+		commands := m.commands
+		if commands == nil {
+			commands = slashCommands
+		}
+		m.slashSuggestions = filterSlashCommandList(commands, input, 6)
+	}
 	if len(m.slashSuggestions) == 0 {
 		m.selectedSlashIdx = -1
 	} else if inputChanged {
@@ -729,8 +885,11 @@ func (m *ChatModel) renderInputForView() string {
 
 	// Show placeholder when empty
 	if len(valueRunes) == 0 {
-		placeholder := placeholderStyle.Render("Type your message or @path/to/file")
-		return promptIndicator.Render("> ") + "█ " + placeholder
+		hint := "Type your message or @path/to/file"
+		if m.isLoading {
+			hint = "Type to steer the running task"
+		}
+		return promptIndicator.Render("> ") + "█ " + placeholderStyle.Render(hint)
 	}
 
 	left := string(valueRunes[:pos])
@@ -222,6 +222,21 @@ func TestChatModelRendersFileDiffBlockWithCap(t *testing.T) {
 	}
 }
 
+func TestChatModelMarksSteeringMessages(t *testing.T) {
+	m := NewChatModel()
+	m.SetSize(120, 40)
+	m.AddUserMessage("add retries to the client", nil)
+	m.AddSteerMessage("only retry idempotent requests", nil)
+
+	view := m.View()
+	if !strings.Contains(view, "> add retries to the client") {
+		t.Fatalf("expected the prompt with the input marker, got %q", view)
+	}
+	if !strings.Contains(view, "» steer only retry idempotent requests") {
+		t.Fatalf("expected the steering marker, got %q", view)
+	}
+}
+
 func TestChatModelActivityLineVisibleAndClearable(t *testing.T) {
 	m := NewChatModel()
 	m.SetSize(100, 20)
@@ -1,10 +1,14 @@
 package tui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/yubzen/orchestra/internal/agent"
 )
 
 type CommandResultMsg struct {
@@ -14,6 +18,32 @@ type CommandResultMsg struct {
 type OpenModelsModalMsg struct{}
 type OpenRolesModalMsg struct{}
 type OpenConnectModalMsg struct{}
+type OpenSessionsModalMsg struct{}
+
+// OpenTraceModalMsg opens the run timeline, for the current session when
+// Session is empty.
+type OpenTraceModalMsg struct {
+	Session string
+}
+
+// ResumeRunMsg continues a saved run, the most recently active resumable
+// one when RunID is empty.
+type ResumeRunMsg struct {
+	RunID string
+}
+
+// PromptCommandMsg runs the prompt rendered from a user-defined command.
+type PromptCommandMsg struct {
+	Input     string
+	Prompt    string
+	Overrides agent.RunOverrides
+}
+
+// PaneLayoutMsg switches between the single transcript and the split-pane
+// role view.
+type PaneLayoutMsg struct {
+	Enabled bool
+}
 
 type slashCommand struct {
 	Name        string
@@ -27,11 +57,37 @@ var slashCommands = []slashCommand{
 	{Name: "/mcps", Description: "Show MCP connections"},
 	{Name: "/status", Description: "Toggle status overlay"},
 	{Name: "/connect", Description: "Connect AI providers"},
+	{Name: "/undo", Description: "Undo file changes of the last task (/undo run for the whole run)"},
+	{Name: "/review", Description: "Stage coder writes for hunk review (/review on|off)"},
+	{Name: "/panes", Description: "Show one live pane per role (/panes on|off)"},
+	{Name: "/sessions", Description: "Browse, search, resume, fork or delete sessions"},
+	{Name: "/commands", Description: "List and reload prompt commands from .orchestra/commands"},
+	{Name: "/trace", Description: "Inspect run timelines: prompts, responses and tool I/O (/trace [session])"},
+	{Name: "/pause", Description: "Pause the running plan after its current task"},
+	{Name: "/resume", Description: "Continue a paused, cancelled or interrupted run (/resume [run-id])"},
 }
 
 func filterSlashCommands(input string, limit int) []slashCommand {
+	return filterSlashCommandList(slashCommands, input, limit)
+}
+
+// isBuiltinSlashCommand reports whether name (with its "/") is taken by a
+// built-in command or alias, which prompt commands cannot replace.
+func isBuiltinSlashCommand(name string) bool {
+	if name == "/key" {
+		return true
+	}
+	for _, c := range slashCommands {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func filterSlashCommandList(commands []slashCommand, input string, limit int) []slashCommand {
 	if limit <= 0 {
-		limit = len(slashCommands)
+		limit = len(commands)
 	}
 
 	raw := strings.TrimSpace(input)
@@ -44,19 +100,19 @@ func filterSlashCommands(input string, limit int) []slashCommand {
 
 	token := strings.Fields(raw)[0]
 	if token == "/" {
-		if limit > len(slashCommands) {
-			limit = len(slashCommands)
+		if limit > len(commands) {
+			limit = len(commands)
 		}
-		return slashCommands[:limit]
+		return commands[:limit]
 	}
 	token = strings.TrimPrefix(token, "/")
 
 	query := strings.ToLower(strings.TrimSpace(token))
 	if query == "" {
-		if limit > len(slashCommands) {
-			limit = len(slashCommands)
+		if limit > len(commands) {
+			limit = len(commands)
 		}
-		return slashCommands[:limit]
+		return commands[:limit]
 	}
 
 	matches := make([]slashCommand, 0, limit)
@@ -69,7 +125,7 @@ func filterSlashCommands(input string, limit int) []slashCommand {
 	}
 
 	// Prefix matches first for intuitive command completion.
-	for _, c := range slashCommands {
+	for _, c := range commands {
 		if strings.HasPrefix(strings.TrimPrefix(strings.ToLower(c.Name), "/"), query) {
 			if !add(c) {
 				return matches
@@ -78,7 +134,7 @@ func filterSlashCommands(input string, limit int) []slashCommand {
 	}
 
 	// If needed, add substring matches to help discovery.
-	for _, c := range slashCommands {
+	for _, c := range commands {
 		name := strings.TrimPrefix(strings.ToLower(c.Name), "/")
 		if strings.HasPrefix(name, query) {
 			continue
@@ -120,11 +176,169 @@ func handleSlashCommand(cmdStr string, app *AppModel) tea.Cmd {
 			return CommandResultMsg{Msg: "MCP connections: None active."}
 		case "/status":
 			return CommandResultMsg{Msg: "Status overlay toggled."}
+		case "/undo":
+			return runUndoCommand(cmdStr, app)
+		case "/review":
+			return runReviewCommand(cmdStr, app)
+		case "/panes":
+			return runPanesCommand(cmdStr, app)
+		case "/sessions":
+			return OpenSessionsModalMsg{}
+		case "/commands":
+			return runCommandsCommand(app)
+		case "/trace":
+			return OpenTraceModalMsg{Session: strings.Join(strings.Fields(cmdStr)[1:], " ")}
+		case "/pause":
+			return runPauseCommand(app)
+		case "/resume":
+			return ResumeRunMsg{RunID: strings.Join(strings.Fields(cmdStr)[1:], " ")}
 		default:
-			if suggestions := filterSlashCommands(cmdStr, 1); len(suggestions) == 1 {
+			if msg, ok := runPromptCommand(cmdStr, app); ok {
+				return msg
+			}
+			if suggestions := filterSlashCommandList(app.slashCommandList(), cmdStr, 1); len(suggestions) == 1 {
 				return CommandResultMsg{Msg: fmt.Sprintf("Unknown command: %s. Did you mean %s?", cmdStr, suggestions[0].Name)}
 			}
 			return CommandResultMsg{Msg: fmt.Sprintf("Unknown command: %s", cmdStr)}
 		}
 	}
 }
+
+func runUndoCommand(cmdStr string, app *AppModel) tea.Msg {
+	if app == nil || app.db == nil || app.session == nil {
+		return CommandResultMsg{Msg: "Undo unavailable: no session state."}
+	}
+	if app.agentRunActive {
+		return CommandResultMsg{Msg: "Undo unavailable while a run is active. Cancel it first with ctrl+c."}
+	}
+
+	opts := agent.RewindOptions{LastTask: true}
+	for _, arg := range strings.Fields(cmdStr)[1:] {
+		switch strings.ToLower(arg) {
+		case "run":
+			opts.LastTask = false
+		case "force", "--force":
+			opts.Force = true
+		default:
+			return CommandResultMsg{Msg: fmt.Sprintf("Unknown /undo argument %q. Usage: /undo [run] [force]", arg)}
+		}
+	}
+
+	workingDir := app.session.WorkingDir
+	if app.orc != nil && strings.TrimSpace(app.orc.WorkingDir) != "" {
+		workingDir = app.orc.WorkingDir
+	}
+	result, err := agent.RewindFiles(context.Background(), app.db, workingDir, app.session.ID, opts)
+	switch {
+	case errors.Is(err, agent.ErrNoCheckpoints):
+		return CommandResultMsg{Msg: "Nothing to undo."}
+	case errors.Is(err, agent.ErrCheckpointConflict):
+		return CommandResultMsg{Msg: fmt.Sprintf("Undo refused: %s changed outside orchestra since the checkpoint. Run /undo force to overwrite them.", strings.Join(result.Conflicts, ", "))}
+	case err != nil:
+		return CommandResultMsg{Msg: fmt.Sprintf("Undo failed: %v", err)}
+	}
+
+	scope := "run " + result.RunID
+	if result.TaskID != "" {
+		scope = "task " + result.TaskID
+	}
+	return CommandResultMsg{Msg: fmt.Sprintf("Undid %s: restored %d file(s), removed %d created file(s).", scope, len(result.Restored), len(result.Removed))}
+}
+
+func runPauseCommand(app *AppModel) tea.Msg {
+	if app == nil || app.orc == nil {
+		return CommandResultMsg{Msg: "Pause unavailable: no orchestrator."}
+	}
+	if err := app.orc.Pause(); err != nil {
+		if errors.Is(err, agent.ErrNoActiveRun) {
+			return CommandResultMsg{Msg: "Nothing to pause: no run in progress."}
+		}
+		return CommandResultMsg{Msg: fmt.Sprintf("Pause failed: %v", err)}
+	}
+	return CommandResultMsg{Msg: "⏸ Pausing after the current task. Continue later with /resume."}
+}
+
+func runReviewCommand(cmdStr string, app *AppModel) tea.Msg {
+	if app == nil || app.orc == nil {
+		return CommandResultMsg{Msg: "Review mode unavailable: no orchestrator."}
+	}
+	if app.agentRunActive {
+		return CommandResultMsg{Msg: "Review mode can't change while a run is active."}
+	}
+
+	enabled := !app.orc.ReviewWrites
+	if args := strings.Fields(cmdStr)[1:]; len(args) > 0 {
+		switch strings.ToLower(args[0]) {
+		case "on":
+			enabled = true
+		case "off":
+			enabled = false
+		default:
+			return CommandResultMsg{Msg: fmt.Sprintf("Unknown /review argument %q. Usage: /review [on|off]", args[0])}
+		}
+	}
+	app.orc.ReviewWrites = enabled
+	if enabled {
+		return CommandResultMsg{Msg: "Review mode on: coder writes are staged and shown for hunk review before they are applied."}
+	}
+	return CommandResultMsg{Msg: "Review mode off: coder writes are applied directly."}
+}
+
+func runPanesCommand(cmdStr string, app *AppModel) tea.Msg {
+	enabled := app == nil || !app.paneLayout
+	if args := strings.Fields(cmdStr)[1:]; len(args) > 0 {
+		switch strings.ToLower(args[0]) {
+		case "on":
+			enabled = true
+		case "off":
+			enabled = false
+		default:
+			return CommandResultMsg{Msg: fmt.Sprintf("Unknown /panes argument %q. Usage: /panes [on|off]", args[0])}
+		}
+	}
+	return PaneLayoutMsg{Enabled: enabled}
+}
+
+func runCommandsCommand(app *AppModel) tea.Msg {
+	if app == nil {
+		return CommandResultMsg{Msg: "Prompt commands unavailable."}
+	}
+	errs := app.loadPromptCommands()
+	var sb strings.Builder
+	if len(app.promptCommands) == 0 {
+		fmt.Fprintf(&sb, "No prompt commands. Add markdown files to %s or %s.", agent.ProjectCommandsDir(app.workspaceRoot()), agent.UserCommandsDir())
+	} else {
+		sb.WriteString("Prompt commands:")
+		for _, cmd := range app.promptCommands {
+			fmt.Fprintf(&sb, "\n  %s  (%s)", cmd.Usage(), cmd.Scope)
+			if cmd.Description != "" {
+				sb.WriteString("  " + cmd.Description)
+			}
+		}
+	}
+	for _, err := range errs {
+		fmt.Fprintf(&sb, "\n  skipped: %v", err)
+	}
+	return CommandResultMsg{Msg: sb.String()}
+}
+
+// runPromptCommand renders a user-defined command. It reports false when
+// the input does not name one.
+func runPromptCommand(cmdStr string, app *AppModel) (tea.Msg, bool) {
+	if app == nil {
+		return nil, false
+	}
+	name := strings.TrimPrefix(normalizeSlashCommand(cmdStr), "/")
+	for _, cmd := range app.promptCommands {
+		if cmd.Name != name {
+			continue
+		}
+		raw := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(cmdStr), "/"+name))
+		prompt, err := cmd.Render(raw)
+		if err != nil {
+			return CommandResultMsg{Msg: err.Error()}, true
+		}
+		return PromptCommandMsg{Input: strings.TrimSpace(cmdStr), Prompt: prompt, Overrides: cmd.Overrides}, true
+	}
+	return nil, false
+}
@@ -1,8 +1,14 @@
 package tui
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/yubzen/orchestra/internal/agent"
+	"github.com/yubzen/orchestra/internal/state"
 )
 
 func TestFilterSlashCommandsRootShowsLimitedList(t *testing.T) {
@@ -56,3 +62,112 @@ func TestHandleSlashCommandKeyAliasOpensConnectModal(t *testing.T) {
 		t.Fatal("expected OpenConnectModalMsg for /key alias")
 	}
 }
+
+func TestHandleSlashCommandUndoWithoutCheckpoints(t *testing.T) {
+	db, err := state.Connect(":memory:")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+	session, err := db.CreateSession(context.Background(), t.TempDir(), "orchestrated")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	app := NewAppModel(nil, db, session, nil)
+
+	msg, ok := handleSlashCommand("/undo", app)().(CommandResultMsg)
+	if !ok {
+		t.Fatal("expected CommandResultMsg for /undo")
+	}
+	if msg.Msg != "Nothing to undo." {
+		t.Fatalf("unexpected /undo result: %q", msg.Msg)
+	}
+
+	msg = handleSlashCommand("/undo everything", app)().(CommandResultMsg)
+	if !strings.Contains(msg.Msg, "Usage: /undo") {
+		t.Fatalf("expected usage hint for bad argument, got %q", msg.Msg)
+	}
+}
+
+func TestStartupOffersInterruptedRunsAndPauseNeedsARun(t *testing.T) {
+	db, err := state.Connect(":memory:")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	session, err := db.CreateSession(ctx, t.TempDir(), "orchestrated")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	if err := db.CreateRun(ctx, state.Run{
+		ID:        "run_crashed",
+		SessionID: session.ID,
+		Prompt:    "add a cache",
+		PlanYAML:  "tasks: []",
+		Tasks:     []state.RunTask{{TaskID: "t1", Status: state.RunTaskDone}, {TaskID: "t2"}},
+	}); err != nil {
+		t.Fatalf("create run: %v", err)
+	}
+
+	app := NewAppModel(nil, db, session, &agent.Orchestrator{})
+	last := app.chat.messages[len(app.chat.messages)-1]
+	for _, want := range []string{"Found 1 unfinished run(s)", "run_crashed  interrupted  1/2 tasks  add a cache", "/resume"} {
+		if !strings.Contains(last.Content, want) {
+			t.Fatalf("expected %q in the startup notice, got %q", want, last.Content)
+		}
+	}
+
+	msg, ok := handleSlashCommand("/pause", app)().(CommandResultMsg)
+	if !ok || msg.Msg != "Nothing to pause: no run in progress." {
+		t.Fatalf("unexpected /pause result: %+v", msg)
+	}
+	if resume, ok := handleSlashCommand("/resume run_crashed", app)().(ResumeRunMsg); !ok || resume.RunID != "run_crashed" {
+		t.Fatalf("expected /resume to request run_crashed, got %+v", resume)
+	}
+}
+
+func TestPromptCommandsAreSuggestedAndRendered(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := t.TempDir()
+	dir := agent.ProjectCommandsDir(root)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	files := map[string]string{
+		"security-review.md": "---\ndescription: Audit a path for vulnerabilities\nargs:\n  - name: path\n    required: true\nrole: reviewer\ndispatch: chat\n---\nReview @{{.path}} for security issues.\n",
+		"models.md":          "Shadowing a built-in.\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	app := NewAppModel(nil, nil, nil, nil)
+	app.repoPath = root
+	errs := app.loadPromptCommands()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "/models is a built-in") {
+		t.Fatalf("expected the built-in name to be refused, got %v", errs)
+	}
+
+	app.chat.SetInputValue("/secu")
+	selected, ok := app.chat.SelectedSlashSuggestion()
+	if !ok || selected.Name != "/security-review" || !strings.Contains(selected.Description, "Audit a path") {
+		t.Fatalf("expected the prompt command to be suggested, got %+v", selected)
+	}
+
+	msg, ok := handleSlashCommand("/security-review internal/auth", app)().(PromptCommandMsg)
+	if !ok {
+		t.Fatal("expected PromptCommandMsg")
+	}
+	if msg.Prompt != "Review @internal/auth for security issues." {
+		t.Fatalf("unexpected prompt %q", msg.Prompt)
+	}
+	if msg.Overrides.Role != agent.RoleReviewer || msg.Overrides.Dispatch != agent.DispatchModeChat {
+		t.Fatalf("unexpected overrides %+v", msg.Overrides)
+	}
+	result := handleSlashCommand("/security-review", app)().(CommandResultMsg)
+	if !strings.Contains(result.Msg, "usage: /security-review <path>") {
+		t.Fatalf("expected a usage error, got %q", result.Msg)
+	}
+}
@@ -8,19 +8,31 @@ import (
 )
 
 var (
-	connectModalBG       = lipgloss.Color("235")
-	connectModalBoxStyle = lipgloss.NewStyle().
-				Border(lipgloss.RoundedBorder()).
-				BorderForeground(lipgloss.Color("205")).
-				Background(connectModalBG).
-				Padding(1, 2)
-	connectTitleStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("255")).Background(connectModalBG).Bold(true)
-	connectHintStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("244")).Background(connectModalBG)
-	connectSelStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("51")).Background(connectModalBG).Bold(true)
-	connectItemStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("252")).Background(connectModalBG)
-	connectOffStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Background(connectModalBG)
+	connectModalBG       lipgloss.Color
+	connectModalBoxStyle lipgloss.Style
+	connectTitleStyle    lipgloss.Style
+	connectHintStyle     lipgloss.Style
+	connectSelStyle      lipgloss.Style
+	connectItemStyle     lipgloss.Style
+	connectOffStyle      lipgloss.Style
+	connectCursorStyle   lipgloss.Style
 )
 
+func applyConnectStyles(p Palette) {
+	connectModalBG = p.Surface
+	connectModalBoxStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(p.Role).
+		Background(connectModalBG).
+		Padding(1, 2)
+	connectTitleStyle = lipgloss.NewStyle().Foreground(p.Bright).Background(connectModalBG).Bold(true)
+	connectHintStyle = lipgloss.NewStyle().Foreground(p.Muted).Background(connectModalBG)
+	connectSelStyle = lipgloss.NewStyle().Foreground(p.Selected).Background(connectModalBG).Bold(true)
+	connectItemStyle = lipgloss.NewStyle().Foreground(p.Text).Background(connectModalBG)
+	connectOffStyle = lipgloss.NewStyle().Foreground(p.Faint).Background(connectModalBG)
+	connectCursorStyle = lipgloss.NewStyle().Foreground(p.Bright)
+}
+
 type SelectOption struct {
 	Label   string
 	Enabled bool
@@ -214,9 +226,9 @@ func (m *APIKeyModal) View() string {
 	}
 	subtitle := connectHintStyle.Render(wrap(subtitleText))
 	inputLabel := connectHintStyle.Render(wrap(m.AuthMethod.InputLabel))
-	inputValue := m.Value + lipgloss.NewStyle().Foreground(lipgloss.Color("255")).Render("█")
+	inputValue := m.Value + connectCursorStyle.Render("█")
 	if strings.TrimSpace(m.Value) == "" {
-		inputValue = lipgloss.NewStyle().Foreground(lipgloss.Color("255")).Render("█")
+		inputValue = connectCursorStyle.Render("█")
 	}
 	statusLine := ""
 	if m.Connecting {
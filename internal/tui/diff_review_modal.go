@@ -0,0 +1,429 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/yubzen/orchestra/internal/agent"
+)
+
+var (
+	diffReviewSelectedStyle lipgloss.Style
+	diffReviewFileStyle     lipgloss.Style
+	diffReviewAcceptStyle   lipgloss.Style
+	diffReviewRejectStyle   lipgloss.Style
+	diffReviewEditStyle     lipgloss.Style
+)
+
+func applyDiffReviewStyles(p Palette) {
+	diffReviewSelectedStyle = lipgloss.NewStyle().Foreground(p.Title).Background(planModalBG).Bold(true)
+	diffReviewFileStyle = lipgloss.NewStyle().Foreground(p.Text).Background(planModalBG)
+	diffReviewAcceptStyle = lipgloss.NewStyle().Foreground(p.Success).Background(planModalBG).Bold(true)
+	diffReviewRejectStyle = lipgloss.NewStyle().Foreground(p.Error).Background(planModalBG).Bold(true)
+	diffReviewEditStyle = lipgloss.NewStyle().Foreground(p.Caution).Background(planModalBG).Bold(true)
+}
+
+type DiffReviewAction struct {
+	DecisionMade bool
+	Review       agent.DiffReview
+}
+
+type diffReviewFile struct {
+	path    string
+	existed bool
+	hunks   []agent.Hunk
+}
+
+// DiffReviewModal lists the files a task staged and lets the user accept,
+// reject or edit each hunk before any of it is written.
+type DiffReviewModal struct {
+	Visible  bool
+	TaskID   string
+	files    []diffReviewFile
+	file     int
+	hunk     int
+	editing  bool
+	notice   string
+	width    int
+	height   int
+	viewport viewport.Model
+	editor   textarea.Model
+}
+
+func NewDiffReviewModal() *DiffReviewModal {
+	ta := textarea.New()
+	ta.Prompt = ""
+	ta.CharLimit = 0
+	ta.ShowLineNumbers = false
+	ta.Focus()
+	return &DiffReviewModal{
+		viewport: viewport.New(70, 14),
+		editor:   ta,
+	}
+}
+
+func (m *DiffReviewModal) SetSize(width, height int) {
+	if m == nil || width <= 0 || height <= 0 {
+		return
+	}
+	m.width = width
+	m.height = height
+
+	bodyWidth := width - 12
+	if bodyWidth < 36 {
+		bodyWidth = 36
+	}
+	bodyHeight := height - 14
+	if bodyHeight < 8 {
+		bodyHeight = 8
+	}
+	m.viewport.Width = bodyWidth
+	m.viewport.Height = bodyHeight
+	m.editor.SetWidth(bodyWidth)
+	m.editor.SetHeight(bodyHeight)
+	m.refresh()
+}
+
+func (m *DiffReviewModal) Open(taskID string, staged []agent.StagedFile) {
+	if m == nil {
+		return
+	}
+	m.Visible = true
+	m.TaskID = strings.TrimSpace(taskID)
+	m.files = make([]diffReviewFile, 0, len(staged))
+	for _, file := range staged {
+		m.files = append(m.files, diffReviewFile{path: file.Path, existed: file.Existed, hunks: file.Hunks()})
+	}
+	m.file = 0
+	m.hunk = 0
+	m.editing = false
+	m.notice = ""
+	m.refresh()
+}
+
+func (m *DiffReviewModal) Close() {
+	if m == nil {
+		return
+	}
+	m.Visible = false
+	m.TaskID = ""
+	m.files = nil
+	m.editing = false
+	m.notice = ""
+	m.editor.SetValue("")
+	m.viewport.SetContent("")
+}
+
+func (m *DiffReviewModal) Update(msg tea.Msg) (DiffReviewAction, tea.Cmd) {
+	if m == nil || !m.Visible {
+		return DiffReviewAction{}, nil
+	}
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		return DiffReviewAction{}, cmd
+	}
+
+	if m.editing {
+		switch key.String() {
+		case "ctrl+s":
+			if h := m.currentHunk(); h != nil {
+				h.Decision = agent.HunkEdited
+				h.Edited = splitEditedLines(m.editor.Value())
+			}
+			m.editing = false
+			m.advance()
+			m.refresh()
+			return DiffReviewAction{}, nil
+		case "esc":
+			m.editing = false
+			m.refresh()
+			return DiffReviewAction{}, nil
+		}
+		var cmd tea.Cmd
+		m.editor, cmd = m.editor.Update(msg)
+		return DiffReviewAction{}, cmd
+	}
+
+	m.notice = ""
+	switch key.String() {
+	case "up", "k":
+		m.moveHunk(-1)
+	case "down", "j":
+		m.moveHunk(1)
+	case "tab", "right", "l":
+		m.moveFile(1)
+	case "shift+tab", "left", "h":
+		m.moveFile(-1)
+	case "a":
+		m.decide(agent.HunkAccepted)
+	case "r":
+		m.decide(agent.HunkRejected)
+	case "A":
+		m.decideRemaining(agent.HunkAccepted)
+	case "R":
+		m.decideRemaining(agent.HunkRejected)
+	case "e":
+		if h := m.currentHunk(); h != nil {
+			lines := h.New
+			if h.Decision == agent.HunkEdited {
+				lines = h.Edited
+			}
+			m.editor.SetValue(strings.Join(lines, "\n"))
+			m.editing = true
+		}
+	case "enter", "ctrl+s":
+		if pending := m.pendingCount(); pending > 0 {
+			m.notice = fmt.Sprintf("%d hunk(s) still undecided", pending)
+			break
+		}
+		return DiffReviewAction{DecisionMade: true, Review: m.review()}, nil
+	default:
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		return DiffReviewAction{}, cmd
+	}
+	m.refresh()
+	return DiffReviewAction{}, nil
+}
+
+func (m *DiffReviewModal) View() string {
+	if m == nil || !m.Visible {
+		return ""
+	}
+	title := planModalTitleStyle.Render(fmt.Sprintf("Review Changes  %s", m.TaskID))
+	if m.editing {
+		body := planModalBodyStyle.Render(m.editor.View())
+		hint := planModalHintStyle.Render("ctrl+s: use edited hunk  esc: cancel edit")
+		return planModalBoxStyle.Render(fmt.Sprintf("%s\n%s\n\n%s\n\n%s", title, m.renderFileList(), body, hint))
+	}
+
+	body := planModalBodyStyle.Render(m.viewport.View())
+	hintText := "a/r: accept/reject hunk  A/R: rest of file  e: edit  j/k: hunk  tab: file  enter: apply"
+	if m.notice != "" {
+		hintText = m.notice + "  |  " + hintText
+	}
+	hint := planModalHintStyle.Render(hintText)
+	return planModalBoxStyle.Render(fmt.Sprintf("%s\n%s\n\n%s\n\n%s", title, m.renderFileList(), body, hint))
+}
+
+// review returns the decisions for every staged file.
+func (m *DiffReviewModal) review() agent.DiffReview {
+	review := agent.DiffReview{TaskID: m.TaskID}
+	for _, file := range m.files {
+		review.Files = append(review.Files, agent.FileReview{Path: file.path, Hunks: file.hunks})
+	}
+	return review
+}
+
+func (m *DiffReviewModal) currentHunk() *agent.Hunk {
+	if m.file >= len(m.files) || m.hunk >= len(m.files[m.file].hunks) {
+		return nil
+	}
+	return &m.files[m.file].hunks[m.hunk]
+}
+
+func (m *DiffReviewModal) decide(decision agent.HunkDecision) {
+	if h := m.currentHunk(); h != nil {
+		h.Decision = decision
+		h.Edited = nil
+		m.advance()
+	}
+}
+
+func (m *DiffReviewModal) decideRemaining(decision agent.HunkDecision) {
+	if m.file >= len(m.files) {
+		return
+	}
+	hunks := m.files[m.file].hunks
+	for idx := range hunks {
+		if hunks[idx].Decision == agent.HunkPending {
+			hunks[idx].Decision = decision
+		}
+	}
+	m.advance()
+}
+
+// advance moves to the next undecided hunk, in this file first.
+func (m *DiffReviewModal) advance() {
+	for offset := 0; offset < len(m.files); offset++ {
+		fileIdx := (m.file + offset) % len(m.files)
+		start := 0
+		if offset == 0 {
+			start = m.hunk
+		}
+		for idx := start; idx < len(m.files[fileIdx].hunks); idx++ {
+			if m.files[fileIdx].hunks[idx].Decision == agent.HunkPending {
+				m.file, m.hunk = fileIdx, idx
+				return
+			}
+		}
+	}
+	// Wrap around to undecided hunks above the cursor in this file.
+	if m.file < len(m.files) {
+		for idx := 0; idx < m.hunk; idx++ {
+			if m.files[m.file].hunks[idx].Decision == agent.HunkPending {
+				m.hunk = idx
+				return
+			}
+		}
+	}
+}
+
+func (m *DiffReviewModal) moveHunk(delta int) {
+	if m.file >= len(m.files) {
+		return
+	}
+	next := m.hunk + delta
+	if next >= 0 && next < len(m.files[m.file].hunks) {
+		m.hunk = next
+	}
+}
+
+func (m *DiffReviewModal) moveFile(delta int) {
+	if len(m.files) == 0 {
+		return
+	}
+	m.file = (m.file + delta + len(m.files)) % len(m.files)
+	m.hunk = 0
+	m.viewport.GotoTop()
+}
+
+func (m *DiffReviewModal) pendingCount() int {
+	count := 0
+	for _, file := range m.files {
+		for _, h := range file.hunks {
+			if h.Decision == agent.HunkPending {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func (m *DiffReviewModal) renderFileList() string {
+	parts := make([]string, 0, len(m.files))
+	for idx, file := range m.files {
+		decided := 0
+		for _, h := range file.hunks {
+			if h.Decision != agent.HunkPending {
+				decided++
+			}
+		}
+		label := fmt.Sprintf("%s %d/%d", file.path, decided, len(file.hunks))
+		if !file.existed {
+			label += " (new)"
+		}
+		if idx == m.file {
+			parts = append(parts, diffReviewSelectedStyle.Render("["+label+"]"))
+		} else {
+			parts = append(parts, diffReviewFileStyle.Render(" "+label+" "))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// refresh renders the current file's hunks and scrolls the selected one
+// into view.
+func (m *DiffReviewModal) refresh() {
+	if m == nil {
+		return
+	}
+	width := m.viewport.Width
+	if width <= 0 {
+		width = 70
+	}
+	if m.file >= len(m.files) {
+		m.viewport.SetContent("Nothing staged.")
+		return
+	}
+	file := m.files[m.file]
+	if len(file.hunks) == 0 {
+		m.viewport.SetContent("No textual changes.")
+		return
+	}
+
+	var lines []string
+	selectedTop, selectedBottom := 0, 0
+	for idx, h := range file.hunks {
+		if idx == m.hunk {
+			selectedTop = len(lines)
+		}
+		marker := "  "
+		headerStyle := diffHeaderStyle
+		if idx == m.hunk {
+			marker = "▶ "
+			headerStyle = diffReviewSelectedStyle
+		}
+		lines = append(lines, headerStyle.Render(marker+h.Header())+"  "+renderHunkDecision(h.Decision))
+		for _, line := range h.Before {
+			lines = append(lines, diffCtxStyle.Render(truncateRunes("    "+line, width)))
+		}
+		for _, line := range h.Old {
+			lines = append(lines, diffDelStyle.Render(truncateRunes("  - "+line, width)))
+		}
+		added := h.New
+		if h.Decision == agent.HunkEdited {
+			added = h.Edited
+		}
+		for _, line := range added {
+			lines = append(lines, diffAddStyle.Render(truncateRunes("  + "+line, width)))
+		}
+		for _, line := range h.After {
+			lines = append(lines, diffCtxStyle.Render(truncateRunes("    "+line, width)))
+		}
+		if idx == m.hunk {
+			selectedBottom = len(lines)
+		}
+		lines = append(lines, "")
+	}
+	m.viewport.SetContent(strings.Join(lines, "\n"))
+	if selectedTop < m.viewport.YOffset || selectedBottom > m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(selectedTop)
+	}
+}
+
+func renderHunkDecision(decision agent.HunkDecision) string {
+	switch decision {
+	case agent.HunkAccepted:
+		return diffReviewAcceptStyle.Render("accepted")
+	case agent.HunkRejected:
+		return diffReviewRejectStyle.Render("rejected")
+	case agent.HunkEdited:
+		return diffReviewEditStyle.Render("edited")
+	default:
+		return planModalHintStyle.Render("pending")
+	}
+}
+
+func splitEditedLines(value string) []string {
+	value = strings.TrimSuffix(strings.ReplaceAll(value, "\r\n", "\n"), "\n")
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, "\n")
+}
+
+// summarizeDiffReview describes a submitted review for the chat log.
+func summarizeDiffReview(review agent.DiffReview) string {
+	counts := map[agent.HunkDecision]int{}
+	for _, file := range review.Files {
+		for _, h := range file.Hunks {
+			counts[h.Decision]++
+		}
+	}
+	msg := fmt.Sprintf("Review submitted: %d hunk(s) accepted", counts[agent.HunkAccepted])
+	if counts[agent.HunkEdited] > 0 {
+		msg += fmt.Sprintf(", %d edited", counts[agent.HunkEdited])
+	}
+	if counts[agent.HunkRejected] > 0 {
+		msg += fmt.Sprintf(", %d rejected and sent back to the coder", counts[agent.HunkRejected])
+	}
+	return msg + "."
+}
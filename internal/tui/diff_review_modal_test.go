@@ -0,0 +1,76 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/yubzen/orchestra/internal/agent"
+)
+
+func runeKey(r rune) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}}
+}
+
+func TestDiffReviewModalBlocksSubmitUntilEveryHunkIsDecided(t *testing.T) {
+	t.Parallel()
+
+	modal := NewDiffReviewModal()
+	modal.SetSize(120, 40)
+	modal.Open("t1", []agent.StagedFile{{
+		Path:    "a.txt",
+		Existed: true,
+		Before:  []byte("one\ntwo\nthree\nfour\n"),
+		After:   []byte("one\nTWO\nthree\nFOUR\n"),
+	}})
+
+	_, _ = modal.Update(runeKey('a'))
+	action, _ := modal.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if action.DecisionMade {
+		t.Fatalf("expected submit to wait for the undecided hunk, got %#v", action)
+	}
+	if !strings.Contains(modal.View(), "1 hunk(s) still undecided") {
+		t.Fatalf("expected a notice about the undecided hunk")
+	}
+
+	_, _ = modal.Update(runeKey('r'))
+	action, _ = modal.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !action.DecisionMade || action.Review.TaskID != "t1" {
+		t.Fatalf("expected a review for t1, got %#v", action)
+	}
+	hunks := action.Review.Files[0].Hunks
+	if hunks[0].Decision != agent.HunkAccepted || hunks[1].Decision != agent.HunkRejected {
+		t.Fatalf("unexpected decisions: %+v", hunks)
+	}
+}
+
+func TestDiffReviewModalEditsAHunk(t *testing.T) {
+	t.Parallel()
+
+	modal := NewDiffReviewModal()
+	modal.SetSize(120, 40)
+	modal.Open("t2", []agent.StagedFile{
+		{Path: "a.txt", Existed: true, Before: []byte("one\n"), After: []byte("uno\n")},
+		{Path: "b.txt", After: []byte("new\n")},
+	})
+
+	_, _ = modal.Update(runeKey('e'))
+	_, _ = modal.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("!")})
+	_, _ = modal.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	_, _ = modal.Update(runeKey('A'))
+	action, _ := modal.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !action.DecisionMade || len(action.Review.Files) != 2 {
+		t.Fatalf("expected a review of both files, got %#v", action)
+	}
+	edited := action.Review.Files[0].Hunks[0]
+	if edited.Decision != agent.HunkEdited || strings.Join(edited.Edited, "\n") != "uno!" {
+		t.Fatalf("expected the edited hunk, got %+v", edited)
+	}
+	if action.Review.Files[1].Hunks[0].Decision != agent.HunkAccepted {
+		t.Fatalf("expected the second file to be accepted, got %+v", action.Review.Files[1].Hunks)
+	}
+	if got := summarizeDiffReview(action.Review); got != "Review submitted: 1 hunk(s) accepted, 1 edited." {
+		t.Fatalf("unexpected summary %q", got)
+	}
+}
@@ -0,0 +1,110 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	helpKeyStyle     lipgloss.Style
+	helpSectionStyle lipgloss.Style
+)
+
+func applyHelpStyles(p Palette) {
+	helpKeyStyle = lipgloss.NewStyle().Foreground(p.Selected).Background(planModalBG).Bold(true)
+	helpSectionStyle = lipgloss.NewStyle().Foreground(p.Title).Background(planModalBG).Bold(true)
+}
+
+// HelpModal lists the active key bindings. It is rebuilt from the keymap
+// each time it opens, so remapped keys show as configured.
+type HelpModal struct {
+	Visible bool
+	lines   []string
+	width   int
+	height  int
+	offset  int
+}
+
+func NewHelpModal() *HelpModal {
+	return &HelpModal{}
+}
+
+func (m *HelpModal) Open(keys *KeyMap) {
+	m.lines = renderKeyHelp(keys)
+	m.offset = 0
+	m.Visible = true
+}
+
+func (m *HelpModal) Close() {
+	m.Visible = false
+}
+
+func (m *HelpModal) SetSize(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+// Scroll moves the visible window when the help is taller than the screen.
+func (m *HelpModal) Scroll(delta int) {
+	m.offset = min(max(m.offset+delta, 0), max(len(m.lines)-m.visibleLines(), 0))
+}
+
+func (m *HelpModal) visibleLines() int {
+	if m.height <= 0 {
+		return len(m.lines)
+	}
+	return max(m.height-10, 5)
+}
+
+func (m *HelpModal) View() string {
+	if m == nil || !m.Visible {
+		return ""
+	}
+	end := min(m.offset+m.visibleLines(), len(m.lines))
+	body := strings.Join(m.lines[m.offset:end], "\n")
+	hint := "esc/?/q: close"
+	if end-m.offset < len(m.lines) {
+		hint = "up/down: scroll  " + hint
+	}
+	return planModalBoxStyle.Render(fmt.Sprintf("%s\n\n%s\n\n%s",
+		planModalTitleStyle.Render("Keys"),
+		planModalBodyStyle.Render(body),
+		planModalHintStyle.Render(hint)))
+}
+
+// renderKeyHelp lists the bindings by section, plus the fixed keys that
+// are not remappable.
+func renderKeyHelp(keys *KeyMap) []string {
+	if keys == nil {
+		keys = DefaultKeyMap()
+	}
+	width := 0
+	for _, binding := range keys.bindings {
+		width = max(width, len(strings.Join(binding.Keys, "/")))
+	}
+	var lines []string
+	section := ""
+	for _, binding := range keys.bindings {
+		if binding.Section != section {
+			if section != "" {
+				lines = append(lines, "")
+			}
+			section = binding.Section
+			lines = append(lines, helpSectionStyle.Render(section))
+		}
+		label := strings.Join(binding.Keys, "/")
+		if label == "" {
+			label = "(unbound)"
+		}
+		lines = append(lines, fmt.Sprintf("  %s  %s", helpKeyStyle.Render(fmt.Sprintf("%-*s", width, label)), binding.Help))
+	}
+	lines = append(lines,
+		fmt.Sprintf("  %s  %s", helpKeyStyle.Render(fmt.Sprintf("%-*s", width, "alt+1…9")), "focus pane 1-9"),
+		"",
+		helpSectionStyle.Render("Input"),
+		"  /  slash commands   @  attach a file, range, directory or symbol",
+	)
+	return lines
+}
@@ -0,0 +1,173 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// keyAction names a remappable key binding. The names are the keys of the
+// [tui.keys] config table.
+type keyAction string
+
+const (
+	keyQuit           keyAction = "quit"
+	keyCancelRun      keyAction = "cancel_run"
+	keySubmit         keyAction = "submit"
+	keyComplete       keyAction = "complete"
+	keyHistoryPrev    keyAction = "history_prev"
+	keyHistoryNext    keyAction = "history_next"
+	keyToggleMode     keyAction = "toggle_mode"
+	keyToggleModeIdle keyAction = "toggle_mode_idle"
+	keyAttachments    keyAction = "toggle_attachments"
+	keyHelp           keyAction = "help"
+	keyPaneNext       keyAction = "pane_next"
+	keyPanePrev       keyAction = "pane_prev"
+	keyPaneMaximize   keyAction = "pane_maximize"
+	keyPaneScrollUp   keyAction = "pane_scroll_up"
+	keyPaneScrollDown keyAction = "pane_scroll_down"
+	keyPanePageUp     keyAction = "pane_page_up"
+	keyPanePageDown   keyAction = "pane_page_down"
+)
+
+const (
+	keySectionChat  = "Chat"
+	keySectionPanes = "Split panes"
+)
+
+type keyBinding struct {
+	Action  keyAction
+	Section string
+	Help    string
+	Keys    []string
+}
+
+// defaultKeyBindings is the built-in keymap, in help overlay order.
+func defaultKeyBindings() []keyBinding {
+	return []keyBinding{
+		{Action: keySubmit, Section: keySectionChat, Help: "send the prompt or run the selected command", Keys: []string{"enter"}},
+		{Action: keyComplete, Section: keySectionChat, Help: "complete the selected command or @mention", Keys: []string{"tab"}},
+		{Action: keyHistoryPrev, Section: keySectionChat, Help: "previous suggestion or input history", Keys: []string{"up"}},
+		{Action: keyHistoryNext, Section: keySectionChat, Help: "next suggestion or input history", Keys: []string{"down"}},
+		{Action: keyToggleMode, Section: keySectionChat, Help: "toggle fast/plan execution mode", Keys: []string{"shift+tab"}},
+		{Action: keyToggleModeIdle, Section: keySectionChat, Help: "toggle execution mode when the input is empty", Keys: []string{"p"}},
+		{Action: keyAttachments, Section: keySectionChat, Help: "expand or collapse attachments", Keys: []string{"ctrl+o"}},
+		{Action: keyCancelRun, Section: keySectionChat, Help: "cancel the running task when the input is empty", Keys: []string{"esc"}},
+		{Action: keyQuit, Section: keySectionChat, Help: "clear the input, cancel the run, or quit", Keys: []string{"ctrl+c"}},
+		{Action: keyHelp, Section: keySectionChat, Help: "show this help when the input is empty", Keys: []string{"?"}},
+		{Action: keyPaneNext, Section: keySectionPanes, Help: "focus the next pane", Keys: []string{"alt+right"}},
+		{Action: keyPanePrev, Section: keySectionPanes, Help: "focus the previous pane", Keys: []string{"alt+left"}},
+		{Action: keyPaneMaximize, Section: keySectionPanes, Help: "maximize or restore the focused pane", Keys: []string{"alt+z"}},
+		{Action: keyPaneScrollUp, Section: keySectionPanes, Help: "scroll the focused pane up", Keys: []string{"alt+up"}},
+		{Action: keyPaneScrollDown, Section: keySectionPanes, Help: "scroll the focused pane down", Keys: []string{"alt+down"}},
+		{Action: keyPanePageUp, Section: keySectionPanes, Help: "page the focused pane up", Keys: []string{"alt+pgup"}},
+		{Action: keyPanePageDown, Section: keySectionPanes, Help: "page the focused pane down", Keys: []string{"alt+pgdown"}},
+	}
+}
+
+// KeyMap resolves key presses to actions.
+type KeyMap struct {
+	bindings []keyBinding
+	byKey    map[string]keyAction
+}
+
+// DefaultKeyMap returns the built-in bindings.
+func DefaultKeyMap() *KeyMap {
+	km, _ := NewKeyMap(nil)
+	return km
+}
+
+// NewKeyMap applies overrides, action name to keys, on top of the defaults.
+// An override replaces all of an action's keys and takes its keys away from
+// any default binding. Unknown actions, empty key lists and a key given to
+// two actions are reported; the first action keeps the key.
+func NewKeyMap(overrides map[string][]string) (*KeyMap, []error) {
+	km := &KeyMap{bindings: defaultKeyBindings()}
+	index := make(map[keyAction]int, len(km.bindings))
+	for idx, binding := range km.bindings {
+		index[binding.Action] = idx
+	}
+
+	var errs []error
+	overridden := make(map[keyAction]bool)
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		idx, ok := index[keyAction(strings.ToLower(strings.TrimSpace(name)))]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown key action %q", name))
+			continue
+		}
+		var keys []string
+		for _, key := range overrides[name] {
+			if key = strings.TrimSpace(key); key != "" {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			errs = append(errs, fmt.Errorf("key action %q has no keys", name))
+			continue
+		}
+		km.bindings[idx].Keys = keys
+		overridden[km.bindings[idx].Action] = true
+	}
+
+	km.byKey = make(map[string]keyAction)
+	for _, pass := range []bool{true, false} {
+		for idx := range km.bindings {
+			binding := &km.bindings[idx]
+			if overridden[binding.Action] != pass {
+				continue
+			}
+			kept := make([]string, 0, len(binding.Keys))
+			for _, key := range binding.Keys {
+				if other, taken := km.byKey[key]; taken {
+					if pass {
+						errs = append(errs, fmt.Errorf("key %q is bound to both %s and %s; keeping %s", key, other, binding.Action, other))
+					}
+					continue
+				}
+				km.byKey[key] = binding.Action
+				kept = append(kept, key)
+			}
+			binding.Keys = kept
+		}
+	}
+	return km, errs
+}
+
+// Action returns the action bound to msg, or "" when there is none.
+func (k *KeyMap) Action(msg tea.KeyMsg) keyAction {
+	if k == nil {
+		return ""
+	}
+	return k.byKey[msg.String()]
+}
+
+// Matches reports whether msg is bound to action.
+func (k *KeyMap) Matches(msg tea.KeyMsg, action keyAction) bool {
+	return k.Action(msg) == action
+}
+
+// Keys lists the keys bound to action.
+func (k *KeyMap) Keys(action keyAction) []string {
+	if k == nil {
+		return nil
+	}
+	for _, binding := range k.bindings {
+		if binding.Action == action {
+			return binding.Keys
+		}
+	}
+	return nil
+}
+
+// Label is the keys bound to action joined for display, such as "up/ctrl+p".
+func (k *KeyMap) Label(action keyAction) string {
+	return strings.Join(k.Keys(action), "/")
+}
@@ -0,0 +1,69 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/yubzen/orchestra/internal/config"
+)
+
+func TestNewKeyMapAppliesOverrides(t *testing.T) {
+	t.Parallel()
+
+	km, errs := NewKeyMap(map[string][]string{
+		"history_prev": {"up", "ctrl+p"},
+		"toggle_mode":  {"p"},
+		"cancel_run":   {"ctrl+p"},
+		"launch":       {"ctrl+l"},
+	})
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+	if !strings.Contains(errs[0].Error(), `unknown key action "launch"`) || !strings.Contains(errs[1].Error(), `"ctrl+p" is bound to both`) {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if got := km.Action(tea.KeyMsg{Type: tea.KeyCtrlP}); got != keyHistoryPrev {
+		t.Fatalf("expected ctrl+p to keep history_prev, got %q", got)
+	}
+	if got := km.Action(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")}); got != keyToggleMode {
+		t.Fatalf("expected p to move to toggle_mode, got %q", got)
+	}
+	if len(km.Keys(keyToggleModeIdle)) != 0 {
+		t.Fatalf("expected the default p binding to be given up, got %v", km.Keys(keyToggleModeIdle))
+	}
+	if km.Matches(tea.KeyMsg{Type: tea.KeyShiftTab}, keyToggleMode) {
+		t.Fatal("expected the override to replace shift+tab")
+	}
+	if got := km.Label(keyHistoryPrev); got != "up/ctrl+p" {
+		t.Fatalf("unexpected label %q", got)
+	}
+}
+
+func TestAppHelpOverlayShowsRemappedKeys(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{}
+	cfg.TUI.Keys = map[string][]string{"toggle_attachments": {"ctrl+a"}}
+	app := NewAppModel(cfg, nil, nil, nil)
+
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("?")})
+	if !app.helpModal.Visible {
+		t.Fatal("expected ? to open the help overlay")
+	}
+	view := app.View()
+	if !strings.Contains(view, "ctrl+a") || !strings.Contains(view, "expand or collapse attachments") || strings.Contains(view, "ctrl+o") {
+		t.Fatalf("expected the help to list the remapped key:\n%s", view)
+	}
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if app.helpModal.Visible {
+		t.Fatal("expected esc to close the help overlay")
+	}
+
+	app.chat.SetInputValue("why?")
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("?")})
+	if app.helpModal.Visible {
+		t.Fatal("expected ? to be typed when the input is not empty")
+	}
+}
@@ -0,0 +1,130 @@
+package tui
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/yubzen/orchestra/internal/agent"
+)
+
+var (
+	attachmentStyle     lipgloss.Style
+	attachmentErrStyle  lipgloss.Style
+	attachmentBodyStyle lipgloss.Style
+)
+
+func applyMentionStyles(p Palette) {
+	attachmentStyle = lipgloss.NewStyle().Foreground(p.Attachment)
+	attachmentErrStyle = lipgloss.NewStyle().Foreground(p.ErrorSoft)
+	attachmentBodyStyle = lipgloss.NewStyle().Foreground(p.Muted)
+}
+
+const maxAttachmentPreview = 30
+
+// mentionToken finds the @mention being typed at pos: it returns where the
+// token starts and the query after "@". Tokens that already carry a line
+// range are left alone.
+func mentionToken(value []rune, pos int) (int, string, bool) {
+	pos = min(max(pos, 0), len(value))
+	start := pos
+	for start > 0 && value[start-1] != ' ' && value[start-1] != '\t' {
+		start--
+	}
+	token := string(value[start:pos])
+	if !strings.HasPrefix(token, "@") || strings.Contains(token, ":") {
+		return 0, "", false
+	}
+	return start, token[1:], true
+}
+
+// rankMentionCandidates orders workspace paths for the @ picker: base name
+// prefixes first, then substrings, then fuzzy subsequence matches, shorter
+// paths winning ties. A directory that is already fully typed is left out
+// so its contents come first.
+func rankMentionCandidates(query string, candidates []string, limit int) []string {
+	query = strings.ToLower(query)
+	type ranked struct {
+		path  string
+		score int
+	}
+	var matches []ranked
+	for _, candidate := range candidates {
+		lower := strings.ToLower(candidate)
+		if lower == query {
+			continue
+		}
+		base := path.Base(strings.TrimSuffix(lower, "/"))
+		score := -1
+		switch {
+		case query == "":
+			score = strings.Count(strings.TrimSuffix(lower, "/"), "/")
+		case strings.HasPrefix(lower, query), strings.HasPrefix(base, query):
+			score = 0
+		case strings.Contains(base, query):
+			score = 1
+		case strings.Contains(lower, query):
+			score = 2
+		case fuzzyMatch(query, lower):
+			score = 3
+		}
+		if score >= 0 {
+			matches = append(matches, ranked{path: candidate, score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score < matches[j].score
+		}
+		if len(matches[i].path) != len(matches[j].path) {
+			return len(matches[i].path) < len(matches[j].path)
+		}
+		return matches[i].path < matches[j].path
+	})
+	out := make([]string, 0, min(limit, len(matches)))
+	for _, match := range matches {
+		if len(out) == limit {
+			break
+		}
+		out = append(out, match.path)
+	}
+	return out
+}
+
+// renderAttachments shows each attachment as a one-line summary, or with a
+// preview of its content when expanded.
+func renderAttachments(attachments []agent.Attachment, expanded bool, width int) string {
+	blocks := make([]string, 0, len(attachments))
+	for _, att := range attachments {
+		if att.Err != "" {
+			blocks = append(blocks, attachmentErrStyle.Render(truncateRunes(fmt.Sprintf("  ✗ @%s: %s", att.Ref, att.Err), width)))
+			continue
+		}
+		marker := "▸"
+		if expanded {
+			marker = "▾"
+		}
+		meta := fmt.Sprintf("%d lines · %s", att.Lines, formatTokenCount(att.Tokens))
+		if att.Truncated {
+			meta += " · truncated"
+		}
+		line := attachmentStyle.Render(truncateRunes(fmt.Sprintf("  %s @%s  %s", marker, att.Label, meta), width))
+		if !expanded {
+			blocks = append(blocks, line)
+			continue
+		}
+		body := strings.Split(att.Content, "\n")
+		more := len(body) - maxAttachmentPreview
+		if more > 0 {
+			body = append(body[:maxAttachmentPreview:maxAttachmentPreview], fmt.Sprintf("… %d more lines", more))
+		}
+		for idx, text := range body {
+			body[idx] = truncateRunes("    "+text, width)
+		}
+		blocks = append(blocks, line+"\n"+attachmentBodyStyle.Render(strings.Join(body, "\n")))
+	}
+	return strings.Join(blocks, "\n")
+}
@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestRankMentionCandidatesPrefersBaseNamePrefixes(t *testing.T) {
+	t.Parallel()
+
+	candidates := []string{"internal/", "internal/tui/", "internal/tui/app.go", "internal/tui/app_test.go", "cmd/orchestra/main.go", "README.md"}
+	got := rankMentionCandidates("app", candidates, 6)
+	want := []string{"internal/tui/app.go", "internal/tui/app_test.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if got := rankMentionCandidates("itui", candidates, 1); !reflect.DeepEqual(got, []string{"internal/tui/"}) {
+		t.Fatalf("expected a fuzzy match on the directory, got %v", got)
+	}
+	if got := rankMentionCandidates("", candidates, 2); !reflect.DeepEqual(got, []string{"README.md", "internal/"}) {
+		t.Fatalf("expected top-level paths first for an empty query, got %v", got)
+	}
+}
+
+func TestChatMentionPickerCompletesTheTypedToken(t *testing.T) {
+	t.Parallel()
+
+	m := NewChatModel()
+	calls := 0
+	m.SetMentionSource(func() []string {
+		calls++
+		return []string{"internal/", "internal/tui/", "internal/tui/app.go", "main.go"}
+	})
+	m.SetInputValue("look at @tu")
+	if !m.MentionActive() {
+		t.Fatal("expected the mention picker to open")
+	}
+	if selected, _ := m.SelectedSlashSuggestion(); selected.Name != "@internal/tui/" {
+		t.Fatalf("expected the tui directory first, got %q", selected.Name)
+	}
+	m.ApplyTopSlashSuggestion()
+	if got := m.GetInputValue(); got != "look at @internal/tui/" {
+		t.Fatalf("expected the directory to complete without a space, got %q", got)
+	}
+	if !m.MentionActive() {
+		t.Fatal("expected the picker to stay open inside the directory")
+	}
+	m.ApplyTopSlashSuggestion()
+	if got := m.GetInputValue(); got != "look at @internal/tui/app.go " {
+		t.Fatalf("expected the file to complete with a space, got %q", got)
+	}
+	if m.MentionActive() {
+		t.Fatal("expected the picker to close after a file")
+	}
+	if calls != 1 {
+		t.Fatalf("expected one candidate load per mention, got %d", calls)
+	}
+
+	m.SetInputValue("@main.go:1-4")
+	if m.MentionActive() {
+		t.Fatal("expected no picker once a line range is typed")
+	}
+}
+
+func TestAppAttachesMentionsAndTogglesPreview(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "notes.txt"), []byte("first\nsecond\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	app := NewAppModel(nil, nil, nil, nil)
+	app.repoPath = root
+
+	expanded, ok := app.expandMentionsCmd(MentionsExpandedMsg{Input: "summarize @notes.txt please"})().(MentionsExpandedMsg)
+	if !ok {
+		t.Fatal("expected the mention command to return the expanded prompt")
+	}
+	prompt, attachments := expanded.Expanded, expanded.Attachments
+	if !strings.HasPrefix(prompt, "summarize @notes.txt please\n\n--- @notes.txt ---\nfirst\nsecond") {
+		t.Fatalf("unexpected prompt: %q", prompt)
+	}
+	app.chat.SetSize(80, 30)
+	app.chat.AddUserMessage("summarize @notes.txt please", attachments)
+	if view := app.chat.viewport.View(); !strings.Contains(view, "▸ @notes.txt") || strings.Contains(view, "second") {
+		t.Fatalf("expected a collapsed attachment:\n%s", view)
+	}
+	_, _ = app.Update(tea.KeyMsg{Type: tea.KeyCtrlO})
+	if view := app.chat.viewport.View(); !strings.Contains(view, "▾ @notes.txt") || !strings.Contains(view, "second") {
+		t.Fatalf("expected ctrl+o to expand the attachment:\n%s", view)
+	}
+
+	escaped := app.expandMentionsCmd(MentionsExpandedMsg{Input: "@../escape.txt"})().(MentionsExpandedMsg)
+	if failed := escaped.Attachments; len(failed) != 1 || failed[0].Err == "" {
+		t.Fatalf("expected the escaping mention to fail, got %+v", escaped.Attachments)
+	}
+}
+
+func TestExpandedPromptCommandWaitsForActiveRun(t *testing.T) {
+	t.Parallel()
+
+	app := NewAppModel(nil, nil, nil, nil)
+	app.agentRunActive = true
+
+	_, _ = app.Update(MentionsExpandedMsg{Input: "Review @auth.", Expanded: "Review @auth.", Command: "/security-review auth"})
+	if app.chat.IsLoading() {
+		t.Fatal("expected no run to start while another is active")
+	}
+	last := app.chat.messages[len(app.chat.messages)-1]
+	if last.Sender != "System" || !strings.Contains(last.Content, "/security-review auth not started") {
+		t.Fatalf("expected the command to be refused, got %+v", last)
+	}
+}
@@ -9,25 +9,38 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
-var modelModalBG = lipgloss.Color("235")
-
 var (
-	modelModalBoxStyle = lipgloss.NewStyle().
-				Border(lipgloss.RoundedBorder()).
-				BorderForeground(lipgloss.Color("39")).
-				Background(modelModalBG).
-				Padding(1, 2)
-	modelModalTitleStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("51")).Background(modelModalBG).Bold(true)
-	modelModalHintStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("244")).Background(modelModalBG)
-	modelModalItemStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("252")).Background(modelModalBG)
-	modelModalTabActiveStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("235")).Background(lipgloss.Color("45")).Bold(true).Padding(0, 1)
-	modelModalTabInactiveStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("250")).Background(lipgloss.Color("238")).Padding(0, 1)
-	modelModalSearchLabelStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("81")).Background(modelModalBG).Bold(true)
-	modelModalSearchValueStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("255")).Background(modelModalBG)
-	modelModalSearchHintStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Background(modelModalBG)
-	modelModalSearchCursor     = lipgloss.NewStyle().Foreground(lipgloss.Color("51")).Background(modelModalBG)
+	modelModalBG               lipgloss.Color
+	modelModalBoxStyle         lipgloss.Style
+	modelModalTitleStyle       lipgloss.Style
+	modelModalHintStyle        lipgloss.Style
+	modelModalItemStyle        lipgloss.Style
+	modelModalTabActiveStyle   lipgloss.Style
+	modelModalTabInactiveStyle lipgloss.Style
+	modelModalSearchLabelStyle lipgloss.Style
+	modelModalSearchValueStyle lipgloss.Style
+	modelModalSearchHintStyle  lipgloss.Style
+	modelModalSearchCursor     lipgloss.Style
 )
 
+func applyModelsModalStyles(p Palette) {
+	modelModalBG = p.Surface
+	modelModalBoxStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(p.Accent).
+		Background(modelModalBG).
+		Padding(1, 2)
+	modelModalTitleStyle = lipgloss.NewStyle().Foreground(p.Selected).Background(modelModalBG).Bold(true)
+	modelModalHintStyle = lipgloss.NewStyle().Foreground(p.Muted).Background(modelModalBG)
+	modelModalItemStyle = lipgloss.NewStyle().Foreground(p.Text).Background(modelModalBG)
+	modelModalTabActiveStyle = lipgloss.NewStyle().Foreground(p.Surface).Background(p.Active).Bold(true).Padding(0, 1)
+	modelModalTabInactiveStyle = lipgloss.NewStyle().Foreground(p.Subtle).Background(p.Border).Padding(0, 1)
+	modelModalSearchLabelStyle = lipgloss.NewStyle().Foreground(p.Title).Background(modelModalBG).Bold(true)
+	modelModalSearchValueStyle = lipgloss.NewStyle().Foreground(p.Bright).Background(modelModalBG)
+	modelModalSearchHintStyle = lipgloss.NewStyle().Foreground(p.Muted).Background(modelModalBG)
+	modelModalSearchCursor = lipgloss.NewStyle().Foreground(p.Selected).Background(modelModalBG)
+}
+
 type ModelOption struct {
 	ProviderName string
 	ProviderKey  string
@@ -67,10 +80,10 @@ type ModelsModal struct {
 func NewModelsModal(models []ModelOption) *ModelsModal {
 	delegate := list.NewDefaultDelegate()
 	delegate.SetSpacing(1)
-	delegate.Styles.NormalTitle = delegate.Styles.NormalTitle.Foreground(lipgloss.Color("252")).Background(modelModalBG)
-	delegate.Styles.NormalDesc = delegate.Styles.NormalDesc.Foreground(lipgloss.Color("244")).Background(modelModalBG)
-	delegate.Styles.SelectedTitle = delegate.Styles.SelectedTitle.Foreground(lipgloss.Color("213")).Background(modelModalBG).Bold(true)
-	delegate.Styles.SelectedDesc = delegate.Styles.SelectedDesc.Foreground(lipgloss.Color("183")).Background(modelModalBG).Bold(true)
+	delegate.Styles.NormalTitle = delegate.Styles.NormalTitle.Foreground(activePalette.Text).Background(modelModalBG)
+	delegate.Styles.NormalDesc = delegate.Styles.NormalDesc.Foreground(activePalette.Muted).Background(modelModalBG)
+	delegate.Styles.SelectedTitle = delegate.Styles.SelectedTitle.Foreground(activePalette.Pick).Background(modelModalBG).Bold(true)
+	delegate.Styles.SelectedDesc = delegate.Styles.SelectedDesc.Foreground(activePalette.PickDesc).Background(modelModalBG).Bold(true)
 	delegate.Styles.DimmedTitle = delegate.Styles.DimmedTitle.Background(modelModalBG)
 	delegate.Styles.DimmedDesc = delegate.Styles.DimmedDesc.Background(modelModalBG)
 	delegate.Styles.FilterMatch = delegate.Styles.FilterMatch.Background(modelModalBG)
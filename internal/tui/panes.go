@@ -0,0 +1,406 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/yubzen/orchestra/internal/agent"
+	"github.com/yubzen/orchestra/internal/rag"
+)
+
+var (
+	paneBoxStyle        lipgloss.Style
+	paneFocusedBoxStyle lipgloss.Style
+	paneTitleStyle      lipgloss.Style
+	paneMetaStyle       lipgloss.Style
+	paneToolStyle       lipgloss.Style
+	paneStreamStyle     lipgloss.Style
+)
+
+func applyPaneStyles(p Palette) {
+	paneBoxStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(p.Border)
+	paneFocusedBoxStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(p.Focus)
+	paneTitleStyle = lipgloss.NewStyle().Foreground(p.Title).Bold(true)
+	paneMetaStyle = lipgloss.NewStyle().Foreground(p.Muted)
+	paneToolStyle = lipgloss.NewStyle().Foreground(p.Active)
+	paneStreamStyle = lipgloss.NewStyle().Foreground(p.Text)
+}
+
+// paneRoleOrder fixes where the built-in roles sit; custom roles follow in
+// the order they first report activity.
+var paneRoleOrder = []agent.Role{agent.RolePlanner, agent.RoleCoder, agent.RoleReviewer}
+
+const (
+	maxPaneLines  = 500
+	minPaneColumn = 40
+)
+
+// rolePane holds one role's output for the split-pane layout.
+type rolePane struct {
+	role     agent.Role
+	label    string
+	lines    []string
+	stream   string
+	tool     string
+	task     string
+	state    string
+	busy     time.Duration
+	since    time.Time
+	chars    int
+	follow   bool
+	viewport viewport.Model
+}
+
+func newRolePane(role agent.Role) *rolePane {
+	return &rolePane{
+		role:     role,
+		label:    formatAgentRoleLabel(role),
+		follow:   true,
+		viewport: viewport.New(0, 0),
+	}
+}
+
+// elapsed is how long the role has been working in the current run, from
+// each first event to the done or error event that ends it.
+func (p *rolePane) elapsed() time.Duration {
+	if p.since.IsZero() {
+		return p.busy
+	}
+	return p.busy + time.Since(p.since)
+}
+
+// tokens estimates the streamed output size the way mention budgets are
+// estimated; providers do not report usage on the streaming path.
+func (p *rolePane) tokens() int {
+	if p.chars == 0 {
+		return 0
+	}
+	return rag.EstimateTokensForLen(p.chars)
+}
+
+func (p *rolePane) addLine(line string) {
+	line = strings.TrimRight(line, "\n")
+	if line == "" {
+		return
+	}
+	p.lines = append(p.lines, line)
+	if len(p.lines) > maxPaneLines {
+		p.lines = p.lines[len(p.lines)-maxPaneLines:]
+	}
+}
+
+// flushStream moves the streamed text into the pane history.
+func (p *rolePane) flushStream() {
+	if text := strings.TrimSpace(p.stream); text != "" {
+		p.addLine(text)
+	}
+	p.stream = ""
+}
+
+func (p *rolePane) handle(event agent.AgentEvent) {
+	at := event.At
+	if at.IsZero() {
+		at = time.Now()
+	}
+	if p.since.IsZero() {
+		p.since = at
+	}
+	if task := strings.TrimSpace(event.TaskID); task != "" {
+		p.task = task
+	}
+	p.state = statusStateForEvent(event.Type)
+
+	if token, ok := extractThinkingToken(event.Payload); event.Type == agent.EventThinking && ok {
+		p.stream += token
+		p.chars += len(token)
+		return
+	}
+	p.flushStream()
+
+	if _, action, target, _, ok := mapEventToActivity(event); ok && event.Type != agent.EventThinking {
+		p.tool = strings.TrimSpace(action + " " + target)
+	}
+	detail := strings.TrimSpace(event.Detail)
+	switch event.Type {
+	case agent.EventThinking:
+		if detail != "" {
+			p.addLine("◌ " + detail)
+		}
+	case agent.EventFileDiff:
+		if diff, ok := extractFileDiffPayload(event.Payload); ok {
+			p.addLine(fmt.Sprintf("● wrote %s (%d → %d lines)", diff.Path, len(diff.OldLines), len(diff.NewLines)))
+		}
+	case agent.EventDone, agent.EventError:
+		p.tool = ""
+		p.busy += at.Sub(p.since)
+		p.since = time.Time{}
+		if reply, ok := extractAgentReply(event.Payload); ok {
+			p.addLine(reply)
+			break
+		}
+		p.addLine(renderAgentEventLine(p.label, event.Type, detail))
+	default:
+		p.addLine(renderAgentEventLine(p.label, event.Type, detail))
+	}
+}
+
+func (p *rolePane) header(width int) string {
+	meta := []string{}
+	if p.task != "" {
+		meta = append(meta, p.task)
+	}
+	state := p.state
+	if state == "" {
+		state = "idle"
+	}
+	meta = append(meta, state, formatElapsed(p.elapsed().Round(time.Second)), formatTokenCount(p.tokens()))
+	line := paneTitleStyle.Render(p.label) + "  " + paneMetaStyle.Render(truncateRunes(strings.Join(meta, " · "), max(width-len(p.label)-2, 0)))
+	tool := p.tool
+	if tool == "" {
+		tool = "no active tool"
+	}
+	return line + "\n" + paneToolStyle.Render(truncateRunes("⚙ "+tool, width))
+}
+
+func (p *rolePane) refresh(width, height int) {
+	p.viewport.Width = max(width, 1)
+	p.viewport.Height = max(height, 1)
+	body := make([]string, 0, len(p.lines)+1)
+	for _, line := range p.lines {
+		body = append(body, wrapToWidth(line, p.viewport.Width))
+	}
+	if stream := strings.TrimSpace(p.stream); stream != "" {
+		body = append(body, paneStreamStyle.Render(wrapToWidth(stream, p.viewport.Width)))
+	}
+	p.viewport.SetContent(strings.Join(body, "\n"))
+	if p.follow {
+		p.viewport.GotoBottom()
+	}
+}
+
+func (p *rolePane) scroll(lines int) {
+	if lines < 0 {
+		p.viewport.LineUp(-lines)
+	} else {
+		p.viewport.LineDown(lines)
+	}
+	p.follow = p.viewport.AtBottom()
+}
+
+// PaneView lays out one pane per active role, each with its own stream,
+// current tool call and task.
+type PaneView struct {
+	panes     []*rolePane
+	focus     int
+	maximized bool
+	width     int
+	height    int
+}
+
+func NewPaneView() *PaneView {
+	return &PaneView{}
+}
+
+func (v *PaneView) SetSize(width, height int) {
+	if v == nil {
+		return
+	}
+	v.width = width
+	v.height = height
+	v.refresh()
+}
+
+// Reset clears every pane before a new run.
+func (v *PaneView) Reset() {
+	if v == nil {
+		return
+	}
+	v.panes = nil
+	v.focus = 0
+	v.maximized = false
+}
+
+func (v *PaneView) HandleEvent(event agent.AgentEvent) {
+	if v == nil {
+		return
+	}
+	role := agent.Role(strings.ToLower(strings.TrimSpace(string(event.Role))))
+	if role == "" {
+		return
+	}
+	v.pane(role).handle(event)
+	v.refresh()
+}
+
+// Usage returns each role's elapsed time and estimated token count.
+func (v *PaneView) Usage() map[agent.Role]RoleUsage {
+	usage := make(map[agent.Role]RoleUsage)
+	if v == nil {
+		return usage
+	}
+	for _, p := range v.panes {
+		usage[p.role] = RoleUsage{Elapsed: p.elapsed(), Tokens: p.tokens()}
+	}
+	return usage
+}
+
+// FocusNext moves focus by delta panes, wrapping around.
+func (v *PaneView) FocusNext(delta int) {
+	if v == nil || len(v.panes) == 0 {
+		return
+	}
+	v.focus = ((v.focus+delta)%len(v.panes) + len(v.panes)) % len(v.panes)
+	v.refresh()
+}
+
+// FocusIndex focuses the pane at idx (zero based) if it exists.
+func (v *PaneView) FocusIndex(idx int) {
+	if v == nil || idx < 0 || idx >= len(v.panes) {
+		return
+	}
+	v.focus = idx
+	v.refresh()
+}
+
+func (v *PaneView) ToggleMaximize() {
+	if v == nil {
+		return
+	}
+	v.maximized = !v.maximized
+	v.refresh()
+}
+
+// Scroll moves the focused pane; scrolling back to the bottom resumes
+// following new output.
+func (v *PaneView) Scroll(lines int) {
+	if v == nil || len(v.panes) == 0 {
+		return
+	}
+	v.panes[v.focus].scroll(lines)
+}
+
+// PageSize is the number of lines a page scroll moves in the focused pane.
+func (v *PaneView) PageSize() int {
+	if v == nil || len(v.panes) == 0 {
+		return 1
+	}
+	return max(v.panes[v.focus].viewport.Height-1, 1)
+}
+
+func (v *PaneView) pane(role agent.Role) *rolePane {
+	for _, p := range v.panes {
+		if p.role == role {
+			return p
+		}
+	}
+	p := newRolePane(role)
+	at := len(v.panes)
+	if rank := paneRank(role); rank < len(paneRoleOrder) {
+		for idx, existing := range v.panes {
+			if paneRank(existing.role) > rank {
+				at = idx
+				break
+			}
+		}
+	}
+	v.panes = append(v.panes[:at], append([]*rolePane{p}, v.panes[at:]...)...)
+	if at <= v.focus && len(v.panes) > 1 {
+		v.focus++
+	}
+	return p
+}
+
+func paneRank(role agent.Role) int {
+	for idx, known := range paneRoleOrder {
+		if role == known {
+			return idx
+		}
+	}
+	return len(paneRoleOrder)
+}
+
+// columns reports whether the panes sit side by side rather than stacked.
+func (v *PaneView) columns() bool {
+	return len(v.panes) > 0 && v.width/len(v.panes) >= minPaneColumn
+}
+
+func (v *PaneView) refresh() {
+	if v == nil || v.width <= 0 || v.height <= 0 || len(v.panes) == 0 {
+		return
+	}
+	if v.maximized {
+		p := v.panes[v.focus]
+		p.refresh(v.width-2, v.height-4)
+		return
+	}
+	for idx, p := range v.panes {
+		width, height := v.paneSize(idx)
+		p.refresh(width-2, height-4)
+	}
+}
+
+// paneSize splits the view evenly, giving leftovers to the last pane.
+func (v *PaneView) paneSize(idx int) (int, int) {
+	n := len(v.panes)
+	if v.columns() {
+		width := v.width / n
+		if idx == n-1 {
+			width = v.width - width*(n-1)
+		}
+		return width, v.height
+	}
+	height := v.height / n
+	if idx == n-1 {
+		height = v.height - height*(n-1)
+	}
+	return v.width, height
+}
+
+func (v *PaneView) View() string {
+	if v == nil || v.width <= 0 || v.height <= 0 {
+		return ""
+	}
+	if len(v.panes) == 0 {
+		return lipgloss.Place(v.width, v.height, lipgloss.Center, lipgloss.Center, paneMetaStyle.Render("No agent activity yet. Each role gets a pane once it starts working."))
+	}
+	if v.maximized {
+		return v.renderPane(v.focus, v.width, v.height)
+	}
+	views := make([]string, 0, len(v.panes))
+	for idx := range v.panes {
+		width, height := v.paneSize(idx)
+		views = append(views, v.renderPane(idx, width, height))
+	}
+	if v.columns() {
+		return lipgloss.JoinHorizontal(lipgloss.Top, views...)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, views...)
+}
+
+func (v *PaneView) renderPane(idx, width, height int) string {
+	p := v.panes[idx]
+	style := paneBoxStyle
+	if idx == v.focus {
+		style = paneFocusedBoxStyle
+	}
+	inner := width - 2
+	content := p.header(inner) + "\n" + p.viewport.View()
+	return style.Width(inner).Height(height - 2).MaxHeight(height).Render(content)
+}
+
+// RoleUsage is a role's activity in the current run, shown in the status bar.
+type RoleUsage struct {
+	Elapsed time.Duration
+	Tokens  int
+}
+
+func formatTokenCount(tokens int) string {
+	if tokens >= 1000 {
+		return fmt.Sprintf("~%.1fk tok", float64(tokens)/1000)
+	}
+	return fmt.Sprintf("~%d tok", tokens)
+}
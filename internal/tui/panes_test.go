@@ -0,0 +1,133 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/yubzen/orchestra/internal/agent"
+)
+
+func TestPaneViewKeepsEachRoleSeparate(t *testing.T) {
+	t.Parallel()
+
+	view := NewPaneView()
+	view.SetSize(160, 30)
+	start := time.Now().Add(-3 * time.Second)
+
+	view.HandleEvent(agent.AgentEvent{Type: agent.EventRunning, Role: agent.RoleCoder, Detail: "executing task-2", TaskID: "task-2", At: start})
+	view.HandleEvent(agent.AgentEvent{Type: agent.EventThinking, Role: agent.RoleCoder, Payload: map[string]any{"token": "writing the handler"}})
+	view.HandleEvent(agent.AgentEvent{Type: agent.EventWriting, Role: agent.RoleCoder, Detail: "writing main.go", Payload: map[string]any{"path": "main.go"}})
+	view.HandleEvent(agent.AgentEvent{Type: agent.EventPlanning, Role: agent.RolePlanner, Detail: "drafting plan", At: start})
+	view.HandleEvent(agent.AgentEvent{Type: agent.EventDone, Role: agent.RolePlanner, Detail: "plan ready", At: start.Add(2 * time.Second)})
+	view.HandleEvent(agent.AgentEvent{Type: agent.EventRunning, Role: agent.Role("security"), Detail: "auditing"})
+
+	if len(view.panes) != 3 {
+		t.Fatalf("expected 3 panes, got %d", len(view.panes))
+	}
+	for idx, role := range []agent.Role{agent.RolePlanner, agent.RoleCoder, agent.Role("security")} {
+		if view.panes[idx].role != role {
+			t.Fatalf("pane %d: expected %s, got %s", idx, role, view.panes[idx].role)
+		}
+	}
+
+	coder := view.panes[1]
+	if coder.task != "task-2" || coder.tool != "writeFile main.go" {
+		t.Fatalf("unexpected coder task/tool: %q / %q", coder.task, coder.tool)
+	}
+	if len(coder.lines) != 3 || coder.lines[1] != "writing the handler" {
+		t.Fatalf("expected the streamed text between the coder's events, got %q", coder.lines)
+	}
+	if strings.Contains(strings.Join(view.panes[0].lines, "\n"), "handler") {
+		t.Fatalf("coder output leaked into the planner pane")
+	}
+
+	usage := view.Usage()
+	if got := usage[agent.RolePlanner]; got.Elapsed != 2*time.Second || got.Tokens != 0 {
+		t.Fatalf("unexpected planner usage %+v", got)
+	}
+	if got := usage[agent.RoleCoder]; got.Tokens != 5 || got.Elapsed < 3*time.Second {
+		t.Fatalf("unexpected coder usage %+v", got)
+	}
+
+	out := view.View()
+	for _, want := range []string{"Planner", "Coder", "Security", "task-2", "⚙ writeFile main.go"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in the pane view", want)
+		}
+	}
+}
+
+func TestPaneViewFocusMaximizeAndScroll(t *testing.T) {
+	t.Parallel()
+
+	view := NewPaneView()
+	view.SetSize(60, 24)
+	for _, role := range []agent.Role{agent.RolePlanner, agent.RoleCoder} {
+		for i := 0; i < 40; i++ {
+			view.HandleEvent(agent.AgentEvent{Type: agent.EventReading, Role: role, Detail: "file"})
+		}
+	}
+	if view.columns() {
+		t.Fatalf("expected stacked panes at 60 columns")
+	}
+
+	view.FocusNext(1)
+	if view.focus != 1 {
+		t.Fatalf("expected focus on the coder pane, got %d", view.focus)
+	}
+	view.FocusNext(1)
+	if view.focus != 0 {
+		t.Fatalf("expected focus to wrap to the planner pane, got %d", view.focus)
+	}
+
+	view.Scroll(-5)
+	planner := view.panes[0]
+	if planner.follow || planner.viewport.AtBottom() {
+		t.Fatalf("expected the planner pane to stop following after scrolling up")
+	}
+	if !view.panes[1].viewport.AtBottom() {
+		t.Fatalf("scrolling one pane moved another")
+	}
+	view.Scroll(view.PageSize() * 10)
+	if !planner.follow {
+		t.Fatalf("expected the pane to follow again at the bottom")
+	}
+
+	view.ToggleMaximize()
+	if out := view.View(); strings.Contains(out, "Coder") || !strings.Contains(out, "Planner") {
+		t.Fatalf("expected only the focused pane when maximized")
+	}
+}
+
+func TestPanesCommandSwitchesLayout(t *testing.T) {
+	t.Parallel()
+
+	app := NewAppModel(nil, nil, nil, nil)
+	app.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	msg := handleSlashCommand("/panes", app)()
+	if layout, ok := msg.(PaneLayoutMsg); !ok || !layout.Enabled {
+		t.Fatalf("expected /panes to enable the layout, got %#v", msg)
+	}
+	app.Update(msg)
+	if !app.paneLayout || app.panes.height != 23 {
+		t.Fatalf("expected panes to take the top of the screen, got layout=%v height=%d", app.paneLayout, app.panes.height)
+	}
+
+	app.statusbar.SetRoleModel("PLANNER", "planner-model")
+	app.handleAgentEvent(agent.AgentEvent{Type: agent.EventRunning, Role: agent.RolePlanner, Detail: "planning", At: time.Now().Add(-90 * time.Second)})
+	app.handleAgentEvent(agent.AgentEvent{Type: agent.EventRunning, Role: agent.RoleCoder, Detail: "coding"})
+	app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'2'}, Alt: true})
+	if app.panes.focus != 1 || app.chat.GetInputValue() != "" {
+		t.Fatalf("expected alt+2 to focus the coder pane without typing, got focus %d input %q", app.panes.focus, app.chat.GetInputValue())
+	}
+	if !strings.Contains(app.statusbar.View(), "1m30s ~0 tok") {
+		t.Fatalf("expected per-role usage in the status bar")
+	}
+
+	if msg := handleSlashCommand("/panes off", app)(); msg.(PaneLayoutMsg).Enabled {
+		t.Fatalf("expected /panes off to disable the layout")
+	}
+}
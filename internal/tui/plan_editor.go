@@ -0,0 +1,508 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/yubzen/orchestra/internal/agent"
+)
+
+var (
+	planEditorSelectedStyle lipgloss.Style
+	planEditorLabelStyle    lipgloss.Style
+	planEditorIssueStyle    lipgloss.Style
+	planEditorOKStyle       lipgloss.Style
+)
+
+func applyPlanEditorStyles(p Palette) {
+	planEditorSelectedStyle = lipgloss.NewStyle().Foreground(p.Title).Background(planModalBG).Bold(true)
+	planEditorLabelStyle = lipgloss.NewStyle().Foreground(p.Muted).Background(planModalBG)
+	planEditorIssueStyle = lipgloss.NewStyle().Foreground(p.ErrorSoft).Background(planModalBG)
+	planEditorOKStyle = lipgloss.NewStyle().Foreground(p.Success).Background(planModalBG)
+}
+
+type planField int
+
+const (
+	planFieldID planField = iota
+	planFieldDescription
+	planFieldFilesToModify
+	planFieldFilesToCreate
+	planFieldDependsOn
+	planFieldVerify
+	planFieldCount
+)
+
+var planFieldNames = [planFieldCount]string{"id", "description", "files_to_modify", "files_to_create", "depends_on", "verify"}
+
+// planEditor edits YAMLPlan.Tasks field by field and validates after every
+// change.
+type planEditor struct {
+	tasks    []agent.PlanTask
+	root     string
+	selected int
+	field    planField
+	editing  bool
+	picking  bool
+	pick     int
+	input    textinput.Model
+	issues   []agent.PlanIssue
+	notice   string
+}
+
+func newPlanEditor() planEditor {
+	ti := textinput.New()
+	ti.Prompt = ""
+	ti.CharLimit = 0
+	return planEditor{input: ti}
+}
+
+func (e *planEditor) load(plan agent.YAMLPlan, root string) {
+	e.tasks = make([]agent.PlanTask, 0, len(plan.Tasks))
+	for _, task := range plan.Tasks {
+		task.FilesToModify = append([]string(nil), task.FilesToModify...)
+		task.FilesToCreate = append([]string(nil), task.FilesToCreate...)
+		task.DependsOn = append([]string(nil), task.DependsOn...)
+		task.Verify = append([]string(nil), task.Verify...)
+		e.tasks = append(e.tasks, task)
+	}
+	e.root = root
+	e.selected = 0
+	e.field = planFieldID
+	e.editing = false
+	e.picking = false
+	e.notice = ""
+	e.validate()
+}
+
+func (e *planEditor) plan() agent.YAMLPlan {
+	return agent.YAMLPlan{Tasks: append([]agent.PlanTask(nil), e.tasks...)}
+}
+
+func (e *planEditor) validate() {
+	e.issues = agent.ValidatePlan(e.plan(), e.root)
+}
+
+// busy reports whether a field edit or the dependency picker has the keys.
+func (e *planEditor) busy() bool {
+	return e.editing || e.picking
+}
+
+func (e *planEditor) Update(msg tea.KeyMsg) tea.Cmd {
+	if e.editing {
+		switch msg.String() {
+		case "enter":
+			e.commitField()
+			e.editing = false
+			e.validate()
+			return nil
+		case "esc":
+			e.editing = false
+			return nil
+		}
+		var cmd tea.Cmd
+		e.input, cmd = e.input.Update(msg)
+		return cmd
+	}
+	if e.picking {
+		e.updatePicker(msg)
+		return nil
+	}
+
+	e.notice = ""
+	switch msg.String() {
+	case "up", "k":
+		if e.selected > 0 {
+			e.selected--
+		}
+	case "down", "j":
+		if e.selected < len(e.tasks)-1 {
+			e.selected++
+		}
+	case "shift+up", "K":
+		e.moveTask(-1)
+	case "shift+down", "J":
+		e.moveTask(1)
+	case "tab":
+		e.field = (e.field + 1) % planFieldCount
+	case "shift+tab":
+		e.field = (e.field + planFieldCount - 1) % planFieldCount
+	case "enter":
+		if len(e.tasks) == 0 {
+			break
+		}
+		if e.field == planFieldDependsOn {
+			e.picking = true
+			e.pick = 0
+			break
+		}
+		e.input.SetValue(e.fieldValue(e.tasks[e.selected], e.field))
+		e.input.CursorEnd()
+		e.input.Focus()
+		e.editing = true
+	case "n":
+		e.addTask()
+	case "x":
+		e.deleteTask()
+	case "s":
+		e.splitTask()
+	}
+	e.validate()
+	return nil
+}
+
+func (e *planEditor) updatePicker(msg tea.KeyMsg) {
+	others := e.otherTaskIDs()
+	switch msg.String() {
+	case "up", "k":
+		if e.pick > 0 {
+			e.pick--
+		}
+	case "down", "j":
+		if e.pick < len(others)-1 {
+			e.pick++
+		}
+	case " ", "space":
+		if e.pick < len(others) {
+			e.toggleDependency(others[e.pick])
+			e.validate()
+		}
+	case "enter", "esc":
+		e.picking = false
+	}
+}
+
+func (e *planEditor) otherTaskIDs() []string {
+	ids := make([]string, 0, len(e.tasks))
+	for idx, task := range e.tasks {
+		if idx != e.selected {
+			ids = append(ids, task.ID)
+		}
+	}
+	return ids
+}
+
+func (e *planEditor) toggleDependency(id string) {
+	task := &e.tasks[e.selected]
+	for idx, dep := range task.DependsOn {
+		if dep == id {
+			task.DependsOn = append(task.DependsOn[:idx:idx], task.DependsOn[idx+1:]...)
+			return
+		}
+	}
+	task.DependsOn = append(task.DependsOn, id)
+}
+
+func (e *planEditor) fieldValue(task agent.PlanTask, field planField) string {
+	switch field {
+	case planFieldID:
+		return task.ID
+	case planFieldDescription:
+		return task.Description
+	case planFieldFilesToModify:
+		return strings.Join(task.FilesToModify, ", ")
+	case planFieldFilesToCreate:
+		return strings.Join(task.FilesToCreate, ", ")
+	case planFieldDependsOn:
+		return strings.Join(task.DependsOn, ", ")
+	case planFieldVerify:
+		return strings.Join(task.Verify, ", ")
+	}
+	return ""
+}
+
+func (e *planEditor) commitField() {
+	if e.selected >= len(e.tasks) {
+		return
+	}
+	task := &e.tasks[e.selected]
+	value := strings.TrimSpace(e.input.Value())
+	switch e.field {
+	case planFieldID:
+		if value != task.ID {
+			e.renameTask(task.ID, value)
+		}
+	case planFieldDescription:
+		task.Description = value
+	case planFieldFilesToModify:
+		task.FilesToModify = splitPlanList(value)
+	case planFieldFilesToCreate:
+		task.FilesToCreate = splitPlanList(value)
+	case planFieldDependsOn:
+		task.DependsOn = splitPlanList(value)
+	case planFieldVerify:
+		task.Verify = splitPlanList(value)
+	}
+}
+
+// renameTask changes a task ID and rewrites every dependency on it.
+func (e *planEditor) renameTask(from, to string) {
+	e.tasks[e.selected].ID = to
+	for idx := range e.tasks {
+		for depIdx, dep := range e.tasks[idx].DependsOn {
+			if dep == from {
+				e.tasks[idx].DependsOn[depIdx] = to
+			}
+		}
+	}
+}
+
+func (e *planEditor) moveTask(delta int) {
+	target := e.selected + delta
+	if target < 0 || target >= len(e.tasks) {
+		return
+	}
+	e.tasks[e.selected], e.tasks[target] = e.tasks[target], e.tasks[e.selected]
+	e.selected = target
+}
+
+func (e *planEditor) addTask() {
+	task := agent.PlanTask{ID: e.unusedID("task"), Description: "New task"}
+	at := e.selected + 1
+	if len(e.tasks) == 0 {
+		at = 0
+	}
+	e.tasks = append(e.tasks[:at], append([]agent.PlanTask{task}, e.tasks[at:]...)...)
+	e.selected = at
+	e.field = planFieldDescription
+}
+
+func (e *planEditor) deleteTask() {
+	if len(e.tasks) == 0 {
+		return
+	}
+	removed := e.tasks[e.selected].ID
+	e.tasks = append(e.tasks[:e.selected], e.tasks[e.selected+1:]...)
+	for idx := range e.tasks {
+		deps := e.tasks[idx].DependsOn[:0:0]
+		for _, dep := range e.tasks[idx].DependsOn {
+			if dep != removed {
+				deps = append(deps, dep)
+			}
+		}
+		e.tasks[idx].DependsOn = deps
+	}
+	if e.selected >= len(e.tasks) && e.selected > 0 {
+		e.selected--
+	}
+	e.notice = fmt.Sprintf("Deleted %s", removed)
+}
+
+// splitTask inserts a follow-up task after the selected one. The follow-up
+// depends on the original and takes over its dependents, so the order of the
+// plan is unchanged.
+func (e *planEditor) splitTask() {
+	if len(e.tasks) == 0 {
+		return
+	}
+	original := e.tasks[e.selected]
+	part := agent.PlanTask{
+		ID:          e.unusedID(original.ID + "-part"),
+		Description: original.Description + " (continued)",
+		DependsOn:   []string{original.ID},
+	}
+	for idx := range e.tasks {
+		if idx == e.selected {
+			continue
+		}
+		for depIdx, dep := range e.tasks[idx].DependsOn {
+			if dep == original.ID {
+				e.tasks[idx].DependsOn[depIdx] = part.ID
+			}
+		}
+	}
+	at := e.selected + 1
+	e.tasks = append(e.tasks[:at], append([]agent.PlanTask{part}, e.tasks[at:]...)...)
+	e.selected = at
+	e.field = planFieldDescription
+	e.notice = fmt.Sprintf("Split %s into %s and %s", original.ID, original.ID, part.ID)
+}
+
+func (e *planEditor) unusedID(prefix string) string {
+	used := make(map[string]bool, len(e.tasks))
+	for _, task := range e.tasks {
+		used[task.ID] = true
+	}
+	for n := len(e.tasks) + 1; ; n++ {
+		id := fmt.Sprintf("%s-%d", prefix, n)
+		if !used[id] {
+			return id
+		}
+	}
+}
+
+func (e *planEditor) View(width int) string {
+	var b strings.Builder
+	issuesByTask := make(map[string]int, len(e.issues))
+	for _, issue := range e.issues {
+		issuesByTask[issue.TaskID]++
+	}
+
+	for idx, task := range e.tasks {
+		line := fmt.Sprintf("%d. %s  %s", idx+1, task.ID, task.Description)
+		if issuesByTask[task.ID] > 0 {
+			line += "  ✗"
+		}
+		line = truncateRunes(line, width-2)
+		if idx == e.selected {
+			b.WriteString(planEditorSelectedStyle.Render("▸ " + line))
+		} else {
+			b.WriteString(planModalBodyStyle.Render("  " + line))
+		}
+		b.WriteString("\n")
+	}
+	if len(e.tasks) == 0 {
+		b.WriteString(planEditorLabelStyle.Render("No tasks. Press n to add one."))
+		b.WriteString("\n")
+	}
+
+	if e.selected < len(e.tasks) {
+		task := e.tasks[e.selected]
+		b.WriteString("\n")
+		for field := planField(0); field < planFieldCount; field++ {
+			label := fmt.Sprintf("%-16s", planFieldNames[field])
+			value := e.fieldValue(task, field)
+			if field == e.field && e.editing {
+				value = e.input.View()
+			} else {
+				value = truncateRunes(value, width-20)
+			}
+			if field == e.field {
+				b.WriteString(planEditorSelectedStyle.Render("▸ "+label) + " " + planModalBodyStyle.Render(value))
+			} else {
+				b.WriteString(planEditorLabelStyle.Render("  "+label) + " " + planModalBodyStyle.Render(value))
+			}
+			b.WriteString("\n")
+		}
+		if e.picking {
+			b.WriteString(e.renderPicker(task))
+		}
+	}
+
+	b.WriteString("\n")
+	b.WriteString(planModalTitleStyle.Render("Dependencies"))
+	b.WriteString("\n")
+	b.WriteString(planModalBodyStyle.Render(renderPlanGraph(e.plan())))
+	b.WriteString("\n\n")
+	if len(e.issues) == 0 {
+		b.WriteString(planEditorOKStyle.Render("✓ plan is valid"))
+	} else {
+		for _, issue := range e.issues {
+			b.WriteString(planEditorIssueStyle.Render("✗ " + truncateRunes(issue.Error(), width-2)))
+			b.WriteString("\n")
+		}
+	}
+	if e.notice != "" {
+		b.WriteString("\n")
+		b.WriteString(planEditorLabelStyle.Render(e.notice))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (e *planEditor) renderPicker(task agent.PlanTask) string {
+	others := e.otherTaskIDs()
+	if len(others) == 0 {
+		return planEditorLabelStyle.Render("    no other tasks to depend on") + "\n"
+	}
+	selected := make(map[string]bool, len(task.DependsOn))
+	for _, dep := range task.DependsOn {
+		selected[dep] = true
+	}
+	var b strings.Builder
+	for idx, id := range others {
+		box := "[ ]"
+		if selected[id] {
+			box = "[x]"
+		}
+		line := fmt.Sprintf("    %s %s", box, id)
+		if idx == e.pick {
+			b.WriteString(planEditorSelectedStyle.Render(line))
+		} else {
+			b.WriteString(planModalBodyStyle.Render(line))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// renderPlanGraph lists tasks by dependency depth, indenting each level and
+// naming what it waits on. Tasks caught in a cycle are listed last.
+func renderPlanGraph(plan agent.YAMLPlan) string {
+	known := make(map[string]bool, len(plan.Tasks))
+	for _, task := range plan.Tasks {
+		known[task.ID] = true
+	}
+	depth := make(map[string]int, len(plan.Tasks))
+	for pass := 0; pass <= len(plan.Tasks); pass++ {
+		changed := false
+		for _, task := range plan.Tasks {
+			if _, done := depth[task.ID]; done {
+				continue
+			}
+			level, ready := 0, true
+			for _, dep := range task.DependsOn {
+				if !known[dep] {
+					continue
+				}
+				d, ok := depth[dep]
+				if !ok {
+					ready = false
+					break
+				}
+				level = max(level, d+1)
+			}
+			if ready {
+				depth[task.ID] = level
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	maxDepth := 0
+	for _, d := range depth {
+		maxDepth = max(maxDepth, d)
+	}
+	var lines []string
+	for level := 0; level <= maxDepth; level++ {
+		for _, task := range plan.Tasks {
+			if d, ok := depth[task.ID]; !ok || d != level {
+				continue
+			}
+			lines = append(lines, graphLine(strings.Repeat("  ", level), task, ""))
+		}
+	}
+	for _, task := range plan.Tasks {
+		if _, ok := depth[task.ID]; !ok {
+			lines = append(lines, graphLine("", task, "  (cycle)"))
+		}
+	}
+	if len(lines) == 0 {
+		return "(empty)"
+	}
+	return strings.Join(lines, "\n")
+}
+
+func graphLine(indent string, task agent.PlanTask, suffix string) string {
+	line := indent + task.ID
+	if len(task.DependsOn) > 0 {
+		line += " ← " + strings.Join(task.DependsOn, ", ")
+	}
+	return line + suffix
+}
+
+func splitPlanList(value string) []string {
+	var out []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
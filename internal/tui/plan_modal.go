@@ -2,26 +2,37 @@ package tui
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+
+	"github.com/yubzen/orchestra/internal/agent"
 )
 
 var (
-	planModalBG       = lipgloss.Color("235")
-	planModalBoxStyle = lipgloss.NewStyle().
-				Border(lipgloss.RoundedBorder()).
-				BorderForeground(lipgloss.Color("75")).
-				Background(planModalBG).
-				Padding(1, 2)
-	planModalTitleStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("81")).Background(planModalBG).Bold(true)
-	planModalHintStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("244")).Background(planModalBG)
-	planModalBodyStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("252")).Background(planModalBG)
+	planModalBG         lipgloss.Color
+	planModalBoxStyle   lipgloss.Style
+	planModalTitleStyle lipgloss.Style
+	planModalHintStyle  lipgloss.Style
+	planModalBodyStyle  lipgloss.Style
 )
 
+func applyPlanModalStyles(p Palette) {
+	planModalBG = p.Surface
+	planModalBoxStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(p.Focus).
+		Background(planModalBG).
+		Padding(1, 2)
+	planModalTitleStyle = lipgloss.NewStyle().Foreground(p.Title).Background(planModalBG).Bold(true)
+	planModalHintStyle = lipgloss.NewStyle().Foreground(p.Muted).Background(planModalBG)
+	planModalBodyStyle = lipgloss.NewStyle().Foreground(p.Text).Background(planModalBG)
+}
+
 type PlanReviewAction struct {
 	DecisionMade bool
 	PlanID       string
@@ -29,15 +40,33 @@ type PlanReviewAction struct {
 	EditedPlan   string
 }
 
+type planEditorTab int
+
+const (
+	planTabTasks planEditorTab = iota
+	planTabYAML
+)
+
+// PlanReviewModal shows the planner's YAML for approval. Editing opens a
+// structured task editor with live validation; raw YAML stays available as
+// a second tab.
 type PlanReviewModal struct {
 	Visible      bool
 	PlanID       string
 	OriginalPlan string
-	editing      bool
-	width        int
-	height       int
-	viewport     viewport.Model
-	editor       textarea.Model
+	// WorkingDir is the workspace that plan file paths must stay inside.
+	WorkingDir string
+	editing    bool
+	tab        planEditorTab
+	parsed     agent.YAMLPlan
+	parseErr   error
+	tasks      planEditor
+	yamlIssues []string
+	notice     string
+	width      int
+	height     int
+	viewport   viewport.Model
+	editor     textarea.Model
 }
 
 func NewPlanReviewModal() *PlanReviewModal {
@@ -51,6 +80,7 @@ func NewPlanReviewModal() *PlanReviewModal {
 	return &PlanReviewModal{
 		viewport: vp,
 		editor:   ta,
+		tasks:    newPlanEditor(),
 	}
 }
 
@@ -75,7 +105,7 @@ func (m *PlanReviewModal) SetSize(width, height int) {
 	m.viewport.Width = bodyWidth
 	m.viewport.Height = bodyHeight
 	m.editor.SetWidth(bodyWidth)
-	m.editor.SetHeight(bodyHeight)
+	m.editor.SetHeight(max(bodyHeight-4, 6))
 	m.refreshPlanPreview()
 }
 
@@ -86,7 +116,9 @@ func (m *PlanReviewModal) Open(planID, planYAML string) {
 	m.Visible = true
 	m.PlanID = strings.TrimSpace(planID)
 	m.OriginalPlan = strings.TrimSpace(planYAML)
+	m.parsed, m.parseErr = agent.ParsePlanYAML(m.OriginalPlan)
 	m.editing = false
+	m.notice = ""
 	m.editor.SetValue(m.OriginalPlan)
 	m.refreshPlanPreview()
 }
@@ -98,7 +130,10 @@ func (m *PlanReviewModal) Close() {
 	m.Visible = false
 	m.PlanID = ""
 	m.OriginalPlan = ""
+	m.parsed = agent.YAMLPlan{}
+	m.parseErr = nil
 	m.editing = false
+	m.notice = ""
 	m.editor.SetValue("")
 	m.viewport.SetContent("")
 }
@@ -109,25 +144,7 @@ func (m *PlanReviewModal) Update(msg tea.Msg) (PlanReviewAction, tea.Cmd) {
 	}
 	if key, ok := msg.(tea.KeyMsg); ok {
 		if m.editing {
-			switch key.String() {
-			case "ctrl+s":
-				return PlanReviewAction{
-					DecisionMade: true,
-					PlanID:       m.PlanID,
-					Approved:     true,
-					EditedPlan:   strings.TrimSpace(m.editor.Value()),
-				}, nil
-			case "esc":
-				m.editing = false
-				m.refreshPlanPreview()
-				return PlanReviewAction{}, nil
-			case "ctrl+r":
-				m.editor.SetValue(m.OriginalPlan)
-				return PlanReviewAction{}, nil
-			}
-			var cmd tea.Cmd
-			m.editor, cmd = m.editor.Update(msg)
-			return PlanReviewAction{}, cmd
+			return m.updateEditing(key)
 		}
 
 		switch key.String() {
@@ -144,8 +161,7 @@ func (m *PlanReviewModal) Update(msg tea.Msg) (PlanReviewAction, tea.Cmd) {
 				Approved:     false,
 			}, nil
 		case "e":
-			m.editing = true
-			m.editor.SetValue(m.OriginalPlan)
+			m.startEditing()
 			return PlanReviewAction{}, nil
 		}
 	}
@@ -155,6 +171,129 @@ func (m *PlanReviewModal) Update(msg tea.Msg) (PlanReviewAction, tea.Cmd) {
 	return PlanReviewAction{}, cmd
 }
 
+func (m *PlanReviewModal) updateEditing(key tea.KeyMsg) (PlanReviewAction, tea.Cmd) {
+	if m.tab == planTabTasks && m.tasks.busy() {
+		cmd := m.tasks.Update(key)
+		m.refreshTaskEditor()
+		return PlanReviewAction{}, cmd
+	}
+
+	m.notice = ""
+	switch key.String() {
+	case "ctrl+s":
+		return m.submitEdited(), nil
+	case "esc":
+		m.editing = false
+		m.refreshPlanPreview()
+		return PlanReviewAction{}, nil
+	case "ctrl+r":
+		m.editor.SetValue(m.OriginalPlan)
+		m.tasks.load(m.parsed, m.WorkingDir)
+		m.validateYAML()
+		m.refreshTaskEditor()
+		return PlanReviewAction{}, nil
+	case "ctrl+t":
+		m.switchTab()
+		return PlanReviewAction{}, nil
+	case "pgup", "pgdown":
+		if m.tab == planTabTasks {
+			var cmd tea.Cmd
+			m.viewport, cmd = m.viewport.Update(key)
+			return PlanReviewAction{}, cmd
+		}
+	}
+
+	if m.tab == planTabYAML {
+		var cmd tea.Cmd
+		m.editor, cmd = m.editor.Update(key)
+		m.validateYAML()
+		return PlanReviewAction{}, cmd
+	}
+	cmd := m.tasks.Update(key)
+	m.refreshTaskEditor()
+	return PlanReviewAction{}, cmd
+}
+
+// startEditing opens the task editor, or the YAML tab when the plan does not
+// parse.
+func (m *PlanReviewModal) startEditing() {
+	m.editing = true
+	m.editor.SetValue(m.OriginalPlan)
+	m.validateYAML()
+	if m.parseErr != nil {
+		m.tab = planTabYAML
+		m.notice = fmt.Sprintf("Plan does not parse, editing YAML: %v", m.parseErr)
+		return
+	}
+	m.tab = planTabTasks
+	m.tasks.load(m.parsed, m.WorkingDir)
+	m.refreshTaskEditor()
+}
+
+func (m *PlanReviewModal) switchTab() {
+	if m.tab == planTabTasks {
+		m.editor.SetValue(m.taskEditorYAML())
+		m.validateYAML()
+		m.tab = planTabYAML
+		return
+	}
+	plan, err := agent.ParsePlanYAML(m.editor.Value())
+	if err != nil {
+		m.notice = fmt.Sprintf("Fix the YAML before switching to tasks: %v", err)
+		return
+	}
+	selected := m.tasks.selected
+	m.tasks.load(plan, m.WorkingDir)
+	m.tasks.selected = min(selected, max(len(m.tasks.tasks)-1, 0))
+	m.tab = planTabTasks
+	m.refreshTaskEditor()
+}
+
+func (m *PlanReviewModal) submitEdited() PlanReviewAction {
+	edited := strings.TrimSpace(m.editor.Value())
+	if m.tab == planTabTasks {
+		if len(m.tasks.issues) > 0 {
+			m.notice = fmt.Sprintf("Fix %d issue(s) before approving", len(m.tasks.issues))
+			return PlanReviewAction{}
+		}
+		edited = m.taskEditorYAML()
+	} else {
+		m.validateYAML()
+		if len(m.yamlIssues) > 0 {
+			m.notice = fmt.Sprintf("Fix %d issue(s) before approving", len(m.yamlIssues))
+			return PlanReviewAction{}
+		}
+	}
+	return PlanReviewAction{
+		DecisionMade: true,
+		PlanID:       m.PlanID,
+		Approved:     true,
+		EditedPlan:   edited,
+	}
+}
+
+// taskEditorYAML renders the structured plan, keeping the planner's original
+// text when nothing changed.
+func (m *PlanReviewModal) taskEditorYAML() string {
+	plan := m.tasks.plan()
+	if m.parseErr == nil && reflect.DeepEqual(plan, m.parsed) {
+		return m.OriginalPlan
+	}
+	return strings.TrimSpace(agent.RenderPlanYAML(plan))
+}
+
+func (m *PlanReviewModal) validateYAML() {
+	m.yamlIssues = nil
+	plan, err := agent.ParsePlanYAML(m.editor.Value())
+	if err != nil {
+		m.yamlIssues = []string{err.Error()}
+		return
+	}
+	for _, issue := range agent.ValidatePlan(plan, m.WorkingDir) {
+		m.yamlIssues = append(m.yamlIssues, issue.Error())
+	}
+}
+
 func (m *PlanReviewModal) View() string {
 	if m == nil || !m.Visible {
 		return ""
@@ -167,9 +306,24 @@ func (m *PlanReviewModal) View() string {
 	titleView := planModalTitleStyle.Render(title)
 
 	if m.editing {
-		body := planModalBodyStyle.Render(m.editor.View())
-		hint := planModalHintStyle.Render("ctrl+s: approve edited plan  esc: cancel edit  ctrl+r: reset")
-		return planModalBoxStyle.Render(fmt.Sprintf("%s\n\n%s\n\n%s", titleView, body, hint))
+		tabs := m.renderTabs()
+		var body, hint string
+		if m.tab == planTabTasks {
+			body = m.viewport.View()
+			hint = "j/k: task  J/K: move  tab: field  enter: edit  n: add  x: delete  s: split  ctrl+t: yaml  ctrl+s: approve  esc: back"
+			if m.tasks.picking {
+				hint = "j/k: move  space: toggle dependency  enter/esc: done"
+			} else if m.tasks.editing {
+				hint = "enter: save field (comma separates list items)  esc: cancel"
+			}
+		} else {
+			body = m.editor.View() + "\n" + m.renderYAMLIssues()
+			hint = "ctrl+t: tasks  ctrl+s: approve edited plan  esc: cancel edit  ctrl+r: reset"
+		}
+		if m.notice != "" {
+			body += "\n" + planEditorIssueStyle.Render(m.notice)
+		}
+		return planModalBoxStyle.Render(fmt.Sprintf("%s  %s\n\n%s\n\n%s", titleView, tabs, planModalBodyStyle.Render(body), planModalHintStyle.Render(hint)))
 	}
 
 	body := planModalBodyStyle.Render(m.viewport.View())
@@ -177,10 +331,53 @@ func (m *PlanReviewModal) View() string {
 	return planModalBoxStyle.Render(fmt.Sprintf("%s\n\n%s\n\n%s", titleView, body, hint))
 }
 
+func (m *PlanReviewModal) renderTabs() string {
+	names := []string{"Tasks", "YAML"}
+	parts := make([]string, len(names))
+	for idx, name := range names {
+		if planEditorTab(idx) == m.tab {
+			parts[idx] = planEditorSelectedStyle.Render("[" + name + "]")
+		} else {
+			parts[idx] = planEditorLabelStyle.Render(" " + name + " ")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func (m *PlanReviewModal) renderYAMLIssues() string {
+	if len(m.yamlIssues) == 0 {
+		return planEditorOKStyle.Render("✓ plan is valid")
+	}
+	lines := make([]string, 0, 3)
+	for idx, issue := range m.yamlIssues {
+		if idx == 2 && len(m.yamlIssues) > 3 {
+			lines = append(lines, planEditorIssueStyle.Render(fmt.Sprintf("✗ … and %d more", len(m.yamlIssues)-2)))
+			break
+		}
+		lines = append(lines, planEditorIssueStyle.Render("✗ "+truncateRunes(issue, m.viewport.Width-2)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m *PlanReviewModal) refreshTaskEditor() {
+	if m == nil || !m.editing || m.tab != planTabTasks {
+		return
+	}
+	width := m.viewport.Width
+	if width <= 0 {
+		width = 70
+	}
+	m.viewport.SetContent(m.tasks.View(width))
+}
+
 func (m *PlanReviewModal) refreshPlanPreview() {
 	if m == nil {
 		return
 	}
+	if m.editing && m.tab == planTabTasks {
+		m.refreshTaskEditor()
+		return
+	}
 	width := m.viewport.Width
 	if width <= 0 {
 		width = 70
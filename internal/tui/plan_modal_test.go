@@ -1,9 +1,12 @@
 package tui
 
 import (
+	"strings"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/yubzen/orchestra/internal/agent"
 )
 
 func TestPlanReviewModalApprove(t *testing.T) {
@@ -38,3 +41,113 @@ func TestPlanReviewModalEditAndApprove(t *testing.T) {
 		t.Fatalf("expected plan id plan-2, got %q", action.PlanID)
 	}
 }
+
+func planKey(s string) tea.KeyMsg {
+	switch s {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "tab":
+		return tea.KeyMsg{Type: tea.KeyTab}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	case "space":
+		return tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+	case "ctrl+s":
+		return tea.KeyMsg{Type: tea.KeyCtrlS}
+	case "ctrl+t":
+		return tea.KeyMsg{Type: tea.KeyCtrlT}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func sendPlanKeys(modal *PlanReviewModal, keys ...string) PlanReviewAction {
+	var action PlanReviewAction
+	for _, key := range keys {
+		action, _ = modal.Update(planKey(key))
+	}
+	return action
+}
+
+func TestPlanReviewModalStructuredEditing(t *testing.T) {
+	t.Parallel()
+
+	modal := NewPlanReviewModal()
+	modal.SetSize(120, 40)
+	modal.WorkingDir = t.TempDir()
+	modal.Open("plan-3", "tasks:\n- id: t1\n  description: first\n- id: t2\n  description: second\n  depends_on: [t1]")
+
+	// Split t1, then move t2 to the top and drop its dependency via the picker.
+	sendPlanKeys(modal, "e", "s")
+	tasks := modal.tasks.tasks
+	if len(tasks) != 3 || tasks[1].DependsOn[0] != "t1" || tasks[2].DependsOn[0] != tasks[1].ID {
+		t.Fatalf("expected the split part to sit between t1 and t2, got %+v", tasks)
+	}
+	sendPlanKeys(modal, "j", "K", "K")
+	if modal.tasks.tasks[0].ID != "t2" || modal.tasks.selected != 0 {
+		t.Fatalf("expected t2 to move to the top, got %+v", modal.tasks.tasks)
+	}
+	sendPlanKeys(modal, "tab", "tab", "tab", "enter", "j", "space", "enter")
+	if deps := modal.tasks.tasks[0].DependsOn; len(deps) != 0 {
+		t.Fatalf("expected the picker to clear t2's dependency, got %v", deps)
+	}
+
+	action := sendPlanKeys(modal, "ctrl+s")
+	if !action.DecisionMade || !action.Approved {
+		t.Fatalf("expected the edited plan to be approved, got %#v", action)
+	}
+	plan, err := agent.ParsePlanYAML(action.EditedPlan)
+	if err != nil {
+		t.Fatalf("edited plan does not parse: %v", err)
+	}
+	if len(plan.Tasks) != 3 || plan.Tasks[0].ID != "t2" || plan.Tasks[1].ID != "t1" {
+		t.Fatalf("unexpected edited plan %+v", plan.Tasks)
+	}
+}
+
+func TestPlanReviewModalBlocksInvalidPlans(t *testing.T) {
+	t.Parallel()
+
+	modal := NewPlanReviewModal()
+	modal.SetSize(120, 40)
+	modal.Open("plan-4", "tasks:\n- id: t1\n  description: first\n- id: t2\n  description: second")
+
+	// Make t1 depend on t2 and t2 on t1.
+	sendPlanKeys(modal, "e", "tab", "tab", "tab", "tab", "enter", "space", "enter", "j", "enter", "space", "enter")
+	if len(modal.tasks.issues) == 0 || !strings.Contains(modal.tasks.issues[0].Error(), "dependency cycle") {
+		t.Fatalf("expected a live cycle issue, got %v", modal.tasks.issues)
+	}
+	if action := sendPlanKeys(modal, "ctrl+s"); action.DecisionMade {
+		t.Fatalf("expected approval to be blocked, got %#v", action)
+	}
+	if !strings.Contains(modal.View(), "Fix 1 issue(s) before approving") {
+		t.Fatalf("expected a notice about the blocking issue")
+	}
+	if !strings.Contains(renderPlanGraph(modal.tasks.plan()), "(cycle)") {
+		t.Fatalf("expected the graph to flag the cycle")
+	}
+
+	// The YAML tab carries the same plan and validates it too.
+	sendPlanKeys(modal, "ctrl+t")
+	if modal.tab != planTabYAML || !strings.Contains(modal.editor.Value(), "depends_on") {
+		t.Fatalf("expected the YAML tab with the edited plan, got %q", modal.editor.Value())
+	}
+	if len(modal.yamlIssues) == 0 {
+		t.Fatalf("expected the YAML tab to report the cycle")
+	}
+}
+
+func TestPlanReviewModalFallsBackToYAMLForUnparsablePlans(t *testing.T) {
+	t.Parallel()
+
+	modal := NewPlanReviewModal()
+	modal.SetSize(120, 40)
+	modal.Open("plan-5", "not: [valid")
+
+	sendPlanKeys(modal, "e")
+	if modal.tab != planTabYAML || !strings.Contains(modal.notice, "does not parse") {
+		t.Fatalf("expected the YAML tab with a parse notice, got tab %d notice %q", modal.tab, modal.notice)
+	}
+	if action := sendPlanKeys(modal, "ctrl+s"); action.DecisionMade {
+		t.Fatalf("expected an unparsable plan to be refused, got %#v", action)
+	}
+}
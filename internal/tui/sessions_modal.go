@@ -0,0 +1,520 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/yubzen/orchestra/internal/state"
+)
+
+var (
+	sessionsSelectedStyle lipgloss.Style
+	sessionsRowStyle      lipgloss.Style
+	sessionsMetaStyle     lipgloss.Style
+	sessionsWarnStyle     lipgloss.Style
+)
+
+func applySessionsStyles(p Palette) {
+	sessionsSelectedStyle = lipgloss.NewStyle().Foreground(p.Title).Background(planModalBG).Bold(true)
+	sessionsRowStyle = lipgloss.NewStyle().Foreground(p.Text).Background(planModalBG)
+	sessionsMetaStyle = lipgloss.NewStyle().Foreground(p.Muted).Background(planModalBG)
+	sessionsWarnStyle = lipgloss.NewStyle().Foreground(p.Caution).Background(planModalBG).Bold(true)
+}
+
+type SessionsActionKind int
+
+const (
+	SessionsActionNone SessionsActionKind = iota
+	SessionsActionResume
+	SessionsActionFork
+	SessionsActionLoadMessages
+	SessionsActionRename
+	SessionsActionDelete
+)
+
+// SessionsAction is a request from the session browser; the app performs
+// the database work and reloads the modal.
+type SessionsAction struct {
+	Kind      SessionsActionKind
+	SessionID string
+	MessageID int64
+	Name      string
+}
+
+type sessionsMode int
+
+const (
+	sessionsBrowse sessionsMode = iota
+	sessionsRename
+	sessionsConfirmDelete
+	sessionsPickMessage
+)
+
+type sessionRow struct {
+	summary state.SessionSummary
+	text    string
+	snippet string
+	score   int
+}
+
+// SessionsModal lists saved sessions with search over their messages, and
+// lets the user resume, fork, rename or delete one.
+type SessionsModal struct {
+	Visible   bool
+	CurrentID string
+	rows      []sessionRow
+	filtered  []int
+	selected  int
+	mode      sessionsMode
+	search    textinput.Model
+	rename    textinput.Model
+	messages  []state.Message
+	message   int
+	notice    string
+	width     int
+	height    int
+	now       func() time.Time
+}
+
+func NewSessionsModal() *SessionsModal {
+	search := textinput.New()
+	search.Prompt = "search: "
+	search.Placeholder = "name, directory or message text"
+	search.Focus()
+	rename := textinput.New()
+	rename.Prompt = "name: "
+	rename.CharLimit = 80
+	rename.Focus()
+	return &SessionsModal{
+		search: search,
+		rename: rename,
+		width:  80,
+		height: 24,
+		now:    time.Now,
+	}
+}
+
+func (m *SessionsModal) SetSize(width, height int) {
+	if m == nil || width <= 0 || height <= 0 {
+		return
+	}
+	m.width = width
+	m.height = height
+	m.search.Width = max(width-24, 20)
+	m.rename.Width = max(width-24, 20)
+}
+
+// Open shows the browser with the given sessions and their searchable text.
+func (m *SessionsModal) Open(summaries []state.SessionSummary, text map[string]string) {
+	if m == nil {
+		return
+	}
+	m.Visible = true
+	m.mode = sessionsBrowse
+	m.search.SetValue("")
+	m.notice = ""
+	m.SetSessions(summaries, text)
+}
+
+// SetSessions replaces the list, keeping the selection on the same session
+// when it still exists.
+func (m *SessionsModal) SetSessions(summaries []state.SessionSummary, text map[string]string) {
+	if m == nil {
+		return
+	}
+	selectedID := m.selectedID()
+	m.rows = make([]sessionRow, 0, len(summaries))
+	for _, summary := range summaries {
+		m.rows = append(m.rows, sessionRow{summary: summary, text: text[summary.ID]})
+	}
+	m.applyFilter()
+	for idx, rowIdx := range m.filtered {
+		if m.rows[rowIdx].summary.ID == selectedID {
+			m.selected = idx
+		}
+	}
+}
+
+// ShowMessages opens the fork picker over a session's messages, with the
+// latest message selected.
+func (m *SessionsModal) ShowMessages(messages []state.Message) {
+	if m == nil {
+		return
+	}
+	if len(messages) == 0 {
+		m.notice = "Session has no messages to fork from."
+		return
+	}
+	m.messages = messages
+	m.message = len(messages) - 1
+	m.mode = sessionsPickMessage
+}
+
+func (m *SessionsModal) SetNotice(notice string) {
+	if m == nil {
+		return
+	}
+	m.notice = strings.TrimSpace(notice)
+}
+
+func (m *SessionsModal) Close() {
+	if m == nil {
+		return
+	}
+	m.Visible = false
+	m.mode = sessionsBrowse
+	m.rows = nil
+	m.filtered = nil
+	m.messages = nil
+	m.notice = ""
+}
+
+func (m *SessionsModal) Update(msg tea.Msg) (SessionsAction, tea.Cmd) {
+	if m == nil || !m.Visible {
+		return SessionsAction{}, nil
+	}
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return SessionsAction{}, nil
+	}
+
+	switch m.mode {
+	case sessionsRename:
+		switch key.String() {
+		case "enter":
+			m.mode = sessionsBrowse
+			return SessionsAction{Kind: SessionsActionRename, SessionID: m.selectedID(), Name: m.rename.Value()}, nil
+		case "esc":
+			m.mode = sessionsBrowse
+			return SessionsAction{}, nil
+		}
+		var cmd tea.Cmd
+		m.rename, cmd = m.rename.Update(msg)
+		return SessionsAction{}, cmd
+
+	case sessionsConfirmDelete:
+		m.mode = sessionsBrowse
+		if key.String() == "y" || key.String() == "Y" {
+			return SessionsAction{Kind: SessionsActionDelete, SessionID: m.selectedID()}, nil
+		}
+		m.notice = "Delete cancelled."
+		return SessionsAction{}, nil
+
+	case sessionsPickMessage:
+		switch key.String() {
+		case "up", "k", "ctrl+p":
+			m.message = max(m.message-1, 0)
+		case "down", "j", "ctrl+n":
+			m.message = min(m.message+1, len(m.messages)-1)
+		case "enter":
+			m.mode = sessionsBrowse
+			return SessionsAction{Kind: SessionsActionFork, SessionID: m.selectedID(), MessageID: m.messages[m.message].ID}, nil
+		case "esc":
+			m.mode = sessionsBrowse
+			m.messages = nil
+		}
+		return SessionsAction{}, nil
+	}
+
+	m.notice = ""
+	switch key.String() {
+	case "up", "ctrl+p":
+		m.move(-1)
+		return SessionsAction{}, nil
+	case "down", "ctrl+n":
+		m.move(1)
+		return SessionsAction{}, nil
+	case "esc":
+		m.Close()
+		return SessionsAction{}, nil
+	}
+
+	id := m.selectedID()
+	if id == "" {
+		var cmd tea.Cmd
+		m.search, cmd = m.search.Update(msg)
+		m.applyFilter()
+		return SessionsAction{}, cmd
+	}
+	switch key.String() {
+	case "enter":
+		if id == m.CurrentID {
+			m.notice = "Already in this session."
+			return SessionsAction{}, nil
+		}
+		return SessionsAction{Kind: SessionsActionResume, SessionID: id}, nil
+	case "ctrl+f":
+		return SessionsAction{Kind: SessionsActionLoadMessages, SessionID: id}, nil
+	case "ctrl+r":
+		m.rename.SetValue(m.rows[m.filtered[m.selected]].summary.Name)
+		m.rename.CursorEnd()
+		m.mode = sessionsRename
+		return SessionsAction{}, nil
+	case "ctrl+d":
+		if id == m.CurrentID {
+			m.notice = "The current session cannot be deleted."
+			return SessionsAction{}, nil
+		}
+		m.mode = sessionsConfirmDelete
+		return SessionsAction{}, nil
+	}
+	var cmd tea.Cmd
+	m.search, cmd = m.search.Update(msg)
+	m.applyFilter()
+	return SessionsAction{}, cmd
+}
+
+func (m *SessionsModal) View() string {
+	if m == nil || !m.Visible {
+		return ""
+	}
+	width := max(m.width-12, 40)
+	title := planModalTitleStyle.Render(fmt.Sprintf("Sessions  %d", len(m.rows)))
+
+	var body, hint string
+	switch m.mode {
+	case sessionsPickMessage:
+		title = planModalTitleStyle.Render("Fork from message")
+		body = m.renderMessages(width)
+		hint = "up/down: choose the last message to keep  enter: fork  esc: back"
+	default:
+		body = m.search.View() + "\n\n" + m.renderRows(width)
+		hint = "enter: resume  ctrl+f: fork  ctrl+r: rename  ctrl+d: delete  esc: close"
+		switch m.mode {
+		case sessionsRename:
+			body += "\n\n" + m.rename.View()
+			hint = "enter: save name (empty clears it)  esc: cancel"
+		case sessionsConfirmDelete:
+			body += "\n\n" + sessionsWarnStyle.Render(fmt.Sprintf("Delete session %s and all of its history? (y/n)", m.selectedLabel()))
+			hint = "y: delete  any other key: cancel"
+		}
+	}
+	if m.notice != "" {
+		hint = m.notice + "  |  " + hint
+	}
+	return planModalBoxStyle.Render(fmt.Sprintf("%s\n\n%s\n\n%s", title, planModalBodyStyle.Render(body), planModalHintStyle.Render(truncateRunes(hint, width))))
+}
+
+func (m *SessionsModal) selectedID() string {
+	if m.selected < 0 || m.selected >= len(m.filtered) {
+		return ""
+	}
+	return m.rows[m.filtered[m.selected]].summary.ID
+}
+
+func (m *SessionsModal) selectedLabel() string {
+	if m.selected < 0 || m.selected >= len(m.filtered) {
+		return ""
+	}
+	return sessionLabel(m.rows[m.filtered[m.selected]].summary)
+}
+
+func (m *SessionsModal) move(delta int) {
+	if len(m.filtered) == 0 {
+		return
+	}
+	m.selected = min(max(m.selected+delta, 0), len(m.filtered)-1)
+}
+
+// visibleRows is how many two-line entries fit in the modal.
+func (m *SessionsModal) visibleRows() int {
+	return max((m.height-16)/2, 3)
+}
+
+func (m *SessionsModal) renderRows(width int) string {
+	if len(m.rows) == 0 {
+		return sessionsMetaStyle.Render("No saved sessions.")
+	}
+	if len(m.filtered) == 0 {
+		return sessionsMetaStyle.Render("No sessions match.")
+	}
+	visible := m.visibleRows()
+	start := 0
+	if m.selected >= visible {
+		start = m.selected - visible + 1
+	}
+	end := min(start+visible, len(m.filtered))
+
+	now := m.now()
+	lines := make([]string, 0, (end-start)*2)
+	for idx := start; idx < end; idx++ {
+		row := m.rows[m.filtered[idx]]
+		s := row.summary
+		label := sessionLabel(s)
+		if s.ID == m.CurrentID {
+			label += " (current)"
+		}
+		meta := fmt.Sprintf("%s · %d msgs · %s", displaySessionDir(s.WorkingDir), s.MessageCount, formatSessionAge(now.Sub(s.LastActivity)))
+		marker, style := "  ", sessionsRowStyle
+		if idx == m.selected {
+			marker, style = "▶ ", sessionsSelectedStyle
+		}
+		lines = append(lines, style.Render(truncateRunes(marker+label, width/2))+"  "+sessionsMetaStyle.Render(truncateRunes(meta, max(width/2-2, 10))))
+
+		detail := row.snippet
+		if detail == "" {
+			detail = s.FirstPrompt
+		}
+		if detail == "" {
+			detail = "(no prompts yet)"
+		}
+		lines = append(lines, sessionsMetaStyle.Render(truncateRunes("    "+singleLine(detail), width)))
+	}
+	if len(m.filtered) > visible {
+		lines = append(lines, sessionsMetaStyle.Render(fmt.Sprintf("  %d of %d", m.selected+1, len(m.filtered))))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m *SessionsModal) renderMessages(width int) string {
+	visible := max(m.height-14, 5)
+	start := 0
+	if m.message >= visible {
+		start = m.message - visible + 1
+	}
+	end := min(start+visible, len(m.messages))
+	lines := make([]string, 0, end-start)
+	for idx := start; idx < end; idx++ {
+		msg := m.messages[idx]
+		who := msg.Role
+		if msg.AgentRole != "" {
+			who = msg.AgentRole
+		}
+		line := fmt.Sprintf("%3d %s: %s", idx+1, strings.ToLower(who), singleLine(msg.Content))
+		if idx == m.message {
+			lines = append(lines, sessionsSelectedStyle.Render(truncateRunes("▶ "+line, width)))
+		} else {
+			lines = append(lines, sessionsRowStyle.Render(truncateRunes("  "+line, width)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// applyFilter ranks sessions against the search query. Every term must
+// match the session's label, directory, first prompt or message text;
+// exact substrings outrank fuzzy subsequence matches. With no query the
+// order is the most recent activity first.
+func (m *SessionsModal) applyFilter() {
+	terms := strings.Fields(strings.ToLower(m.search.Value()))
+	m.filtered = m.filtered[:0]
+	for idx := range m.rows {
+		row := &m.rows[idx]
+		row.snippet, row.score = "", 0
+		if len(terms) == 0 {
+			m.filtered = append(m.filtered, idx)
+			continue
+		}
+		s := row.summary
+		header := strings.ToLower(strings.Join([]string{s.Name, s.ID, s.WorkingDir, s.FirstPrompt}, " "))
+		matched := true
+		for _, term := range terms {
+			score, snippet := matchSessionTerm(term, header, row.text)
+			if score == 0 {
+				matched = false
+				break
+			}
+			row.score += score
+			if row.snippet == "" {
+				row.snippet = snippet
+			}
+		}
+		if matched {
+			m.filtered = append(m.filtered, idx)
+		}
+	}
+	sort.SliceStable(m.filtered, func(i, j int) bool {
+		return m.rows[m.filtered[i]].score > m.rows[m.filtered[j]].score
+	})
+	m.selected = min(m.selected, max(len(m.filtered)-1, 0))
+}
+
+// matchSessionTerm scores one lower-cased search term, returning the
+// matching message line when the match came from message text.
+func matchSessionTerm(term, header, text string) (int, string) {
+	if strings.Contains(header, term) {
+		return 4, ""
+	}
+	lower := strings.ToLower(text)
+	if at := strings.Index(lower, term); at >= 0 {
+		return 3, lineAround(text, at)
+	}
+	if fuzzyMatch(term, header) {
+		return 2, ""
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if fuzzyMatch(term, strings.ToLower(line)) {
+			return 1, line
+		}
+	}
+	return 0, ""
+}
+
+// fuzzyMatch reports whether every rune of term appears in text in order.
+func fuzzyMatch(term, text string) bool {
+	rest := []rune(term)
+	for _, r := range text {
+		if len(rest) == 0 {
+			break
+		}
+		if r == rest[0] {
+			rest = rest[1:]
+		}
+	}
+	return len(rest) == 0
+}
+
+// lineAround returns the line of text containing byte offset at.
+func lineAround(text string, at int) string {
+	start := strings.LastIndex(text[:at], "\n") + 1
+	end := strings.Index(text[at:], "\n")
+	if end < 0 {
+		return text[start:]
+	}
+	return text[start : at+end]
+}
+
+func singleLine(text string) string {
+	return strings.Join(strings.FieldsFunc(text, unicode.IsSpace), " ")
+}
+
+func sessionLabel(s state.SessionSummary) string {
+	if name := strings.TrimSpace(s.Name); name != "" {
+		return name
+	}
+	if len(s.ID) > 8 {
+		return s.ID[:8]
+	}
+	return s.ID
+}
+
+func displaySessionDir(dir string) string {
+	dir = strings.TrimSpace(dir)
+	if dir == "" || dir == "." {
+		return "."
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return displayRepoPath(dir)
+}
+
+func formatSessionAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
@@ -0,0 +1,168 @@
+package tui
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/yubzen/orchestra/internal/state"
+)
+
+func typeSessionsQuery(modal *SessionsModal, query string) {
+	for _, r := range query {
+		_, _ = modal.Update(runeKey(r))
+	}
+}
+
+func testSessionsModal() *SessionsModal {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	modal := NewSessionsModal()
+	modal.now = func() time.Time { return now }
+	modal.SetSize(120, 40)
+	modal.CurrentID = "current0000"
+	modal.Open([]state.SessionSummary{
+		{Session: state.Session{ID: "current0000", WorkingDir: "/work"}, FirstPrompt: "add a cache", MessageCount: 2, LastActivity: now.Add(-time.Minute * 5)},
+		{Session: state.Session{ID: "login00000", WorkingDir: "/work", Name: "login fix"}, FirstPrompt: "fix login", MessageCount: 4, LastActivity: now.Add(-3 * time.Hour)},
+		{Session: state.Session{ID: "other00000", WorkingDir: "/work"}, FirstPrompt: "write docs", MessageCount: 1, LastActivity: now.Add(-50 * time.Hour)},
+	}, map[string]string{
+		"current0000": "add a cache\nuse an LRU",
+		"login00000":  "fix login\nthe session token expires too early",
+		"other00000":  "write docs",
+	})
+	return modal
+}
+
+func TestSessionsModalSearchesMessageContent(t *testing.T) {
+	t.Parallel()
+
+	modal := testSessionsModal()
+	view := modal.View()
+	for _, want := range []string{"login fix", "4 msgs", "3h ago", "2d ago", "(current)"} {
+		if !strings.Contains(view, want) {
+			t.Fatalf("expected %q in view:\n%s", want, view)
+		}
+	}
+
+	typeSessionsQuery(modal, "token expires")
+	if len(modal.filtered) != 1 || modal.selectedID() != "login00000" {
+		t.Fatalf("expected only the login session to match, got %v", modal.filtered)
+	}
+	if !strings.Contains(modal.View(), "the session token expires too early") {
+		t.Fatalf("expected the matching message as the snippet:\n%s", modal.View())
+	}
+
+	modal.search.SetValue("lru")
+	modal.applyFilter()
+	if modal.selectedID() != "current0000" {
+		t.Fatalf("expected a case-insensitive content match, got %q", modal.selectedID())
+	}
+
+	modal.search.SetValue("wdcs")
+	modal.applyFilter()
+	if modal.selectedID() != "other00000" {
+		t.Fatalf("expected a fuzzy match on the first prompt, got %q", modal.selectedID())
+	}
+}
+
+func TestSessionsModalActions(t *testing.T) {
+	t.Parallel()
+
+	modal := testSessionsModal()
+	action, _ := modal.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if action.Kind != SessionsActionNone || !strings.Contains(modal.View(), "Already in this session") {
+		t.Fatalf("expected resuming the current session to be refused, got %#v", action)
+	}
+	action, _ = modal.Update(tea.KeyMsg{Type: tea.KeyCtrlD})
+	if action.Kind != SessionsActionNone || !strings.Contains(modal.View(), "cannot be deleted") {
+		t.Fatalf("expected deleting the current session to be refused, got %#v", action)
+	}
+
+	_, _ = modal.Update(tea.KeyMsg{Type: tea.KeyDown})
+	action, _ = modal.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if action.Kind != SessionsActionResume || action.SessionID != "login00000" {
+		t.Fatalf("expected a resume of the login session, got %#v", action)
+	}
+
+	_, _ = modal.Update(tea.KeyMsg{Type: tea.KeyCtrlD})
+	action, _ = modal.Update(runeKey('n'))
+	if action.Kind != SessionsActionNone {
+		t.Fatalf("expected n to cancel the delete, got %#v", action)
+	}
+	_, _ = modal.Update(tea.KeyMsg{Type: tea.KeyCtrlD})
+	action, _ = modal.Update(runeKey('y'))
+	if action.Kind != SessionsActionDelete || action.SessionID != "login00000" {
+		t.Fatalf("expected a confirmed delete, got %#v", action)
+	}
+
+	_, _ = modal.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	for range "login fix" {
+		_, _ = modal.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	}
+	typeSessionsQuery(modal, "auth")
+	action, _ = modal.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if action.Kind != SessionsActionRename || action.Name != "auth" {
+		t.Fatalf("expected a rename to auth, got %#v", action)
+	}
+
+	action, _ = modal.Update(tea.KeyMsg{Type: tea.KeyCtrlF})
+	if action.Kind != SessionsActionLoadMessages || action.SessionID != "login00000" {
+		t.Fatalf("expected a message load for the fork picker, got %#v", action)
+	}
+	modal.ShowMessages([]state.Message{{ID: 7, Role: "user", Content: "fix login"}, {ID: 8, Role: "assistant", AgentRole: "coder", Content: "done"}})
+	_, _ = modal.Update(tea.KeyMsg{Type: tea.KeyUp})
+	action, _ = modal.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if action.Kind != SessionsActionFork || action.MessageID != 7 {
+		t.Fatalf("expected a fork through message 7, got %#v", action)
+	}
+}
+
+func TestResumeSessionSwitchesInPlace(t *testing.T) {
+	t.Parallel()
+
+	db, err := state.Connect(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("connect db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	current, err := db.CreateSession(ctx, ".", "orchestrated")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	previous, err := db.CreateSession(ctx, ".", "orchestrated")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	elsewhere, err := db.CreateSession(ctx, t.TempDir(), "orchestrated")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	if err := db.SaveMessage(ctx, previous.ID, "user", "", "fix login", 0); err != nil {
+		t.Fatalf("save message: %v", err)
+	}
+	if err := db.AppendSessionInputHistory(ctx, previous.ID, "fix login"); err != nil {
+		t.Fatalf("append history: %v", err)
+	}
+
+	app := NewAppModel(nil, db, current, nil)
+	if err := app.resumeSession(elsewhere.ID); err == nil || !strings.Contains(err.Error(), "orchestra -s "+elsewhere.ID) {
+		t.Fatalf("expected a restart hint for another directory, got %v", err)
+	}
+	if err := app.resumeSession(previous.ID); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if app.session.ID != previous.ID {
+		t.Fatalf("expected session %s, got %s", previous.ID, app.session.ID)
+	}
+	if len(app.chat.messages) != 2 || app.chat.messages[0].Content != "fix login" {
+		t.Fatalf("expected the transcript to be replayed, got %+v", app.chat.messages)
+	}
+	if len(app.inputHistory) != 1 || app.inputHistory[0] != "fix login" {
+		t.Fatalf("expected the resumed input history, got %v", app.inputHistory)
+	}
+}
@@ -3,6 +3,7 @@ package tui
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -12,20 +13,35 @@ import (
 )
 
 var (
-	sbBaseStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("255")).Background(lipgloss.Color("235")).Padding(0, 1)
-	sbRoleStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Bold(true)
-	sbModelStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("39"))
-	sbRepoStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("243"))
-	sbHintStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("220"))
-	sbStateBusyStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("45"))
-	sbStateIdleStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
-	sbStateErrStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true)
-	sbModelOffStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("220"))
-	sbCtxGreenStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
-	sbCtxYellowStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("220"))
-	sbCtxRedStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	sbBaseStyle      lipgloss.Style
+	sbRoleStyle      lipgloss.Style
+	sbModelStyle     lipgloss.Style
+	sbRepoStyle      lipgloss.Style
+	sbHintStyle      lipgloss.Style
+	sbStateBusyStyle lipgloss.Style
+	sbStateIdleStyle lipgloss.Style
+	sbStateErrStyle  lipgloss.Style
+	sbModelOffStyle  lipgloss.Style
+	sbCtxGreenStyle  lipgloss.Style
+	sbCtxYellowStyle lipgloss.Style
+	sbCtxRedStyle    lipgloss.Style
 )
 
+func applyStatusBarStyles(p Palette) {
+	sbBaseStyle = lipgloss.NewStyle().Foreground(p.Bright).Background(p.Surface).Padding(0, 1)
+	sbRoleStyle = lipgloss.NewStyle().Foreground(p.Role).Bold(true)
+	sbModelStyle = lipgloss.NewStyle().Foreground(p.Accent)
+	sbRepoStyle = lipgloss.NewStyle().Foreground(p.Muted)
+	sbHintStyle = lipgloss.NewStyle().Foreground(p.Warning)
+	sbStateBusyStyle = lipgloss.NewStyle().Foreground(p.Active)
+	sbStateIdleStyle = lipgloss.NewStyle().Foreground(p.Muted)
+	sbStateErrStyle = lipgloss.NewStyle().Foreground(p.Error).Bold(true)
+	sbModelOffStyle = lipgloss.NewStyle().Foreground(p.Warning)
+	sbCtxGreenStyle = lipgloss.NewStyle().Foreground(p.Success)
+	sbCtxYellowStyle = lipgloss.NewStyle().Foreground(p.Warning)
+	sbCtxRedStyle = lipgloss.NewStyle().Foreground(p.Error)
+}
+
 var trackedTeamRoles = []string{"PLANNER", "CODER", "REVIEWER"}
 
 type StatusBarModel struct {
@@ -33,7 +49,9 @@ type StatusBarModel struct {
 	CtxPercent    int
 	roleModels    map[string]string
 	roleStates    map[string]string
+	roleUsage     map[string]RoleUsage
 	repoPath      string
+	indexStatus   string
 	hint          string
 	width         int
 }
@@ -44,6 +62,7 @@ func NewStatusBarModel() *StatusBarModel {
 		CtxPercent:    0,
 		roleModels:    make(map[string]string),
 		roleStates:    make(map[string]string),
+		roleUsage:     make(map[string]RoleUsage),
 	}
 	for _, role := range trackedTeamRoles {
 		m.roleModels[role] = ""
@@ -99,6 +118,30 @@ func (m *StatusBarModel) SetRoleState(role, state string) {
 	m.roleStates[role] = strings.ToLower(strings.TrimSpace(state))
 }
 
+// SetRoleUsage shows a role's elapsed time and estimated token count for
+// the current run.
+func (m *StatusBarModel) SetRoleUsage(role string, usage RoleUsage) {
+	if m == nil {
+		return
+	}
+	role = strings.ToUpper(strings.TrimSpace(role))
+	if role == "" {
+		return
+	}
+	if m.roleUsage == nil {
+		m.roleUsage = make(map[string]RoleUsage)
+	}
+	m.roleUsage[role] = usage
+}
+
+// ResetRoleUsage clears the per-role usage shown for the previous run.
+func (m *StatusBarModel) ResetRoleUsage() {
+	if m == nil {
+		return
+	}
+	m.roleUsage = make(map[string]RoleUsage)
+}
+
 func (m *StatusBarModel) SetRepoPath(path string) {
 	if m == nil {
 		return
@@ -106,6 +149,14 @@ func (m *StatusBarModel) SetRepoPath(path string) {
 	m.repoPath = strings.TrimSpace(path)
 }
 
+// SetIndexStatus shows RAG indexing progress; an empty status hides it.
+func (m *StatusBarModel) SetIndexStatus(status string) {
+	if m == nil {
+		return
+	}
+	m.indexStatus = strings.TrimSpace(status)
+}
+
 func (m *StatusBarModel) SetHint(hint string) {
 	if m == nil {
 		return
@@ -143,6 +194,9 @@ func (m *StatusBarModel) View() string {
 	if repo != "" {
 		parts = append([]string{sbRepoStyle.Render(repo)}, parts...)
 	}
+	if m.indexStatus != "" {
+		parts = append(parts, sbRepoStyle.Render(fmt.Sprintf("[IDX: %s]", m.indexStatus)))
+	}
 	if hint := strings.TrimSpace(m.hint); hint != "" {
 		parts = append(parts, sbHintStyle.Render(truncateStatusValue(hint, 44)))
 	}
@@ -181,11 +235,15 @@ func (m *StatusBarModel) teamRoleView(role string) string {
 	case "error":
 		stateStyle = sbStateErrStyle
 	}
-	return fmt.Sprintf("%s %s %s",
+	view := fmt.Sprintf("%s %s %s",
 		sbRoleStyle.Render("["+label+"]"),
 		sbModelStyle.Render(truncateStatusValue(model, 28)),
 		stateStyle.Render("("+state+")"),
 	)
+	if usage, ok := m.roleUsage[strings.ToUpper(strings.TrimSpace(role))]; ok && (usage.Elapsed > 0 || usage.Tokens > 0) {
+		view += " " + sbRepoStyle.Render(fmt.Sprintf("%s %s", formatElapsed(usage.Elapsed.Round(time.Second)), formatTokenCount(usage.Tokens)))
+	}
+	return view
 }
 
 func truncateStatusValue(value string, maxRunes int) string {
@@ -0,0 +1,198 @@
+package tui
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Palette holds the colors every TUI style is built from. Each field is a
+// role rather than a hue so themes can remap them freely.
+type Palette struct {
+	Text       lipgloss.Color // body text
+	Bright     lipgloss.Color // emphasized text and cursors
+	Subtle     lipgloss.Color // suggestion list text
+	Muted      lipgloss.Color // hints and metadata
+	Faint      lipgloss.Color // system messages and disabled items
+	Surface    lipgloss.Color // modal and status bar background
+	SurfaceAlt lipgloss.Color // splash card background
+	Border     lipgloss.Color // separators and idle pane borders
+	Accent     lipgloss.Color // logo, model names, command names
+	Selected   lipgloss.Color // selected list entries
+	Title      lipgloss.Color // modal and pane titles
+	Active     lipgloss.Color // running activity
+	Focus      lipgloss.Color // focused pane and plan modal border
+	Prompt     lipgloss.Color // user input
+	Assistant  lipgloss.Color // agent replies
+	Role       lipgloss.Color // active role label
+	Success    lipgloss.Color
+	Warning    lipgloss.Color
+	Caution    lipgloss.Color
+	Error      lipgloss.Color
+	ErrorSoft  lipgloss.Color
+	Attachment lipgloss.Color // @mention attachments
+	Pick       lipgloss.Color // selected model title
+	PickDesc   lipgloss.Color // selected model description
+}
+
+// Theme is a named palette.
+type Theme struct {
+	Name    string
+	Palette Palette
+}
+
+const DefaultThemeName = "dark"
+
+var themes = map[string]Palette{
+	"dark": {
+		Text: "252", Bright: "255", Subtle: "250", Muted: "244", Faint: "240",
+		Surface: "235", SurfaceAlt: "236", Border: "238",
+		Accent: "39", Selected: "51", Title: "81", Active: "45", Focus: "75",
+		Prompt: "86", Assistant: "212", Role: "205",
+		Success: "42", Warning: "220", Caution: "214", Error: "196", ErrorSoft: "203",
+		Attachment: "110", Pick: "213", PickDesc: "183",
+	},
+	// light keeps dark text on pale surfaces for light terminal backgrounds.
+	"light": {
+		Text: "236", Bright: "232", Subtle: "238", Muted: "242", Faint: "245",
+		Surface: "254", SurfaceAlt: "255", Border: "250",
+		Accent: "25", Selected: "27", Title: "24", Active: "30", Focus: "26",
+		Prompt: "28", Assistant: "126", Role: "161",
+		Success: "28", Warning: "130", Caution: "166", Error: "160", ErrorSoft: "124",
+		Attachment: "31", Pick: "90", PickDesc: "96",
+	},
+	// high-contrast uses the basic 16 colors on black, which every terminal
+	// renders at full intensity.
+	"high-contrast": {
+		Text: "15", Bright: "15", Subtle: "15", Muted: "250", Faint: "248",
+		Surface: "0", SurfaceAlt: "0", Border: "15",
+		Accent: "14", Selected: "11", Title: "14", Active: "14", Focus: "11",
+		Prompt: "10", Assistant: "13", Role: "13",
+		Success: "10", Warning: "11", Caution: "11", Error: "9", ErrorSoft: "9",
+		Attachment: "14", Pick: "11", PickDesc: "15",
+	},
+}
+
+// activePalette is the palette the current styles were built from.
+var activePalette Palette
+
+func init() {
+	ApplyTheme(Theme{Name: DefaultThemeName, Palette: themes[DefaultThemeName]})
+}
+
+// ThemeNames lists the built-in themes.
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var hexColorPattern = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// LoadTheme returns the named theme with colors overridden per palette slot,
+// for example {"accent": "#ff8800", "surface": "0"}. Slot names are the
+// Palette fields in snake_case. An unknown theme falls back to dark; bad
+// overrides are skipped. Both are reported in the error.
+func LoadTheme(name string, colors map[string]string) (Theme, error) {
+	var problems []string
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = DefaultThemeName
+	}
+	palette, ok := themes[name]
+	if !ok {
+		problems = append(problems, fmt.Sprintf("unknown theme %q (available: %s)", name, strings.Join(ThemeNames(), ", ")))
+		name = DefaultThemeName
+		palette = themes[name]
+	}
+
+	slots := paletteSlots(&palette)
+	keys := make([]string, 0, len(colors))
+	for key := range colors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		slot, ok := slots[strings.ToLower(strings.TrimSpace(key))]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown theme color %q", key))
+			continue
+		}
+		value := strings.TrimSpace(colors[key])
+		if !validColor(value) {
+			problems = append(problems, fmt.Sprintf("invalid color %q for %s: use 0-255 or #rrggbb", value, key))
+			continue
+		}
+		*slot = lipgloss.Color(value)
+	}
+
+	theme := Theme{Name: name, Palette: palette}
+	if len(problems) > 0 {
+		return theme, fmt.Errorf("tui theme: %s", strings.Join(problems, "; "))
+	}
+	return theme, nil
+}
+
+func validColor(value string) bool {
+	if hexColorPattern.MatchString(value) {
+		return true
+	}
+	n, err := strconv.Atoi(value)
+	return err == nil && n >= 0 && n <= 255
+}
+
+func paletteSlots(p *Palette) map[string]*lipgloss.Color {
+	return map[string]*lipgloss.Color{
+		"text":        &p.Text,
+		"bright":      &p.Bright,
+		"subtle":      &p.Subtle,
+		"muted":       &p.Muted,
+		"faint":       &p.Faint,
+		"surface":     &p.Surface,
+		"surface_alt": &p.SurfaceAlt,
+		"border":      &p.Border,
+		"accent":      &p.Accent,
+		"selected":    &p.Selected,
+		"title":       &p.Title,
+		"active":      &p.Active,
+		"focus":       &p.Focus,
+		"prompt":      &p.Prompt,
+		"assistant":   &p.Assistant,
+		"role":        &p.Role,
+		"success":     &p.Success,
+		"warning":     &p.Warning,
+		"caution":     &p.Caution,
+		"error":       &p.Error,
+		"error_soft":  &p.ErrorSoft,
+		"attachment":  &p.Attachment,
+		"pick":        &p.Pick,
+		"pick_desc":   &p.PickDesc,
+	}
+}
+
+// ApplyTheme rebuilds every style from theme. Call it before the program
+// starts; models built afterwards pick up the new colors.
+func ApplyTheme(theme Theme) {
+	p := theme.Palette
+	activePalette = p
+	// The plan modal background is shared by the other modals' styles.
+	applyPlanModalStyles(p)
+	applyChatStyles(p)
+	applyConnectStyles(p)
+	applyDiffReviewStyles(p)
+	applyMentionStyles(p)
+	applyModelsModalStyles(p)
+	applyPaneStyles(p)
+	applyPlanEditorStyles(p)
+	applySessionsStyles(p)
+	applyStatusBarStyles(p)
+	applyHelpStyles(p)
+	applyTraceStyles(p)
+}
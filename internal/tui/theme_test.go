@@ -0,0 +1,33 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestLoadThemeAppliesColorOverrides(t *testing.T) {
+	t.Parallel()
+
+	theme, err := LoadTheme("Light", map[string]string{"accent": "#ff8800", "surface_alt": "231"})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if theme.Name != "light" || theme.Palette.Accent != lipgloss.Color("#ff8800") || theme.Palette.SurfaceAlt != lipgloss.Color("231") {
+		t.Fatalf("unexpected theme: %+v", theme)
+	}
+	if theme.Palette.Text != themes["light"].Text {
+		t.Fatalf("expected untouched slots to keep the light palette, got %q", theme.Palette.Text)
+	}
+
+	theme, err = LoadTheme("solarized", map[string]string{"accent": "blue", "glow": "1"})
+	if theme.Name != DefaultThemeName {
+		t.Fatalf("expected an unknown theme to fall back to %s, got %s", DefaultThemeName, theme.Name)
+	}
+	for _, want := range []string{`unknown theme "solarized"`, `invalid color "blue"`, `unknown theme color "glow"`} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in the error, got %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,539 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/yubzen/orchestra/internal/state"
+)
+
+var (
+	traceSelectedStyle lipgloss.Style
+	traceRowStyle      lipgloss.Style
+	traceMetaStyle     lipgloss.Style
+	traceOKStyle       lipgloss.Style
+	traceErrorStyle    lipgloss.Style
+	traceRunningStyle  lipgloss.Style
+	traceLabelStyle    lipgloss.Style
+)
+
+func applyTraceStyles(p Palette) {
+	traceSelectedStyle = lipgloss.NewStyle().Foreground(p.Title).Background(planModalBG).Bold(true)
+	traceRowStyle = lipgloss.NewStyle().Foreground(p.Text).Background(planModalBG)
+	traceMetaStyle = lipgloss.NewStyle().Foreground(p.Muted).Background(planModalBG)
+	traceOKStyle = lipgloss.NewStyle().Foreground(p.Success).Background(planModalBG)
+	traceErrorStyle = lipgloss.NewStyle().Foreground(p.Error).Background(planModalBG)
+	traceRunningStyle = lipgloss.NewStyle().Foreground(p.Active).Background(planModalBG)
+	traceLabelStyle = lipgloss.NewStyle().Foreground(p.Accent).Background(planModalBG).Bold(true)
+}
+
+type TraceActionKind int
+
+const (
+	TraceActionNone TraceActionKind = iota
+	TraceActionLoadRun
+)
+
+// TraceAction asks the app to load the spans of one run into the modal.
+type TraceAction struct {
+	Kind  TraceActionKind
+	RunID string
+}
+
+type traceMode int
+
+const (
+	traceRuns traceMode = iota
+	traceTree
+	traceDetail
+)
+
+type traceRow struct {
+	span  int
+	depth int
+}
+
+// TraceModal is the run timeline: a list of a session's runs, the span tree
+// of the chosen run, and the full input and output of any span.
+type TraceModal struct {
+	Visible      bool
+	SessionLabel string
+	mode         traceMode
+	runs         []state.TraceSpan
+	run          int
+	runID        string
+	spans        []state.TraceSpan
+	children     map[int64][]int
+	expanded     map[int64]bool
+	rows         []traceRow
+	selected     int
+	detail       []string
+	offset       int
+	notice       string
+	width        int
+	height       int
+	now          func() time.Time
+}
+
+func NewTraceModal() *TraceModal {
+	return &TraceModal{
+		width:  80,
+		height: 24,
+		now:    time.Now,
+	}
+}
+
+func (m *TraceModal) SetSize(width, height int) {
+	if m == nil || width <= 0 || height <= 0 {
+		return
+	}
+	m.width = width
+	m.height = height
+	if m.mode == traceDetail {
+		m.buildDetail()
+	}
+}
+
+// Open shows the run list for a session, newest run first.
+func (m *TraceModal) Open(sessionLabel string, runs []state.TraceSpan) {
+	if m == nil {
+		return
+	}
+	m.Visible = true
+	m.SessionLabel = sessionLabel
+	m.mode = traceRuns
+	m.run = 0
+	m.runID = ""
+	m.spans = nil
+	m.rows = nil
+	m.expanded = nil
+	m.notice = ""
+	m.runs = runs
+}
+
+// SetRuns refreshes the run list, keeping the selected run.
+func (m *TraceModal) SetRuns(runs []state.TraceSpan) {
+	if m == nil {
+		return
+	}
+	selected := ""
+	if m.run < len(m.runs) {
+		selected = m.runs[m.run].RunID
+	}
+	m.runs = runs
+	m.run = 0
+	for idx, run := range runs {
+		if run.RunID == selected {
+			m.run = idx
+		}
+	}
+}
+
+// ShowRun switches to the span tree of runID. Called again for the same run
+// it refreshes the tree in place, keeping expansion and selection, so a
+// live run can be watched as it grows.
+func (m *TraceModal) ShowRun(runID string, spans []state.TraceSpan) {
+	if m == nil {
+		return
+	}
+	selectedID := m.selectedSpanID()
+	if runID != m.runID || m.expanded == nil {
+		m.expanded = make(map[int64]bool)
+		selectedID = 0
+	}
+	m.runID = runID
+	m.spans = spans
+	m.children = make(map[int64][]int, len(spans))
+	known := make(map[int64]bool, len(spans))
+	for _, span := range spans {
+		known[span.ID] = true
+	}
+	for idx, span := range spans {
+		parent := span.ParentID
+		if !known[parent] {
+			parent = 0
+		}
+		m.children[parent] = append(m.children[parent], idx)
+		// Runs, tasks and failed attempts start open so a retry loop is
+		// visible without digging.
+		if _, seen := m.expanded[span.ID]; !seen {
+			m.expanded[span.ID] = span.Kind == state.SpanKindRun || span.Kind == state.SpanKindTask ||
+				(span.Kind == state.SpanKindAttempt && span.Status == state.SpanStatusError)
+		}
+	}
+	if m.mode != traceDetail {
+		m.mode = traceTree
+	}
+	m.flatten()
+	m.selected = 0
+	for idx, row := range m.rows {
+		if m.spans[row.span].ID == selectedID {
+			m.selected = idx
+		}
+	}
+	if m.mode == traceDetail {
+		m.buildDetail()
+	}
+}
+
+// RunID is the run whose tree is showing, or "" on the run list.
+func (m *TraceModal) RunID() string {
+	if m == nil || m.mode == traceRuns {
+		return ""
+	}
+	return m.runID
+}
+
+func (m *TraceModal) SetNotice(notice string) {
+	if m == nil {
+		return
+	}
+	m.notice = strings.TrimSpace(notice)
+}
+
+func (m *TraceModal) Close() {
+	if m == nil {
+		return
+	}
+	m.Visible = false
+	m.mode = traceRuns
+	m.runs = nil
+	m.spans = nil
+	m.rows = nil
+	m.detail = nil
+	m.expanded = nil
+	m.runID = ""
+	m.notice = ""
+}
+
+func (m *TraceModal) Update(msg tea.Msg) (TraceAction, tea.Cmd) {
+	if m == nil || !m.Visible {
+		return TraceAction{}, nil
+	}
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return TraceAction{}, nil
+	}
+	m.notice = ""
+
+	switch m.mode {
+	case traceDetail:
+		switch key.String() {
+		case "up", "k":
+			m.scrollDetail(-1)
+		case "down", "j":
+			m.scrollDetail(1)
+		case "pgup":
+			m.scrollDetail(-m.visibleLines())
+		case "pgdown", " ":
+			m.scrollDetail(m.visibleLines())
+		case "esc", "enter", "q":
+			m.mode = traceTree
+			m.detail = nil
+		}
+		return TraceAction{}, nil
+
+	case traceTree:
+		switch key.String() {
+		case "up", "k":
+			m.selected = max(m.selected-1, 0)
+		case "down", "j":
+			m.selected = min(m.selected+1, max(len(m.rows)-1, 0))
+		case "right", "l":
+			m.setExpanded(true)
+		case " ":
+			m.setExpanded(!m.expanded[m.selectedSpanID()])
+		case "left", "h":
+			m.collapseSelected()
+		case "enter":
+			if len(m.rows) > 0 {
+				m.mode = traceDetail
+				m.offset = 0
+				m.buildDetail()
+			}
+		case "esc":
+			m.mode = traceRuns
+			m.runID = ""
+		}
+		return TraceAction{}, nil
+	}
+
+	switch key.String() {
+	case "up", "k":
+		m.run = max(m.run-1, 0)
+	case "down", "j":
+		m.run = min(m.run+1, max(len(m.runs)-1, 0))
+	case "enter":
+		if m.run < len(m.runs) {
+			return TraceAction{Kind: TraceActionLoadRun, RunID: m.runs[m.run].RunID}, nil
+		}
+	case "esc", "q":
+		m.Close()
+	}
+	return TraceAction{}, nil
+}
+
+func (m *TraceModal) setExpanded(open bool) {
+	if id := m.selectedSpanID(); id != 0 && len(m.children[id]) > 0 {
+		m.expanded[id] = open
+		m.flatten()
+	}
+}
+
+// collapseSelected closes the selected node, or moves to its parent when it
+// is already closed.
+func (m *TraceModal) collapseSelected() {
+	id := m.selectedSpanID()
+	if id == 0 {
+		return
+	}
+	if m.expanded[id] && len(m.children[id]) > 0 {
+		m.expanded[id] = false
+		m.flatten()
+		return
+	}
+	parent := m.spans[m.rows[m.selected].span].ParentID
+	for idx, row := range m.rows {
+		if m.spans[row.span].ID == parent {
+			m.selected = idx
+			return
+		}
+	}
+}
+
+// flatten lists the visible nodes depth first.
+func (m *TraceModal) flatten() {
+	m.rows = m.rows[:0]
+	var walk func(parent int64, depth int)
+	walk = func(parent int64, depth int) {
+		for _, idx := range m.children[parent] {
+			m.rows = append(m.rows, traceRow{span: idx, depth: depth})
+			if id := m.spans[idx].ID; m.expanded[id] {
+				walk(id, depth+1)
+			}
+		}
+	}
+	walk(0, 0)
+	m.selected = min(m.selected, max(len(m.rows)-1, 0))
+}
+
+func (m *TraceModal) selectedSpanID() int64 {
+	if m == nil || m.selected < 0 || m.selected >= len(m.rows) {
+		return 0
+	}
+	return m.spans[m.rows[m.selected].span].ID
+}
+
+func (m *TraceModal) visibleLines() int {
+	return max(m.height-12, 5)
+}
+
+func (m *TraceModal) scrollDetail(delta int) {
+	m.offset = min(max(m.offset+delta, 0), max(len(m.detail)-m.visibleLines(), 0))
+}
+
+func (m *TraceModal) contentWidth() int {
+	return max(m.width-12, 40)
+}
+
+// buildDetail renders the selected span's metadata, input, output and
+// error as wrapped lines.
+func (m *TraceModal) buildDetail() {
+	if m.selected >= len(m.rows) {
+		m.detail = nil
+		return
+	}
+	span := m.spans[m.rows[m.selected].span]
+	width := m.contentWidth()
+	lines := []string{
+		traceLabelStyle.Render(span.Kind) + " " + traceRowStyle.Render(span.Name),
+		traceMetaStyle.Render(traceSpanMeta(span, m.now())),
+	}
+	section := func(label, text string) {
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		lines = append(lines, "", traceLabelStyle.Render(label))
+		lines = append(lines, strings.Split(wrapToWidth(text, width), "\n")...)
+	}
+	section(traceInputLabel(span.Kind), span.Input)
+	section(traceOutputLabel(span.Kind), span.Output)
+	section("Error", span.Error)
+	m.detail = lines
+	m.offset = min(m.offset, max(len(lines)-m.visibleLines(), 0))
+}
+
+func (m *TraceModal) View() string {
+	if m == nil || !m.Visible {
+		return ""
+	}
+	width := m.contentWidth()
+	var title, body, hint string
+	switch m.mode {
+	case traceDetail:
+		title = "Trace span"
+		end := min(m.offset+m.visibleLines(), len(m.detail))
+		body = strings.Join(m.detail[m.offset:end], "\n")
+		hint = "esc: back"
+		if len(m.detail) > m.visibleLines() {
+			hint = fmt.Sprintf("up/down/pgup/pgdown: scroll (%d-%d of %d)  %s", m.offset+1, end, len(m.detail), hint)
+		}
+	case traceTree:
+		title = "Trace  " + m.runID
+		body = m.renderTree(width)
+		hint = "right/space: expand  left: collapse  enter: inspect  esc: runs"
+	default:
+		title = "Runs  " + m.SessionLabel
+		body = m.renderRuns(width)
+		hint = "enter: open timeline  esc: close"
+	}
+	if m.notice != "" {
+		hint = m.notice + "  |  " + hint
+	}
+	return planModalBoxStyle.Render(fmt.Sprintf("%s\n\n%s\n\n%s",
+		planModalTitleStyle.Render(title),
+		planModalBodyStyle.Render(body),
+		planModalHintStyle.Render(truncateRunes(hint, width))))
+}
+
+func (m *TraceModal) renderRuns(width int) string {
+	if len(m.runs) == 0 {
+		return traceMetaStyle.Render("No traced runs in this session.")
+	}
+	visible := m.visibleLines()
+	start := 0
+	if m.run >= visible {
+		start = m.run - visible + 1
+	}
+	end := min(start+visible, len(m.runs))
+	now := m.now()
+	lines := make([]string, 0, end-start)
+	for idx := start; idx < end; idx++ {
+		run := m.runs[idx]
+		marker, style := "  ", traceRowStyle
+		if idx == m.run {
+			marker, style = "▶ ", traceSelectedStyle
+		}
+		meta := fmt.Sprintf("%s · %s", formatSessionAge(now.Sub(run.StartedAt)), traceDuration(run, now))
+		lines = append(lines, traceStatusIcon(run.Status)+" "+
+			style.Render(truncateRunes(marker+run.Name, max(width-len(meta)-6, 10)))+"  "+traceMetaStyle.Render(meta))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m *TraceModal) renderTree(width int) string {
+	if len(m.rows) == 0 {
+		return traceMetaStyle.Render("No spans recorded for this run.")
+	}
+	visible := m.visibleLines()
+	start := 0
+	if m.selected >= visible {
+		start = m.selected - visible + 1
+	}
+	end := min(start+visible, len(m.rows))
+	now := m.now()
+	lines := make([]string, 0, end-start+1)
+	for idx := start; idx < end; idx++ {
+		row := m.rows[idx]
+		span := m.spans[row.span]
+		fold := "  "
+		if len(m.children[span.ID]) > 0 {
+			fold = "▸ "
+			if m.expanded[span.ID] {
+				fold = "▾ "
+			}
+		}
+		label := strings.Repeat("  ", row.depth) + fold + span.Kind + "  " + span.Name
+		if span.Role != "" && span.Kind != state.SpanKindRun {
+			label += " (" + span.Role + ")"
+		}
+		meta := traceDuration(span, now)
+		if span.Error != "" {
+			meta += " · " + singleLine(span.Error)
+		}
+		style := traceRowStyle
+		if idx == m.selected {
+			style = traceSelectedStyle
+		}
+		labelWidth := min(lipgloss.Width(label), max(width*3/5, 20))
+		lines = append(lines, traceStatusIcon(span.Status)+" "+
+			style.Render(truncateRunes(label, labelWidth))+"  "+
+			traceMetaStyle.Render(truncateRunes(meta, max(width-labelWidth-4, 8))))
+	}
+	if len(m.rows) > visible {
+		lines = append(lines, traceMetaStyle.Render(fmt.Sprintf("  %d of %d", m.selected+1, len(m.rows))))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func traceStatusIcon(status string) string {
+	switch status {
+	case state.SpanStatusOK:
+		return traceOKStyle.Render("✓")
+	case state.SpanStatusError:
+		return traceErrorStyle.Render("✗")
+	case state.SpanStatusCancelled:
+		return traceMetaStyle.Render("⊘")
+	default:
+		return traceRunningStyle.Render("…")
+	}
+}
+
+// traceDuration is a span's duration, or its age so far while it runs.
+func traceDuration(span state.TraceSpan, now time.Time) string {
+	if span.Status == state.SpanStatusRunning {
+		return "running " + formatTraceDuration(now.Sub(span.StartedAt))
+	}
+	return formatTraceDuration(span.Duration)
+}
+
+func formatTraceDuration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	case d < time.Minute:
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	default:
+		return d.Truncate(time.Second).String()
+	}
+}
+
+func traceSpanMeta(span state.TraceSpan, now time.Time) string {
+	parts := []string{span.Status, traceDuration(span, now), "started " + span.StartedAt.Local().Format("15:04:05")}
+	if span.Role != "" {
+		parts = append(parts, "role "+span.Role)
+	}
+	if span.TaskID != "" {
+		parts = append(parts, "task "+span.TaskID)
+	}
+	if span.Attempt > 0 {
+		parts = append(parts, fmt.Sprintf("attempt %d", span.Attempt))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func traceInputLabel(kind string) string {
+	switch kind {
+	case state.SpanKindLLM:
+		return "Prompt"
+	case state.SpanKindTool:
+		return "Arguments"
+	default:
+		return "Input"
+	}
+}
+
+func traceOutputLabel(kind string) string {
+	switch kind {
+	case state.SpanKindLLM:
+		return "Response"
+	case state.SpanKindTool:
+		return "Result"
+	default:
+		return "Output"
+	}
+}
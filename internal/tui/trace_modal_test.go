@@ -0,0 +1,105 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/yubzen/orchestra/internal/state"
+)
+
+func testTraceSpans(started time.Time) []state.TraceSpan {
+	span := func(id, parent int64, kind, name, status string) state.TraceSpan {
+		return state.TraceSpan{ID: id, ParentID: parent, RunID: "run_1", Kind: kind, Name: name, Status: status, StartedAt: started, Duration: 1500 * time.Millisecond}
+	}
+	attempt1 := span(3, 2, state.SpanKindAttempt, "attempt 1/3", state.SpanStatusError)
+	attempt1.Error = "coder did not read the plan file .orchestra/plans/p1.md"
+	llm := span(4, 3, state.SpanKindLLM, "test-model call 1", state.SpanStatusOK)
+	llm.Input = "[system]\nYou are the coder.\n\n[user]\nImplement task-1"
+	llm.Output = "-> write_file {\"path\":\"a.go\"}"
+	tool := span(5, 4, state.SpanKindTool, "write_file", state.SpanStatusOK)
+	tool.Input = `{"path":"a.go"}`
+	tool.Output = "wrote a.go"
+	return []state.TraceSpan{
+		span(1, 0, state.SpanKindRun, "add a cache", state.SpanStatusRunning),
+		span(2, 1, state.SpanKindTask, "task-1: add a cache", state.SpanStatusRunning),
+		attempt1,
+		llm,
+		tool,
+		span(6, 2, state.SpanKindAttempt, "attempt 2/3", state.SpanStatusRunning),
+	}
+}
+
+func TestTraceModalExpandsSpansAndShowsDetail(t *testing.T) {
+	t.Parallel()
+
+	started := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	spans := testTraceSpans(started)
+	modal := NewTraceModal()
+	modal.now = func() time.Time { return started.Add(time.Minute) }
+	modal.SetSize(140, 40)
+	modal.Open("current", []state.TraceSpan{spans[0]})
+
+	if view := modal.View(); !strings.Contains(view, "add a cache") || !strings.Contains(view, "running 1m0s") {
+		t.Fatalf("expected the run list with a live duration:\n%s", view)
+	}
+	action, _ := modal.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if action.Kind != TraceActionLoadRun || action.RunID != "run_1" {
+		t.Fatalf("expected enter to request the run, got %+v", action)
+	}
+	modal.ShowRun("run_1", spans)
+
+	// The run, task and failed attempt start open; the LLM call does not.
+	view := modal.View()
+	for _, want := range []string{"attempt 1/3", "coder did not read the plan file", "test-model call 1", "attempt 2/3"} {
+		if !strings.Contains(view, want) {
+			t.Fatalf("expected %q in tree:\n%s", want, view)
+		}
+	}
+	if strings.Contains(view, "write_file") {
+		t.Fatalf("expected the tool call to stay folded:\n%s", view)
+	}
+
+	for range 3 {
+		_, _ = modal.Update(tea.KeyMsg{Type: tea.KeyDown})
+	}
+	if modal.selectedSpanID() != 4 {
+		t.Fatalf("expected the LLM span selected, got %d", modal.selectedSpanID())
+	}
+	_, _ = modal.Update(tea.KeyMsg{Type: tea.KeyRight})
+	if !strings.Contains(modal.View(), "write_file") {
+		t.Fatalf("expected the tool call after expanding:\n%s", modal.View())
+	}
+
+	_, _ = modal.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	view = modal.View()
+	for _, want := range []string{"Prompt", "Implement task-1", "Response", "-> write_file"} {
+		if !strings.Contains(view, want) {
+			t.Fatalf("expected %q in span detail:\n%s", want, view)
+		}
+	}
+
+	// A live refresh keeps the selection and the expanded nodes.
+	_, _ = modal.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	spans[5].Status = state.SpanStatusOK
+	modal.ShowRun("run_1", spans)
+	if modal.selectedSpanID() != 4 || !strings.Contains(modal.View(), "write_file") {
+		t.Fatalf("expected refresh to keep selection and expansion:\n%s", modal.View())
+	}
+
+	_, _ = modal.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	_, _ = modal.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	if modal.selectedSpanID() != 3 {
+		t.Fatalf("expected left on a folded node to move to its parent, got %d", modal.selectedSpanID())
+	}
+	_, _ = modal.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if modal.RunID() != "" || !modal.Visible {
+		t.Fatal("expected esc to go back to the run list")
+	}
+	_, _ = modal.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if modal.Visible {
+		t.Fatal("expected esc on the run list to close the modal")
+	}
+}